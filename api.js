@@ -1,6 +1,36 @@
 // Configurar axios para incluir credenciales
 axios.defaults.withCredentials = true;
 
+// Renovación de la sesión en curso (compartida por las peticiones que fallen a la vez)
+let refreshPromise = null;
+
+// Si el token de acceso ha caducado, renovar la sesión con el refresh token y repetir la petición
+axios.interceptors.response.use(
+  response => response,
+  async error => {
+    const original = error.config;
+    const skipRefresh = !original || original._retry ||
+      /\/auth\/(login|logout|refresh)$/.test(original.url || '');
+
+    if (!error.response || error.response.status !== 401 || skipRefresh) {
+      return Promise.reject(error);
+    }
+
+    original._retry = true;
+    try {
+      if (!refreshPromise) {
+        refreshPromise = axios.post(`${API_AUTH_URL}/refresh`).finally(() => {
+          refreshPromise = null;
+        });
+      }
+      await refreshPromise;
+      return axios(original);
+    } catch (refreshError) {
+      return Promise.reject(error);
+    }
+  }
+);
+
 /**
  * Cliente API para realizar peticiones al backend
  */
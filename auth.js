@@ -29,7 +29,8 @@ const authService = {
    * Inicia sesión en el sistema
    * @param {string} username - Nombre de usuario
    * @param {string} password - Contraseña
-   * @returns {Promise<Object>} Datos del usuario
+   * @returns {Promise<Object>} Datos del usuario, o la respuesta con challenge_token si falta
+   * la verificación en dos pasos (two_factor_required o two_factor_setup_required)
    */
   async login(username, password) {
     try {
@@ -39,6 +40,11 @@ const authService = {
         password
       });
 
+      if (data && data.challenge_token) {
+        console.log("Se requiere la verificación en dos pasos para:", username);
+        return data;
+      }
+
       if (data && data.user) {
         this.currentUser = data.user;
       } else {
@@ -53,6 +59,49 @@ const authService = {
     }
   },
 
+  /**
+   * Completa el login con el código de la aplicación de autenticación o un código de recuperación
+   * @param {string} challengeToken - Token devuelto por login()
+   * @param {string} code - Código de 6 dígitos o código de recuperación
+   * @returns {Promise<Object>} Datos del usuario
+   */
+  async verifyTwoFactor(challengeToken, code) {
+    try {
+      const data = await apiClient.post(`${API_AUTH_URL}/login/2fa`, {
+        challenge_token: challengeToken,
+        code
+      });
+
+      this.currentUser = data && data.user ? data.user : await this.getUserInfo();
+      return this.currentUser;
+    } catch (error) {
+      console.error("Error en la verificación en dos pasos:", error);
+      throw error;
+    }
+  },
+
+  /**
+   * Obtiene los métodos de inicio de sesión disponibles (contraseña y OIDC)
+   * @returns {Promise<Object>} Métodos disponibles
+   */
+  async getProviders() {
+    try {
+      return await apiClient.get(`${API_AUTH_URL}/providers`);
+    } catch (error) {
+      console.error("Error al obtener los métodos de inicio de sesión:", error);
+      return { local: "all", oidc: { enabled: false } };
+    }
+  },
+
+  /**
+   * Inicia sesión con el proveedor de identidad de la empresa (redirige el navegador)
+   * @param {string} [returnTo] - Página a la que volver tras el inicio de sesión
+   */
+  loginWithSSO(returnTo = window.location.href) {
+    const params = new URLSearchParams({ return_to: returnTo });
+    window.location.href = `${API_AUTH_URL}/oidc/login?${params.toString()}`;
+  },
+
   /**
    * Obtiene información del usuario actual
    * @returns {Promise<Object|null>} Datos del usuario
@@ -70,18 +119,14 @@ const authService = {
   },
 
   /**
-   * Registra un nuevo usuario
-   * @param {string} username - Nombre de usuario
-   * @param {string} password - Contraseña
-   * @returns {Promise<Object>} Resultado del registro
+   * Solicita el autorregistro: si el dominio del email está permitido se envía una invitación
+   * @param {string} email - Email del usuario
+   * @returns {Promise<Object>} Resultado de la solicitud
    */
-  async register(username, password) {
+  async register(email) {
     try {
-      console.log(`Intentando registrar usuario: ${username}`);
-      const data = await apiClient.post(`${API_AUTH_URL}/register`, {
-        username,
-        password
-      });
+      console.log(`Solicitando autorregistro para: ${email}`);
+      const data = await apiClient.post(`${API_AUTH_URL}/register`, { email });
       
       console.log("Respuesta de registro:", data);
       return data;
@@ -91,6 +136,67 @@ const authService = {
     }
   },
 
+  /**
+   * Obtiene el email y el rol de una invitación pendiente
+   * @param {string} token - Token del enlace de invitación (?invite=)
+   * @returns {Promise<Object>} Email, rol y caducidad
+   */
+  async getInvitation(token) {
+    return await apiClient.get(`${API_AUTH_URL}/invitations/${encodeURIComponent(token)}`);
+  },
+
+  /**
+   * Acepta una invitación y crea la cuenta
+   * @param {string} token - Token del enlace de invitación
+   * @param {string} username - Nombre de usuario elegido
+   * @param {string} password - Contraseña
+   * @returns {Promise<Object>} Usuario creado
+   */
+  async acceptInvitation(token, username, password) {
+    try {
+      return await apiClient.post(`${API_AUTH_URL}/invitations/accept`, {
+        token,
+        username,
+        password
+      });
+    } catch (error) {
+      console.error("Error al aceptar la invitación:", error);
+      throw error;
+    }
+  },
+
+  /**
+   * Solicita por correo un enlace para restablecer la contraseña
+   * @param {string} email - Email de la cuenta
+   * @returns {Promise<Object>} Resultado de la solicitud
+   */
+  async requestPasswordReset(email) {
+    try {
+      return await apiClient.post(`${API_AUTH_URL}/password-reset`, { email });
+    } catch (error) {
+      console.error("Error al solicitar el restablecimiento de contraseña:", error);
+      throw error;
+    }
+  },
+
+  /**
+   * Restablece la contraseña con el token del enlace (cierra todas las sesiones)
+   * @param {string} token - Token del enlace (?reset_token=)
+   * @param {string} newPassword - Nueva contraseña
+   * @returns {Promise<Object>} Resultado
+   */
+  async resetPassword(token, newPassword) {
+    try {
+      return await apiClient.post(`${API_AUTH_URL}/password-reset/confirm`, {
+        token,
+        new_password: newPassword
+      });
+    } catch (error) {
+      console.error("Error al restablecer la contraseña:", error);
+      throw error;
+    }
+  },
+
   /**
    * Cierra la sesión actual
    * @returns {Promise<void>}
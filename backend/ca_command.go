@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/config"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/agentca"
+)
+
+// caUsage ayuda del subcomando de la CA de los agentes
+const caUsage = `Uso: server ca <comando> [opciones]
+
+Comandos:
+  init [-cn nombre] [-days N]                      Crear la CA y una CRL vacía
+  issue [-days N] [-out dir] <hostname> [san...]   Emitir el certificado de cliente de un agente
+  server [-days N] [-out dir] <nombre> [san...]    Emitir el certificado HTTPS del backend (server.crt)
+  revoke <serie>                                   Revocar un certificado y regenerar la CRL
+  crl                                              Regenerar la CRL (antes de que caduque)
+  list                                             Listar los certificados emitidos
+
+El directorio de la CA es AGENT_CA_DIR (%s).
+`
+
+// runCA ejecuta el subcomando "ca" y devuelve el código de salida
+func runCA(cfg *config.TLSConfig, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, caUsage, cfg.CADir)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "init":
+		err = caInit(cfg, args[1:])
+	case "issue":
+		err = caIssue(cfg, agentca.KindAgent, args[1:])
+	case "server":
+		err = caIssue(cfg, agentca.KindServer, args[1:])
+	case "revoke":
+		err = caRevoke(cfg, args[1:])
+	case "crl":
+		err = caWriteCRL(cfg)
+	case "list":
+		err = caList(cfg)
+	default:
+		fmt.Fprintf(os.Stderr, caUsage, cfg.CADir)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// caInit crea la CA y su primera CRL
+func caInit(cfg *config.TLSConfig, args []string) error {
+	flags := flag.NewFlagSet("ca init", flag.ContinueOnError)
+	commonName := flags.String("cn", "Dashboard Servers Agent CA", "nombre común de la CA")
+	days := flags.Int("days", 3650, "validez de la CA en días")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ca, err := agentca.Init(cfg.CADir, *commonName, time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	crlPath, err := ca.WriteCRL(cfg.CRLValidity)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("CA creada en %s\n", cfg.CADir)
+	fmt.Printf("  Certificado: %s (TLS_CLIENT_CA_FILE, y CA de confianza de los agentes)\n", filepath.Join(cfg.CADir, agentca.CertFile))
+	fmt.Printf("  CRL:         %s (TLS_CRL_FILE)\n", crlPath)
+	return nil
+}
+
+// caIssue emite un certificado de agente o de servidor y guarda el certificado y la clave
+func caIssue(cfg *config.TLSConfig, kind string, args []string) error {
+	flags := flag.NewFlagSet("ca "+kind, flag.ContinueOnError)
+	days := flags.Int("days", int(cfg.AgentCertValidity/(24*time.Hour)), "validez del certificado en días")
+	out := flags.String("out", ".", "directorio donde guardar el certificado y la clave")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("indique el hostname del servidor (o el nombre del backend)")
+	}
+
+	ca, err := agentca.Load(cfg.CADir)
+	if err != nil {
+		return err
+	}
+
+	name := flags.Arg(0)
+	issued, err := ca.Issue(kind, name, flags.Args()[1:], time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	// El certificado del backend se guarda con el nombre que espera la configuración por defecto
+	base := name
+	if kind == agentca.KindServer {
+		base = "server"
+	}
+	certPath := filepath.Join(*out, base+".crt")
+	keyPath := filepath.Join(*out, base+".key")
+	if err := os.WriteFile(certPath, issued.CertPEM, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, issued.KeyPEM, 0o600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Certificado emitido para %s (serie %s, caduca %s)\n", name, issued.Record.Serial, issued.Record.NotAfter.Format(time.RFC3339))
+	fmt.Printf("  Certificado: %s\n", certPath)
+	fmt.Printf("  Clave:       %s\n", keyPath)
+	return nil
+}
+
+// caRevoke revoca un certificado y publica la nueva CRL. El backend la relee en el siguiente
+// handshake
+func caRevoke(cfg *config.TLSConfig, args []string) error {
+	if len(args) != 1 {
+		return errors.New("indique el número de serie del certificado (ca list)")
+	}
+
+	ca, err := agentca.Load(cfg.CADir)
+	if err != nil {
+		return err
+	}
+
+	record, err := ca.Revoke(args[0])
+	if err != nil && !errors.Is(err, agentca.ErrAlreadyRevoked) {
+		return err
+	}
+	if _, err := ca.WriteCRL(cfg.CRLValidity); err != nil {
+		return err
+	}
+
+	fmt.Printf("Certificado %s (%s) revocado; CRL actualizada\n", record.Serial, record.CommonName)
+	return nil
+}
+
+// caWriteCRL regenera la CRL con una nueva validez
+func caWriteCRL(cfg *config.TLSConfig) error {
+	ca, err := agentca.Load(cfg.CADir)
+	if err != nil {
+		return err
+	}
+
+	crlPath, err := ca.WriteCRL(cfg.CRLValidity)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("CRL generada en %s (válida hasta %s)\n", crlPath, time.Now().Add(cfg.CRLValidity).Format(time.RFC3339))
+	return nil
+}
+
+// caList muestra los certificados emitidos
+func caList(cfg *config.TLSConfig) error {
+	ca, err := agentca.Load(cfg.CADir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIE\tTIPO\tNOMBRE\tCADUCA\tESTADO")
+	now := time.Now()
+	for _, record := range ca.Records() {
+		status := "válido"
+		switch {
+		case record.RevokedAt != nil:
+			status = "revocado " + record.RevokedAt.Format(time.RFC3339)
+		case record.NotAfter.Before(now):
+			status = "caducado"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", record.Serial, record.Kind, record.CommonName, record.NotAfter.Format("2006-01-02"), status)
+	}
+	return w.Flush()
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -18,6 +21,10 @@ type Config struct {
 	Redis         RedisConfig
 	WebSocket     WebSocketConfig
 	Notifications NotificationsConfig
+	Alerting      AlertingConfig
+	OIDC          OIDCConfig
+	LDAP          LDAPConfig
+	TLS           TLSConfig
 }
 
 // DatabaseConfig contiene la configuración de la base de datos
@@ -32,14 +39,99 @@ type DatabaseConfig struct {
 
 // ServerConfig contiene la configuración del servidor
 type ServerConfig struct {
-	Port string
-	Env  string
+	Port           string
+	Env            string
+	TrustedProxies []string // Proxies cuyas cabeceras X-Forwarded-For se aceptan (vacío = ninguno)
 }
 
 // AuthConfig contiene la configuración de autenticación
 type AuthConfig struct {
 	JWTSecret            string
 	DefaultAdminPassword string
+	AccessTokenTTL       time.Duration // Duración de los tokens de acceso
+	RefreshTokenTTL      time.Duration // Duración de los refresh tokens (inactividad máxima de una sesión)
+	TOTPIssuer           string        // Nombre con el que aparece la cuenta en la aplicación de autenticación
+
+	// Protección del login contra fuerza bruta
+	LoginProtection      bool
+	LoginMaxFailures     int           // Fallos de un usuario antes del bloqueo
+	LoginMaxIPFailures   int           // Fallos desde una IP antes del bloqueo
+	LoginFailureWindow   time.Duration // Los fallos más antiguos dejan de contar
+	LoginLockoutDuration time.Duration // Duración del bloqueo temporal
+	LoginDelayBase       time.Duration // Retraso tras el primer fallo (se duplica en cada fallo)
+	LoginDelayMax        time.Duration // Retraso máximo de la respuesta a un fallo
+
+	// Política de contraseñas locales
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordRejectUser    bool // No admitir contraseñas que contengan el nombre de usuario
+
+	// Alta por invitación y restablecimiento de contraseña
+	AppURL           string        // Página del frontend a la que apuntan los enlaces enviados
+	InviteTTL        time.Duration // Validez de las invitaciones
+	PasswordResetTTL time.Duration // Validez de los enlaces para restablecer la contraseña
+	SignupDomains    []string      // Dominios de email con autorregistro como VIEWER (vacío = deshabilitado)
+
+	// Límite por IP de las solicitudes públicas que envían correo (autorregistro y restablecimiento)
+	AccountRequestLimit  int           // Solicitudes por ventana (0 = sin límite)
+	AccountRequestWindow time.Duration // Duración de la ventana
+}
+
+// OIDCConfig contiene la configuración del inicio de sesión único con OpenID Connect
+type OIDCConfig struct {
+	Enabled       bool
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string // URL de /api/auth/oidc/callback registrada en el IdP
+	Scopes        []string
+	UsernameClaim string   // Claim con el nombre de usuario
+	GroupsClaim   string   // Claim con los grupos del usuario (admite rutas con puntos, ej. realm_access.roles)
+	RoleMapping   []string // Grupos del IdP que conceden un rol global ("grupo=rol")
+	DefaultRole   string   // Rol si ningún grupo está mapeado (vacío = denegar el acceso)
+	GroupMapping  []string // Grupos del IdP que conceden permisos sobre grupos de servidores ("grupo=idGrupo:rol")
+	LocalLogin    string   // Login con contraseña: "all" o "admin" (solo administradores, acceso de emergencia)
+	PostLoginURL  string   // Página a la que volver tras el inicio de sesión
+}
+
+// LDAPConfig contiene la configuración de la autenticación contra LDAP / Active Directory
+type LDAPConfig struct {
+	Enabled      bool
+	URL          string // ldap://host:389 o ldaps://host:636
+	StartTLS     bool
+	SkipVerify   bool   // No verificar el certificado del servidor (solo pruebas)
+	CACertFile   string // CA para verificar el certificado del servidor
+	BindDN       string // Cuenta de servicio para las búsquedas
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s se sustituye por el nombre de usuario
+	UsernameAttr string
+	EmailAttr    string
+	MemberOfAttr string
+	GroupBaseDN  string   // Buscar los grupos aquí en lugar de leer MemberOfAttr
+	GroupFilter  string   // %s se sustituye por el DN del usuario
+	RoleMapping  []string // Grupos que conceden un rol global ("dnDelGrupo:rol", separados por ";")
+	DefaultRole  string   // Rol si ningún grupo está mapeado (vacío = denegar el acceso)
+	AutoCreate   bool     // Dar de alta a los usuarios del directorio en su primer login
+	SyncInterval time.Duration
+}
+
+// TLSConfig contiene la configuración de HTTPS y de la autenticación de los agentes con
+// certificados de cliente (TLS mutuo)
+type TLSConfig struct {
+	Enabled           bool
+	CertFile          string // Certificado del servidor
+	KeyFile           string
+	AgentMTLS         bool          // Aceptar certificados de cliente de los agentes
+	ClientCAFile      string        // CA de los certificados de los agentes
+	CRLFile           string        // Lista de revocación comprobada en cada handshake
+	AgentCertRequired bool          // La ingesta de métricas solo acepta agentes con certificado
+	CADir             string        // Directorio de la CA interna (subcomando "ca")
+	AgentCertValidity time.Duration // Validez de los certificados emitidos
+	CRLValidity       time.Duration // Validez de cada CRL generada
 }
 
 // RedisConfig contiene la configuración de Redis para Pub/Sub
@@ -67,6 +159,31 @@ type NotificationsConfig struct {
 	EmailPassword     string
 	DiscordEnabled    bool
 	DiscordWebhookURL string
+	TelegramEnabled   bool
+	TelegramBotToken  string
+	TelegramChatID    string
+	NtfyEnabled       bool
+	NtfyServerURL     string
+	NtfyTopic         string
+	NtfyToken         string
+}
+
+// AlertingConfig contiene la configuración de los procesos de alertas en segundo plano
+type AlertingConfig struct {
+	EscalationInterval time.Duration // Frecuencia de comprobación de escalados pendientes
+	BaselineInterval   time.Duration // Frecuencia de recálculo de las líneas base de anomalías
+	BaselineLookback   time.Duration // Historial usado para aprender las líneas base
+	AnomalyZScore      float64       // z-score por defecto para considerar anómala una muestra
+
+	// Agrupación de alertas en incidentes
+	IncidentGroupingEnabled bool          // Agrupar las alertas relacionadas en incidentes
+	IncidentGroupBy         []string      // Claves de agrupación (location, group, server, severity, metric_type, tag:<prefijo>)
+	IncidentGroupWindow     time.Duration // Tiempo tras la última alerta durante el que se añaden nuevas al incidente
+	IncidentGroupWait       time.Duration // Espera antes de enviar el resumen para reunir las alertas relacionadas
+
+	// API compatible con Alertmanager para las alertas externas
+	AlertmanagerAPIToken       string        // Token para POST /api/v2/alerts (vacío = API deshabilitada)
+	AlertmanagerResolveTimeout time.Duration // Vencimiento de las alertas externas recibidas sin endsAt
 }
 
 // LoadConfig carga la configuración desde el archivo .env
@@ -78,6 +195,8 @@ func LoadConfig() (*Config, error) {
 
 	viper.AutomaticEnv()
 
+	caDir := getEnv("AGENT_CA_DIR", "agent-ca")
+
 	config := &Config{
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -88,12 +207,39 @@ func LoadConfig() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Env:  getEnv("ENV", "development"),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Env:            getEnv("ENV", "development"),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES", ","),
 		},
 		Auth: AuthConfig{
 			JWTSecret:            getEnv("JWT_SECRET", "mi_clave_secreta_jwt_para_desarrollo"),
 			DefaultAdminPassword: getEnv("ADMIN_PASSWORD", ""),
+			AccessTokenTTL:       getEnvAsDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL:      getEnvAsDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+			TOTPIssuer:           getEnv("TOTP_ISSUER", "Dashboard Servers"),
+
+			LoginProtection:      getEnvAsBool("LOGIN_PROTECTION_ENABLED", true),
+			LoginMaxFailures:     getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+			LoginMaxIPFailures:   getEnvAsInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+			LoginFailureWindow:   getEnvAsDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			LoginLockoutDuration: getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			LoginDelayBase:       getEnvAsDuration("LOGIN_DELAY_BASE", 250*time.Millisecond),
+			LoginDelayMax:        getEnvAsDuration("LOGIN_DELAY_MAX", 4*time.Second),
+
+			PasswordMinLength:     getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+			PasswordRequireUpper:  getEnvAsBool("PASSWORD_REQUIRE_UPPER", false),
+			PasswordRequireLower:  getEnvAsBool("PASSWORD_REQUIRE_LOWER", false),
+			PasswordRequireDigit:  getEnvAsBool("PASSWORD_REQUIRE_DIGIT", false),
+			PasswordRequireSymbol: getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+			PasswordRejectUser:    getEnvAsBool("PASSWORD_REJECT_USERNAME", false),
+
+			AppURL:           getEnv("APP_URL", "http://localhost:5500/index.html"),
+			InviteTTL:        getEnvAsDuration("INVITE_TTL", 72*time.Hour),
+			PasswordResetTTL: getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+			SignupDomains:    getEnvAsList("SIGNUP_ALLOWED_DOMAINS", ","),
+
+			AccountRequestLimit:  getEnvAsInt("ACCOUNT_REQUEST_LIMIT", 5),
+			AccountRequestWindow: getEnvAsDuration("ACCOUNT_REQUEST_WINDOW", 15*time.Minute),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -115,6 +261,74 @@ func LoadConfig() (*Config, error) {
 			EmailPassword:     getEnv("EMAIL_PASSWORD", ""),
 			DiscordEnabled:    getEnvAsBool("DISCORD_ENABLED", false),
 			DiscordWebhookURL: getEnv("DISCORD_WEBHOOK_URL", ""),
+			TelegramEnabled:   getEnvAsBool("TELEGRAM_ENABLED", false),
+			TelegramBotToken:  getEnv("TELEGRAM_BOT_TOKEN", ""),
+			TelegramChatID:    getEnv("TELEGRAM_CHAT_ID", ""),
+			NtfyEnabled:       getEnvAsBool("NTFY_ENABLED", false),
+			NtfyServerURL:     getEnv("NTFY_SERVER_URL", "https://ntfy.sh"),
+			NtfyTopic:         getEnv("NTFY_TOPIC", ""),
+			NtfyToken:         getEnv("NTFY_TOKEN", ""),
+		},
+		Alerting: AlertingConfig{
+			EscalationInterval: getEnvAsDuration("ESCALATION_CHECK_INTERVAL", 30*time.Second),
+			BaselineInterval:   getEnvAsDuration("BASELINE_RECOMPUTE_INTERVAL", time.Hour),
+			BaselineLookback:   getEnvAsDuration("BASELINE_LOOKBACK", 28*24*time.Hour),
+			AnomalyZScore:      getEnvAsFloat("ANOMALY_Z_SCORE", 3),
+
+			IncidentGroupingEnabled: getEnvAsBool("INCIDENT_GROUPING_ENABLED", false),
+			IncidentGroupBy:         getEnvAsStringSlice("INCIDENT_GROUP_BY", []string{"location"}),
+			IncidentGroupWindow:     getEnvAsDuration("INCIDENT_GROUP_WINDOW", 10*time.Minute),
+			IncidentGroupWait:       getEnvAsDuration("INCIDENT_GROUP_WAIT", 30*time.Second),
+
+			AlertmanagerAPIToken:       getEnv("ALERTMANAGER_API_TOKEN", ""),
+			AlertmanagerResolveTimeout: getEnvAsDuration("ALERTMANAGER_RESOLVE_TIMEOUT", 5*time.Minute),
+		},
+		OIDC: OIDCConfig{
+			Enabled:       getEnvAsBool("OIDC_ENABLED", false),
+			IssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:      getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+			Scopes:        getEnvAsStringSlice("OIDC_SCOPES", []string{"openid", "profile", "email"}),
+			UsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+			GroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			RoleMapping:   getEnvAsStringSlice("OIDC_ROLE_MAPPING", nil),
+			DefaultRole:   getEnv("OIDC_DEFAULT_ROLE", ""),
+			GroupMapping:  getEnvAsStringSlice("OIDC_GROUP_MAPPING", nil),
+			LocalLogin:    getEnv("OIDC_LOCAL_LOGIN", "all"),
+			PostLoginURL:  getEnv("OIDC_POST_LOGIN_URL", "/"),
+		},
+		LDAP: LDAPConfig{
+			Enabled:      getEnvAsBool("LDAP_ENABLED", false),
+			URL:          getEnv("LDAP_URL", "ldap://localhost:389"),
+			StartTLS:     getEnvAsBool("LDAP_START_TLS", false),
+			SkipVerify:   getEnvAsBool("LDAP_TLS_SKIP_VERIFY", false),
+			CACertFile:   getEnv("LDAP_CA_CERT", ""),
+			BindDN:       getEnv("LDAP_BIND_DN", ""),
+			BindPassword: getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:       getEnv("LDAP_BASE_DN", ""),
+			UserFilter:   getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+			UsernameAttr: getEnv("LDAP_USERNAME_ATTR", "uid"),
+			EmailAttr:    getEnv("LDAP_EMAIL_ATTR", "mail"),
+			MemberOfAttr: getEnv("LDAP_MEMBEROF_ATTR", "memberOf"),
+			GroupBaseDN:  getEnv("LDAP_GROUP_BASE_DN", ""),
+			GroupFilter:  getEnv("LDAP_GROUP_FILTER", "(member=%s)"),
+			RoleMapping:  getEnvAsList("LDAP_ROLE_MAPPING", ";"),
+			DefaultRole:  getEnv("LDAP_DEFAULT_ROLE", ""),
+			AutoCreate:   getEnvAsBool("LDAP_AUTO_CREATE", true),
+			SyncInterval: getEnvAsDuration("LDAP_SYNC_INTERVAL", 15*time.Minute),
+		},
+		TLS: TLSConfig{
+			Enabled:           getEnvAsBool("TLS_ENABLED", false),
+			CertFile:          getEnv("TLS_CERT_FILE", filepath.Join(caDir, "server.crt")),
+			KeyFile:           getEnv("TLS_KEY_FILE", filepath.Join(caDir, "server.key")),
+			AgentMTLS:         getEnvAsBool("AGENT_MTLS_ENABLED", true),
+			ClientCAFile:      getEnv("TLS_CLIENT_CA_FILE", filepath.Join(caDir, "ca.crt")),
+			CRLFile:           getEnv("TLS_CRL_FILE", filepath.Join(caDir, "crl.pem")),
+			AgentCertRequired: getEnvAsBool("AGENT_MTLS_REQUIRED", false),
+			CADir:             caDir,
+			AgentCertValidity: getEnvAsDuration("AGENT_CERT_VALIDITY", 365*24*time.Hour),
+			CRLValidity:       getEnvAsDuration("AGENT_CRL_VALIDITY", 30*24*time.Hour),
 		},
 	}
 
@@ -143,9 +357,8 @@ func getEnvAsInt(key string, defaultValue int) int {
 		return defaultValue
 	}
 
-	var intValue int
-	_, err := fmt.Sscanf(value, "%d", &intValue)
-	if err != nil || intValue != defaultValue {
+	intValue, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
 		return defaultValue
 	}
 
@@ -162,6 +375,36 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return value == "true" || value == "1" || value == "yes" || value == "y"
 }
 
+// getEnvAsDuration obtiene variable de entorno como duración (ej. "30s", "5m")
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return defaultValue
+	}
+
+	return duration
+}
+
+// getEnvAsFloat obtiene variable de entorno como número decimal positivo
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil || floatValue <= 0 {
+		return defaultValue
+	}
+
+	return floatValue
+}
+
 // getEnvAsStringSlice obtiene variable de entorno como slice de strings
 func getEnvAsStringSlice(key string, defaultValue []string) []string {
 	value := os.Getenv(key)
@@ -172,3 +415,21 @@ func getEnvAsStringSlice(key string, defaultValue []string) []string {
 	// Implementación simple para separar por comas
 	return strings.Split(value, ",")
 }
+
+// getEnvAsList obtiene variable de entorno como lista con el separador indicado, para valores
+// que pueden contener comas (p. ej. DN de LDAP)
+func getEnvAsList(key, separator string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var values []string
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
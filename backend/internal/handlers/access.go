@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+)
+
+// accessScope obtiene el alcance de acceso del usuario autenticado. Si no está en el contexto
+// responde con un error interno y devuelve false
+func accessScope(c *gin.Context) (*services.AccessScope, bool) {
+	scope, ok := middleware.GetAccessScope(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return nil, false
+	}
+
+	return scope, true
+}
+
+// authorizeServer comprueba que el usuario tenga al menos minRole sobre un servidor. Si no,
+// responde con 403 y devuelve false
+func authorizeServer(c *gin.Context, serverID uint, minRole models.Role) bool {
+	scope, ok := accessScope(c)
+	if !ok {
+		return false
+	}
+
+	if !scope.Allows(serverID, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+		return false
+	}
+
+	return true
+}
+
+// authorizeGroup comprueba que el usuario tenga al menos minRole sobre un grupo. Si no,
+// responde con 403 y devuelve false
+func authorizeGroup(c *gin.Context, groupID uint, minRole models.Role) bool {
+	scope, ok := accessScope(c)
+	if !ok {
+		return false
+	}
+
+	if !scope.AllowsGroup(groupID, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+		return false
+	}
+
+	return true
+}
+
+// authorizeThreshold comprueba que el usuario tenga al menos minRole sobre el alcance de un
+// umbral. Si no, responde con 403 y devuelve false
+func authorizeThreshold(c *gin.Context, threshold *models.AlertThreshold, minRole models.Role) bool {
+	scope, ok := accessScope(c)
+	if !ok {
+		return false
+	}
+
+	if !scope.AllowsThreshold(threshold, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+		return false
+	}
+
+	return true
+}
+
+// authorizeAlert comprueba que el usuario tenga al menos minRole sobre el servidor de una
+// alerta. Si no, responde con el error correspondiente y devuelve false
+func authorizeAlert(c *gin.Context, alertService *services.AlertService, id uint, minRole models.Role) bool {
+	alert, err := alertService.GetAlert(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alerta no encontrada"})
+		return false
+	}
+
+	return authorizeServer(c, alert.ServerID, minRole)
+}
+
+// authorizeSilence comprueba que el usuario tenga al menos minRole sobre el alcance de un
+// silencio. Si no, responde con 403 y devuelve false
+func authorizeSilence(c *gin.Context, silence *models.Silence, minRole models.Role) bool {
+	scope, ok := accessScope(c)
+	if !ok {
+		return false
+	}
+
+	if !scope.AllowsSilence(silence, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+		return false
+	}
+
+	return true
+}
+
+// authorizeIncident comprueba que el usuario tenga al menos minRole sobre el servidor de cada
+// una de las alertas de un incidente. Si no, responde con 403 y devuelve false
+func authorizeIncident(c *gin.Context, incident *models.Incident, minRole models.Role) bool {
+	scope, ok := accessScope(c)
+	if !ok {
+		return false
+	}
+
+	for _, alert := range incident.Alerts {
+		if !scope.Allows(alert.ServerID, minRole) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+			return false
+		}
+	}
+
+	return true
+}
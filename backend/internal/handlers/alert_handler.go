@@ -34,20 +34,34 @@ func (h *AlertHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middle
 		alerts.GET("", h.GetAllAlerts)
 		alerts.GET("/active", h.GetActiveAlerts)
 		alerts.GET("/:id", h.GetAlertByID)
+		alerts.GET("/:id/timeline", h.GetAlertTimeline)
+		alerts.GET("/:id/comments", h.GetAlertComments)
 
-		// Rutas para gestionar alertas (requieren rol de admin o user)
+		// Rutas para gestionar alertas (requieren rol de admin o user, global o sobre el
+		// grupo del servidor de la alerta)
 		adminOrUser := alerts.Group("")
-		adminOrUser.Use(authMiddleware.RequireRole(models.RoleAdmin, models.RoleUser))
+		adminOrUser.Use(authMiddleware.RequireScopedRole(models.RoleUser))
 		{
 			adminOrUser.POST("/:id/acknowledge", h.AcknowledgeAlert)
 			adminOrUser.POST("/:id/resolve", h.ResolveAlert)
+			adminOrUser.POST("/:id/comments", h.AddAlertComment)
+			adminOrUser.POST("/:id/unacknowledge", h.UnacknowledgeAlert)
+			adminOrUser.POST("/:id/reopen", h.ReopenAlert)
+			adminOrUser.POST("/:id/snooze", h.SnoozeAlert)
+
+			// Acciones masivas sobre las alertas que cumplen un filtro
+			adminOrUser.POST("/bulk/acknowledge", h.BulkAcknowledge)
+			adminOrUser.POST("/bulk/resolve", h.BulkResolve)
+			adminOrUser.POST("/bulk/snooze", h.BulkSnooze)
 		}
 
-		// Rutas de umbrales (todas requieren admin)
+		// Rutas de umbrales (todas requieren admin, global o sobre el alcance del umbral)
 		thresholds := router.Group("/alert-thresholds")
-		thresholds.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		thresholds.Use(authMiddleware.RequireScopedRole(models.RoleAdmin))
 		{
 			thresholds.GET("", h.GetAllThresholds)
+			thresholds.GET("/variables", h.GetExpressionVariables)
+			thresholds.POST("/backtest", h.BacktestThreshold)
 			thresholds.GET("/:id", h.GetThresholdByID)
 			thresholds.POST("", h.CreateThreshold)
 			thresholds.PUT("/:id", h.UpdateThreshold)
@@ -101,6 +115,30 @@ func (h *AlertHandler) GetAllAlerts(c *gin.Context) {
 		}
 	}
 
+	// Filtro por alertas inhibidas por otra alerta
+	if inhibitedStr := c.Query("inhibited"); inhibitedStr != "" {
+		inhibited, err := strconv.ParseBool(inhibitedStr)
+		if err == nil {
+			filters["inhibited"] = inhibited
+		} else {
+			h.logger.Warnf("Valor de inhibited inválido: %s", inhibitedStr)
+		}
+	}
+
+	// Limitar a los servidores que el usuario puede ver
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+	if serverIDs, restricted := scope.ServerIDs(models.RoleViewer); restricted {
+		filters["server_ids"] = serverIDs
+	}
+
+	// Filtro por equipo propietario
+	if teamIDs, ok := h.ownerTeamFilter(c, scope); ok {
+		filters["team_ids"] = teamIDs
+	}
+
 	// Obtener alertas
 	alerts, err := h.service.GetAllAlerts(filters)
 	if err != nil {
@@ -114,6 +152,11 @@ func (h *AlertHandler) GetAllAlerts(c *gin.Context) {
 
 // GetActiveAlerts obtiene solo las alertas activas
 func (h *AlertHandler) GetActiveAlerts(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+
 	alerts, err := h.service.GetActiveAlerts()
 	if err != nil {
 		h.logger.Errorf("Error al obtener alertas activas: %v", err)
@@ -121,7 +164,58 @@ func (h *AlertHandler) GetActiveAlerts(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, alerts)
+	teamIDs, filterTeams := h.ownerTeamFilter(c, scope)
+
+	visible := make([]models.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if !scope.CanView(alert.ServerID) {
+			continue
+		}
+		if filterTeams && !ownedByAny(alert.OwnerTeamID, teamIDs) {
+			continue
+		}
+		visible = append(visible, alert)
+	}
+
+	c.JSON(http.StatusOK, visible)
+}
+
+// ownerTeamFilter interpreta el filtro por equipo propietario: ?team_id= para un equipo
+// concreto o ?my_teams=true para los equipos del usuario. Devuelve false si no se filtra
+func (h *AlertHandler) ownerTeamFilter(c *gin.Context, scope *services.AccessScope) ([]uint, bool) {
+	if teamIDStr := c.Query("team_id"); teamIDStr != "" {
+		teamID, err := strconv.ParseUint(teamIDStr, 10, 32)
+		if err == nil {
+			return []uint{uint(teamID)}, true
+		}
+		h.logger.Warnf("ID de equipo inválido: %s", teamIDStr)
+	}
+
+	if myTeamsStr := c.Query("my_teams"); myTeamsStr != "" {
+		myTeams, err := strconv.ParseBool(myTeamsStr)
+		if err == nil && myTeams {
+			return scope.TeamIDs, true
+		}
+		if err != nil {
+			h.logger.Warnf("Valor de my_teams inválido: %s", myTeamsStr)
+		}
+	}
+
+	return nil, false
+}
+
+// ownedByAny indica si el equipo propietario está entre los indicados
+func ownedByAny(ownerTeamID *uint, teamIDs []uint) bool {
+	if ownerTeamID == nil {
+		return false
+	}
+
+	for _, id := range teamIDs {
+		if id == *ownerTeamID {
+			return true
+		}
+	}
+	return false
 }
 
 // GetAlertByID obtiene una alerta por su ID
@@ -139,9 +233,19 @@ func (h *AlertHandler) GetAlertByID(c *gin.Context) {
 		return
 	}
 
+	if !authorizeServer(c, alert.ServerID, models.RoleViewer) {
+		return
+	}
+
 	c.JSON(http.StatusOK, alert)
 }
 
+// authorizeAlert comprueba que el usuario tenga al menos minRole sobre el servidor de una
+// alerta. Si no, responde con el error correspondiente y devuelve false
+func (h *AlertHandler) authorizeAlert(c *gin.Context, id uint, minRole models.Role) bool {
+	return authorizeAlert(c, h.service, id, minRole)
+}
+
 // AcknowledgeAlert marca una alerta como reconocida
 func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -150,6 +254,10 @@ func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeAlert(c, uint(id), models.RoleUser) {
+		return
+	}
+
 	var input struct {
 		Notes string `json:"notes"`
 	}
@@ -183,6 +291,10 @@ func (h *AlertHandler) ResolveAlert(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeAlert(c, uint(id), models.RoleUser) {
+		return
+	}
+
 	var input struct {
 		Notes string `json:"notes"`
 	}
@@ -208,8 +320,258 @@ func (h *AlertHandler) ResolveAlert(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Alerta resuelta correctamente"})
 }
 
-// GetAllThresholds obtiene todos los umbrales de alerta
+// UnacknowledgeAlert retira el reconocimiento de una alerta
+func (h *AlertHandler) UnacknowledgeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeAlert(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	if err := h.service.UnacknowledgeAlert(uint(id), userID, input.Notes); err != nil {
+		h.logger.Errorf("Error al retirar el reconocimiento de la alerta %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reconocimiento retirado correctamente"})
+}
+
+// ReopenAlert vuelve a abrir una alerta resuelta
+func (h *AlertHandler) ReopenAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeAlert(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	if err := h.service.ReopenAlert(uint(id), userID, input.Notes); err != nil {
+		h.logger.Errorf("Error al reabrir alerta %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alerta reabierta correctamente"})
+}
+
+// SnoozeAlert pospone una alerta durante los minutos indicados
+func (h *AlertHandler) SnoozeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeAlert(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	var input struct {
+		Minutes int    `json:"minutes" binding:"required"`
+		Notes   string `json:"notes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	alert, err := h.service.SnoozeAlert(uint(id), userID, input.Minutes, input.Notes)
+	if err != nil {
+		h.logger.Errorf("Error al posponer alerta %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// bulkInput cuerpo de las acciones masivas: filtro, notas y, al posponer, la duración
+type bulkInput struct {
+	services.AlertBulkFilter
+	Notes   string `json:"notes"`
+	Minutes int    `json:"minutes"`
+}
+
+// BulkAcknowledge reconoce las alertas activas que cumplen el filtro
+func (h *AlertHandler) BulkAcknowledge(c *gin.Context) {
+	h.bulkAction(c, func(input bulkInput, userID uint) (*services.AlertBulkResult, error) {
+		return h.service.BulkAcknowledge(input.AlertBulkFilter, userID, input.Notes)
+	})
+}
+
+// BulkResolve resuelve las alertas pendientes que cumplen el filtro
+func (h *AlertHandler) BulkResolve(c *gin.Context) {
+	h.bulkAction(c, func(input bulkInput, userID uint) (*services.AlertBulkResult, error) {
+		return h.service.BulkResolve(input.AlertBulkFilter, userID, input.Notes)
+	})
+}
+
+// BulkSnooze pospone las alertas activas o reconocidas que cumplen el filtro
+func (h *AlertHandler) BulkSnooze(c *gin.Context) {
+	h.bulkAction(c, func(input bulkInput, userID uint) (*services.AlertBulkResult, error) {
+		return h.service.BulkSnooze(input.AlertBulkFilter, userID, input.Minutes, input.Notes)
+	})
+}
+
+// bulkAction lee el cuerpo de una acción masiva y devuelve su resultado
+func (h *AlertHandler) bulkAction(c *gin.Context, action func(input bulkInput, userID uint) (*services.AlertBulkResult, error)) {
+	var input bulkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+	input.Scope = scope
+
+	result, err := action(input, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAlertTimeline obtiene el historial de eventos de una alerta
+func (h *AlertHandler) GetAlertTimeline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeAlert(c, uint(id), models.RoleViewer) {
+		return
+	}
+
+	events, err := h.service.GetAlertTimeline(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alerta no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetAlertComments obtiene los comentarios de una alerta
+func (h *AlertHandler) GetAlertComments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeAlert(c, uint(id), models.RoleViewer) {
+		return
+	}
+
+	comments, err := h.service.GetAlertComments(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alerta no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// AddAlertComment añade un comentario a una alerta
+func (h *AlertHandler) AddAlertComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeAlert(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	var input struct {
+		Text string `json:"text" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	comment, err := h.service.AddComment(uint(id), userID, input.Text)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetAllThresholds obtiene los umbrales de alerta que el usuario administra
 func (h *AlertHandler) GetAllThresholds(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+
 	thresholds, err := h.service.GetAllThresholds()
 	if err != nil {
 		h.logger.Errorf("Error al obtener umbrales: %v", err)
@@ -217,7 +579,14 @@ func (h *AlertHandler) GetAllThresholds(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, thresholds)
+	visible := make([]models.AlertThreshold, 0, len(thresholds))
+	for i := range thresholds {
+		if scope.AllowsThreshold(&thresholds[i], models.RoleAdmin) {
+			visible = append(visible, thresholds[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, visible)
 }
 
 // GetThresholdByID obtiene un umbral por su ID
@@ -235,9 +604,41 @@ func (h *AlertHandler) GetThresholdByID(c *gin.Context) {
 		return
 	}
 
+	if !authorizeThreshold(c, threshold, models.RoleAdmin) {
+		return
+	}
+
 	c.JSON(http.StatusOK, threshold)
 }
 
+// GetExpressionVariables lista las variables disponibles en las reglas de expresión
+func (h *AlertHandler) GetExpressionVariables(c *gin.Context) {
+	c.JSON(http.StatusOK, models.MetricVariableCatalog)
+}
+
+// BacktestThreshold reproduce el historial de métricas a través de una regla sin guardarla
+func (h *AlertHandler) BacktestThreshold(c *gin.Context) {
+	var req services.BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	if !authorizeThreshold(c, &req.Threshold, models.RoleAdmin) {
+		return
+	}
+
+	result, err := h.service.BacktestThreshold(&req)
+	if err != nil {
+		h.logger.Warnf("Error en el backtest de umbral: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // CreateThreshold crea un nuevo umbral de alerta
 func (h *AlertHandler) CreateThreshold(c *gin.Context) {
 	var threshold models.AlertThreshold
@@ -255,6 +656,10 @@ func (h *AlertHandler) CreateThreshold(c *gin.Context) {
 	}
 	threshold.CreatedBy = userID
 
+	if !authorizeThreshold(c, &threshold, models.RoleAdmin) {
+		return
+	}
+
 	if err := h.service.CreateThreshold(&threshold); err != nil {
 		h.logger.Errorf("Error al crear umbral: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -281,6 +686,16 @@ func (h *AlertHandler) UpdateThreshold(c *gin.Context) {
 
 	threshold.ID = uint(id)
 
+	// El usuario debe administrar tanto el alcance actual del umbral como el nuevo
+	existing, err := h.service.GetThreshold(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Umbral no encontrado"})
+		return
+	}
+	if !authorizeThreshold(c, existing, models.RoleAdmin) || !authorizeThreshold(c, &threshold, models.RoleAdmin) {
+		return
+	}
+
 	if err := h.service.UpdateThreshold(&threshold); err != nil {
 		h.logger.Errorf("Error al actualizar umbral %d: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -298,6 +713,15 @@ func (h *AlertHandler) DeleteThreshold(c *gin.Context) {
 		return
 	}
 
+	threshold, err := h.service.GetThreshold(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Umbral no encontrado"})
+		return
+	}
+	if !authorizeThreshold(c, threshold, models.RoleAdmin) {
+		return
+	}
+
 	if err := h.service.DeleteThreshold(uint(id)); err != nil {
 		h.logger.Errorf("Error al eliminar umbral %d: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -315,6 +739,10 @@ func (h *AlertHandler) GetThresholdsByServer(c *gin.Context) {
 		return
 	}
 
+	if !authorizeServer(c, uint(serverID), models.RoleAdmin) {
+		return
+	}
+
 	thresholds, err := h.service.GetThresholdsByServer(uint(serverID))
 	if err != nil {
 		h.logger.Errorf("Error al obtener umbrales para servidor %d: %v", serverID, err)
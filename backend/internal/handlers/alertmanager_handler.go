@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// AlertmanagerHandler manejador de la API compatible con Alertmanager para recibir alertas
+// de Prometheus u otras fuentes externas
+type AlertmanagerHandler struct {
+	service *services.ExternalAlertService
+	token   string
+	logger  logger.Logger
+}
+
+// NewAlertmanagerHandler crea un nuevo manejador de la API compatible con Alertmanager.
+// token es el secreto que deben presentar los clientes
+func NewAlertmanagerHandler(service *services.ExternalAlertService, token string, log logger.Logger) *AlertmanagerHandler {
+	return &AlertmanagerHandler{
+		service: service,
+		token:   token,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de la API compatible con Alertmanager. Prometheus no
+// puede iniciar sesión, por lo que se autentica con el token de la API
+func (h *AlertmanagerHandler) RegisterRoutes(router gin.IRouter) {
+	v2 := router.Group("/api/v2")
+	v2.Use(h.requireToken())
+	{
+		v2.POST("/alerts", h.PostAlerts)
+	}
+}
+
+// requireToken comprueba el token enviado como "Authorization: Bearer <token>" o como
+// contraseña de autenticación básica (basic_auth en la configuración de Prometheus)
+func (h *AlertmanagerHandler) requireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := ""
+		if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			provided = strings.TrimPrefix(header, "Bearer ")
+		} else if _, password, ok := c.Request.BasicAuth(); ok {
+			provided = password
+		}
+
+		if provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(h.token)) != 1 {
+			h.logger.Warnf("Acceso no autorizado a la API de Alertmanager desde %s", c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PostAlerts recibe un lote de alertas en el formato de Alertmanager
+func (h *AlertmanagerHandler) PostAlerts(c *gin.Context) {
+	var alerts []services.PostableAlert
+	if err := c.ShouldBindJSON(&alerts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	result, err := h.service.Ingest(alerts)
+	if errors.Is(err, services.ErrInvalidExternalAlert) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Error al procesar alertas externas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar las alertas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
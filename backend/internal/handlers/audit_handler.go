@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// AuditQueryParams filtros de la consulta de auditoría
+type AuditQueryParams struct {
+	ActorID      uint   `form:"actor_id"`
+	Actor        string `form:"actor"`
+	Action       string `form:"action"` // Exacta o prefijo con * (users.*)
+	ResourceType string `form:"resource_type"`
+	ResourceID   string `form:"resource_id"`
+	Outcome      string `form:"outcome"`
+	StartDate    string `form:"start_date"`
+	EndDate      string `form:"end_date"`
+	Limit        int    `form:"limit,default=100"`
+	Offset       int    `form:"offset,default=0"`
+}
+
+// AuditHandler manejador para la consulta y exportación del registro de auditoría (solo admin)
+type AuditHandler struct {
+	service *services.AuditService
+	logger  logger.Logger
+}
+
+// NewAuditHandler crea un nuevo manejador de auditoría
+func NewAuditHandler(service *services.AuditService, log logger.Logger) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de auditoría
+func (h *AuditHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middleware.AuthMiddleware) {
+	audit := router.Group("/api/audit")
+	audit.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		audit.GET("", h.GetEntries)
+		audit.GET("/export", h.Export)
+	}
+}
+
+// GetEntries consulta el registro de auditoría con filtros y paginación. El total de entradas
+// que cumplen el filtro se devuelve en la cabecera X-Total-Count
+func (h *AuditHandler) GetEntries(c *gin.Context) {
+	filter, ok := h.bindFilter(c)
+	if !ok {
+		return
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	} else if filter.Limit > 1000 {
+		filter.Limit = 1000
+	}
+
+	entries, total, err := h.service.GetEntries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al consultar la auditoría"})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, entries)
+}
+
+// Export descarga las entradas que cumplen el filtro como JSON lines (sin límite salvo ?limit=)
+func (h *AuditHandler) Export(c *gin.Context) {
+	filter, ok := h.bindFilter(c)
+	if !ok {
+		return
+	}
+	if c.Query("limit") == "" {
+		filter.Limit = 0
+	}
+	filter.Offset = 0
+
+	filename := fmt.Sprintf("audit-%s.jsonl", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	count, err := h.service.Export(filter, c.Writer)
+	if err != nil {
+		// La respuesta ya ha empezado: solo se puede cortar la descarga
+		h.logger.Errorf("Exportación de auditoría interrumpida tras %d entradas: %v", count, err)
+		c.Abort()
+		return
+	}
+
+	h.logger.Infof("Auditoría exportada: %d entradas", count)
+}
+
+// bindFilter lee los filtros de la consulta
+func (h *AuditHandler) bindFilter(c *gin.Context) (services.AuditFilter, bool) {
+	var params AuditQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetros de consulta inválidos"})
+		return services.AuditFilter{}, false
+	}
+
+	filter := services.AuditFilter{
+		ActorID:      params.ActorID,
+		ActorName:    params.Actor,
+		Action:       params.Action,
+		ResourceType: params.ResourceType,
+		ResourceID:   params.ResourceID,
+		Outcome:      params.Outcome,
+		Limit:        params.Limit,
+		Offset:       params.Offset,
+	}
+
+	var err error
+	if params.StartDate != "" {
+		if filter.StartDate, err = time.Parse(time.RFC3339, params.StartDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha de inicio inválido"})
+			return services.AuditFilter{}, false
+		}
+	}
+	if params.EndDate != "" {
+		if filter.EndDate, err = time.Parse(time.RFC3339, params.EndDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha de fin inválido"})
+			return services.AuditFilter{}, false
+		}
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return filter, true
+}
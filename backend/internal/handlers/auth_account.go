@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+)
+
+// AcceptInvitationRequest datos para darse de alta con una invitación
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Password string `json:"password" binding:"required"`
+}
+
+// PasswordResetRequest email de la cuenta cuya contraseña se quiere restablecer
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// PasswordResetConfirmRequest token del enlace y nueva contraseña
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// registerAccountRoutes registra las rutas públicas de alta por invitación y de
+// restablecimiento de contraseña
+func (h *AuthHandler) registerAccountRoutes(auth *gin.RouterGroup) {
+	if h.invitationService != nil {
+		auth.GET("/invitations/:token", h.GetInvitation)
+		auth.POST("/invitations/accept", h.AcceptInvitation)
+	}
+
+	if h.passwordResetService != nil {
+		auth.POST("/password-reset", h.accountLimiter.Limit(), h.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", h.ConfirmPasswordReset)
+	}
+}
+
+// GetInvitation muestra el email y el rol de una invitación pendiente antes de aceptarla
+func (h *AuthHandler) GetInvitation(c *gin.Context) {
+	invitation, err := h.invitationService.GetByToken(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvitationInvalid) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Errorf("Error al obtener la invitación: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":      invitation.Email,
+		"role":       invitation.Role,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation crea la cuenta de una invitación con el nombre de usuario y la contraseña
+// elegidos. Después el usuario inicia sesión con normalidad
+func (h *AuthHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requieren el token, el nombre de usuario y la contraseña"})
+		return
+	}
+
+	middleware.SetAuditActor(c, 0, req.Username)
+	user, err := h.invitationService.Accept(req.Token, req.Username, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvitationInvalid):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPasswordPolicy):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUserExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Errorf("Error al aceptar la invitación: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		}
+		return
+	}
+
+	middleware.SetAuditActor(c, user.ID, user.Username)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Cuenta creada: ya puede iniciar sesión",
+		"user":    loginUserResponse(user),
+	})
+}
+
+// RequestPasswordReset envía por correo un enlace para restablecer la contraseña. La respuesta
+// es la misma exista o no la cuenta
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere un email válido"})
+		return
+	}
+
+	middleware.SetAuditActor(c, 0, req.Email)
+	if err := h.passwordResetService.RequestReset(req.Email); err != nil {
+		if errors.Is(err, notifications.ErrEmailDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "El envío de correo no está configurado: solicite el enlace a un administrador",
+			})
+			return
+		}
+		h.logger.Errorf("Error al solicitar el restablecimiento de contraseña: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo enviar el enlace"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Si la cuenta existe recibirá un correo con un enlace para restablecer la contraseña",
+	})
+}
+
+// ConfirmPasswordReset establece la nueva contraseña con el token del enlace. Se cierran todas
+// las sesiones del usuario
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requieren el token y la nueva contraseña"})
+		return
+	}
+
+	user, err := h.passwordResetService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrResetTokenInvalid), errors.Is(err, services.ErrPasswordPolicy):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Errorf("Error al restablecer la contraseña: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		}
+		return
+	}
+
+	middleware.SetAuditActor(c, user.ID, user.Username)
+	c.JSON(http.StatusOK, gin.H{"message": "Contraseña restablecida: ya puede iniciar sesión"})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,23 +13,20 @@ import (
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
 )
 
-// Duración de la cookie de autenticación
-const (
-	cookieDuration = 24 * time.Hour // 24 horas
-)
-
 // LoginRequest representa los datos para iniciar sesión
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
-// RegisterRequest representa los datos para registrar un nuevo usuario
+// RegisterRequest representa los datos para el autorregistro (se envía una invitación al email)
 type RegisterRequest struct {
-	Username string      `json:"username" binding:"required,min=3,max=50"`
-	Email    string      `json:"email" binding:"required,email"`
-	Password string      `json:"password" binding:"required,min=6"`
-	Role     models.Role `json:"role"`
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RefreshRequest representa los datos para renovar la sesión (si no se usa la cookie)
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // ChangePasswordRequest representa los datos para cambiar la contraseña
@@ -38,9 +37,19 @@ type ChangePasswordRequest struct {
 
 // AuthHandler maneja las rutas relacionadas con autenticación
 type AuthHandler struct {
-	authService *services.AuthService
-	userService *services.UserService
-	logger      logger.Logger
+	authService  *services.AuthService
+	userService  *services.UserService
+	oidcService  *services.OIDCService
+	postLoginURL string // Página a la que volver tras el inicio de sesión único
+	logger       logger.Logger
+	
+	// Verificación en dos pasos (TOTP)
+	twoFactorService *services.TwoFactorService
+	
+	// Invitaciones y restablecimiento de contraseña
+	invitationService    *services.InvitationService
+	passwordResetService *services.PasswordResetService
+	accountLimiter       *middleware.RateLimiter // Límite por IP del registro y de la solicitud de restablecimiento
 }
 
 // NewAuthHandler crea una nueva instancia del manejador de autenticación
@@ -52,13 +61,45 @@ func NewAuthHandler(authService *services.AuthService, userService *services.Use
 	}
 }
 
+// SetOIDCService habilita el inicio de sesión único con OpenID Connect
+func (h *AuthHandler) SetOIDCService(oidcService *services.OIDCService, postLoginURL string) {
+	h.oidcService = oidcService
+	h.postLoginURL = postLoginURL
+}
+
+// SetTwoFactorService habilita la verificación en dos pasos
+func (h *AuthHandler) SetTwoFactorService(twoFactorService *services.TwoFactorService) {
+	h.twoFactorService = twoFactorService
+}
+
+// SetInvitationService habilita la aceptación de invitaciones y el autorregistro por dominio
+func (h *AuthHandler) SetInvitationService(invitationService *services.InvitationService) {
+	h.invitationService = invitationService
+}
+
+// SetPasswordResetService habilita el restablecimiento de contraseña
+func (h *AuthHandler) SetPasswordResetService(passwordResetService *services.PasswordResetService) {
+	h.passwordResetService = passwordResetService
+}
+
+// SetAccountRateLimiter limita por IP las solicitudes públicas que envían correo
+func (h *AuthHandler) SetAccountRateLimiter(limiter *middleware.RateLimiter) {
+	h.accountLimiter = limiter
+}
+
 // RegisterRoutes registra las rutas del manejador en el router
 func (h *AuthHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middleware.AuthMiddleware) {
 	auth := router.Group("/api/auth")
 	{
 		auth.POST("/login", h.Login)
+		auth.POST("/refresh", h.Refresh)
 		auth.POST("/logout", h.Logout)
-		auth.POST("/register", h.Register)
+		auth.POST("/register", h.accountLimiter.Limit(), h.Register)
+		auth.GET("/providers", h.GetProviders)
+		
+		// Inicio de sesión único (OpenID Connect)
+		auth.GET("/oidc/login", h.OIDCLogin)
+		auth.GET("/oidc/callback", h.OIDCCallback)
 		
 		// Rutas protegidas
 		protected := auth.Group("")
@@ -66,7 +107,20 @@ func (h *AuthHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middlew
 		{
 			protected.GET("/me", h.GetCurrentUser)
 			protected.POST("/change-password", h.ChangePassword)
+			
+			// Sesiones abiertas del usuario
+			protected.GET("/sessions", h.GetSessions)
+			protected.DELETE("/sessions", h.RevokeOtherSessions)
+			protected.DELETE("/sessions/:id", h.RevokeSession)
 		}
+		
+		// Verificación en dos pasos
+		if h.twoFactorService != nil {
+			h.registerTwoFactorRoutes(auth, protected, authMiddleware)
+		}
+		
+		// Alta por invitación y restablecimiento de contraseña
+		h.registerAccountRoutes(auth)
 	}
 }
 
@@ -80,33 +134,93 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 	
-	// Autenticar usuario
-	token, user, err := h.authService.Login(req.Username, req.Password)
+	// Autenticar usuario (la auditoría guarda el usuario con el que se intenta entrar)
+	middleware.SetAuditActor(c, 0, req.Username)
+	tokens, user, err := h.authService.Login(req.Username, req.Password, clientInfo(c))
+	if user != nil {
+		middleware.SetAuditActor(c, user.ID, user.Username)
+	}
+	if err == services.ErrTwoFactorRequired || err == services.ErrTwoFactorSetupRequired {
+		// Contraseña correcta: falta el segundo paso
+		h.respondTwoFactorChallenge(c, user, err)
+		return
+	}
 	if err != nil {
+		// Bloqueo temporal por intentos fallidos: indicar cuándo se puede reintentar
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(locked.Until).Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_at": locked.Until})
+			return
+		}
+		
 		statusCode := http.StatusInternalServerError
 		
-		if err == services.ErrInvalidCredentials {
+		switch err {
+		case services.ErrInvalidCredentials:
 			statusCode = http.StatusUnauthorized
+		case services.ErrLocalLoginDisabled, services.ErrUserDisabled, services.ErrLDAPNotAuthorized:
+			statusCode = http.StatusForbidden
+		case services.ErrLDAPUnavailable:
+			statusCode = http.StatusServiceUnavailable
 		}
 		
 		c.JSON(statusCode, gin.H{"error": err.Error()})
 		return
 	}
 	
-	// Establecer cookie segura
-	c.SetCookie(
-		middleware.AuthCookieName,
-		token,
-		int(cookieDuration.Seconds()),
-		"/",           // Path
-		"",            // Domain (vacío = dominio actual)
-		false,         // Secure (en producción debería ser true)
-		true,          // HttpOnly (protege contra XSS)
-	)
+	// Establecer cookies seguras
+	setAuthCookies(c, tokens)
 	
 	// No enviar la contraseña en la respuesta
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login exitoso",
+		"message":    "Login exitoso",
+		"expires_at": tokens.AccessExpiresAt,
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+			"role":     user.Role,
+		},
+	})
+}
+
+// Refresh renueva la sesión: rota el refresh token (cookie o cuerpo) y emite un nuevo token de acceso
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	refreshToken, _ := c.Cookie(middleware.RefreshCookieName)
+	if refreshToken == "" {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err == nil {
+			refreshToken = req.RefreshToken
+		}
+	}
+	
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+	
+	tokens, user, err := h.authService.Refresh(refreshToken, clientInfo(c))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		
+		switch err {
+		case services.ErrTokenInvalid, services.ErrSessionRevoked, services.ErrRefreshTokenReused, services.ErrUserDisabled:
+			statusCode = http.StatusUnauthorized
+			clearAuthCookies(c)
+		default:
+			h.logger.Errorf("Error al renovar sesión: %v", err)
+		}
+		
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+	
+	setAuthCookies(c, tokens)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Sesión renovada",
+		"expires_at": tokens.AccessExpiresAt,
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
@@ -116,78 +230,51 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// Logout cierra la sesión eliminando la cookie
+// Logout cierra la sesión: la revoca en el servidor y elimina las cookies
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// Establecer una cookie expirada para eliminarla
-	c.SetCookie(
-		middleware.AuthCookieName,
-		"",
-		-1,   // MaxAge < 0 elimina la cookie
-		"/",
-		"",
-		false,
-		true,
-	)
+	accessToken, _ := c.Cookie(middleware.AuthCookieName)
+	refreshToken, _ := c.Cookie(middleware.RefreshCookieName)
+	if userID, _ := h.authService.Logout(accessToken, refreshToken); userID != 0 {
+		middleware.SetAuditActor(c, userID, "")
+	}
+	
+	// Establecer cookies expiradas para eliminarlas
+	clearAuthCookies(c)
 	
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Sesión cerrada exitosamente",
 	})
 }
 
-// Register registra un nuevo usuario
+// Register autorregistro: si el dominio del email está permitido se le envía una invitación
+// con rol VIEWER. El registro abierto está deshabilitado; el resto de altas son por invitación
 func (h *AuthHandler) Register(c *gin.Context) {
-	var req RegisterRequest
+	if h.invitationService == nil || !h.invitationService.SignupEnabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrSignupDisabled.Error()})
+		return
+	}
 	
+	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warnf("Datos de registro inválidos: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de registro inválidos"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere un email válido"})
 		return
 	}
 	
-	// Establecer rol por defecto si no se proporciona
-	if req.Role == "" {
-		req.Role = models.RoleUser
-	}
-	
-	// Solo admins pueden crear otros admins
-	if req.Role == models.RoleAdmin {
-		// Obtener el token de la cookie para verificar si es admin
-		tokenCookie, err := c.Cookie(middleware.AuthCookieName)
-		if err != nil || tokenCookie == "" {
-			h.logger.Warn("Intento de crear usuario admin sin autenticación")
-			c.JSON(http.StatusForbidden, gin.H{"error": "No autorizado para crear usuarios administradores"})
+	middleware.SetAuditActor(c, 0, req.Email)
+	if err := h.invitationService.SignUp(req.Email); err != nil {
+		if errors.Is(err, services.ErrSignupDomain) || errors.Is(err, services.ErrSignupDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
-		
-		// Verificar token y rol
-		claims, err := h.authService.VerifyToken(tokenCookie)
-		if err != nil || claims.Role != models.RoleAdmin {
-			h.logger.Warn("Intento de crear usuario admin por un no-admin")
-			c.JSON(http.StatusForbidden, gin.H{"error": "No autorizado para crear usuarios administradores"})
-			return
-		}
-	}
-	
-	user := &models.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Role:     req.Role,
-	}
-	
-	if err := h.userService.CreateUser(user, req.Password); err != nil {
-		h.logger.Errorf("Error al crear usuario: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.logger.Errorf("Error en el autorregistro: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo enviar la invitación"})
 		return
 	}
 	
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Usuario registrado exitosamente",
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-			"role":     user.Role,
-		},
+	// La respuesta no indica si el email ya estaba registrado
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Revise su correo: recibirá un enlace para completar el registro",
 	})
 }
 
@@ -207,12 +294,13 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"role":       user.Role,
-		"last_login": user.LastLogin,
-		"created_at": user.CreatedAt,
+		"id":            user.ID,
+		"username":      user.Username,
+		"email":         user.Email,
+		"role":          user.Role,
+		"auth_provider": user.AuthProvider,
+		"last_login":    user.LastLogin,
+		"created_at":    user.CreatedAt,
 	})
 }
 
@@ -239,20 +327,143 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 	
+	// La contraseña de los usuarios LDAP u OIDC se gestiona en su proveedor
+	if !user.IsLocal() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La contraseña de este usuario se gestiona en su proveedor de identidad"})
+		return
+	}
+	
 	if !user.CheckPassword(req.CurrentPassword) {
 		h.logger.Warnf("Contraseña actual incorrecta para usuario ID %d", userID)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Contraseña actual incorrecta"})
 		return
 	}
 	
-	// Cambiar contraseña
+	// Cambiar contraseña (revoca todas las sesiones del usuario)
 	if err := h.userService.ChangePassword(userID, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrPasswordPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Errorf("Error al cambiar contraseña: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al cambiar contraseña"})
 		return
 	}
 	
+	// Abrir una sesión nueva para el dispositivo actual
+	tokens, err := h.authService.CreateSession(user, clientInfo(c))
+	if err != nil {
+		clearAuthCookies(c)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Contraseña cambiada exitosamente. Inicie sesión de nuevo",
+		})
+		return
+	}
+	setAuthCookies(c, tokens)
+	
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Contraseña cambiada exitosamente",
 	})
+}
+
+// GetSessions obtiene las sesiones abiertas del usuario autenticado
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(c)
+	
+	sessions, err := h.authService.GetSessions(userID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener sesiones"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession cierra una de las sesiones del usuario autenticado
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+	
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de sesión inválido"})
+		return
+	}
+	
+	if err := h.authService.RevokeSession(userID, uint(id), models.SessionRevokedByUser); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	// Si se cierra la sesión actual, eliminar también las cookies
+	if sessionID, _ := middleware.GetSessionID(c); sessionID == uint(id) {
+		clearAuthCookies(c)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión cerrada correctamente"})
+}
+
+// RevokeOtherSessions cierra todas las sesiones del usuario autenticado salvo la actual
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(c)
+	
+	count, err := h.authService.RevokeOtherSessions(userID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al cerrar sesiones"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sesiones cerradas correctamente",
+		"revoked": count,
+	})
+}
+
+// clientInfo obtiene el dispositivo y la IP de la petición
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+// setAuthCookies establece las cookies del token de acceso y del refresh token
+func setAuthCookies(c *gin.Context, tokens *services.TokenPair) {
+	c.SetCookie(
+		middleware.AuthCookieName,
+		tokens.AccessToken,
+		int(time.Until(tokens.AccessExpiresAt).Seconds()),
+		"/",           // Path
+		"",            // Domain (vacío = dominio actual)
+		false,         // Secure (en producción debería ser true)
+		true,          // HttpOnly (protege contra XSS)
+	)
+	c.SetCookie(
+		middleware.RefreshCookieName,
+		tokens.RefreshToken,
+		int(time.Until(tokens.RefreshExpiresAt).Seconds()),
+		middleware.RefreshCookiePath,
+		"",
+		false,
+		true,
+	)
+}
+
+// clearAuthCookies elimina las cookies de autenticación
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie(middleware.AuthCookieName, "", -1, "/", "", false, true) // MaxAge < 0 elimina la cookie
+	c.SetCookie(middleware.RefreshCookieName, "", -1, middleware.RefreshCookiePath, "", false, true)
 } 
\ No newline at end of file
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+)
+
+// GetProviders indica los métodos de inicio de sesión disponibles
+func (h *AuthHandler) GetProviders(c *gin.Context) {
+	local := "all"
+	if h.authService.LocalLoginAdminOnly() {
+		local = "admin"
+	}
+
+	oidcEnabled := h.oidcService != nil && h.oidcService.Enabled()
+	response := gin.H{
+		"local": local,
+		"oidc": gin.H{
+			"enabled": oidcEnabled,
+		},
+	}
+	if oidcEnabled {
+		response["oidc"] = gin.H{
+			"enabled":   true,
+			"login_url": "/api/auth/oidc/login",
+		}
+	}
+	response["signup"] = h.invitationService != nil && h.invitationService.SignupEnabled()
+	response["password_reset"] = h.passwordResetService != nil && h.passwordResetService.EmailEnabled()
+
+	c.JSON(http.StatusOK, response)
+}
+
+// OIDCLogin inicia el inicio de sesión único: guarda el estado del flujo en una cookie firmada
+// y redirige al proveedor de identidad
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if h.oidcService == nil || !h.oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrOIDCDisabled.Error()})
+		return
+	}
+
+	returnTo := h.safeReturnTo(c.Query("return_to"))
+
+	authURL, state, err := h.oidcService.BeginLogin(c.Request.Context(), returnTo)
+	if err != nil {
+		h.logger.Errorf("Error al iniciar el login OIDC: %v", err)
+		h.redirectOIDCError(c, returnTo, "No se pudo contactar con el proveedor de identidad")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode) // El IdP vuelve al callback con una navegación de nivel superior
+	c.SetCookie(middleware.OIDCCookieName, state, int(services.OIDCStateTTL.Seconds()), middleware.OIDCCookiePath, "", false, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completa el inicio de sesión único al volver del proveedor de identidad
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.oidcService == nil || !h.oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrOIDCDisabled.Error()})
+		return
+	}
+
+	state, _ := c.Cookie(middleware.OIDCCookieName)
+	c.SetCookie(middleware.OIDCCookieName, "", -1, middleware.OIDCCookiePath, "", false, true) // Un solo uso
+
+	// El IdP informa de los errores (p. ej. acceso denegado) en la propia redirección
+	if idpError := c.Query("error"); idpError != "" {
+		h.logger.Warnf("El proveedor de identidad devolvió un error: %s %s", idpError, c.Query("error_description"))
+		h.redirectOIDCError(c, "", "El proveedor de identidad rechazó el inicio de sesión")
+		return
+	}
+
+	tokens, user, returnTo, err := h.oidcService.CompleteLogin(c.Request.Context(), c.Query("code"), c.Query("state"), state, clientInfo(c))
+	if err != nil {
+		message := "Error en el inicio de sesión único"
+		switch {
+		case errors.Is(err, services.ErrOIDCState), errors.Is(err, services.ErrOIDCNotAuthorized),
+			errors.Is(err, services.ErrOIDCAccount), errors.Is(err, services.ErrUserDisabled):
+			h.logger.Warnf("Login OIDC rechazado: %v", err)
+			message = err.Error()
+		default:
+			h.logger.Errorf("Error en el login OIDC: %v", err)
+		}
+
+		h.redirectOIDCError(c, returnTo, message)
+		return
+	}
+
+	setAuthCookies(c, tokens)
+	middleware.SetAuditActor(c, user.ID, user.Username)
+	h.logger.Infof("Sesión abierta por inicio de sesión único para %s", user.Username)
+
+	c.Redirect(http.StatusFound, h.safeReturnTo(returnTo))
+}
+
+// redirectOIDCError vuelve a la aplicación indicando el motivo del fallo en sso_error
+func (h *AuthHandler) redirectOIDCError(c *gin.Context, returnTo, message string) {
+	middleware.SetAuditOutcome(c, models.AuditOutcomeDenied)
+	middleware.SetAuditDetail(c, "error", message)
+
+	target, err := url.Parse(h.safeReturnTo(returnTo))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	query := target.Query()
+	query.Set("sso_error", message)
+	target.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, target.String())
+}
+
+// safeReturnTo evita las redirecciones abiertas: solo se admiten rutas relativas o URLs del
+// mismo origen que la página configurada tras el login
+func (h *AuthHandler) safeReturnTo(returnTo string) string {
+	fallback := h.postLoginURL
+	if fallback == "" {
+		fallback = "/"
+	}
+
+	if returnTo == "" {
+		return fallback
+	}
+
+	// Rutas relativas al propio origen (no "//host" ni "/\host")
+	if strings.HasPrefix(returnTo, "/") && !strings.HasPrefix(returnTo, "//") && !strings.HasPrefix(returnTo, "/\\") {
+		return returnTo
+	}
+
+	target, err := url.Parse(returnTo)
+	if err != nil {
+		return fallback
+	}
+	base, err := url.Parse(fallback)
+	if err != nil || base.Host == "" {
+		return fallback
+	}
+	if target.Scheme == base.Scheme && target.Host == base.Host {
+		return returnTo
+	}
+
+	return fallback
+}
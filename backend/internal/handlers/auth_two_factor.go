@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+)
+
+// TwoFactorLoginRequest segundo paso del login: token de verificación y código
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"` // Código de la aplicación o de recuperación
+}
+
+// TwoFactorCodeRequest código de la aplicación (o de recuperación) para confirmar una acción
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorPolicyRequest roles para los que la verificación en dos pasos es obligatoria
+type TwoFactorPolicyRequest struct {
+	Roles []models.Role `json:"roles"`
+}
+
+// registerTwoFactorRoutes registra las rutas de la verificación en dos pasos
+func (h *AuthHandler) registerTwoFactorRoutes(auth, protected *gin.RouterGroup, authMiddleware *middleware.AuthMiddleware) {
+	// Segundo paso del login (antes de abrir sesión, con el token de verificación)
+	auth.POST("/login/2fa", h.LoginTwoFactor)
+	auth.POST("/login/2fa/setup", h.LoginTwoFactorSetup)
+	auth.POST("/login/2fa/enable", h.LoginTwoFactorEnable)
+
+	// Gestión de la verificación del propio usuario
+	protected.GET("/2fa", h.GetTwoFactor)
+	protected.POST("/2fa/setup", h.SetupTwoFactor)
+	protected.POST("/2fa/enable", h.EnableTwoFactor)
+	protected.POST("/2fa/disable", h.DisableTwoFactor)
+	protected.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+
+	// Roles para los que es obligatoria (solo admin)
+	protected.GET("/2fa/policy", authMiddleware.RequireRole(models.RoleAdmin), h.GetTwoFactorPolicy)
+	protected.PUT("/2fa/policy", authMiddleware.RequireRole(models.RoleAdmin), h.UpdateTwoFactorPolicy)
+}
+
+// respondTwoFactorChallenge responde al login con contraseña que requiere un segundo paso
+func (h *AuthHandler) respondTwoFactorChallenge(c *gin.Context, user *models.User, loginErr error) {
+	purpose := services.TwoFactorPurposeLogin
+	if errors.Is(loginErr, services.ErrTwoFactorSetupRequired) {
+		purpose = services.TwoFactorPurposeSetup
+	}
+
+	// La sesión no se abre hasta completar el segundo paso
+	middleware.SetAuditDetail(c, "two_factor", purpose)
+
+	challenge, expiresAt, err := h.twoFactorService.IssueChallenge(user, purpose)
+	if err != nil {
+		h.logger.Errorf("Error al emitir el token de verificación en dos pasos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                   loginErr.Error(),
+		"two_factor_required":       purpose == services.TwoFactorPurposeLogin,
+		"two_factor_setup_required": purpose == services.TwoFactorPurposeSetup,
+		"challenge_token":           challenge,
+		"challenge_expires_at":      expiresAt,
+	})
+}
+
+// LoginTwoFactor completa el login con el código de la aplicación o un código de recuperación
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requieren el token de verificación y el código"})
+		return
+	}
+
+	tokens, user, err := h.twoFactorService.VerifyLogin(req.ChallengeToken, req.Code, clientInfo(c))
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+	middleware.SetAuditActor(c, user.ID, user.Username)
+
+	setAuthCookies(c, tokens)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Login exitoso",
+		"expires_at": tokens.AccessExpiresAt,
+		"user":       loginUserResponse(user),
+	})
+}
+
+// LoginTwoFactorSetup genera el secreto para el alta obligatoria durante el login
+func (h *AuthHandler) LoginTwoFactorSetup(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el token de verificación"})
+		return
+	}
+
+	setup, err := h.twoFactorService.BeginSetupWithChallenge(req.ChallengeToken)
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// LoginTwoFactorEnable activa la verificación con el primer código y abre la sesión
+func (h *AuthHandler) LoginTwoFactorEnable(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requieren el token de verificación y el código"})
+		return
+	}
+
+	tokens, user, codes, err := h.twoFactorService.EnableWithChallenge(req.ChallengeToken, req.Code, clientInfo(c))
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+	middleware.SetAuditActor(c, user.ID, user.Username)
+
+	setAuthCookies(c, tokens)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Verificación en dos pasos activada. Guarde los códigos de recuperación: no se volverán a mostrar",
+		"expires_at":     tokens.AccessExpiresAt,
+		"user":           loginUserResponse(user),
+		"recovery_codes": codes,
+	})
+}
+
+// GetTwoFactor obtiene el estado de la verificación en dos pasos del usuario autenticado
+func (h *AuthHandler) GetTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactorService.Status(user)
+	if err != nil {
+		h.logger.Errorf("Error al obtener el estado 2FA del usuario %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener la verificación en dos pasos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTwoFactor inicia el alta: devuelve el secreto y la URI para el código QR
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	setup, err := h.twoFactorService.BeginSetup(user)
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableTwoFactor activa la verificación con el primer código de la aplicación. Se cierran las
+// demás sesiones del usuario
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el código de verificación"})
+		return
+	}
+
+	sessionID, _ := middleware.GetSessionID(c)
+	codes, err := h.twoFactorService.Enable(user, req.Code, sessionID)
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Verificación en dos pasos activada. Guarde los códigos de recuperación: no se volverán a mostrar",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor desactiva la verificación del usuario autenticado y cierra sus sesiones
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el código de verificación"})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(user, req.Code); err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Verificación en dos pasos desactivada. Inicie sesión de nuevo"})
+}
+
+// RegenerateRecoveryCodes sustituye los códigos de recuperación del usuario autenticado
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el código de verificación"})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Códigos de recuperación regenerados. Los anteriores ya no son válidos",
+		"recovery_codes": codes,
+	})
+}
+
+// GetTwoFactorPolicy obtiene los roles para los que la verificación es obligatoria
+func (h *AuthHandler) GetTwoFactorPolicy(c *gin.Context) {
+	policies, err := h.twoFactorService.GetPolicy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener la política de verificación en dos pasos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// UpdateTwoFactorPolicy fija los roles para los que la verificación es obligatoria
+func (h *AuthHandler) UpdateTwoFactorPolicy(c *gin.Context) {
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	for _, role := range req.Roles {
+		if models.RoleRank(role) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rol inválido: " + string(role)})
+			return
+		}
+	}
+
+	policies, err := h.twoFactorService.SetPolicy(req.Roles, adminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al guardar la política de verificación en dos pasos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// currentUser carga el usuario autenticado (responde 401 si no lo hay)
+func (h *AuthHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return nil, false
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// respondTwoFactorError traduce los errores de la verificación en dos pasos a códigos HTTP
+func (h *AuthHandler) respondTwoFactorError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, services.ErrTwoFactorInvalidCode), errors.Is(err, services.ErrTwoFactorChallenge):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, services.ErrTwoFactorLocked):
+		statusCode = http.StatusTooManyRequests
+	case errors.Is(err, services.ErrUserDisabled), errors.Is(err, services.ErrTwoFactorEnforced),
+		errors.Is(err, services.ErrTwoFactorProvider):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, services.ErrTwoFactorNotStarted):
+		statusCode = http.StatusConflict
+	default:
+		h.logger.Errorf("Error en la verificación en dos pasos: %v", err)
+		c.JSON(statusCode, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	c.JSON(statusCode, gin.H{"error": err.Error()})
+}
+
+// loginUserResponse datos del usuario que se devuelven al iniciar sesión
+func loginUserResponse(user *models.User) gin.H {
+	return gin.H{
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     user.Role,
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// BaselineHandler manejador para las líneas base y la detección de anomalías
+type BaselineHandler struct {
+	service *services.BaselineService
+	logger  logger.Logger
+}
+
+// NewBaselineHandler crea un nuevo manejador de líneas base
+func NewBaselineHandler(service *services.BaselineService, log logger.Logger) *BaselineHandler {
+	return &BaselineHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de líneas base y anomalías
+func (h *BaselineHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	router.GET("/servers/:id/baselines", h.GetBaselines)
+	router.GET("/servers/:id/anomalies", h.GetAnomalies)
+	router.POST("/servers/:id/baselines/recompute", authMiddleware.RequireRole(models.RoleAdmin), h.RecomputeBaselines)
+}
+
+// GetBaselines obtiene las franjas aprendidas de un servidor (?metric= para filtrar una variable)
+func (h *BaselineHandler) GetBaselines(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !authorizeServer(c, uint(id), models.RoleViewer) {
+		return
+	}
+
+	baselines, err := h.service.GetBaselines(uint(id), c.Query("metric"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener líneas base"})
+		return
+	}
+
+	c.JSON(http.StatusOK, baselines)
+}
+
+// GetAnomalies obtiene las muestras anómalas de una variable en un rango de tiempo.
+// Parámetros: metric (obligatorio), start y end en RFC3339 (últimas 24 h por defecto), z (opcional)
+func (h *BaselineHandler) GetAnomalies(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !authorizeServer(c, uint(id), models.RoleViewer) {
+		return
+	}
+
+	metric := c.Query("metric")
+	if metric == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro metric es obligatorio"})
+		return
+	}
+
+	endTime := time.Now()
+	if endStr := c.Query("end"); endStr != "" {
+		if endTime, err = time.Parse(time.RFC3339, endStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de tiempo de fin inválido"})
+			return
+		}
+	}
+
+	startTime := endTime.Add(-24 * time.Hour)
+	if startStr := c.Query("start"); startStr != "" {
+		if startTime, err = time.Parse(time.RFC3339, startStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de tiempo de inicio inválido"})
+			return
+		}
+	}
+
+	var zScore float64
+	if zStr := c.Query("z"); zStr != "" {
+		if zScore, err = strconv.ParseFloat(zStr, 64); err != nil || zScore <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "z-score inválido"})
+			return
+		}
+	}
+
+	anomalies, err := h.service.DetectAnomalies(uint(id), metric, startTime, endTime, zScore)
+	if err != nil {
+		h.logger.Warnf("Error al detectar anomalías del servidor %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, anomalies)
+}
+
+// RecomputeBaselines recalcula inmediatamente las líneas base de un servidor
+func (h *BaselineHandler) RecomputeBaselines(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.RecomputeServer(uint(id), time.Now()); err != nil {
+		h.logger.Errorf("Error al recalcular líneas base del servidor %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al recalcular líneas base"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Líneas base recalculadas correctamente"})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// EscalationHandler manejador para las políticas de escalado
+type EscalationHandler struct {
+	service      *services.EscalationService
+	alertService *services.AlertService
+	logger       logger.Logger
+}
+
+// NewEscalationHandler crea un nuevo manejador de políticas de escalado
+func NewEscalationHandler(service *services.EscalationService, alertService *services.AlertService, log logger.Logger) *EscalationHandler {
+	return &EscalationHandler{
+		service:      service,
+		alertService: alertService,
+		logger:       log,
+	}
+}
+
+// RegisterRoutes registra las rutas de políticas de escalado
+func (h *EscalationHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	// Historial de escalado de una alerta (cualquier usuario que pueda ver su servidor)
+	router.GET("/alerts/:id/escalations", h.GetAlertEscalations)
+
+	// Políticas (todas requieren admin)
+	policies := router.Group("/escalation-policies")
+	policies.Use(authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		policies.GET("", h.GetAllPolicies)
+		policies.GET("/:id", h.GetPolicyByID)
+		policies.POST("", h.CreatePolicy)
+		policies.PUT("/:id", h.UpdatePolicy)
+		policies.DELETE("/:id", h.DeletePolicy)
+	}
+}
+
+// GetAllPolicies obtiene todas las políticas de escalado
+func (h *EscalationHandler) GetAllPolicies(c *gin.Context) {
+	policies, err := h.service.GetAllPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener políticas de escalado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetPolicyByID obtiene una política de escalado por su ID
+func (h *EscalationHandler) GetPolicyByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	policy, err := h.service.GetPolicy(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Política de escalado no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// CreatePolicy crea una nueva política de escalado
+func (h *EscalationHandler) CreatePolicy(c *gin.Context) {
+	var policy models.EscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	policy.ID = 0
+	policy.CreatedBy = userID
+	for i := range policy.Steps {
+		policy.Steps[i].ID = 0
+	}
+
+	if err := h.service.CreatePolicy(&policy); err != nil {
+		h.logger.Errorf("Error al crear política de escalado: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// UpdatePolicy actualiza una política de escalado existente
+func (h *EscalationHandler) UpdatePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var policy models.EscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	policy.ID = uint(id)
+
+	if err := h.service.UpdatePolicy(&policy); err != nil {
+		h.logger.Errorf("Error al actualizar política de escalado %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy elimina una política de escalado
+func (h *EscalationHandler) DeletePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.DeletePolicy(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar política de escalado %d: %v", id, err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Política de escalado eliminada correctamente"})
+}
+
+// GetAlertEscalations obtiene los pasos de escalado ejecutados sobre una alerta
+func (h *EscalationHandler) GetAlertEscalations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !authorizeAlert(c, h.alertService, uint(id), models.RoleViewer) {
+		return
+	}
+
+	escalations, err := h.service.GetAlertEscalations(uint(id))
+	if err != nil {
+		h.logger.Errorf("Error al obtener escalados de la alerta %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener escalados"})
+		return
+	}
+
+	c.JSON(http.StatusOK, escalations)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/forecast"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// ForecastHandler manejador para las previsiones de agotamiento de recursos
+type ForecastHandler struct {
+	service *services.ForecastService
+	logger  logger.Logger
+}
+
+// NewForecastHandler crea un nuevo manejador de previsiones
+func NewForecastHandler(service *services.ForecastService, log logger.Logger) *ForecastHandler {
+	return &ForecastHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de previsiones
+func (h *ForecastHandler) RegisterRoutes(router gin.IRouter) {
+	router.GET("/servers/:id/forecast", h.GetServerForecast)
+}
+
+// GetServerForecast estima cuándo se llenarán el disco y la memoria de un servidor.
+// Parámetros opcionales: window (duración del historial, p. ej. 6h; 24h por defecto y como
+// máximo 7 días) y method (linear u holt)
+func (h *ForecastHandler) GetServerForecast(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !authorizeServer(c, uint(id), models.RoleViewer) {
+		return
+	}
+
+	window := services.DefaultForecastWindow
+	if windowStr := c.Query("window"); windowStr != "" {
+		window, err = time.ParseDuration(windowStr)
+		if err != nil || window <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ventana inválida (ejemplo: 6h, 24h)"})
+			return
+		}
+	}
+
+	method := forecast.Method(c.DefaultQuery("method", string(forecast.MethodLinear)))
+	if method != forecast.MethodLinear && method != forecast.MethodHolt {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Método inválido (linear u holt)"})
+		return
+	}
+
+	result, err := h.service.GetServerForecast(uint(id), window, method)
+	if errors.Is(err, services.ErrForecastServerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Servidor no encontrado"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Error al calcular la previsión del servidor %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al calcular la previsión"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// IncidentHandler manejador para los incidentes que agrupan alertas relacionadas
+type IncidentHandler struct {
+	service *services.IncidentService
+	logger  logger.Logger
+}
+
+// NewIncidentHandler crea un nuevo manejador de incidentes
+func NewIncidentHandler(service *services.IncidentService, log logger.Logger) *IncidentHandler {
+	return &IncidentHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de incidentes
+func (h *IncidentHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	incidents := router.Group("/incidents")
+	{
+		// Rutas accesibles a todos los usuarios autenticados
+		incidents.GET("", h.GetIncidents)
+		incidents.GET("/:id", h.GetIncidentByID)
+
+		// Rutas para gestionar incidentes (requieren rol de admin o user, global o sobre los
+		// servidores de todas las alertas del incidente)
+		adminOrUser := incidents.Group("")
+		adminOrUser.Use(authMiddleware.RequireScopedRole(models.RoleUser))
+		{
+			adminOrUser.POST("/:id/acknowledge", h.AcknowledgeIncident)
+			adminOrUser.POST("/:id/resolve", h.ResolveIncident)
+			adminOrUser.POST("/:id/merge", h.MergeIncidents)
+			adminOrUser.POST("/:id/split", h.SplitIncident)
+		}
+	}
+}
+
+// GetIncidents obtiene los incidentes, con filtro opcional ?status=open|acknowledged|resolved|merged
+func (h *IncidentHandler) GetIncidents(c *gin.Context) {
+	status := models.IncidentStatus(c.Query("status"))
+	switch status {
+	case "", models.IncidentStatusOpen, models.IncidentStatusAcknowledged, models.IncidentStatusResolved, models.IncidentStatusMerged:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado de incidente inválido"})
+		return
+	}
+
+	// Limitar a los incidentes con alertas de servidores que el usuario puede ver
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+	serverIDs, restricted := scope.ServerIDs(models.RoleViewer)
+	if !restricted {
+		serverIDs = nil
+	}
+
+	incidents, err := h.service.GetIncidents(status, serverIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener incidentes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, incidents)
+}
+
+// GetIncidentByID obtiene un incidente con sus alertas
+func (h *IncidentHandler) GetIncidentByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	incident, err := h.service.GetIncident(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incidente no encontrado"})
+		return
+	}
+
+	// Solo se muestran las alertas de servidores que el usuario puede ver
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+	if scope.Restricted() {
+		visible := make([]models.Alert, 0, len(incident.Alerts))
+		for _, alert := range incident.Alerts {
+			if scope.CanView(alert.ServerID) {
+				visible = append(visible, alert)
+			}
+		}
+		if len(visible) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+			return
+		}
+		incident.Alerts = visible
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// authorizeIncident comprueba que el usuario tenga al menos minRole sobre todas las alertas de
+// un incidente. Si no, responde con el error correspondiente y devuelve false
+func (h *IncidentHandler) authorizeIncident(c *gin.Context, id uint, minRole models.Role) bool {
+	incident, err := h.service.GetIncident(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incidente no encontrado"})
+		return false
+	}
+
+	return authorizeIncident(c, incident, minRole)
+}
+
+// AcknowledgeIncident reconoce un incidente y sus alertas activas
+func (h *IncidentHandler) AcknowledgeIncident(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	if !h.authorizeIncident(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	if err := h.service.AcknowledgeIncident(uint(id), userID, input.Notes); err != nil {
+		h.logger.Errorf("Error al reconocer incidente %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Incidente reconocido correctamente"})
+}
+
+// ResolveIncident resuelve un incidente y sus alertas pendientes
+func (h *IncidentHandler) ResolveIncident(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var input struct {
+		Notes string `json:"notes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	if !h.authorizeIncident(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	if err := h.service.ResolveIncident(uint(id), userID, input.Notes); err != nil {
+		h.logger.Errorf("Error al resolver incidente %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Incidente resuelto correctamente"})
+}
+
+// MergeIncidents fusiona otros incidentes en el indicado
+func (h *IncidentHandler) MergeIncidents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var input struct {
+		IncidentIDs []uint `json:"incident_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	// Se necesita acceso a las alertas del incidente destino y de todos los fusionados
+	for _, incidentID := range append([]uint{uint(id)}, input.IncidentIDs...) {
+		if !h.authorizeIncident(c, incidentID, models.RoleUser) {
+			return
+		}
+	}
+
+	incident, err := h.service.MergeIncidents(uint(id), input.IncidentIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// SplitIncident separa alertas de un incidente en uno nuevo
+func (h *IncidentHandler) SplitIncident(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var input struct {
+		AlertIDs []uint `json:"alert_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	if !h.authorizeIncident(c, uint(id), models.RoleUser) {
+		return
+	}
+
+	incident, err := h.service.SplitIncident(uint(id), input.AlertIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, incident)
+}
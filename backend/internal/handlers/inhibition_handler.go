@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// InhibitionHandler manejador para las reglas de inhibición de alertas
+type InhibitionHandler struct {
+	service *services.InhibitionService
+	logger  logger.Logger
+}
+
+// NewInhibitionHandler crea un nuevo manejador de reglas de inhibición
+func NewInhibitionHandler(service *services.InhibitionService, log logger.Logger) *InhibitionHandler {
+	return &InhibitionHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de reglas de inhibición (todas requieren admin)
+func (h *InhibitionHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	rules := router.Group("/inhibition-rules")
+	rules.Use(authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		rules.GET("", h.GetAllRules)
+		rules.GET("/:id", h.GetRuleByID)
+		rules.GET("/:id/alerts", h.GetInhibitedAlerts)
+		rules.POST("", h.CreateRule)
+		rules.PUT("/:id", h.UpdateRule)
+		rules.DELETE("/:id", h.DeleteRule)
+	}
+}
+
+// GetAllRules obtiene todas las reglas de inhibición
+func (h *InhibitionHandler) GetAllRules(c *gin.Context) {
+	rules, err := h.service.GetAllRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener reglas de inhibición"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetRuleByID obtiene una regla de inhibición por su ID
+func (h *InhibitionHandler) GetRuleByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	rule, err := h.service.GetRule(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regla de inhibición no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// GetInhibitedAlerts obtiene las alertas inhibidas actualmente por una regla
+func (h *InhibitionHandler) GetInhibitedAlerts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	alerts, err := h.service.GetInhibitedAlerts(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener alertas inhibidas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// CreateRule crea una nueva regla de inhibición
+func (h *InhibitionHandler) CreateRule(c *gin.Context) {
+	var rule models.InhibitionRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	rule.ID = 0
+	rule.CreatedBy = userID
+
+	if err := h.service.CreateRule(&rule); err != nil {
+		h.logger.Errorf("Error al crear regla de inhibición: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule actualiza una regla de inhibición existente
+func (h *InhibitionHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var rule models.InhibitionRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	rule.ID = uint(id)
+
+	if err := h.service.UpdateRule(&rule); err != nil {
+		h.logger.Errorf("Error al actualizar regla de inhibición %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule elimina una regla de inhibición
+func (h *InhibitionHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.DeleteRule(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar regla de inhibición %d: %v", id, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Regla de inhibición eliminada correctamente"})
+}
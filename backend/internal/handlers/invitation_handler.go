@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// InvitationRequest datos para invitar a un usuario
+type InvitationRequest struct {
+	Email string      `json:"email" binding:"required,email"`
+	Role  models.Role `json:"role"` // VIEWER por defecto
+}
+
+// InvitationHandler manejador para las invitaciones de alta de usuarios (solo admin)
+type InvitationHandler struct {
+	service *services.InvitationService
+	logger  logger.Logger
+}
+
+// NewInvitationHandler crea un nuevo manejador de invitaciones
+func NewInvitationHandler(service *services.InvitationService, log logger.Logger) *InvitationHandler {
+	return &InvitationHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de invitaciones
+func (h *InvitationHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middleware.AuthMiddleware) {
+	invitations := router.Group("/api/invitations")
+	invitations.Use(authMiddleware.RequireAuth(), authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		invitations.GET("", h.GetInvitations)
+		invitations.POST("", h.CreateInvitation)
+		invitations.DELETE("/:id", h.RevokeInvitation)
+	}
+}
+
+// GetInvitations obtiene las invitaciones (?pending=true para ver solo las pendientes)
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	invitations, err := h.service.GetInvitations(c.Query("pending") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las invitaciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// CreateInvitation invita a un email con un rol. Si el correo no está configurado (o falla el
+// envío) se devuelve el enlace para que el admin se lo haga llegar al invitado
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere un email válido"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+
+	adminID, _ := middleware.GetUserID(c)
+	result, err := h.service.CreateInvitation(req.Email, req.Role, adminID)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if models.RoleRank(req.Role) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear la invitación"})
+		return
+	}
+
+	response := gin.H{
+		"invitation": result.Invitation,
+		"emailed":    result.Emailed,
+	}
+	if !result.Emailed {
+		response["invite_link"] = result.Link
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// RevokeInvitation anula una invitación pendiente
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de invitación inválido"})
+		return
+	}
+
+	if err := h.service.RevokeInvitation(uint(id)); err != nil {
+		if errors.Is(err, services.ErrInvitationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al anular la invitación"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitación anulada"})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
 	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
 	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
@@ -49,10 +50,18 @@ func (h *MetricHandler) RegisterRoutes(router gin.IRouter) {
 		
 		// Ruta para WebSocket de métricas en tiempo real
 		metrics.GET("/live/:server_id", h.HandleLiveMetrics)
-		
-		// Ruta para creación de métricas (normalmente solo desde agentes o sistemas, 
-		// pero lo dejamos protegido con autenticación básica)
-		metrics.POST("", h.CreateMetric)
+	}
+}
+
+// RegisterIngestRoutes registra la ruta de ingesta de métricas. Los agentes se autentican con
+// su certificado de cliente (TLS mutuo) y los usuarios con la cookie de sesión
+func (h *MetricHandler) RegisterIngestRoutes(router *gin.Engine, agentMiddleware *middleware.AgentMiddleware, authMiddleware *middleware.AuthMiddleware) {
+	ingest := router.Group("/api/metrics")
+	ingest.Use(agentMiddleware.Identify())
+	ingest.Use(middleware.UnlessAgent(authMiddleware.RequireAuth()))
+	ingest.Use(middleware.UnlessAgent(authMiddleware.LoadAccessScope()))
+	{
+		ingest.POST("", h.CreateMetric)
 	}
 }
 
@@ -72,6 +81,20 @@ func (h *MetricHandler) CreateMetric(c *gin.Context) {
 		return
 	}
 	
+	if agentServerID, ok := middleware.GetAgentServerID(c); ok {
+		// Un agente solo puede enviar métricas de su propio servidor
+		if metric.ServerID == 0 {
+			metric.ServerID = agentServerID
+		} else if metric.ServerID != agentServerID {
+			h.logger.Warnf("Métrica rechazada: el agente del servidor %d envió métricas del servidor %d", agentServerID, metric.ServerID)
+			c.JSON(http.StatusForbidden, gin.H{"error": "El certificado del agente no corresponde a ese servidor"})
+			return
+		}
+	} else if !authorizeServer(c, metric.ServerID, models.RoleViewer) {
+		// Solo se aceptan métricas de servidores visibles para el usuario
+		return
+	}
+	
 	// Verificar que el servidor existe
 	_, err := h.serverService.GetServerByID(metric.ServerID)
 	if err != nil {
@@ -99,6 +122,10 @@ func (h *MetricHandler) GetMetricsByServerID(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeServer(c, uint(serverID), models.RoleViewer) {
+		return
+	}
+	
 	// Parámetros de paginación con valores por defecto
 	limitStr := c.DefaultQuery("limit", "100")
 	offsetStr := c.DefaultQuery("offset", "0")
@@ -133,6 +160,10 @@ func (h *MetricHandler) GetLatestMetricByServerID(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeServer(c, uint(serverID), models.RoleViewer) {
+		return
+	}
+	
 	metric, err := h.metricService.GetLatestMetricByServerID(uint(serverID))
 	if err != nil {
 		h.logger.Errorf("Error al obtener última métrica: %v", err)
@@ -153,6 +184,10 @@ func (h *MetricHandler) GetMetricsByTimeRange(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeServer(c, uint(serverID), models.RoleViewer) {
+		return
+	}
+	
 	// Parámetros de rango de tiempo
 	startStr := c.Query("start")
 	endStr := c.Query("end")
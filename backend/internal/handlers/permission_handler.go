@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// PermissionHandler manejador para los permisos de los usuarios y equipos sobre grupos de servidores
+type PermissionHandler struct {
+	service *services.PermissionService
+	logger  logger.Logger
+}
+
+// NewPermissionHandler crea un nuevo manejador de permisos por grupo
+func NewPermissionHandler(service *services.PermissionService, log logger.Logger) *PermissionHandler {
+	return &PermissionHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de permisos por grupo
+func (h *PermissionHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	permissions := router.Group("/permissions")
+	{
+		// Permisos del propio usuario (cualquier usuario autenticado)
+		permissions.GET("/me", h.GetMyPermissions)
+
+		// Gestión de permisos (solo admin)
+		admin := permissions.Group("")
+		admin.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			admin.GET("", h.GetPermissions)
+			admin.POST("", h.CreatePermission)
+			admin.PUT("/:id", h.UpdatePermission)
+			admin.DELETE("/:id", h.DeletePermission)
+		}
+	}
+}
+
+// GetMyPermissions obtiene los permisos de grupo del usuario autenticado (propios y de sus
+// equipos) e indica si su acceso está limitado a esos grupos
+func (h *PermissionHandler) GetMyPermissions(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+
+	permissions, err := h.service.GetUserPermissions(scope.UserID, scope.TeamIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener permisos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":        scope.Role,
+		"restricted":  scope.Restricted(),
+		"team_ids":    scope.TeamIDs,
+		"permissions": permissions,
+	})
+}
+
+// GetPermissions obtiene los permisos de grupo (?user_id=, ?team_id= y ?group_id= para filtrar)
+func (h *PermissionHandler) GetPermissions(c *gin.Context) {
+	var userID, teamID, groupID *uint
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		id, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+			return
+		}
+		value := uint(id)
+		userID = &value
+	}
+
+	if teamIDStr := c.Query("team_id"); teamIDStr != "" {
+		id, err := strconv.ParseUint(teamIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de equipo inválido"})
+			return
+		}
+		value := uint(id)
+		teamID = &value
+	}
+
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		id, err := strconv.ParseUint(groupIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de grupo inválido"})
+			return
+		}
+		value := uint(id)
+		groupID = &value
+	}
+
+	permissions, err := h.service.GetPermissions(userID, teamID, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener permisos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// CreatePermission concede a un usuario o a un equipo un rol sobre un grupo
+func (h *PermissionHandler) CreatePermission(c *gin.Context) {
+	var permission models.GroupPermission
+	if err := c.ShouldBindJSON(&permission); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	permission.ID = 0
+	permission.CreatedBy = userID
+	permission.Source = ""
+	permission.User = nil
+	permission.Team = nil
+	permission.Group = nil
+
+	if err := h.service.CreatePermission(&permission); err != nil {
+		h.logger.Errorf("Error al crear permiso de grupo: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, permission)
+}
+
+// UpdatePermission cambia el rol de un permiso de grupo
+func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var input struct {
+		Role models.Role `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	permission, err := h.service.UpdatePermissionRole(uint(id), input.Role)
+	if err != nil {
+		h.logger.Errorf("Error al actualizar permiso de grupo %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, permission)
+}
+
+// DeletePermission retira un permiso de grupo
+func (h *PermissionHandler) DeletePermission(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.DeletePermission(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar permiso de grupo %d: %v", id, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Permiso eliminado correctamente"})
+}
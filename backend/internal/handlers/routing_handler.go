@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// RoutingHandler manejador para puntos de contacto y reglas de enrutamiento
+type RoutingHandler struct {
+	service *services.RoutingService
+	logger  logger.Logger
+}
+
+// NewRoutingHandler crea un nuevo manejador de enrutamiento de notificaciones
+func NewRoutingHandler(service *services.RoutingService, log logger.Logger) *RoutingHandler {
+	return &RoutingHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de puntos de contacto y reglas (todas requieren admin)
+func (h *RoutingHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	contactPoints := router.Group("/contact-points")
+	contactPoints.Use(authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		contactPoints.GET("", h.GetAllContactPoints)
+		contactPoints.GET("/:id", h.GetContactPointByID)
+		contactPoints.POST("", h.CreateContactPoint)
+		contactPoints.PUT("/:id", h.UpdateContactPoint)
+		contactPoints.DELETE("/:id", h.DeleteContactPoint)
+	}
+
+	routes := router.Group("/notification-routes")
+	routes.Use(authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		routes.GET("", h.GetAllRoutes)
+		routes.GET("/:id", h.GetRouteByID)
+		routes.POST("", h.CreateRoute)
+		routes.PUT("/:id", h.UpdateRoute)
+		routes.DELETE("/:id", h.DeleteRoute)
+	}
+}
+
+// GetAllContactPoints obtiene todos los puntos de contacto
+func (h *RoutingHandler) GetAllContactPoints(c *gin.Context) {
+	contactPoints, err := h.service.GetAllContactPoints()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener puntos de contacto"})
+		return
+	}
+
+	c.JSON(http.StatusOK, contactPoints)
+}
+
+// GetContactPointByID obtiene un punto de contacto por su ID
+func (h *RoutingHandler) GetContactPointByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	cp, err := h.service.GetContactPoint(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Punto de contacto no encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cp)
+}
+
+// CreateContactPoint crea un nuevo punto de contacto
+func (h *RoutingHandler) CreateContactPoint(c *gin.Context) {
+	var cp models.ContactPoint
+	if err := c.ShouldBindJSON(&cp); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	cp.ID = 0
+	cp.CreatedBy = userID
+
+	if err := h.service.CreateContactPoint(&cp); err != nil {
+		h.logger.Errorf("Error al crear punto de contacto: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cp)
+}
+
+// UpdateContactPoint actualiza un punto de contacto existente
+func (h *RoutingHandler) UpdateContactPoint(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var cp models.ContactPoint
+	if err := c.ShouldBindJSON(&cp); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	cp.ID = uint(id)
+
+	if err := h.service.UpdateContactPoint(&cp); err != nil {
+		h.logger.Errorf("Error al actualizar punto de contacto %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cp)
+}
+
+// DeleteContactPoint elimina un punto de contacto
+func (h *RoutingHandler) DeleteContactPoint(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.DeleteContactPoint(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar punto de contacto %d: %v", id, err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Punto de contacto eliminado correctamente"})
+}
+
+// GetAllRoutes obtiene todas las reglas de enrutamiento en orden de evaluación
+func (h *RoutingHandler) GetAllRoutes(c *gin.Context) {
+	routes, err := h.service.GetAllRoutes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener reglas de enrutamiento"})
+		return
+	}
+
+	c.JSON(http.StatusOK, routes)
+}
+
+// GetRouteByID obtiene una regla de enrutamiento por su ID
+func (h *RoutingHandler) GetRouteByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	route, err := h.service.GetRoute(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regla de enrutamiento no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, route)
+}
+
+// CreateRoute crea una nueva regla de enrutamiento
+func (h *RoutingHandler) CreateRoute(c *gin.Context) {
+	var route models.NotificationRoute
+	if err := c.ShouldBindJSON(&route); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	route.ID = 0
+	route.CreatedBy = userID
+
+	if err := h.service.CreateRoute(&route); err != nil {
+		h.logger.Errorf("Error al crear regla de enrutamiento: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, route)
+}
+
+// UpdateRoute actualiza una regla de enrutamiento existente
+func (h *RoutingHandler) UpdateRoute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var route models.NotificationRoute
+	if err := c.ShouldBindJSON(&route); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	route.ID = uint(id)
+
+	if err := h.service.UpdateRoute(&route); err != nil {
+		h.logger.Errorf("Error al actualizar regla de enrutamiento %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, route)
+}
+
+// DeleteRoute elimina una regla de enrutamiento
+func (h *RoutingHandler) DeleteRoute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.DeleteRoute(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar regla de enrutamiento %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Regla de enrutamiento eliminada correctamente"})
+}
@@ -35,9 +35,9 @@ func (h *ServerGroupHandler) RegisterRoutes(router gin.IRouter, authMiddleware *
 		groups.GET("/:id", h.GetGroupByID)
 		groups.GET("/:id/servers", h.GetServersInGroup)
 
-		// Rutas que requieren rol de admin o user
+		// Rutas que requieren rol de admin o user, global o sobre el grupo
 		adminOrUser := groups.Group("")
-		adminOrUser.Use(authMiddleware.RequireRole(models.RoleAdmin, models.RoleUser))
+		adminOrUser.Use(authMiddleware.RequireScopedRole(models.RoleUser))
 		{
 			adminOrUser.POST("", h.CreateGroup)
 			adminOrUser.PUT("/:id", h.UpdateGroup)
@@ -48,8 +48,13 @@ func (h *ServerGroupHandler) RegisterRoutes(router gin.IRouter, authMiddleware *
 	}
 }
 
-// GetAllGroups obtiene todos los grupos
+// GetAllGroups obtiene todos los grupos que el usuario puede ver
 func (h *ServerGroupHandler) GetAllGroups(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+
 	includeChildren := c.Query("include_children") == "true"
 	includeServers := c.Query("include_servers") == "true"
 
@@ -60,13 +65,31 @@ func (h *ServerGroupHandler) GetAllGroups(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, groups)
+	visible := make([]models.ServerGroup, 0, len(groups))
+	for _, group := range groups {
+		if scope.AllowsGroup(group.ID, models.RoleViewer) {
+			visible = append(visible, group)
+		}
+	}
+
+	c.JSON(http.StatusOK, visible)
 }
 
-// GetGroupTree obtiene el árbol jerárquico de grupos
+// GetGroupTree obtiene el árbol jerárquico de grupos. Para los usuarios con permisos de grupo
+// el árbol parte de los grupos visibles más altos
 func (h *ServerGroupHandler) GetGroupTree(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+
 	includeServers := c.Query("include_servers") == "true"
 
+	if scope.Restricted() {
+		h.getRestrictedGroupTree(c, scope, includeServers)
+		return
+	}
+
 	// Obtener solo los grupos raíz con sus hijos
 	groups, err := h.service.GetRootGroups(true, includeServers)
 	if err != nil {
@@ -78,6 +101,34 @@ func (h *ServerGroupHandler) GetGroupTree(c *gin.Context) {
 	c.JSON(http.StatusOK, groups)
 }
 
+// getRestrictedGroupTree responde con los subárboles de los grupos visibles cuyo padre no lo es
+func (h *ServerGroupHandler) getRestrictedGroupTree(c *gin.Context, scope *services.AccessScope, includeServers bool) {
+	groups, err := h.service.GetAllGroups(false, false)
+	if err != nil {
+		h.logger.Errorf("Error al obtener árbol de grupos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener árbol de grupos"})
+		return
+	}
+
+	var topIDs []uint
+	for _, group := range groups {
+		if !scope.AllowsGroup(group.ID, models.RoleViewer) {
+			continue
+		}
+		if group.ParentID == nil || !scope.AllowsGroup(*group.ParentID, models.RoleViewer) {
+			topIDs = append(topIDs, group.ID)
+		}
+	}
+
+	trees, err := h.service.GetSubtrees(topIDs, includeServers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener árbol de grupos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trees)
+}
+
 // GetGroupByID obtiene un grupo por su ID
 func (h *ServerGroupHandler) GetGroupByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -86,6 +137,10 @@ func (h *ServerGroupHandler) GetGroupByID(c *gin.Context) {
 		return
 	}
 
+	if !authorizeGroup(c, uint(id), models.RoleViewer) {
+		return
+	}
+
 	includeChildren := c.Query("include_children") == "true"
 	includeServers := c.Query("include_servers") == "true"
 
@@ -109,12 +164,17 @@ func (h *ServerGroupHandler) CreateGroup(c *gin.Context) {
 	}
 
 	// Obtener el ID del usuario del contexto
-	userID, exists := c.Get("userID")
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Usuario no identificado"})
 		return
 	}
-	group.CreatedBy = userID.(uint)
+	group.CreatedBy = userID
+
+	// Crear un subgrupo exige rol user sobre el padre; un grupo raíz, rol user global
+	if !h.authorizeParent(c, group.ParentID) {
+		return
+	}
 
 	if err := h.service.CreateGroup(&group); err != nil {
 		h.logger.Errorf("Error al crear grupo: %v", err)
@@ -142,6 +202,21 @@ func (h *ServerGroupHandler) UpdateGroup(c *gin.Context) {
 
 	group.ID = uint(id)
 
+	if !authorizeGroup(c, group.ID, models.RoleUser) {
+		return
+	}
+
+	// Mover el grupo exige además permiso sobre el nuevo padre
+	existing, err := h.service.GetGroup(group.ID, false, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Grupo no encontrado"})
+		return
+	}
+	if !sameParent(existing.ParentID, group.ParentID) && !h.authorizeParent(c, group.ParentID) {
+		return
+	}
+	group.CreatedBy = existing.CreatedBy
+
 	if err := h.service.UpdateGroup(&group); err != nil {
 		h.logger.Errorf("Error al actualizar grupo %d: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -159,6 +234,10 @@ func (h *ServerGroupHandler) DeleteGroup(c *gin.Context) {
 		return
 	}
 
+	if !authorizeGroup(c, uint(id), models.RoleUser) {
+		return
+	}
+
 	if err := h.service.DeleteGroup(uint(id)); err != nil {
 		h.logger.Errorf("Error al eliminar grupo %d: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -176,6 +255,10 @@ func (h *ServerGroupHandler) GetServersInGroup(c *gin.Context) {
 		return
 	}
 
+	if !authorizeGroup(c, uint(id), models.RoleViewer) {
+		return
+	}
+
 	group, err := h.service.GetGroup(uint(id), false, true)
 	if err != nil {
 		h.logger.Errorf("Error al obtener servidores del grupo %d: %v", id, err)
@@ -200,6 +283,10 @@ func (h *ServerGroupHandler) AddServerToGroup(c *gin.Context) {
 		return
 	}
 
+	if !authorizeGroup(c, uint(groupID), models.RoleUser) || !authorizeServer(c, uint(serverID), models.RoleUser) {
+		return
+	}
+
 	if err := h.service.AddServerToGroup(uint(groupID), uint(serverID)); err != nil {
 		h.logger.Errorf("Error al añadir servidor %d al grupo %d: %v", serverID, groupID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -223,6 +310,10 @@ func (h *ServerGroupHandler) RemoveServerFromGroup(c *gin.Context) {
 		return
 	}
 
+	if !authorizeGroup(c, uint(groupID), models.RoleUser) || !authorizeServer(c, uint(serverID), models.RoleUser) {
+		return
+	}
+
 	if err := h.service.RemoveServerFromGroup(uint(groupID), uint(serverID)); err != nil {
 		h.logger.Errorf("Error al eliminar servidor %d del grupo %d: %v", serverID, groupID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -231,3 +322,31 @@ func (h *ServerGroupHandler) RemoveServerFromGroup(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Servidor eliminado del grupo correctamente"})
 }
+
+// authorizeParent comprueba que el usuario pueda crear grupos bajo el padre indicado: rol user
+// sobre el padre o, si es un grupo raíz, rol user global. Si no, responde con 403
+func (h *ServerGroupHandler) authorizeParent(c *gin.Context, parentID *uint) bool {
+	if parentID != nil {
+		return authorizeGroup(c, *parentID, models.RoleUser)
+	}
+
+	scope, ok := accessScope(c)
+	if !ok {
+		return false
+	}
+
+	if !scope.AllowsGlobal(models.RoleUser) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+		return false
+	}
+
+	return true
+}
+
+// sameParent indica si dos referencias al grupo padre apuntan al mismo grupo
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
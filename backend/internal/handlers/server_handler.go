@@ -32,13 +32,9 @@ func (h *ServerHandler) RegisterRoutes(router gin.IRouter) {
 		servers.GET("", h.GetAllServers)
 		servers.GET("/:id", h.GetServerByID)
 		
-		// Rutas que requieren rol de admin o usuario normal (no viewer)
+		// Rutas que requieren rol de admin o usuario normal (no viewer) sobre el servidor,
+		// comprobado en cada manejador según los permisos de grupo del usuario
 		serverAdmin := servers.Group("")
-		serverAdmin.Use(func(c *gin.Context) {
-			// Aquí iría un middleware que verifica rol, pero esta 
-			// lógica ahora está en main.go con RequireRole
-			c.Next()
-		})
 		{
 			serverAdmin.POST("", h.CreateServer)
 			serverAdmin.PUT("/:id", h.UpdateServer)
@@ -47,8 +43,13 @@ func (h *ServerHandler) RegisterRoutes(router gin.IRouter) {
 	}
 }
 
-// GetAllServers obtiene todos los servidores
+// GetAllServers obtiene todos los servidores que el usuario puede ver
 func (h *ServerHandler) GetAllServers(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+
 	servers, err := h.serverService.GetAllServers()
 	if err != nil {
 		h.logger.Errorf("Error al obtener servidores: %v", err)
@@ -56,7 +57,14 @@ func (h *ServerHandler) GetAllServers(c *gin.Context) {
 		return
 	}
 	
-	c.JSON(http.StatusOK, servers)
+	visible := make([]models.Server, 0, len(servers))
+	for _, server := range servers {
+		if scope.CanView(server.ID) {
+			visible = append(visible, server)
+		}
+	}
+	
+	c.JSON(http.StatusOK, visible)
 }
 
 // GetServerByID obtiene un servidor por su ID
@@ -69,6 +77,10 @@ func (h *ServerHandler) GetServerByID(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeServer(c, uint(id), models.RoleViewer) {
+		return
+	}
+	
 	server, err := h.serverService.GetServerByID(uint(id))
 	if err != nil {
 		h.logger.Errorf("Error al obtener servidor: %v", err)
@@ -79,8 +91,19 @@ func (h *ServerHandler) GetServerByID(c *gin.Context) {
 	c.JSON(http.StatusOK, server)
 }
 
-// CreateServer crea un nuevo servidor
+// CreateServer crea un nuevo servidor. Los usuarios con permisos de grupo no pueden crearlos:
+// el servidor nuevo no pertenece a ninguno de sus grupos
 func (h *ServerHandler) CreateServer(c *gin.Context) {
+	scope, ok := accessScope(c)
+	if !ok {
+		return
+	}
+	
+	if !scope.AllowsGlobal(models.RoleUser) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+		return
+	}
+	
 	var server models.Server
 	
 	if err := c.ShouldBindJSON(&server); err != nil {
@@ -108,6 +131,10 @@ func (h *ServerHandler) UpdateServer(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeServer(c, uint(id), models.RoleUser) {
+		return
+	}
+	
 	// Primero verificar que el servidor existe
 	_, err = h.serverService.GetServerByID(uint(id))
 	if err != nil {
@@ -146,6 +173,10 @@ func (h *ServerHandler) DeleteServer(c *gin.Context) {
 		return
 	}
 	
+	if !authorizeServer(c, uint(id), models.RoleUser) {
+		return
+	}
+	
 	if err := h.serverService.DeleteServer(uint(id)); err != nil {
 		h.logger.Errorf("Error al eliminar servidor: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar servidor"})
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// SilenceHandler manejador para los silencios de alertas
+type SilenceHandler struct {
+	service *services.SilenceService
+	logger  logger.Logger
+}
+
+// NewSilenceHandler crea un nuevo manejador de silencios
+func NewSilenceHandler(service *services.SilenceService, log logger.Logger) *SilenceHandler {
+	return &SilenceHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de silencios
+func (h *SilenceHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	silences := router.Group("/silences")
+	{
+		// Rutas accesibles a todos los usuarios autenticados
+		silences.GET("", h.GetSilences)
+		silences.GET("/:id", h.GetSilenceByID)
+
+		// Rutas para gestionar silencios (requieren rol de admin o user, global o sobre los
+		// servidores y grupos del silencio)
+		adminOrUser := silences.Group("")
+		adminOrUser.Use(authMiddleware.RequireScopedRole(models.RoleUser))
+		{
+			adminOrUser.POST("", h.CreateSilence)
+			adminOrUser.PUT("/:id", h.UpdateSilence)
+			adminOrUser.POST("/:id/expire", h.ExpireSilence)
+			adminOrUser.DELETE("/:id", h.DeleteSilence)
+		}
+	}
+}
+
+// GetSilences obtiene los silencios, con filtro opcional ?state=pending|active|expired
+func (h *SilenceHandler) GetSilences(c *gin.Context) {
+	state := models.SilenceState(c.Query("state"))
+	switch state {
+	case "", models.SilenceStatePending, models.SilenceStateActive, models.SilenceStateExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado de silencio inválido"})
+		return
+	}
+
+	silences, err := h.service.GetSilences(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener silencios"})
+		return
+	}
+
+	c.JSON(http.StatusOK, silences)
+}
+
+// GetSilenceByID obtiene un silencio por su ID
+func (h *SilenceHandler) GetSilenceByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	silence, err := h.service.GetSilence(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Silencio no encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+// CreateSilence crea un nuevo silencio
+func (h *SilenceHandler) CreateSilence(c *gin.Context) {
+	var silence models.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	silence.ID = 0
+	silence.CreatedBy = userID
+
+	if !authorizeSilence(c, &silence, models.RoleUser) {
+		return
+	}
+
+	if err := h.service.CreateSilence(&silence); err != nil {
+		h.logger.Errorf("Error al crear silencio: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, silence)
+}
+
+// UpdateSilence actualiza un silencio existente
+func (h *SilenceHandler) UpdateSilence(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var silence models.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	silence.ID = uint(id)
+
+	// Hay que poder gestionar tanto lo que silenciaba como lo que va a silenciar
+	if !h.authorizeSilence(c, uint(id)) || !authorizeSilence(c, &silence, models.RoleUser) {
+		return
+	}
+
+	if err := h.service.UpdateSilence(&silence); err != nil {
+		h.logger.Errorf("Error al actualizar silencio %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+// ExpireSilence finaliza un silencio inmediatamente
+func (h *SilenceHandler) ExpireSilence(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeSilence(c, uint(id)) {
+		return
+	}
+
+	silence, err := h.service.ExpireSilence(uint(id))
+	if err != nil {
+		h.logger.Errorf("Error al expirar silencio %d: %v", id, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+// DeleteSilence elimina un silencio
+func (h *SilenceHandler) DeleteSilence(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if !h.authorizeSilence(c, uint(id)) {
+		return
+	}
+
+	if err := h.service.DeleteSilence(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar silencio %d: %v", id, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Silencio eliminado correctamente"})
+}
+
+// authorizeSilence comprueba que el usuario pueda gestionar un silencio existente. Si no,
+// responde con el error correspondiente y devuelve false
+func (h *SilenceHandler) authorizeSilence(c *gin.Context, id uint) bool {
+	silence, err := h.service.GetSilence(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Silencio no encontrado"})
+		return false
+	}
+
+	return authorizeSilence(c, silence, models.RoleUser)
+}
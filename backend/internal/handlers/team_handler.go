@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// TeamHandler manejador para las rutas de equipos y sus miembros
+type TeamHandler struct {
+	service *services.TeamService
+	logger  logger.Logger
+}
+
+// NewTeamHandler crea un nuevo manejador de equipos
+func NewTeamHandler(service *services.TeamService, log logger.Logger) *TeamHandler {
+	return &TeamHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de equipos
+func (h *TeamHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	teams := router.Group("/teams")
+	{
+		// Rutas accesibles a todos los usuarios autenticados
+		teams.GET("", h.GetTeams)
+		teams.GET("/mine", h.GetMyTeams)
+		teams.GET("/:id", h.GetTeam)
+
+		// Crear equipos requiere rol de admin o user; el creador pasa a administrar el equipo
+		teams.POST("", authMiddleware.RequireRole(models.RoleAdmin, models.RoleUser), h.CreateTeam)
+
+		// Rutas que requieren ser administrador del equipo (o admin global)
+		teams.PUT("/:id", h.UpdateTeam)
+		teams.DELETE("/:id", h.DeleteTeam)
+		teams.POST("/:id/members", h.AddMember)
+		teams.PUT("/:id/members/:user_id", h.UpdateMember)
+		teams.DELETE("/:id/members/:user_id", h.RemoveMember)
+	}
+}
+
+// GetTeams obtiene todos los equipos
+func (h *TeamHandler) GetTeams(c *gin.Context) {
+	teams, err := h.service.GetTeams()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener equipos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, teams)
+}
+
+// GetMyTeams obtiene los equipos del usuario autenticado
+func (h *TeamHandler) GetMyTeams(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+
+	teams, err := h.service.GetTeamsForUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener equipos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, teams)
+}
+
+// GetTeam obtiene un equipo con sus miembros
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	team, err := h.service.GetTeam(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, team)
+}
+
+// CreateTeam crea un nuevo equipo
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var team models.Team
+	if err := c.ShouldBindJSON(&team); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	team.ID = 0
+	team.CreatedBy = userID
+
+	if err := h.service.CreateTeam(&team); err != nil {
+		h.logger.Errorf("Error al crear equipo: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.service.GetTeam(team.ID)
+	if err != nil {
+		c.JSON(http.StatusCreated, team)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateTeam actualiza el nombre y la descripción de un equipo
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	id, ok := h.authorizeTeamAdmin(c)
+	if !ok {
+		return
+	}
+
+	var team models.Team
+	if err := c.ShouldBindJSON(&team); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	team.ID = id
+
+	if err := h.service.UpdateTeam(&team); err != nil {
+		h.logger.Errorf("Error al actualizar equipo %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.service.GetTeam(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el equipo actualizado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteTeam elimina un equipo
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	id, ok := h.authorizeTeamAdmin(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTeam(id); err != nil {
+		h.logger.Errorf("Error al eliminar equipo %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Equipo eliminado correctamente"})
+}
+
+// AddMember añade un usuario al equipo
+func (h *TeamHandler) AddMember(c *gin.Context) {
+	id, ok := h.authorizeTeamAdmin(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID uint            `json:"user_id" binding:"required"`
+		Role   models.TeamRole `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	member, err := h.service.AddMember(id, input.UserID, input.Role)
+	if err != nil {
+		h.logger.Errorf("Error al añadir miembro al equipo %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateMember cambia el rol de un miembro del equipo
+func (h *TeamHandler) UpdateMember(c *gin.Context) {
+	id, ok := h.authorizeTeamAdmin(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	var input struct {
+		Role models.TeamRole `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	member, err := h.service.UpdateMemberRole(id, uint(userID), input.Role)
+	if err != nil {
+		h.logger.Errorf("Error al actualizar miembro %d del equipo %d: %v", userID, id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember retira a un usuario del equipo
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	id, ok := h.authorizeTeamAdmin(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	if err := h.service.RemoveMember(id, uint(userID)); err != nil {
+		h.logger.Errorf("Error al retirar al usuario %d del equipo %d: %v", userID, id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Miembro retirado correctamente"})
+}
+
+// authorizeTeamAdmin obtiene el ID del equipo de la ruta y comprueba que el usuario sea
+// administrador global o administrador del equipo. Si no, responde con el error y devuelve false
+func (h *TeamHandler) authorizeTeamAdmin(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return 0, false
+	}
+
+	if _, err := h.service.GetTeam(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return 0, false
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return 0, false
+	}
+
+	if role, _ := middleware.GetUserRole(c); role == models.RoleAdmin {
+		return uint(id), true
+	}
+
+	isAdmin, err := h.service.IsTeamAdmin(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return 0, false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Se requiere ser administrador del equipo"})
+		return 0, false
+	}
+
+	return uint(id), true
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// TemplateHandler manejador para las plantillas de notificación
+type TemplateHandler struct {
+	service *services.TemplateService
+	logger  logger.Logger
+}
+
+// NewTemplateHandler crea un nuevo manejador de plantillas de notificación
+func NewTemplateHandler(service *services.TemplateService, log logger.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		service: service,
+		logger:  log,
+	}
+}
+
+// RegisterRoutes registra las rutas de plantillas (todas requieren admin)
+func (h *TemplateHandler) RegisterRoutes(router gin.IRouter, authMiddleware *middleware.AuthMiddleware) {
+	templates := router.Group("/notification-templates")
+	templates.Use(authMiddleware.RequireRole(models.RoleAdmin))
+	{
+		templates.GET("", h.GetAllTemplates)
+		templates.GET("/:id", h.GetTemplateByID)
+		templates.POST("", h.CreateTemplate)
+		templates.PUT("/:id", h.UpdateTemplate)
+		templates.DELETE("/:id", h.DeleteTemplate)
+		templates.POST("/preview", h.PreviewTemplate)
+		templates.GET("/:id/preview", h.PreviewStoredTemplate)
+	}
+}
+
+// GetAllTemplates obtiene todas las plantillas
+func (h *TemplateHandler) GetAllTemplates(c *gin.Context) {
+	templates, err := h.service.GetAllTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener plantillas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplateByID obtiene una plantilla por su ID
+func (h *TemplateHandler) GetTemplateByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	tmpl, err := h.service.GetTemplateByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// CreateTemplate crea una nueva plantilla
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var tmpl models.NotificationTemplate
+	if err := c.ShouldBindJSON(&tmpl); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+		return
+	}
+	tmpl.ID = 0
+	tmpl.CreatedBy = userID
+
+	if err := h.service.CreateTemplate(&tmpl); err != nil {
+		h.logger.Errorf("Error al crear plantilla: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tmpl)
+}
+
+// UpdateTemplate actualiza una plantilla existente
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var tmpl models.NotificationTemplate
+	if err := c.ShouldBindJSON(&tmpl); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	tmpl.ID = uint(id)
+
+	if err := h.service.UpdateTemplate(&tmpl); err != nil {
+		h.logger.Errorf("Error al actualizar plantilla %d: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// DeleteTemplate elimina una plantilla
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.service.DeleteTemplate(uint(id)); err != nil {
+		h.logger.Errorf("Error al eliminar plantilla %d: %v", id, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Plantilla eliminada correctamente"})
+}
+
+// PreviewTemplate renderiza una plantilla enviada en el cuerpo contra una alerta de ejemplo
+func (h *TemplateHandler) PreviewTemplate(c *gin.Context) {
+	var tmpl models.NotificationTemplate
+	if err := c.ShouldBindJSON(&tmpl); err != nil {
+		h.logger.Warnf("Error en binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	if tmpl.State == "" {
+		tmpl.State = models.TemplateStateFiring
+	}
+
+	rendered, err := h.service.Preview(&tmpl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channel": tmpl.Channel,
+		"state":   tmpl.State,
+		"title":   rendered.Title,
+		"body":    rendered.Body,
+	})
+}
+
+// PreviewStoredTemplate renderiza una plantilla almacenada contra una alerta de ejemplo
+func (h *TemplateHandler) PreviewStoredTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	tmpl, err := h.service.GetTemplateByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada"})
+		return
+	}
+
+	rendered, err := h.service.Preview(tmpl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channel": tmpl.Channel,
+		"state":   tmpl.State,
+		"title":   rendered.Title,
+		"body":    rendered.Body,
+	})
+}
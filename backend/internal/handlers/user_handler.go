@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -15,14 +16,20 @@ import (
 type UserRequest struct {
 	Username string      `json:"username" binding:"required,min=3,max=50"`
 	Email    string      `json:"email" binding:"required,email"`
-	Password string      `json:"password"` // No requerido en actualizaciones
+	Password string      `json:"password"` // No requerido en actualizaciones ni para usuarios LDAP
 	Role     models.Role `json:"role"`
+	
+	// Proveedor de autenticación: "local" (contraseña propia) o "ldap" (directorio)
+	AuthProvider string `json:"auth_provider"`
 }
 
 // UserHandler maneja las rutas relacionadas con usuarios
 type UserHandler struct {
-	userService *services.UserService
-	logger      logger.Logger
+	userService      *services.UserService
+	twoFactorService *services.TwoFactorService
+	loginProtection  *services.LoginProtectionService
+	passwordReset    *services.PasswordResetService
+	logger           logger.Logger
 }
 
 // NewUserHandler crea una nueva instancia del manejador de usuarios
@@ -33,6 +40,21 @@ func NewUserHandler(userService *services.UserService, logger logger.Logger) *Us
 	}
 }
 
+// SetTwoFactorService permite restablecer la verificación en dos pasos de los usuarios
+func (h *UserHandler) SetTwoFactorService(twoFactorService *services.TwoFactorService) {
+	h.twoFactorService = twoFactorService
+}
+
+// SetLoginProtection permite consultar y levantar los bloqueos del login
+func (h *UserHandler) SetLoginProtection(loginProtection *services.LoginProtectionService) {
+	h.loginProtection = loginProtection
+}
+
+// SetPasswordResetService permite generar enlaces para restablecer la contraseña
+func (h *UserHandler) SetPasswordResetService(passwordReset *services.PasswordResetService) {
+	h.passwordReset = passwordReset
+}
+
 // RegisterRoutes registra las rutas del manejador en el router
 func (h *UserHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middleware.AuthMiddleware) {
 	users := router.Group("/api/users")
@@ -48,6 +70,23 @@ func (h *UserHandler) RegisterRoutes(router *gin.Engine, authMiddleware *middlew
 		users.POST("", h.CreateUser)
 		users.PUT("/:id", h.UpdateUser)
 		users.DELETE("/:id", h.DeleteUser)
+		
+		// Restablecer la verificación en dos pasos (p. ej. si el usuario perdió su dispositivo)
+		if h.twoFactorService != nil {
+			users.DELETE("/:id/2fa", h.ResetTwoFactor)
+		}
+		
+		// Bloqueos del login por intentos fallidos
+		if h.loginProtection != nil {
+			users.GET("/lockouts", h.GetLockouts)
+			users.DELETE("/lockouts/:id", h.DeleteLockout)
+			users.POST("/:id/unlock", h.UnlockUser)
+		}
+		
+		// Enlace para restablecer la contraseña (se muestra al admin para que se lo haga llegar)
+		if h.passwordReset != nil {
+			users.POST("/:id/password-reset", h.IssuePasswordReset)
+		}
 	}
 }
 
@@ -64,12 +103,13 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	var response []gin.H
 	for _, user := range users {
 		response = append(response, gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"email":      user.Email,
-			"role":       user.Role,
-			"last_login": user.LastLogin,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"role":          user.Role,
+			"auth_provider": user.AuthProvider,
+			"last_login":    user.LastLogin,
+			"created_at":    user.CreatedAt,
 		})
 	}
 	
@@ -93,13 +133,14 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"role":       user.Role,
-		"last_login": user.LastLogin,
-		"created_at": user.CreatedAt,
-		"updated_at": user.UpdatedAt,
+		"id":            user.ID,
+		"username":      user.Username,
+		"email":         user.Email,
+		"role":          user.Role,
+		"auth_provider": user.AuthProvider,
+		"last_login":    user.LastLogin,
+		"created_at":    user.CreatedAt,
+		"updated_at":    user.UpdatedAt,
 	})
 }
 
@@ -113,8 +154,13 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 	
-	// Verificar que hay contraseña para nuevos usuarios
-	if req.Password == "" {
+	if !validAuthProvider(req.AuthProvider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Proveedor de autenticación inválido (local o ldap)"})
+		return
+	}
+	
+	// Verificar que hay contraseña para nuevos usuarios locales
+	if req.Password == "" && req.AuthProvider != models.AuthProviderLDAP {
 		h.logger.Warn("Intento de crear usuario sin contraseña")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "La contraseña es obligatoria para nuevos usuarios"})
 		return
@@ -126,12 +172,17 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	}
 	
 	user := &models.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Role:     req.Role,
+		Username:     req.Username,
+		Email:        req.Email,
+		Role:         req.Role,
+		AuthProvider: req.AuthProvider,
 	}
 	
 	if err := h.userService.CreateUser(user, req.Password); err != nil {
+		if errors.Is(err, services.ErrPasswordPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Errorf("Error al crear usuario: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -172,12 +223,34 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 	
+	if !validAuthProvider(req.AuthProvider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Proveedor de autenticación inválido (local o ldap)"})
+		return
+	}
+	
+	// Pasar a contraseña local exige fijar una contraseña
+	if req.AuthProvider == models.AuthProviderLocal && !existingUser.IsLocal() && req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La contraseña es obligatoria para pasar el usuario a autenticación local"})
+		return
+	}
+	
+	// Comprobar la nueva contraseña antes de guardar ningún cambio
+	if req.Password != "" {
+		if err := h.userService.ValidatePassword(req.Password, req.Username); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	
 	// Actualizar campos del usuario
 	existingUser.Username = req.Username
 	existingUser.Email = req.Email
 	if req.Role != "" {
 		existingUser.Role = req.Role
 	}
+	if req.AuthProvider != "" {
+		existingUser.AuthProvider = req.AuthProvider
+	}
 	
 	// Actualizar el usuario
 	if err := h.userService.UpdateUser(existingUser); err != nil {
@@ -238,4 +311,128 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Usuario eliminado exitosamente",
 	})
-} 
\ No newline at end of file
+} 
+
+// ResetTwoFactor elimina la verificación en dos pasos de un usuario y cierra sus sesiones. Si es
+// obligatoria para su rol, deberá configurarla de nuevo en su próximo login
+func (h *UserHandler) ResetTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+	
+	if err := h.twoFactorService.Reset(uint(id)); err != nil {
+		switch err {
+		case services.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		case services.ErrTwoFactorNotEnabled:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al restablecer la verificación en dos pasos"})
+		}
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Verificación en dos pasos restablecida",
+	})
+}
+
+// GetLockouts obtiene los bloqueos vigentes del login (usuarios e IPs)
+func (h *UserHandler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.loginProtection.GetLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener los bloqueos"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// DeleteLockout levanta un bloqueo del login (de un usuario o de una IP)
+func (h *UserHandler) DeleteLockout(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de bloqueo inválido"})
+		return
+	}
+	
+	adminID, _ := middleware.GetUserID(c)
+	if err := h.loginProtection.Unlock(uint(id), adminID); err != nil {
+		if err == services.ErrLoginThrottleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al levantar el bloqueo"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bloqueo levantado",
+	})
+}
+
+// UnlockUser levanta el bloqueo del login de un usuario
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+	
+	user, err := h.userService.GetUserByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+	
+	adminID, _ := middleware.GetUserID(c)
+	if err := h.loginProtection.UnlockUser(user.Username, adminID); err != nil {
+		if err == services.ErrLoginThrottleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "El usuario no tiene intentos fallidos registrados"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al desbloquear el usuario"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Usuario desbloqueado",
+	})
+}
+
+// IssuePasswordReset genera un enlace de un solo uso para que el usuario elija una nueva
+// contraseña
+func (h *UserHandler) IssuePasswordReset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+	
+	link, expiresAt, err := h.passwordReset.IssueResetLink(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrResetNotLocal):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		default:
+			h.logger.Errorf("Error al generar el enlace de restablecimiento: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al generar el enlace"})
+		}
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"reset_link": link,
+		"expires_at": expiresAt,
+	})
+}
+
+// validAuthProvider indica si el proveedor puede asignarse a mano (los usuarios OIDC se crean
+// al iniciar sesión con el proveedor de identidad)
+func validAuthProvider(provider string) bool {
+	return provider == "" || provider == models.AuthProviderLocal || provider == models.AuthProviderLDAP
+}
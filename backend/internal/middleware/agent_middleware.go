@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// AgentServerIDKey clave de contexto con el servidor identificado por el certificado del agente
+const AgentServerIDKey = "agent_server_id"
+
+// AgentMiddleware autentica a los agentes por su certificado de cliente (TLS mutuo). El
+// certificado ya se ha verificado en el handshake (CA y CRL); aquí se asocia a un servidor
+type AgentMiddleware struct {
+	serverService *services.ServerService
+	required      bool
+	logger        logger.Logger
+}
+
+// NewAgentMiddleware crea el middleware de agentes. Con required las rutas de ingesta solo
+// aceptan peticiones con certificado de agente
+func NewAgentMiddleware(serverService *services.ServerService, required bool, log logger.Logger) *AgentMiddleware {
+	return &AgentMiddleware{
+		serverService: serverService,
+		required:      required,
+		logger:        log,
+	}
+}
+
+// Identify asocia el certificado de cliente al servidor cuyo hostname coincide con su nombre
+// común o sus SAN DNS (o, si no, con el único servidor registrado con una de sus IP). Sin
+// certificado la petición sigue con la autenticación de usuario, salvo que sea obligatorio
+func (m *AgentMiddleware) Identify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cert := clientCertificate(c)
+		if cert == nil {
+			if m.required {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Se requiere un certificado de agente"})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		serverID, ok := m.resolveServer(cert)
+		if !ok {
+			m.logger.Warnf("Certificado de agente %q (serie %s) sin servidor registrado", cert.Subject.CommonName, cert.SerialNumber.Text(16))
+			c.JSON(http.StatusForbidden, gin.H{"error": "El certificado no corresponde a ningún servidor registrado"})
+			c.Abort()
+			return
+		}
+
+		c.Set(AgentServerIDKey, serverID)
+		c.Next()
+	}
+}
+
+// UnlessAgent ejecuta handler solo en las peticiones no autenticadas con certificado de agente
+// (p. ej. la autenticación por cookie en las rutas de ingesta)
+func UnlessAgent(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetAgentServerID(c); ok {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+// GetAgentServerID obtiene el servidor del agente autenticado con certificado
+func GetAgentServerID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get(AgentServerIDKey)
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := value.(uint)
+	return id, ok
+}
+
+// resolveServer busca el servidor del certificado: primero por hostname y luego por IP
+func (m *AgentMiddleware) resolveServer(cert *x509.Certificate) (uint, bool) {
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if server, err := m.serverService.GetServerByHostname(name); err == nil {
+			return server.ID, true
+		}
+	}
+
+	// Una IP compartida por varios servidores no identifica a ninguno
+	for _, ip := range cert.IPAddresses {
+		servers, err := m.serverService.GetServersByIP(ip.String())
+		if err == nil && len(servers) == 1 {
+			return servers[0].ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// clientCertificate certificado de cliente verificado en el handshake TLS (nil sin TLS o sin
+// certificado)
+func clientCertificate(c *gin.Context) *x509.Certificate {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
@@ -0,0 +1,334 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// Claves de contexto de la auditoría
+const (
+	AuditActorIDKey   = "audit_actor_id"
+	AuditActorNameKey = "audit_actor_name"
+	AuditOutcomeKey   = "audit_outcome"
+	AuditDetailsKey   = "audit_details"
+)
+
+// auditBodyLimit tamaño máximo de las peticiones y respuestas que se leen para la auditoría
+const auditBodyLimit = 64 << 10
+
+// auditSkippedRoutes rutas que modifican datos pero no son acciones de configuración ni de
+// seguridad (ingesta de métricas y alertas externas, simulaciones, renovación de la sesión)
+var auditSkippedRoutes = map[string]bool{
+	"POST /api/metrics":                        true,
+	"POST /api/v2/alerts":                      true,
+	"POST /api/auth/refresh":                   true,
+	"POST /api/alert-thresholds/backtest":      true,
+	"POST /api/notification-templates/preview": true,
+}
+
+// auditedReads rutas GET que se auditan porque abren una sesión
+var auditedReads = map[string]bool{
+	"GET /api/auth/oidc/callback": true,
+}
+
+// auditSubCollections subrecursos que se crean con POST /recurso/:id/<subrecurso>
+var auditSubCollections = map[string]bool{
+	"members":  true,
+	"comments": true,
+}
+
+// AuditMiddleware registra en la auditoría las peticiones que modifican la configuración o
+// afectan a la seguridad
+type AuditMiddleware struct {
+	auditService *services.AuditService
+	logger       logger.Logger
+}
+
+// NewAuditMiddleware crea el middleware de auditoría
+func NewAuditMiddleware(auditService *services.AuditService, log logger.Logger) *AuditMiddleware {
+	return &AuditMiddleware{
+		auditService: auditService,
+		logger:       log,
+	}
+}
+
+// SetAuditActor indica el actor de una petición sin sesión (p. ej. el usuario que intenta
+// iniciar sesión). Con userID 0 solo se guarda el nombre
+func SetAuditActor(c *gin.Context, userID uint, name string) {
+	if userID != 0 {
+		c.Set(AuditActorIDKey, userID)
+	}
+	if name != "" {
+		c.Set(AuditActorNameKey, name)
+	}
+}
+
+// SetAuditOutcome fija el resultado de una petición cuyo estado HTTP no lo refleja (p. ej. un
+// inicio de sesión único rechazado que responde con una redirección)
+func SetAuditOutcome(c *gin.Context, outcome string) {
+	c.Set(AuditOutcomeKey, outcome)
+}
+
+// SetAuditDetail añade un dato a la entrada de auditoría de la petición
+func SetAuditDetail(c *gin.Context, key string, value interface{}) {
+	details, _ := c.Get(AuditDetailsKey)
+	metadata, ok := details.(models.Metadata)
+	if !ok {
+		metadata = models.Metadata{}
+		c.Set(AuditDetailsKey, metadata)
+	}
+	metadata[key] = value
+}
+
+// Record middleware global que audita las peticiones POST, PUT, PATCH y DELETE de la API.
+// La acción y el recurso se obtienen de la ruta; para los recursos conocidos se guarda qué
+// campos cambian comparando su estado antes y después de la petición. Se registran las
+// acciones completadas y las rechazadas por autenticación, permisos o bloqueo
+func (m *AuditMiddleware) Record() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		route := c.FullPath()
+		if !auditedRoute(method, route) {
+			c.Next()
+			return
+		}
+
+		action, resourceType, idParam := auditAction(method, route)
+		resourceID := ""
+		if idParam != "" {
+			resourceID = c.Param(idParam)
+		}
+
+		tracked := m.auditService.Tracks(resourceType)
+		var before models.Metadata
+		if tracked && resourceID != "" {
+			before = m.auditService.Snapshot(resourceType, resourceID)
+		}
+
+		request := readAuditRequest(c)
+
+		// En las altas el ID del recurso se obtiene de la respuesta
+		var response *auditResponseWriter
+		if tracked && resourceID == "" && method == http.MethodPost {
+			response = &auditResponseWriter{ResponseWriter: c.Writer}
+			c.Writer = response
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		outcome := auditOutcome(status)
+		if value, ok := c.Get(AuditOutcomeKey); ok {
+			outcome, _ = value.(string)
+		}
+		if outcome == "" {
+			return
+		}
+
+		entry := &models.AuditEntry{
+			Action:       action,
+			ResourceType: resourceType,
+			Method:       method,
+			Route:        route,
+			Status:       status,
+			Outcome:      outcome,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+		}
+
+		if outcome == models.AuditOutcomeSuccess && tracked {
+			if response != nil {
+				resourceID = createdResourceID(response.body.Bytes())
+			}
+			var after models.Metadata
+			if resourceID != "" {
+				after = m.auditService.Snapshot(resourceType, resourceID)
+			}
+			entry.Changes = services.AuditDiff(before, after)
+		}
+		entry.ResourceID = resourceID
+
+		// Datos añadidos por el manejador y parámetros de la ruta distintos del ID
+		details := models.Metadata{}
+		if value, ok := c.Get(AuditDetailsKey); ok {
+			if extra, ok := value.(models.Metadata); ok {
+				for key, item := range extra {
+					details[key] = item
+				}
+			}
+		}
+		for _, param := range c.Params {
+			if param.Key != idParam {
+				details[param.Key] = param.Value
+			}
+		}
+		if query := c.Request.URL.RawQuery; query != "" && method != http.MethodGet {
+			details["query"] = query
+		}
+		// Sin cambios que comparar se guarda la petición (sin secretos) para saber qué se pidió
+		if entry.Changes == nil && request != nil {
+			details["request"] = request
+		}
+		if len(details) > 0 {
+			entry.Details = details
+		}
+
+		// Actor: el usuario autenticado o el indicado por el manejador
+		if id, ok := c.Get(AuditActorIDKey); ok {
+			if actorID, ok := id.(uint); ok {
+				entry.ActorID = &actorID
+			}
+		} else if userID, ok := GetUserID(c); ok {
+			entry.ActorID = &userID
+		}
+		if name, ok := c.Get(AuditActorNameKey); ok {
+			entry.ActorName, _ = name.(string)
+		}
+
+		m.auditService.Record(entry)
+	}
+}
+
+// auditedRoute indica si una petición se audita
+func auditedRoute(method, route string) bool {
+	if !strings.HasPrefix(route, "/api/") {
+		return false
+	}
+
+	key := method + " " + route
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return !auditSkippedRoutes[key]
+	default:
+		return auditedReads[key]
+	}
+}
+
+// auditAction obtiene de la ruta la acción (servers.update, alerts.resolve, auth.login...), el
+// tipo de recurso (tramos anteriores al primer parámetro) y el parámetro con su ID
+func auditAction(method, route string) (action, resourceType, idParam string) {
+	segments := strings.Split(strings.TrimPrefix(route, "/api/"), "/")
+
+	var static []string
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			if idParam == "" {
+				idParam = segment[1:]
+				resourceType = strings.Join(static, "/")
+			}
+			continue
+		}
+		static = append(static, segment)
+	}
+	if idParam == "" {
+		resourceType = static[0]
+	}
+
+	// Las operaciones sobre un recurso (/:id/resolve, /login) se nombran por su último tramo; el
+	// resto por el verbo del método
+	last := segments[len(segments)-1]
+	endsWithParam := strings.HasPrefix(last, ":")
+
+	action = strings.Join(static, ".")
+	switch method {
+	case http.MethodPut, http.MethodPatch:
+		action += ".update"
+	case http.MethodDelete:
+		action += ".delete"
+	case http.MethodPost:
+		if endsWithParam || len(static) == 1 || auditSubCollections[last] {
+			action += ".create"
+		}
+	}
+
+	return action, resourceType, idParam
+}
+
+// auditOutcome resultado de la petición según su estado; vacío si no se audita (errores de
+// validación o internos, en los que no cambia nada)
+func auditOutcome(status int) string {
+	switch {
+	case status < http.StatusBadRequest:
+		return models.AuditOutcomeSuccess
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusTooManyRequests:
+		return models.AuditOutcomeDenied
+	default:
+		return ""
+	}
+}
+
+// readAuditRequest lee el cuerpo JSON de la petición sin consumirlo y quita los secretos
+func readAuditRequest(c *gin.Context) interface{} {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, auditBodyLimit+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	if err != nil || len(data) == 0 || len(data) > auditBodyLimit {
+		return nil
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil
+	}
+
+	return services.RedactSecrets(body)
+}
+
+// createdResourceID ID del recurso creado según la respuesta: {"id"} o {"<recurso>": {"id"}}
+func createdResourceID(body []byte) string {
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+
+	if id := jsonID(response["id"]); id != "" {
+		return id
+	}
+	for _, value := range response {
+		if object, ok := value.(map[string]interface{}); ok {
+			if id := jsonID(object["id"]); id != "" {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
+
+// jsonID convierte un ID numérico de JSON a texto
+func jsonID(value interface{}) string {
+	if id, ok := value.(float64); ok && id > 0 {
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+	return ""
+}
+
+// auditResponseWriter guarda una copia de la respuesta (hasta auditBodyLimit)
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write escribe la respuesta y guarda la copia
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if remaining := auditBodyLimit - w.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			w.body.Write(data[:remaining])
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
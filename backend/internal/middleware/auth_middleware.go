@@ -11,16 +11,23 @@ import (
 
 // Constantes para cookies y contexto
 const (
-	AuthCookieName = "auth_token"
-	UserContextKey = "user"
-	UserIDKey      = "user_id"
-	UserRoleKey    = "user_role"
+	AuthCookieName    = "auth_token"
+	RefreshCookieName = "refresh_token"
+	RefreshCookiePath = "/api/auth" // El refresh token solo se envía a las rutas de autenticación
+	OIDCCookieName    = "oidc_state"
+	OIDCCookiePath    = "/api/auth/oidc" // El estado del inicio de sesión único solo se envía al callback
+	UserContextKey    = "user"
+	UserIDKey         = "user_id"
+	UserRoleKey       = "user_role"
+	SessionIDKey      = "session_id"
+	AccessScopeKey    = "access_scope"
 )
 
 // AuthMiddleware gestiona la autenticación mediante cookies JWT
 type AuthMiddleware struct {
-	authService *services.AuthService
-	logger      logger.Logger
+	authService       *services.AuthService
+	permissionService *services.PermissionService
+	logger            logger.Logger
 }
 
 // NewAuthMiddleware crea una nueva instancia del middleware de autenticación
@@ -31,6 +38,11 @@ func NewAuthMiddleware(authService *services.AuthService, logger logger.Logger)
 	}
 }
 
+// SetPermissionService establece el servicio que calcula el acceso por grupos de servidores
+func (m *AuthMiddleware) SetPermissionService(permissionService *services.PermissionService) {
+	m.permissionService = permissionService
+}
+
 // RequireAuth middleware para verificar si el usuario está autenticado
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -43,13 +55,13 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Verificar token
-		claims, err := m.authService.VerifyToken(tokenCookie)
+		// Verificar token y que su sesión siga activa
+		claims, user, err := m.authService.Authenticate(tokenCookie)
 		if err != nil {
 			m.logger.Warnf("Token inválido: %v", err)
 
-			// Si el token expiró, eliminar la cookie
-			if err == services.ErrTokenExpired {
+			// Si el token expiró o su sesión se revocó, eliminar la cookie
+			if err == services.ErrTokenExpired || err == services.ErrSessionRevoked {
 				c.SetCookie(AuthCookieName, "", -1, "/", "", false, true)
 			}
 
@@ -58,9 +70,11 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Almacenar información del usuario en el contexto
-		c.Set(UserIDKey, claims.UserID)
-		c.Set(UserRoleKey, claims.Role)
+		// Almacenar información del usuario en el contexto. El rol es el actual del usuario,
+		// no el del token, para que un cambio de rol se aplique de inmediato
+		c.Set(UserIDKey, user.ID)
+		c.Set(UserRoleKey, user.Role)
+		c.Set(SessionIDKey, claims.SessionID)
 
 		c.Next()
 	}
@@ -123,6 +137,65 @@ func (m *AuthMiddleware) RequireRole(roles ...models.Role) gin.HandlerFunc {
 	}
 }
 
+// LoadAccessScope middleware que calcula los servidores y grupos a los que puede acceder el
+// usuario según sus permisos de grupo. Debe usarse después de RequireAuth
+func (m *AuthMiddleware) LoadAccessScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+			c.Abort()
+			return
+		}
+
+		role, ok := GetUserRole(c)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+			c.Abort()
+			return
+		}
+
+		scope := &services.AccessScope{UserID: userID, Role: role}
+		if m.permissionService != nil {
+			var err error
+			scope, err = m.permissionService.ScopeFor(userID, role)
+			if err != nil {
+				m.logger.Errorf("Error al calcular los permisos del usuario %d: %v", userID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set(AccessScopeKey, scope)
+		c.Next()
+	}
+}
+
+// RequireScopedRole middleware que exige al menos minRole de forma global o, para los usuarios
+// con permisos de grupo, en alguno de sus grupos. Cada manejador comprueba después el servidor
+// o grupo concreto. Debe usarse después de LoadAccessScope
+func (m *AuthMiddleware) RequireScopedRole(minRole models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope, ok := GetAccessScope(c)
+		if !ok {
+			m.logger.Warn("Verificación de rol fallida: permisos no calculados en el contexto")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno del servidor"})
+			c.Abort()
+			return
+		}
+
+		if !scope.AllowsAny(minRole) {
+			m.logger.Warnf("Acceso denegado: usuario %d sin rol %s en ningún grupo", scope.UserID, minRole)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserID obtiene el ID del usuario autenticado desde el contexto
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get(UserIDKey)
@@ -134,6 +207,17 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	return id, ok
 }
 
+// GetSessionID obtiene el ID de la sesión del usuario autenticado
+func GetSessionID(c *gin.Context) (uint, bool) {
+	sessionID, exists := c.Get(SessionIDKey)
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := sessionID.(uint)
+	return id, ok
+}
+
 // GetUserRole obtiene el rol del usuario autenticado desde el contexto
 func GetUserRole(c *gin.Context) (models.Role, bool) {
 	userRole, exists := c.Get(UserRoleKey)
@@ -144,3 +228,14 @@ func GetUserRole(c *gin.Context) (models.Role, bool) {
 	role, ok := userRole.(models.Role)
 	return role, ok
 }
+
+// GetAccessScope obtiene el alcance de acceso del usuario autenticado desde el contexto
+func GetAccessScope(c *gin.Context) (*services.AccessScope, bool) {
+	value, exists := c.Get(AccessScopeKey)
+	if !exists {
+		return nil, false
+	}
+
+	scope, ok := value.(*services.AccessScope)
+	return scope, ok
+}
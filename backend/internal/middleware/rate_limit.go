@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter limita las peticiones por IP en ventanas fijas. Los recuentos se guardan en
+// memoria, así que con varias instancias del backend el límite se aplica en cada una
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastPurge time.Time
+}
+
+// rateWindow peticiones de una IP en la ventana actual
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter crea un limitador de limit peticiones por IP cada window. Devuelve nil si
+// limit no es positivo (sin límite)
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	if window <= 0 {
+		window = time.Hour
+	}
+
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Limit rechaza con 429 las peticiones que superan el límite de su IP. Con un limitador nil no
+// limita nada
+func (l *RateLimiter) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+
+		if retryAfter, ok := l.allow(c.ClientIP(), time.Now()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Demasiadas solicitudes: inténtelo de nuevo más tarde"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allow cuenta una petición de la IP. Si supera el límite devuelve el tiempo hasta que termina
+// la ventana
+func (l *RateLimiter) allow(ip string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Olvidar las ventanas terminadas para que el mapa no crezca sin límite
+	if now.Sub(l.lastPurge) >= l.window {
+		for key, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, key)
+			}
+		}
+		l.lastPurge = now
+	}
+
+	w, ok := l.windows[ip]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.windows[ip] = &rateWindow{start: now, count: 1}
+		return 0, true
+	}
+
+	if w.count >= l.limit {
+		return w.start.Add(l.window).Sub(now), false
+	}
+
+	w.count++
+	return 0, true
+}
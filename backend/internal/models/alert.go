@@ -13,7 +13,7 @@ const (
 	AlertStatusActive       AlertStatus = "active"       // Alerta activa
 	AlertStatusResolved     AlertStatus = "resolved"     // Problema resuelto
 	AlertStatusAcknowledged AlertStatus = "acknowledged" // Reconocida pero no resuelta
-	AlertStatusSuppressed   AlertStatus = "suppressed"   // Alerta suprimida temporalmente
+	AlertStatusSuppressed   AlertStatus = "suppressed"   // Alerta suprimida temporalmente (silencio, inhibición o pospuesta)
 )
 
 // Alert representa una alerta generada a partir de un umbral
@@ -30,11 +30,35 @@ type Alert struct {
 	Severity    AlertSeverity `json:"severity" gorm:"size:10;not null"`
 	Status      AlertStatus   `json:"status" gorm:"size:15;not null;default:'active'"`
 
+	// Banda esperada según la línea base (solo alertas de anomalía)
+	ExpectedMin  *float64 `json:"expected_min,omitempty"`
+	ExpectedMax  *float64 `json:"expected_max,omitempty"`
+	ExpectedMean *float64 `json:"expected_mean,omitempty"`
+	AnomalyScore *float64 `json:"anomaly_score,omitempty"` // z-score de la muestra que disparó la alerta
+
 	// Relaciones
 	ServerID       uint           `json:"server_id" gorm:"index;not null"`
 	Server         Server         `json:"server" gorm:"foreignKey:ServerID"`
 	ThresholdID    uint           `json:"threshold_id" gorm:"index"`
 	AlertThreshold AlertThreshold `json:"alert_threshold,omitempty" gorm:"foreignKey:ThresholdID"`
+	SilenceID      *uint          `json:"silence_id,omitempty" gorm:"index"`    // Silencio que suprimió la alerta
+	IncidentID     *uint          `json:"incident_id,omitempty" gorm:"index"`   // Incidente que agrupa la alerta
+	OwnerTeamID    *uint          `json:"owner_team_id,omitempty" gorm:"index"` // Equipo propietario al disparar la alerta
+
+	// Inhibición: alerta activa y regla que suprimieron ésta, con el motivo legible
+	InhibitedByID    *uint  `json:"inhibited_by_id,omitempty" gorm:"index"`
+	InhibitionRuleID *uint  `json:"inhibition_rule_id,omitempty" gorm:"index"`
+	InhibitionReason string `json:"inhibition_reason,omitempty" gorm:"size:500"`
+
+	// Alertas externas recibidas por la API compatible con Alertmanager (metric_type "external")
+	Fingerprint  string            `json:"fingerprint,omitempty" gorm:"size:16;index"` // Huella de las etiquetas para deduplicar
+	Labels       map[string]string `json:"labels,omitempty" gorm:"serializer:json"`
+	Annotations  map[string]string `json:"annotations,omitempty" gorm:"serializer:json"`
+	GeneratorURL string            `json:"generator_url,omitempty" gorm:"size:500"`
+	EndsAt       *time.Time        `json:"ends_at,omitempty" gorm:"index"` // Se resuelve sola si la fuente no la renueva antes
+
+	// Incidente resumido por la alerta (solo en la notificación resumen de un incidente, no se guarda)
+	Incident *Incident `json:"-" gorm:"-"`
 
 	// Campos temporales
 	TriggeredAt    time.Time  `json:"triggered_at"`    // Momento en que se detectó la condición de alerta
@@ -42,10 +66,19 @@ type Alert struct {
 	AcknowledgedAt *time.Time `json:"acknowledged_at"` // Momento en que se reconoció la alerta
 	AcknowledgedBy *uint      `json:"acknowledged_by"` // Usuario que reconoció la alerta
 
+	// Alerta pospuesta: suprimida hasta SnoozedUntil, cuando vuelve a estar activa
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty" gorm:"index"`
+	SnoozedBy    *uint      `json:"snoozed_by,omitempty"`
+
 	// Campos para notificaciones
 	NotifiedAt     *time.Time `json:"notified_at"`                            // Momento en que se envió la notificación
 	NotifyChannels []string   `json:"notify_channels" gorm:"serializer:json"` // Canales por los que se notificó
 
+	// Campos de escalado
+	EscalationPolicyID *uint      `json:"escalation_policy_id,omitempty" gorm:"index"` // Política aplicada a la alerta
+	EscalationLevel    int        `json:"escalation_level"`                            // Pasos de escalado ya ejecutados
+	NextEscalationAt   *time.Time `json:"next_escalation_at" gorm:"index"`             // Próximo paso (nulo si no hay o se detuvo)
+
 	// Notas y comentarios
 	Notes string `json:"notes" gorm:"type:text"`
 
@@ -72,5 +105,55 @@ func (a *Alert) CanAcknowledge() bool {
 
 // CanResolve verifica si la alerta puede ser resuelta
 func (a *Alert) CanResolve() bool {
-	return a.Status == AlertStatusActive || a.Status == AlertStatusAcknowledged
+	return a.Status == AlertStatusActive || a.Status == AlertStatusAcknowledged || a.Status == AlertStatusSuppressed
+}
+
+// CanUnacknowledge verifica si se puede retirar el reconocimiento de la alerta
+func (a *Alert) CanUnacknowledge() bool {
+	return a.Status == AlertStatusAcknowledged
+}
+
+// CanReopen verifica si la alerta puede volver a abrirse
+func (a *Alert) CanReopen() bool {
+	return a.Status == AlertStatusResolved
+}
+
+// CanSnooze verifica si la alerta puede posponerse. Las alertas ya pospuestas pueden
+// posponerse de nuevo para cambiar el plazo
+func (a *Alert) CanSnooze() bool {
+	return a.Status == AlertStatusActive || a.Status == AlertStatusAcknowledged || a.IsSnoozed()
+}
+
+// IsSnoozed verifica si la alerta está suprimida porque un usuario la pospuso
+func (a *Alert) IsSnoozed() bool {
+	return a.Status == AlertStatusSuppressed && a.SnoozedUntil != nil
+}
+
+// IsExternal verifica si la alerta se recibió de una fuente externa (Prometheus/Alertmanager)
+func (a *Alert) IsExternal() bool {
+	return a.MetricType == MetricTypeExternal
+}
+
+// SameRule indica si dos alertas proceden de la misma regla en el mismo servidor: el mismo
+// umbral o, en las alertas externas, el mismo alertname
+func (a *Alert) SameRule(other *Alert) bool {
+	if a.ServerID != other.ServerID {
+		return false
+	}
+
+	if a.IsExternal() || other.IsExternal() {
+		return a.IsExternal() && other.IsExternal() && a.Labels["alertname"] == other.Labels["alertname"]
+	}
+
+	return a.ThresholdID == other.ThresholdID
+}
+
+// IsSuppressed verifica si la alerta fue suprimida por un silencio, inhibida por otra alerta o pospuesta
+func (a *Alert) IsSuppressed() bool {
+	return a.Status == AlertStatusSuppressed
+}
+
+// IsInhibited verifica si la alerta está suprimida por una regla de inhibición
+func (a *Alert) IsInhibited() bool {
+	return a.Status == AlertStatusSuppressed && a.InhibitedByID != nil
 }
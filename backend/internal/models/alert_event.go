@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// AlertEventType define los tipos de evento del historial de una alerta
+type AlertEventType string
+
+const (
+	AlertEventTriggered      AlertEventType = "triggered"      // Se detectó la condición
+	AlertEventNotified       AlertEventType = "notified"       // Se notificó por un canal (Channel)
+	AlertEventAcknowledged   AlertEventType = "acknowledged"   // Un operador la reconoció
+	AlertEventUnacknowledged AlertEventType = "unacknowledged" // Un operador retiró el reconocimiento
+	AlertEventSnoozed        AlertEventType = "snoozed"        // Un operador la pospuso
+	AlertEventCommented      AlertEventType = "commented"      // Comentario de un operador
+	AlertEventEscalated      AlertEventType = "escalated"      // Se ejecutó un paso de escalado
+	AlertEventSuppressed     AlertEventType = "suppressed"     // Un silencio o una regla de inhibición la suprimió
+	AlertEventResolved       AlertEventType = "resolved"       // Se resolvió (manual o automáticamente)
+	AlertEventReopened       AlertEventType = "reopened"       // Volvió a estar activa (reabierta, liberada o al terminar de posponerse)
+)
+
+// AlertEvent entrada del historial de una alerta. Un actor nulo indica una acción del sistema
+type AlertEvent struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	AlertID   uint           `json:"alert_id" gorm:"index;not null"`
+	Type      AlertEventType `json:"type" gorm:"size:20;not null"`
+	Channel   string         `json:"channel,omitempty" gorm:"size:100"` // Canal notificado (solo notified y escalated)
+	Message   string         `json:"message,omitempty" gorm:"type:text"`
+	ActorID   *uint          `json:"actor_id,omitempty" gorm:"index"`
+	ActorName string         `json:"actor_name,omitempty" gorm:"size:50"` // Nombre de usuario en el momento del evento
+	CreatedAt time.Time      `json:"created_at" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (AlertEvent) TableName() string {
+	return "alert_events"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,8 +16,62 @@ const (
 	MetricTypeDisk       MetricType = "disk"
 	MetricTypeNetworkIn  MetricType = "network_in"
 	MetricTypeNetworkOut MetricType = "network_out"
+	MetricTypeExpression MetricType = "expression" // Condición definida en AlertThreshold.Expression
+	MetricTypeExternal   MetricType = "external"   // Alerta recibida de Prometheus/Alertmanager (sin umbral)
 )
 
+// DisplayName devuelve el nombre legible de la métrica
+func (mt MetricType) DisplayName() string {
+	switch mt {
+	case MetricTypeCPU:
+		return "CPU"
+	case MetricTypeMemory:
+		return "Memoria"
+	case MetricTypeDisk:
+		return "Disco"
+	case MetricTypeNetworkIn:
+		return "Red (entrada)"
+	case MetricTypeNetworkOut:
+		return "Red (salida)"
+	case MetricTypeExpression:
+		return "Expresión"
+	case MetricTypeExternal:
+		return "Externa"
+	default:
+		return string(mt)
+	}
+}
+
+// ThresholdCondition define cómo se compara la métrica con el valor del umbral
+type ThresholdCondition string
+
+const (
+	ThresholdConditionStatic        ThresholdCondition = "static"         // Valor actual (comportamiento por defecto)
+	ThresholdConditionDelta         ThresholdCondition = "delta"          // Variación absoluta en la ventana
+	ThresholdConditionPercentChange ThresholdCondition = "percent_change" // % de cambio frente a la misma ventana en el pasado
+	ThresholdConditionMovingAverage ThresholdCondition = "moving_average" // Desviación del valor actual respecto a la media móvil
+	ThresholdConditionForecast      ThresholdCondition = "forecast"       // Horas previstas hasta llenar disco o memoria
+	ThresholdConditionAnomaly       ThresholdCondition = "anomaly"        // |z-score| respecto a la línea base de la hora de la semana
+)
+
+// VariableName nombre de la variable de métrica equivalente al tipo ("" si no tiene)
+func (mt MetricType) VariableName() string {
+	switch mt {
+	case MetricTypeCPU:
+		return "cpu_usage"
+	case MetricTypeMemory:
+		return "memory_percent"
+	case MetricTypeDisk:
+		return "disk_percent"
+	case MetricTypeNetworkIn:
+		return "net_in_mb"
+	case MetricTypeNetworkOut:
+		return "net_out_mb"
+	default:
+		return ""
+	}
+}
+
 // AlertSeverity define los niveles de severidad para las alertas
 type AlertSeverity string
 
@@ -39,21 +94,41 @@ type AlertThreshold struct {
 	Duration   int           `json:"duration"`                        // Duración en segundos que debe mantenerse la condición
 	Severity   AlertSeverity `json:"severity" gorm:"size:10;not null"`
 
+	// Condición sobre varios campos de la métrica (solo con metric_type "expression"),
+	// p. ej. "memory_percent > 90 && swap_percent > 50"
+	Expression string `json:"expression,omitempty" gorm:"type:text"`
+
+	// Condiciones de tendencia sobre el historial (delta, percent_change, moving_average, forecast, anomaly).
+	// En forecast, value es el horizonte en horas ("< 24" dispara si se prevé lleno en menos de 24 h);
+	// en anomaly, el z-score a partir del cual la muestra se considera anómala ("> 3")
+	Condition            ThresholdCondition `json:"condition" gorm:"size:20;default:'static'"`
+	MetricField          string             `json:"metric_field,omitempty" gorm:"size:50"` // Variable de métrica a usar en lugar de metric_type (p. ej. disk_used)
+	WindowMinutes        int                `json:"window_minutes"`                        // Ventana de historial en minutos
+	CompareOffsetMinutes int                `json:"compare_offset_minutes"`                // percent_change: antigüedad de la ventana de referencia (60 = hace una hora, 1440 = hace un día)
+
 	// Notificaciones
-	EnableEmail   bool   `json:"enable_email" gorm:"default:false"`
-	EnableDiscord bool   `json:"enable_discord" gorm:"default:false"`
-	EnableWebhook bool   `json:"enable_webhook" gorm:"default:false"`
-	WebhookURL    string `json:"webhook_url" gorm:"size:255"`
+	EnableEmail    bool   `json:"enable_email" gorm:"default:false"`
+	EnableDiscord  bool   `json:"enable_discord" gorm:"default:false"`
+	EnableTelegram bool   `json:"enable_telegram" gorm:"default:false"`
+	EnableNtfy     bool   `json:"enable_ntfy" gorm:"default:false"`
+	EnableWebhook  bool   `json:"enable_webhook" gorm:"default:false"`
+	WebhookURL     string `json:"webhook_url" gorm:"size:255"`
 
 	// Configuración de cooldown
 	CooldownMinutes int `json:"cooldown_minutes" gorm:"default:15"` // Evitar múltiples alertas en este periodo
 
+	// Escalado si la alerta no se reconoce (si es nulo se usa la política de su severidad)
+	EscalationPolicyID *uint `json:"escalation_policy_id" gorm:"index"`
+
 	// Relaciones
 	ServerID *uint        `json:"server_id" gorm:"index"` // Puede ser nulo para aplicar a todos los servidores
 	Server   *Server      `json:"server,omitempty" gorm:"foreignKey:ServerID"`
 	GroupID  *uint        `json:"group_id" gorm:"index"` // Aplicar a todos los servidores del grupo
 	Group    *ServerGroup `json:"group,omitempty" gorm:"foreignKey:GroupID"`
 
+	// Equipo propietario de las alertas del umbral (si es nulo, el del servidor o sus grupos)
+	OwnerTeamID *uint `json:"owner_team_id,omitempty" gorm:"index"`
+
 	// Campos comunes
 	Enabled   bool           `json:"enabled" gorm:"default:true"`
 	CreatedBy uint           `json:"created_by"` // Usuario que creó el umbral
@@ -72,6 +147,11 @@ func (at *AlertThreshold) ValidateThreshold() bool {
 		return false
 	}
 
+	// Las reglas de expresión no usan operador ni valor (se valida la expresión al guardarla)
+	if at.IsExpression() {
+		return at.Expression != ""
+	}
+
 	// Verificar que el operador sea válido
 	validOperators := map[string]bool{">": true, "<": true, ">=": true, "<=": true, "==": true}
 	if !validOperators[at.Operator] {
@@ -80,3 +160,82 @@ func (at *AlertThreshold) ValidateThreshold() bool {
 
 	return true
 }
+
+// IsExpression indica si la regla se define mediante una expresión
+func (at *AlertThreshold) IsExpression() bool {
+	return at.MetricType == MetricTypeExpression
+}
+
+// IsTrend indica si la regla compara contra una ventana de historial
+func (at *AlertThreshold) IsTrend() bool {
+	return at.Condition != "" && at.Condition != ThresholdConditionStatic
+}
+
+// ValidateCondition valida la variable de métrica y la configuración de las condiciones de tendencia
+func (at *AlertThreshold) ValidateCondition() error {
+	if at.MetricType == MetricTypeExternal {
+		return fmt.Errorf("el tipo de métrica external está reservado para las alertas externas")
+	}
+
+	if at.MetricField != "" && !IsMetricVariable(at.MetricField) {
+		return fmt.Errorf("variable de métrica desconocida: %s", at.MetricField)
+	}
+
+	switch at.Condition {
+	case "", ThresholdConditionStatic:
+		return nil
+	case ThresholdConditionDelta, ThresholdConditionPercentChange, ThresholdConditionMovingAverage,
+		ThresholdConditionForecast, ThresholdConditionAnomaly:
+	default:
+		return fmt.Errorf("condición desconocida: %s", at.Condition)
+	}
+
+	if at.IsExpression() {
+		return fmt.Errorf("las reglas de expresión no admiten condiciones de tendencia")
+	}
+
+	// Las anomalías se comparan con la línea base aprendida, no con una ventana
+	if at.Condition == ThresholdConditionAnomaly {
+		if at.Operator != ">" && at.Operator != ">=" {
+			return fmt.Errorf("la condición anomaly requiere el operador > o >=")
+		}
+		if at.Value <= 0 {
+			return fmt.Errorf("la condición anomaly requiere un z-score mayor que 0")
+		}
+		return nil
+	}
+
+	if at.WindowMinutes <= 0 {
+		return fmt.Errorf("la condición %s requiere window_minutes mayor que 0", at.Condition)
+	}
+
+	switch at.Condition {
+	case ThresholdConditionPercentChange:
+		if at.CompareOffsetMinutes < at.WindowMinutes {
+			return fmt.Errorf("compare_offset_minutes debe ser mayor o igual que window_minutes")
+		}
+	case ThresholdConditionForecast:
+		if at.MetricType != MetricTypeDisk && at.MetricType != MetricTypeMemory {
+			return fmt.Errorf("la condición forecast solo admite las métricas disk y memory")
+		}
+		if at.MetricField != "" {
+			return fmt.Errorf("la condición forecast no admite metric_field")
+		}
+		if at.Operator != "<" && at.Operator != "<=" {
+			return fmt.Errorf("la condición forecast requiere el operador < o <=")
+		}
+		if at.Value <= 0 {
+			return fmt.Errorf("la condición forecast requiere un horizonte en horas mayor que 0")
+		}
+	}
+
+	return nil
+}
+
+// SourceVariable variable de métrica que evalúa la regla: metric_field o la equivalente al tipo
+func (at *AlertThreshold) SourceVariable() string {
+	if at.MetricField != "" {
+		return at.MetricField
+	}
+	return at.MetricType.VariableName()
+}
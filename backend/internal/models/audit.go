@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Resultados de una acción auditada
+const (
+	AuditOutcomeSuccess = "success" // La acción se completó
+	AuditOutcomeDenied  = "denied"  // Rechazada por falta de autenticación o permisos, o por bloqueo
+)
+
+// ErrAuditAppendOnly el registro de auditoría no admite modificaciones ni borrados
+var ErrAuditAppendOnly = errors.New("el registro de auditoría es de solo inserción")
+
+// AuditEntry entrada del registro de auditoría: quién hizo qué, sobre qué recurso y desde dónde.
+// Changes guarda los campos modificados ({"campo": {"before": ..., "after": ...}}) y Details la
+// petición (sin secretos) cuando no hay un estado del recurso que comparar
+type AuditEntry struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ActorID      *uint     `json:"actor_id,omitempty" gorm:"index"`
+	ActorName    string    `json:"actor_name,omitempty" gorm:"size:100;index"` // Usuario en el momento de la acción (o el que intentó entrar)
+	Action       string    `json:"action" gorm:"size:100;not null;index"`      // p. ej. servers.update, alerts.resolve, auth.login
+	ResourceType string    `json:"resource_type" gorm:"size:50;not null;index:idx_audit_resource"`
+	ResourceID   string    `json:"resource_id,omitempty" gorm:"size:64;index:idx_audit_resource"`
+	Method       string    `json:"method" gorm:"size:10;not null"`
+	Route        string    `json:"route" gorm:"size:200;not null"` // Ruta con parámetros (/api/servers/:id)
+	Status       int       `json:"status"`
+	Outcome      string    `json:"outcome" gorm:"size:10;not null;index"`
+	Changes      Metadata  `json:"changes,omitempty" gorm:"type:jsonb"`
+	Details      Metadata  `json:"details,omitempty" gorm:"type:jsonb"`
+	IPAddress    string    `json:"ip_address" gorm:"size:45"`
+	UserAgent    string    `json:"user_agent" gorm:"size:255"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (AuditEntry) TableName() string {
+	return "audit_entries"
+}
+
+// BeforeUpdate impide modificar entradas de auditoría desde la aplicación
+func (AuditEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// BeforeDelete impide borrar entradas de auditoría desde la aplicación
+func (AuditEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
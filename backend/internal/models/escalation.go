@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EscalationPolicy define los pasos de notificación que se ejecutan mientras una alerta
+// siga sin reconocerse. Se asigna a un umbral o, en su defecto, por severidad.
+type EscalationPolicy struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Description string `json:"description" gorm:"type:text"`
+	Enabled     bool   `json:"enabled" gorm:"default:true"`
+
+	// Severidades a las que se aplica cuando el umbral no indica política
+	Severities []AlertSeverity `json:"severities" gorm:"serializer:json"`
+
+	// Veces que se repite la secuencia completa tras la primera pasada
+	RepeatCount int `json:"repeat_count" gorm:"default:0"`
+
+	// Pasos ordenados por Position
+	Steps []EscalationStep `json:"steps" gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+
+	// Campos comunes
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (EscalationPolicy) TableName() string {
+	return "escalation_policies"
+}
+
+// EscalationStep paso de una política: pasados DelayMinutes desde el paso anterior
+// (o desde el disparo de la alerta en el primero) se notifica a los puntos de contacto
+type EscalationStep struct {
+	ID              uint   `json:"id" gorm:"primaryKey"`
+	PolicyID        uint   `json:"policy_id" gorm:"index;not null"`
+	Position        int    `json:"position"`
+	DelayMinutes    int    `json:"delay_minutes" gorm:"not null"`
+	ContactPointIDs []uint `json:"contact_point_ids" gorm:"serializer:json"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (EscalationStep) TableName() string {
+	return "escalation_steps"
+}
+
+// Validate verifica nombre, repeticiones y pasos de la política
+func (p *EscalationPolicy) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("el nombre de la política es obligatorio")
+	}
+
+	if p.RepeatCount < 0 {
+		return fmt.Errorf("el número de repeticiones no puede ser negativo")
+	}
+
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("la política debe tener al menos un paso")
+	}
+
+	for i, step := range p.Steps {
+		if step.DelayMinutes < 1 {
+			return fmt.Errorf("el paso %d debe esperar al menos un minuto", i+1)
+		}
+		if len(step.ContactPointIDs) == 0 {
+			return fmt.Errorf("el paso %d debe tener al menos un punto de contacto", i+1)
+		}
+	}
+
+	return nil
+}
+
+// AppliesToSeverity indica si la política se aplica por defecto a la severidad indicada
+func (p *EscalationPolicy) AppliesToSeverity(severity AlertSeverity) bool {
+	return p.Enabled && containsValue(p.Severities, severity)
+}
+
+// TotalSteps número total de pasos a ejecutar teniendo en cuenta las repeticiones
+func (p *EscalationPolicy) TotalSteps() int {
+	return len(p.Steps) * (p.RepeatCount + 1)
+}
+
+// StepAt devuelve el paso correspondiente a un nivel de escalado (0 = primer paso)
+// y si ese nivel existe dentro de la política
+func (p *EscalationPolicy) StepAt(level int) (*EscalationStep, bool) {
+	if len(p.Steps) == 0 || level < 0 || level >= p.TotalSteps() {
+		return nil, false
+	}
+	return &p.Steps[level%len(p.Steps)], true
+}
+
+// AlertEscalation registro de un paso de escalado ejecutado sobre una alerta
+type AlertEscalation struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	AlertID         uint      `json:"alert_id" gorm:"index;not null"`
+	PolicyID        uint      `json:"policy_id" gorm:"index"`
+	Level           int       `json:"level"`      // Nivel de escalado (0 = primer paso)
+	StepPosition    int       `json:"step"`       // Posición del paso dentro de la política
+	Repetition      int       `json:"repetition"` // Repetición de la secuencia (0 = primera pasada)
+	ContactPointIDs []uint    `json:"contact_point_ids" gorm:"serializer:json"`
+	NotifyChannels  []string  `json:"notify_channels" gorm:"serializer:json"` // Canales que recibieron la notificación
+	EscalatedAt     time.Time `json:"escalated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (AlertEscalation) TableName() string {
+	return "alert_escalations"
+}
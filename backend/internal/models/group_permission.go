@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// PermissionSourceOIDC origen de los permisos sincronizados desde los grupos del IdP
+const PermissionSourceOIDC = "oidc"
+
+// GroupPermission concede a un usuario o a un equipo un rol sobre un grupo de servidores y
+// todos sus subgrupos. Un usuario (no admin) con algún permiso de grupo, propio o de sus
+// equipos, solo ve los servidores de esos grupos, con el rol del permiso; sin permisos de
+// grupo se aplica su rol global
+type GroupPermission struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	UserID    *uint        `json:"user_id,omitempty" gorm:"uniqueIndex:idx_group_permission_user_group"`
+	User      *User        `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	TeamID    *uint        `json:"team_id,omitempty" gorm:"index;uniqueIndex:idx_group_permission_team_group"`
+	Team      *Team        `json:"team,omitempty" gorm:"foreignKey:TeamID"`
+	GroupID   uint         `json:"group_id" gorm:"not null;index;uniqueIndex:idx_group_permission_user_group;uniqueIndex:idx_group_permission_team_group"`
+	Group     *ServerGroup `json:"group,omitempty" gorm:"foreignKey:GroupID"`
+	Role      Role         `json:"role" gorm:"size:20;not null"`
+	Source    string       `json:"source,omitempty" gorm:"size:20"` // Vacío si se concedió a mano; "oidc" si se sincroniza desde el IdP
+	CreatedBy uint         `json:"created_by"`                      // Usuario que concedió el permiso
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Validate verifica el destinatario (usuario o equipo), el grupo y el rol del permiso
+func (gp *GroupPermission) Validate() error {
+	if (gp.UserID == nil) == (gp.TeamID == nil) {
+		return fmt.Errorf("el permiso debe concederse a un usuario o a un equipo")
+	}
+
+	if gp.GroupID == 0 {
+		return fmt.Errorf("el grupo es obligatorio")
+	}
+
+	if RoleRank(gp.Role) == 0 {
+		return fmt.Errorf("rol inválido: %s", gp.Role)
+	}
+
+	return nil
+}
+
+// GranteeID ID del usuario o del equipo al que se concede el permiso
+func (gp *GroupPermission) GranteeID() uint {
+	if gp.UserID != nil {
+		return *gp.UserID
+	}
+	if gp.TeamID != nil {
+		return *gp.TeamID
+	}
+	return 0
+}
+
+// RoleRank orden de privilegio de los roles (0 si el rol no es válido)
+func RoleRank(role Role) int {
+	switch role {
+	case RoleAdmin:
+		return 3
+	case RoleUser:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// RoleIncludes indica si role concede al menos los privilegios de minRole
+func RoleIncludes(role, minRole Role) bool {
+	return RoleRank(role) > 0 && RoleRank(role) >= RoleRank(minRole)
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IncidentStatus define los estados del ciclo de vida de un incidente
+type IncidentStatus string
+
+const (
+	IncidentStatusOpen         IncidentStatus = "open"         // Con alertas sin reconocer
+	IncidentStatusAcknowledged IncidentStatus = "acknowledged" // Reconocido pero no resuelto
+	IncidentStatusResolved     IncidentStatus = "resolved"     // Todas sus alertas resueltas
+	IncidentStatusMerged       IncidentStatus = "merged"       // Fusionado en otro incidente (MergedIntoID)
+)
+
+// Claves de agrupación de alertas en incidentes
+const (
+	IncidentKeyLocation   = "location"    // Ubicación del servidor
+	IncidentKeyGroup      = "group"       // Grupo directo del servidor (el de menor ID)
+	IncidentKeyServer     = "server"      // Servidor
+	IncidentKeySeverity   = "severity"    // Severidad de la alerta
+	IncidentKeyMetricType = "metric_type" // Tipo de métrica de la alerta
+	IncidentKeyTagPrefix  = "tag:"        // Primera etiqueta del servidor con el prefijo indicado (p. ej. "tag:switch-")
+)
+
+// Incident agrupa alertas relacionadas (mismo grupo, ubicación, etiqueta...) que se producen
+// dentro de una ventana de tiempo para notificarlas con un único mensaje
+type Incident struct {
+	ID       uint           `json:"id" gorm:"primaryKey"`
+	Title    string         `json:"title" gorm:"size:200;not null"`
+	Status   IncidentStatus `json:"status" gorm:"size:15;not null;default:'open';index"`
+	Severity AlertSeverity  `json:"severity" gorm:"size:10;not null"` // La mayor de sus alertas
+
+	// Clave de agrupación, p. ej. "location=Madrid-DC1" o "group=3,severity=critical"
+	GroupKey    string            `json:"group_key" gorm:"size:255;index"`
+	GroupLabels map[string]string `json:"group_labels" gorm:"serializer:json"`
+	AlertCount  int               `json:"alert_count"`
+
+	// Campos temporales
+	OpenedAt       time.Time  `json:"opened_at"`
+	LastAlertAt    time.Time  `json:"last_alert_at" gorm:"index"` // Última alerta añadida (inicio de la ventana de agrupación)
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy *uint      `json:"acknowledged_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+
+	// Notificación resumen: se envía en NotifyAt para dar tiempo a que lleguen las alertas relacionadas
+	NotifyAt       *time.Time `json:"notify_at" gorm:"index"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+	NotifyChannels []string   `json:"notify_channels" gorm:"serializer:json"`
+
+	// Incidente en el que se fusionó éste
+	MergedIntoID *uint `json:"merged_into_id,omitempty" gorm:"index"`
+
+	Notes string `json:"notes" gorm:"type:text"`
+
+	// Relaciones
+	Alerts []Alert `json:"alerts,omitempty" gorm:"foreignKey:IncidentID"`
+
+	// Campos comunes
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Incident) TableName() string {
+	return "incidents"
+}
+
+// IsClosed indica si el incidente ya no admite alertas nuevas
+func (i *Incident) IsClosed() bool {
+	return i.Status == IncidentStatusResolved || i.Status == IncidentStatusMerged
+}
+
+// CanAcknowledge verifica si el incidente puede ser reconocido
+func (i *Incident) CanAcknowledge() bool {
+	return i.Status == IncidentStatusOpen
+}
+
+// SeverityRank orden de las severidades para quedarse con la mayor
+func SeverityRank(severity AlertSeverity) int {
+	switch severity {
+	case AlertSeverityCritical:
+		return 3
+	case AlertSeverityWarning:
+		return 2
+	case AlertSeverityInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ValidateIncidentGroupKey verifica que una clave de agrupación sea conocida
+func ValidateIncidentGroupKey(key string) error {
+	switch key {
+	case IncidentKeyLocation, IncidentKeyGroup, IncidentKeyServer, IncidentKeySeverity, IncidentKeyMetricType:
+		return nil
+	}
+
+	if strings.HasPrefix(key, IncidentKeyTagPrefix) && len(key) > len(IncidentKeyTagPrefix) {
+		return nil
+	}
+
+	return fmt.Errorf("clave de agrupación desconocida: %s", key)
+}
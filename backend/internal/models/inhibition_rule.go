@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Etiquetas que pueden exigirse iguales entre la alerta origen y la inhibida
+const (
+	InhibitionLabelServer     = "server"      // Mismo servidor
+	InhibitionLabelLocation   = "location"    // Misma ubicación
+	InhibitionLabelGroup      = "group"       // El servidor inhibido está en un grupo del origen o en uno de sus subgrupos
+	InhibitionLabelMetricType = "metric_type" // Mismo tipo de métrica
+)
+
+// InhibitionMatcher criterios que identifican alertas en una regla de inhibición.
+// Un criterio vacío coincide con cualquier valor
+type InhibitionMatcher struct {
+	ThresholdIDs []uint          `json:"threshold_ids" gorm:"serializer:json"`
+	MetricTypes  []MetricType    `json:"metric_types" gorm:"serializer:json"`
+	Severities   []AlertSeverity `json:"severities" gorm:"serializer:json"`
+	ServerTags   []string        `json:"server_tags" gorm:"serializer:json"` // El servidor debe tener alguna de estas etiquetas
+	GroupIDs     []uint          `json:"group_ids" gorm:"serializer:json"`   // El servidor debe pertenecer a alguno (incluye subgrupos)
+}
+
+// IsEmpty indica si el criterio no restringe ninguna alerta
+func (m *InhibitionMatcher) IsEmpty() bool {
+	return len(m.ThresholdIDs) == 0 && len(m.MetricTypes) == 0 && len(m.Severities) == 0 &&
+		len(m.ServerTags) == 0 && len(m.GroupIDs) == 0
+}
+
+// Matches determina si una alerta cumple los criterios. groupIDs son los grupos del servidor
+// (incluidos los ancestros)
+func (m *InhibitionMatcher) Matches(alert *Alert, groupIDs []uint) bool {
+	if len(m.ThresholdIDs) > 0 && !containsValue(m.ThresholdIDs, alert.ThresholdID) {
+		return false
+	}
+
+	if len(m.MetricTypes) > 0 && !containsValue(m.MetricTypes, alert.MetricType) {
+		return false
+	}
+
+	if len(m.Severities) > 0 && !containsValue(m.Severities, alert.Severity) {
+		return false
+	}
+
+	if len(m.ServerTags) > 0 && !containsAny(m.ServerTags, alert.Server.Tags) {
+		return false
+	}
+
+	if len(m.GroupIDs) > 0 && !containsAny(m.GroupIDs, groupIDs) {
+		return false
+	}
+
+	return true
+}
+
+// InhibitionRule suprime las alertas que cumplen Target mientras haya otra alerta activa que
+// cumpla Source y comparta las etiquetas Equal (p. ej. la caída de un servidor inhibe sus
+// alertas de CPU, memoria y disco)
+type InhibitionRule struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"size:100;not null"`
+	Description string `json:"description" gorm:"type:text"`
+	Enabled     bool   `json:"enabled" gorm:"default:true"`
+
+	Source InhibitionMatcher `json:"source" gorm:"embedded;embeddedPrefix:source_"` // Alertas que inhiben
+	Target InhibitionMatcher `json:"target" gorm:"embedded;embeddedPrefix:target_"` // Alertas inhibidas
+	Equal  []string          `json:"equal" gorm:"serializer:json"`                  // Etiquetas que deben coincidir
+
+	// Campos comunes
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (InhibitionRule) TableName() string {
+	return "inhibition_rules"
+}
+
+// Validate verifica el nombre, los criterios y las etiquetas de la regla
+func (r *InhibitionRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("el nombre de la regla de inhibición es obligatorio")
+	}
+
+	if r.Source.IsEmpty() {
+		return fmt.Errorf("la regla debe tener al menos un criterio para las alertas origen")
+	}
+
+	if r.Target.IsEmpty() && len(r.Equal) == 0 {
+		return fmt.Errorf("la regla debe tener al menos un criterio o una etiqueta común para las alertas inhibidas")
+	}
+
+	for _, label := range r.Equal {
+		switch label {
+		case InhibitionLabelServer, InhibitionLabelLocation, InhibitionLabelGroup, InhibitionLabelMetricType:
+		default:
+			return fmt.Errorf("etiqueta de inhibición desconocida: %s", label)
+		}
+	}
+
+	return nil
+}
+
+// SharesLabels comprueba que la alerta origen y la candidata a inhibir coincidan en las
+// etiquetas Equal. sourceGroups son los grupos directos del servidor origen y targetGroups
+// los del servidor candidato incluidos sus ancestros
+func (r *InhibitionRule) SharesLabels(source, target *Alert, sourceGroups, targetGroups []uint) bool {
+	for _, label := range r.Equal {
+		switch label {
+		case InhibitionLabelServer:
+			if source.ServerID != target.ServerID {
+				return false
+			}
+		case InhibitionLabelLocation:
+			if source.Server.Location == "" || source.Server.Location != target.Server.Location {
+				return false
+			}
+		case InhibitionLabelGroup:
+			if !containsAny(sourceGroups, targetGroups) {
+				return false
+			}
+		case InhibitionLabelMetricType:
+			if source.MetricType != target.MetricType {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Reason motivo legible que se guarda en la alerta inhibida
+func (r *InhibitionRule) Reason(source *Alert) string {
+	return fmt.Sprintf("Inhibida por la alerta %d (%s) según la regla \"%s\"", source.ID, source.Title, r.Name)
+}
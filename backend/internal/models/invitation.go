@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Invitation invitación de un solo uso para darse de alta con un email y un rol fijados por un
+// admin (o con rol VIEWER en el autorregistro por dominio). Solo se guarda el hash del token
+type Invitation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Email          string     `json:"email" gorm:"size:100;not null;index"`
+	Role           Role       `json:"role" gorm:"size:20;not null"`
+	TokenHash      string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // SHA-256 del token
+	InvitedBy      *uint      `json:"invited_by,omitempty"`                  // Admin que la emitió (nil = autorregistro)
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"index"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *uint      `json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// IsPending indica si la invitación todavía puede aceptarse
+func (i *Invitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
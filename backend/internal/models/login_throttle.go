@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Ámbitos del recuento de intentos de login fallidos
+const (
+	LoginThrottleUser = "user" // Por nombre de usuario (en minúsculas, exista o no)
+	LoginThrottleIP   = "ip"   // Por dirección IP de origen
+)
+
+// LoginThrottle recuento de intentos de login fallidos de un usuario o una IP y su bloqueo
+// temporal. Se elimina tras un login correcto o cuando deja de ser relevante
+type LoginThrottle struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Scope          string     `json:"scope" gorm:"size:10;not null;uniqueIndex:idx_login_throttle_identifier"`
+	Identifier     string     `json:"identifier" gorm:"size:255;not null;uniqueIndex:idx_login_throttle_identifier"`
+	Failures       int        `json:"failures"` // Fallos consecutivos dentro de la ventana
+	FirstFailureAt time.Time  `json:"first_failure_at"`
+	LastFailureAt  time.Time  `json:"last_failure_at" gorm:"index"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty" gorm:"index"` // Bloqueo temporal (se levanta solo)
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// IsLocked indica si el bloqueo sigue vigente
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// HoursPerWeek número de franjas horarias de la línea base semanal
+const HoursPerWeek = 7 * 24
+
+// MinBaselineSamples muestras mínimas de una franja para usarla en la detección de anomalías
+const MinBaselineSamples = 10
+
+// MetricBaseline media y desviación típica aprendidas de una variable de métrica de un servidor
+// para una hora de la semana (0 = domingo 00:00 UTC, 167 = sábado 23:00 UTC)
+type MetricBaseline struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ServerID   uint      `json:"server_id" gorm:"not null;uniqueIndex:idx_baseline_bucket"`
+	Metric     string    `json:"metric" gorm:"size:50;not null;uniqueIndex:idx_baseline_bucket"` // Nombre de la variable (cpu_usage, memory_percent...)
+	HourOfWeek int       `json:"hour_of_week" gorm:"not null;uniqueIndex:idx_baseline_bucket"`
+	Samples    int       `json:"samples"`
+	Mean       float64   `json:"mean"`
+	StdDev     float64   `json:"std_dev"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla
+func (MetricBaseline) TableName() string {
+	return "metric_baselines"
+}
+
+// HourOfWeek franja de la semana (en UTC) de un instante
+func HourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// IsReliable indica si la franja tiene muestras suficientes
+func (b *MetricBaseline) IsReliable() bool {
+	return b.Samples >= MinBaselineSamples
+}
+
+// effectiveStdDev desviación usada para puntuar: se limita inferiormente al 1% de la media
+// (o 0.001) para que una serie casi constante no genere anomalías por variaciones mínimas
+func (b *MetricBaseline) effectiveStdDev() float64 {
+	return math.Max(b.StdDev, math.Max(math.Abs(b.Mean)*0.01, 0.001))
+}
+
+// ZScore desviaciones típicas que separan el valor de la media de la franja
+func (b *MetricBaseline) ZScore(value float64) float64 {
+	return (value - b.Mean) / b.effectiveStdDev()
+}
+
+// Band banda esperada para el umbral de z-score indicado
+func (b *MetricBaseline) Band(zScore float64) (lower, upper float64) {
+	margin := zScore * b.effectiveStdDev()
+	return b.Mean - margin, b.Mean + margin
+}
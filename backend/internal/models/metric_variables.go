@@ -0,0 +1,140 @@
+package models
+
+// MetricVariable variable disponible en las expresiones de las reglas de alerta
+type MetricVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// MetricVariableCatalog variables de métrica admitidas en las expresiones: los campos
+// numéricos de Metric (con su nombre JSON) y algunos ratios derivados
+var MetricVariableCatalog = []MetricVariable{
+	{"cpu_usage", "Uso de CPU (%)"},
+	{"cpu_temp", "Temperatura de CPU (°C)"},
+	{"cpu_freq", "Frecuencia de CPU (MHz)"},
+	{"load_avg_1", "Carga promedio 1 minuto"},
+	{"load_avg_5", "Carga promedio 5 minutos"},
+	{"load_avg_15", "Carga promedio 15 minutos"},
+	{"memory_total", "Memoria total (bytes)"},
+	{"memory_used", "Memoria usada (bytes)"},
+	{"memory_free", "Memoria libre (bytes)"},
+	{"memory_cache", "Memoria caché (bytes)"},
+	{"memory_buffers", "Memoria en buffers (bytes)"},
+	{"memory_percent", "Memoria usada (%)"},
+	{"swap_total", "Swap total (bytes)"},
+	{"swap_used", "Swap usada (bytes)"},
+	{"swap_free", "Swap libre (bytes)"},
+	{"swap_percent", "Swap usada (%)"},
+	{"disk_total", "Disco total (bytes)"},
+	{"disk_used", "Disco usado (bytes)"},
+	{"disk_free", "Disco libre (bytes)"},
+	{"disk_percent", "Disco usado (%)"},
+	{"disk_reads", "Lecturas de disco desde el último muestreo"},
+	{"disk_writes", "Escrituras de disco desde el último muestreo"},
+	{"disk_read_bytes", "Bytes leídos desde el último muestreo"},
+	{"disk_write_bytes", "Bytes escritos desde el último muestreo"},
+	{"disk_io_time", "Tiempo de IO (ms)"},
+	{"net_upload", "Bytes subidos desde la última medición"},
+	{"net_download", "Bytes descargados desde la última medición"},
+	{"net_in_mb", "Red de entrada (MB)"},
+	{"net_out_mb", "Red de salida (MB)"},
+	{"net_packets_in", "Paquetes recibidos"},
+	{"net_packets_out", "Paquetes enviados"},
+	{"net_errors_in", "Errores de recepción"},
+	{"net_errors_out", "Errores de envío"},
+	{"net_drops_in", "Paquetes desechados de entrada"},
+	{"net_drops_out", "Paquetes desechados de salida"},
+	{"process_count", "Número de procesos"},
+	{"thread_count", "Número de hilos"},
+	{"handle_count", "Handles/descriptores abiertos"},
+	{"uptime", "Tiempo de actividad (segundos)"},
+}
+
+// IsMetricVariable indica si el nombre corresponde a una variable de métrica
+func IsMetricVariable(name string) bool {
+	for _, v := range MetricVariableCatalog {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MetricVariables devuelve los valores de todas las variables de una métrica.
+// Los porcentajes derivados valen 0 cuando el total es 0.
+func MetricVariables(m *Metric) map[string]float64 {
+	return map[string]float64{
+		"cpu_usage":        m.CPUUsage,
+		"cpu_temp":         m.CPUTemp,
+		"cpu_freq":         m.CPUFreq,
+		"load_avg_1":       m.LoadAvg1,
+		"load_avg_5":       m.LoadAvg5,
+		"load_avg_15":      m.LoadAvg15,
+		"memory_total":     float64(m.MemoryTotal),
+		"memory_used":      float64(m.MemoryUsed),
+		"memory_free":      float64(m.MemoryFree),
+		"memory_cache":     float64(m.MemoryCache),
+		"memory_buffers":   float64(m.MemoryBuffers),
+		"memory_percent":   ratioPercent(m.MemoryUsed, m.MemoryTotal),
+		"swap_total":       float64(m.SwapTotal),
+		"swap_used":        float64(m.SwapUsed),
+		"swap_free":        float64(m.SwapFree),
+		"swap_percent":     ratioPercent(m.SwapUsed, m.SwapTotal),
+		"disk_total":       float64(m.DiskTotal),
+		"disk_used":        float64(m.DiskUsed),
+		"disk_free":        float64(m.DiskFree),
+		"disk_percent":     ratioPercent(m.DiskUsed, m.DiskTotal),
+		"disk_reads":       float64(m.DiskReads),
+		"disk_writes":      float64(m.DiskWrites),
+		"disk_read_bytes":  float64(m.DiskReadBytes),
+		"disk_write_bytes": float64(m.DiskWriteBytes),
+		"disk_io_time":     float64(m.DiskIOTime),
+		"net_upload":       float64(m.NetUpload),
+		"net_download":     float64(m.NetDownload),
+		"net_in_mb":        float64(m.NetDownload) / 1024 / 1024,
+		"net_out_mb":       float64(m.NetUpload) / 1024 / 1024,
+		"net_packets_in":   float64(m.NetPacketsIn),
+		"net_packets_out":  float64(m.NetPacketsOut),
+		"net_errors_in":    float64(m.NetErrorsIn),
+		"net_errors_out":   float64(m.NetErrorsOut),
+		"net_drops_in":     float64(m.NetDropsIn),
+		"net_drops_out":    float64(m.NetDropsOut),
+		"process_count":    float64(m.ProcessCount),
+		"thread_count":     float64(m.ThreadCount),
+		"handle_count":     float64(m.HandleCount),
+		"uptime":           float64(m.Uptime),
+	}
+}
+
+// ratioPercent porcentaje de part sobre total (0 si total es 0)
+func ratioPercent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+// metricVariableExpressions expresiones SQL de las variables que no son una columna de la tabla
+// metrics con el mismo nombre. Deben calcular lo mismo que MetricVariables
+var metricVariableExpressions = map[string]string{
+	"load_avg_1":     "load_avg1",
+	"load_avg_5":     "load_avg5",
+	"load_avg_15":    "load_avg15",
+	"memory_percent": "CASE WHEN memory_total = 0 THEN 0 ELSE memory_used::double precision / memory_total * 100 END",
+	"swap_percent":   "CASE WHEN swap_total = 0 THEN 0 ELSE swap_used::double precision / swap_total * 100 END",
+	"disk_percent":   "CASE WHEN disk_total = 0 THEN 0 ELSE disk_used::double precision / disk_total * 100 END",
+	"net_in_mb":      "net_download::double precision / 1024 / 1024",
+	"net_out_mb":     "net_upload::double precision / 1024 / 1024",
+}
+
+// MetricVariableSQL expresión SQL (PostgreSQL) que calcula una variable sobre la tabla metrics,
+// para agregarla en la base de datos. Devuelve false si la variable no existe
+func MetricVariableSQL(name string) (string, bool) {
+	if !IsMetricVariable(name) {
+		return "", false
+	}
+	if expression, ok := metricVariableExpressions[name]; ok {
+		return "(" + expression + ")::double precision", true
+	}
+	return name + "::double precision", true
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContactPointType define los tipos de canal soportados por un punto de contacto
+type ContactPointType string
+
+const (
+	ContactPointDiscord  ContactPointType = "discord"
+	ContactPointTelegram ContactPointType = "telegram"
+	ContactPointNtfy     ContactPointType = "ntfy"
+	ContactPointWebhook  ContactPointType = "webhook"
+)
+
+// contactPointRequiredSettings claves obligatorias en Settings según el tipo de canal
+var contactPointRequiredSettings = map[ContactPointType][]string{
+	ContactPointDiscord:  {"webhook_url"},
+	ContactPointTelegram: {"bot_token", "chat_id"},
+	ContactPointNtfy:     {"topic"},
+	ContactPointWebhook:  {"url"},
+}
+
+// ContactPoint representa un destino de notificación con nombre (canal + configuración)
+type ContactPoint struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	Name        string            `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Description string            `json:"description" gorm:"type:text"`
+	Type        ContactPointType  `json:"type" gorm:"size:20;not null"`
+	Settings    map[string]string `json:"settings" gorm:"serializer:json"` // Ej: webhook_url, bot_token, chat_id, topic...
+	Enabled     bool              `json:"enabled" gorm:"default:true"`
+	OwnerTeamID *uint             `json:"owner_team_id,omitempty" gorm:"index"` // Equipo propietario del canal
+
+	// Campos comunes
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (ContactPoint) TableName() string {
+	return "contact_points"
+}
+
+// Validate verifica que el punto de contacto tenga un tipo conocido y su configuración obligatoria
+func (cp *ContactPoint) Validate() error {
+	if strings.TrimSpace(cp.Name) == "" {
+		return fmt.Errorf("el nombre del punto de contacto es obligatorio")
+	}
+
+	required, ok := contactPointRequiredSettings[cp.Type]
+	if !ok {
+		return fmt.Errorf("tipo de punto de contacto desconocido: %s", cp.Type)
+	}
+
+	for _, key := range required {
+		if strings.TrimSpace(cp.Settings[key]) == "" {
+			return fmt.Errorf("falta el parámetro '%s' para puntos de contacto de tipo %s", key, cp.Type)
+		}
+	}
+
+	return nil
+}
+
+// NotificationRoute representa una regla de enrutamiento de notificaciones.
+// Las reglas se evalúan en orden de Position; un criterio vacío coincide con cualquier valor.
+type NotificationRoute struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"size:100;not null"`
+	Position int    `json:"position" gorm:"index"` // Orden de evaluación (menor primero)
+	Enabled  bool   `json:"enabled" gorm:"default:true"`
+	Continue bool   `json:"continue" gorm:"default:false"` // Seguir evaluando reglas tras una coincidencia
+
+	// Criterios de coincidencia
+	Severities  []AlertSeverity `json:"severities" gorm:"serializer:json"`
+	MetricTypes []MetricType    `json:"metric_types" gorm:"serializer:json"`
+	ServerTags  []string        `json:"server_tags" gorm:"serializer:json"` // El servidor debe tener alguna de estas etiquetas
+	GroupIDs    []uint          `json:"group_ids" gorm:"serializer:json"`   // El servidor debe pertenecer a alguno (incluye subgrupos)
+	Locations   []string        `json:"locations" gorm:"serializer:json"`
+
+	// Franja horaria (formato HH:MM). Si TimeStart > TimeEnd la franja cruza la medianoche
+	TimeStart string `json:"time_start" gorm:"size:5"`
+	TimeEnd   string `json:"time_end" gorm:"size:5"`
+	Weekdays  []int  `json:"weekdays" gorm:"serializer:json"` // 0 = domingo ... 6 = sábado
+	Timezone  string `json:"timezone" gorm:"size:50"`         // Zona horaria IANA (por defecto la del servidor)
+
+	// Destinos. NotifyOwningTeam envía además a los puntos de contacto del equipo propietario de la alerta
+	ContactPointIDs  []uint `json:"contact_point_ids" gorm:"serializer:json"`
+	NotifyOwningTeam bool   `json:"notify_owning_team" gorm:"default:false"`
+
+	// Campos comunes
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (NotificationRoute) TableName() string {
+	return "notification_routes"
+}
+
+// Validate verifica que la regla tenga destinos y una franja horaria bien formada
+func (r *NotificationRoute) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("el nombre de la regla es obligatorio")
+	}
+
+	if len(r.ContactPointIDs) == 0 && !r.NotifyOwningTeam {
+		return fmt.Errorf("la regla debe tener al menos un punto de contacto o notificar al equipo propietario")
+	}
+
+	return validateWeeklyWindow(r.TimeStart, r.TimeEnd, r.Weekdays, r.Timezone)
+}
+
+// Matches determina si la regla coincide con una alerta. groupIDs son los grupos del servidor
+// (incluidos los ancestros) y now el instante de evaluación.
+func (r *NotificationRoute) Matches(alert *Alert, groupIDs []uint, now time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+
+	if len(r.Severities) > 0 && !containsValue(r.Severities, alert.Severity) {
+		return false
+	}
+
+	if len(r.MetricTypes) > 0 && !containsValue(r.MetricTypes, alert.MetricType) {
+		return false
+	}
+
+	if len(r.Locations) > 0 && !containsValue(r.Locations, alert.Server.Location) {
+		return false
+	}
+
+	if len(r.ServerTags) > 0 && !containsAny(r.ServerTags, alert.Server.Tags) {
+		return false
+	}
+
+	if len(r.GroupIDs) > 0 && !containsAny(r.GroupIDs, groupIDs) {
+		return false
+	}
+
+	return r.inTimeWindow(now)
+}
+
+// inTimeWindow comprueba los criterios de día de la semana y franja horaria
+func (r *NotificationRoute) inTimeWindow(now time.Time) bool {
+	return inWeeklyWindow(now, r.TimeStart, r.TimeEnd, r.Weekdays, r.Timezone)
+}
+
+// validateWeeklyWindow verifica una franja horaria semanal (HH:MM, días 0-6 y zona IANA)
+func validateWeeklyWindow(timeStart, timeEnd string, weekdays []int, timezone string) error {
+	if (timeStart == "") != (timeEnd == "") {
+		return fmt.Errorf("la franja horaria requiere inicio y fin")
+	}
+
+	if timeStart != "" {
+		if _, err := parseClock(timeStart); err != nil {
+			return fmt.Errorf("hora de inicio inválida: %s", timeStart)
+		}
+		if _, err := parseClock(timeEnd); err != nil {
+			return fmt.Errorf("hora de fin inválida: %s", timeEnd)
+		}
+	}
+
+	for _, day := range weekdays {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("día de la semana inválido: %d", day)
+		}
+	}
+
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("zona horaria inválida: %s", timezone)
+		}
+	}
+
+	return nil
+}
+
+// inWeeklyWindow indica si now cae en los días y la franja horaria indicados.
+// Los criterios vacíos no restringen
+func inWeeklyWindow(now time.Time, timeStart, timeEnd string, weekdays []int, timezone string) bool {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+
+	if len(weekdays) > 0 && !containsValue(weekdays, int(now.Weekday())) {
+		return false
+	}
+
+	if timeStart == "" || timeEnd == "" {
+		return true
+	}
+
+	start, errStart := parseClock(timeStart)
+	end, errEnd := parseClock(timeEnd)
+	if errStart != nil || errEnd != nil {
+		return false
+	}
+
+	return clockInRange(now.Hour()*60+now.Minute(), start, end)
+}
+
+// parseClock convierte una hora "HH:MM" en minutos desde la medianoche
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// clockInRange indica si un minuto del día está en [start, end), admitiendo franjas que cruzan la medianoche
+func clockInRange(minute, start, end int) bool {
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// containsValue indica si value está en list
+func containsValue[T comparable](list []T, value T) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny indica si alguno de los valores está en list
+func containsAny[T comparable](list []T, values []T) bool {
+	for _, value := range values {
+		if containsValue(list, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TemplateState define el estado de la alerta al que aplica una plantilla
+type TemplateState string
+
+const (
+	TemplateStateFiring       TemplateState = "firing"       // Alerta disparada
+	TemplateStateResolved     TemplateState = "resolved"     // Alerta resuelta
+	TemplateStateAcknowledged TemplateState = "acknowledged" // Alerta reconocida
+)
+
+// TemplateChannelDefault canal genérico: genera el título y mensaje almacenados en la alerta
+// y sirve de respaldo para los canales sin plantilla propia
+const TemplateChannelDefault = "default"
+
+// NotificationTemplate representa una plantilla (text/template de Go) para los mensajes de alerta
+type NotificationTemplate struct {
+	ID            uint          `json:"id" gorm:"primaryKey"`
+	Name          string        `json:"name" gorm:"size:100;not null"`
+	Channel       string        `json:"channel" gorm:"size:20;not null;uniqueIndex:idx_template_channel_state"` // default, discord, telegram, ntfy, webhook
+	State         TemplateState `json:"state" gorm:"size:15;not null;uniqueIndex:idx_template_channel_state"`
+	TitleTemplate string        `json:"title_template" gorm:"type:text"`
+	BodyTemplate  string        `json:"body_template" gorm:"type:text;not null"`
+	Enabled       bool          `json:"enabled" gorm:"default:true"`
+
+	// Campos comunes
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
+
+// BeforeSave es un hook GORM que normaliza el canal antes de guardar
+func (nt *NotificationTemplate) BeforeSave(tx *gorm.DB) error {
+	if nt.Channel == "" {
+		nt.Channel = TemplateChannelDefault
+	}
+	return nil
+}
+
+// IsValidTemplateChannel indica si el canal admite plantillas
+func IsValidTemplateChannel(channel string) bool {
+	switch channel {
+	case TemplateChannelDefault, string(ContactPointDiscord), string(ContactPointTelegram),
+		string(ContactPointNtfy), string(ContactPointWebhook):
+		return true
+	}
+	return false
+}
+
+// IsValidTemplateState indica si el estado es uno de los soportados
+func IsValidTemplateState(state TemplateState) bool {
+	switch state {
+	case TemplateStateFiring, TemplateStateResolved, TemplateStateAcknowledged:
+		return true
+	}
+	return false
+}
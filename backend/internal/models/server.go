@@ -38,9 +38,11 @@ type Server struct {
 	LastMaintenanceDate *time.Time `json:"last_maintenance_date"`
 	NextMaintenanceDate *time.Time `json:"next_maintenance_date"`
 	MaintenanceNotes    string     `gorm:"type:text" json:"maintenance_notes"`
+	MaintenanceDuration int        `json:"maintenance_duration"` // Duración en minutos del próximo mantenimiento (silencia sus alertas)
 
 	// Relaciones
 	Metrics           []Metric       `gorm:"foreignKey:ServerID" json:"metrics,omitempty"`
 	ServerGroups      []*ServerGroup `gorm:"many2many:server_group_servers;" json:"server_groups,omitempty"`
 	ResponsibleUserID *uint          `gorm:"index" json:"responsible_user_id,omitempty"` // Usuario responsable
+	OwnerTeamID       *uint          `gorm:"index" json:"owner_team_id,omitempty"`       // Equipo propietario
 }
@@ -15,6 +15,7 @@ type ServerGroup struct {
 	Parent      *ServerGroup   `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
 	Children    []*ServerGroup `json:"children,omitempty" gorm:"foreignKey:ParentID"`
 	Servers     []Server       `json:"servers,omitempty" gorm:"many2many:server_group_servers;"`
+	OwnerTeamID *uint          `json:"owner_team_id,omitempty" gorm:"index"` // Equipo propietario (lo heredan sus servidores y subgrupos)
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
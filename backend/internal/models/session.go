@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Motivos de revocación de una sesión
+const (
+	SessionRevokedLogout         = "logout"          // El usuario cerró la sesión
+	SessionRevokedByUser         = "revoked"         // El usuario la cerró desde la lista de sesiones
+	SessionRevokedPasswordChange = "password_change" // Cambio de contraseña
+	SessionRevokedRoleChange     = "role_change"     // Cambio de rol del usuario
+	SessionRevokedUserDeleted    = "user_deleted"    // Usuario eliminado
+	SessionRevokedUserDisabled   = "user_disabled"   // Usuario deshabilitado en el directorio
+	SessionRevokedTokenReuse     = "token_reuse"     // Se reutilizó un refresh token ya rotado
+	SessionRevokedTwoFactor      = "two_factor"      // Cambio en la verificación en dos pasos
+)
+
+// Session representa una sesión iniciada por un usuario en un dispositivo. Los tokens de acceso
+// (de corta duración) llevan el ID de la sesión y dejan de ser válidos al revocarla
+type Session struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"index;not null"`
+	UserAgent    string     `json:"user_agent" gorm:"size:500"` // Dispositivo o navegador
+	IPAddress    string     `json:"ip_address" gorm:"size:45"`
+	LastUsedAt   time.Time  `json:"last_used_at"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"index"` // Caducidad del refresh token vigente
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	RevokeReason string     `json:"revoke_reason,omitempty" gorm:"size:30"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	// Indica si es la sesión desde la que se hace la petición (no se guarda)
+	Current bool `json:"current" gorm:"-"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive indica si la sesión no se ha revocado ni ha caducado
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken token de renovación de una sesión. Solo se guarda el hash; cada renovación
+// rota el token y marca el anterior como usado, de modo que presentarlo de nuevo revela
+// que se ha filtrado
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	SessionID uint       `json:"session_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // SHA-256 en hexadecimal
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"` // Momento en que se rotó
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SilenceSource define el origen de un silencio
+type SilenceSource string
+
+const (
+	SilenceSourceManual      SilenceSource = "manual"      // Creado por un usuario
+	SilenceSourceMaintenance SilenceSource = "maintenance" // Generado por el mantenimiento programado de un servidor
+)
+
+// SilenceState estado de un silencio en un instante dado
+type SilenceState string
+
+const (
+	SilenceStatePending SilenceState = "pending" // Aún no ha comenzado
+	SilenceStateActive  SilenceState = "active"  // En vigor
+	SilenceStateExpired SilenceState = "expired" // Finalizado
+)
+
+// DefaultMaintenanceDuration duración del silencio de mantenimiento si el servidor no indica otra
+const DefaultMaintenanceDuration = 60 * time.Minute
+
+// Silence representa un periodo durante el cual las alertas que coinciden se suprimen.
+// Puede ser puntual (StartsAt-EndsAt) o recurrente dentro de ese periodo mediante una
+// franja horaria semanal. Un criterio vacío coincide con cualquier valor.
+type Silence struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	Comment   string        `json:"comment" gorm:"type:text;not null"`
+	Source    SilenceSource `json:"source" gorm:"size:15;not null;default:'manual'"`
+	StartsAt  time.Time     `json:"starts_at" gorm:"index;not null"`
+	EndsAt    time.Time     `json:"ends_at" gorm:"index;not null"`
+	CreatedBy uint          `json:"created_by"`
+
+	// Criterios de coincidencia
+	ServerIDs    []uint       `json:"server_ids" gorm:"serializer:json"`
+	GroupIDs     []uint       `json:"group_ids" gorm:"serializer:json"`   // Incluye subgrupos
+	ServerTags   []string     `json:"server_tags" gorm:"serializer:json"` // El servidor debe tener alguna de estas etiquetas
+	MetricTypes  []MetricType `json:"metric_types" gorm:"serializer:json"`
+	ThresholdIDs []uint       `json:"threshold_ids" gorm:"serializer:json"`
+
+	// Recurrencia semanal (opcional). Si TimeStart > TimeEnd la franja cruza la medianoche
+	Weekdays  []int  `json:"weekdays" gorm:"serializer:json"` // 0 = domingo ... 6 = sábado
+	TimeStart string `json:"time_start" gorm:"size:5"`
+	TimeEnd   string `json:"time_end" gorm:"size:5"`
+	Timezone  string `json:"timezone" gorm:"size:50"`
+
+	// Servidor cuyo mantenimiento programado generó el silencio
+	MaintenanceServerID *uint `json:"maintenance_server_id,omitempty" gorm:"index"`
+
+	// Campos comunes
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Silence) TableName() string {
+	return "silences"
+}
+
+// Validate verifica el comentario, el periodo, los criterios y la franja recurrente
+func (s *Silence) Validate() error {
+	if strings.TrimSpace(s.Comment) == "" {
+		return fmt.Errorf("el comentario del silencio es obligatorio")
+	}
+
+	if s.StartsAt.IsZero() || s.EndsAt.IsZero() {
+		return fmt.Errorf("el silencio requiere inicio y fin")
+	}
+
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("el fin del silencio debe ser posterior al inicio")
+	}
+
+	if len(s.ServerIDs) == 0 && len(s.GroupIDs) == 0 && len(s.ServerTags) == 0 &&
+		len(s.MetricTypes) == 0 && len(s.ThresholdIDs) == 0 {
+		return fmt.Errorf("el silencio debe tener al menos un criterio de coincidencia")
+	}
+
+	return validateWeeklyWindow(s.TimeStart, s.TimeEnd, s.Weekdays, s.Timezone)
+}
+
+// IsRecurring indica si el silencio solo aplica en una franja semanal
+func (s *Silence) IsRecurring() bool {
+	return len(s.Weekdays) > 0 || s.TimeStart != ""
+}
+
+// State devuelve el estado del silencio en el instante indicado
+func (s *Silence) State(now time.Time) SilenceState {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilenceStatePending
+	case now.Before(s.EndsAt):
+		return SilenceStateActive
+	default:
+		return SilenceStateExpired
+	}
+}
+
+// ActiveAt indica si el silencio está en vigor en el instante indicado,
+// teniendo en cuenta la franja recurrente
+func (s *Silence) ActiveAt(now time.Time) bool {
+	if s.State(now) != SilenceStateActive {
+		return false
+	}
+
+	return inWeeklyWindow(now, s.TimeStart, s.TimeEnd, s.Weekdays, s.Timezone)
+}
+
+// Matches determina si el silencio suprime una alerta. groupIDs son los grupos del servidor
+// (incluidos los ancestros) y now el instante de evaluación.
+func (s *Silence) Matches(alert *Alert, groupIDs []uint, now time.Time) bool {
+	if !s.ActiveAt(now) {
+		return false
+	}
+
+	if len(s.ServerIDs) > 0 && !containsValue(s.ServerIDs, alert.ServerID) {
+		return false
+	}
+
+	if len(s.ThresholdIDs) > 0 && !containsValue(s.ThresholdIDs, alert.ThresholdID) {
+		return false
+	}
+
+	if len(s.MetricTypes) > 0 && !containsValue(s.MetricTypes, alert.MetricType) {
+		return false
+	}
+
+	if len(s.ServerTags) > 0 && !containsAny(s.ServerTags, alert.Server.Tags) {
+		return false
+	}
+
+	if len(s.GroupIDs) > 0 && !containsAny(s.GroupIDs, groupIDs) {
+		return false
+	}
+
+	return true
+}
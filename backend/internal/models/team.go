@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TeamRole define el rol de un miembro dentro de un equipo
+type TeamRole string
+
+const (
+	TeamRoleMember TeamRole = "member" // Miembro del equipo
+	TeamRoleAdmin  TeamRole = "admin"  // Administrador del equipo: gestiona sus datos y sus miembros
+)
+
+// Team representa un equipo de usuarios. Los servidores, grupos, umbrales y puntos de contacto
+// pueden pertenecer a un equipo; las alertas heredan el equipo propietario y las reglas de
+// enrutamiento pueden notificar a los puntos de contacto del equipo propietario
+type Team struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Description string       `json:"description" gorm:"type:text"`
+	Members     []TeamMember `json:"members,omitempty" gorm:"foreignKey:TeamID"`
+
+	// Campos comunes
+	CreatedBy uint      `json:"created_by"` // Usuario que creó el equipo
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (Team) TableName() string {
+	return "teams"
+}
+
+// Validate verifica el nombre del equipo
+func (t *Team) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("el nombre del equipo es obligatorio")
+	}
+
+	return nil
+}
+
+// TeamMember pertenencia de un usuario a un equipo
+type TeamMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TeamID    uint      `json:"team_id" gorm:"not null;uniqueIndex:idx_team_member_team_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;index;uniqueIndex:idx_team_member_team_user"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Role      TeamRole  `json:"role" gorm:"size:20;not null;default:'member'"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (TeamMember) TableName() string {
+	return "team_members"
+}
+
+// ValidTeamRole indica si el rol de equipo es válido
+func ValidTeamRole(role TeamRole) bool {
+	return role == TeamRoleMember || role == TeamRoleAdmin
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// TwoFactor configuración de la verificación en dos pasos (TOTP) de un usuario. Se crea al
+// iniciar el alta y solo se activa tras verificar un primer código
+type TwoFactor struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	Secret         string     `json:"-" gorm:"size:255;not null"` // Secreto TOTP cifrado con AES-GCM
+	Enabled        bool       `json:"enabled" gorm:"not null;default:false"`
+	EnabledAt      *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep   int64      `json:"-"` // Último periodo aceptado, para impedir reutilizar un código
+	FailedAttempts int        `json:"-"` // Códigos incorrectos consecutivos
+	LockedUntil    *time.Time `json:"-"` // Bloqueo temporal tras demasiados códigos incorrectos
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (TwoFactor) TableName() string {
+	return "two_factors"
+}
+
+// RecoveryCode código de recuperación de un solo uso para entrar sin la aplicación TOTP.
+// Solo se guarda el hash
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // SHA-256 en hexadecimal
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// TwoFactorPolicy rol para el que la verificación en dos pasos es obligatoria
+type TwoFactorPolicy struct {
+	Role      Role      `json:"role" gorm:"primaryKey;size:20"`
+	CreatedBy uint      `json:"created_by"` // Admin que la exigió
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName especifica el nombre de la tabla en la base de datos
+func (TwoFactorPolicy) TableName() string {
+	return "two_factor_policies"
+}
@@ -17,17 +17,32 @@ const (
 	RoleViewer Role = "VIEWER"
 )
 
+// Proveedores de autenticación de los usuarios
+const (
+	AuthProviderLocal = "local" // Usuario y contraseña locales
+	AuthProviderOIDC  = "oidc"  // Inicio de sesión único con OpenID Connect
+	AuthProviderLDAP  = "ldap"  // Usuario y contraseña del directorio LDAP / Active Directory
+)
+
 // User representa un usuario del sistema
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Username  string         `gorm:"size:50;not null;uniqueIndex" json:"username"`
-	Email     string         `gorm:"size:100;not null;uniqueIndex" json:"email"`
-	Password  string         `gorm:"size:100;not null" json:"-"` // No se devuelve en JSON
-	Role      Role           `gorm:"size:20;not null" json:"role"`
-	LastLogin *time.Time     `json:"last_login,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Username     string         `gorm:"size:50;not null;uniqueIndex" json:"username"`
+	Email        string         `gorm:"size:100;not null;uniqueIndex" json:"email"`
+	Password     string         `gorm:"size:100;not null" json:"-"` // No se devuelve en JSON
+	Role         Role           `gorm:"size:20;not null" json:"role"`
+	AuthProvider string         `gorm:"size:20;not null;default:'local';uniqueIndex:idx_user_provider_external" json:"auth_provider"`
+	ExternalID   *string        `gorm:"size:255;uniqueIndex:idx_user_provider_external" json:"external_id,omitempty"` // Identificador (sub) en el proveedor externo
+	Disabled     bool           `gorm:"not null;default:false" json:"disabled"` // Cuenta deshabilitada en el directorio
+	LastLogin    *time.Time     `json:"last_login,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// IsLocal indica si el usuario inicia sesión con contraseña local
+func (u *User) IsLocal() bool {
+	return u.AuthProvider == "" || u.AuthProvider == AuthProviderLocal
 }
 
 // SetPassword cifra y establece la contraseña del usuario
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+)
+
+// SnoozeCheckInterval frecuencia con la que se reactivan las alertas pospuestas vencidas
+const SnoozeCheckInterval = 30 * time.Second
+
+// MaxSnoozeMinutes plazo máximo para posponer una alerta (7 días)
+const MaxSnoozeMinutes = 7 * 24 * 60
+
+// AlertBulkFilter criterios que seleccionan las alertas de una acción masiva. Se combinan
+// con AND y debe indicarse al menos uno
+type AlertBulkFilter struct {
+	AlertIDs []uint               `json:"alert_ids"`
+	ServerID *uint                `json:"server_id"`
+	GroupID  *uint                `json:"group_id"` // Incluye los servidores de sus subgrupos
+	Severity models.AlertSeverity `json:"severity"`
+
+	// Alcance del usuario que ejecuta la acción: solo se seleccionan alertas de servidores
+	// sobre los que tiene al menos el rol user
+	Scope *AccessScope `json:"-"`
+}
+
+// IsEmpty indica si el filtro no restringe ninguna alerta
+func (f *AlertBulkFilter) IsEmpty() bool {
+	return len(f.AlertIDs) == 0 && f.ServerID == nil && f.GroupID == nil && f.Severity == ""
+}
+
+// AlertBulkResult resultado de una acción masiva
+type AlertBulkResult struct {
+	Matched int                `json:"matched"`          // Alertas que cumplían el filtro y el estado requerido
+	Updated []uint             `json:"updated"`          // Alertas modificadas
+	Failed  []AlertBulkFailure `json:"failed,omitempty"` // Alertas en las que la acción falló
+}
+
+// AlertBulkFailure alerta en la que falló una acción masiva
+type AlertBulkFailure struct {
+	AlertID uint   `json:"alert_id"`
+	Error   string `json:"error"`
+}
+
+// UnacknowledgeAlert retira el reconocimiento de una alerta, que vuelve a estar activa y
+// reinicia su escalado
+func (as *AlertService) UnacknowledgeAlert(id, userID uint, notes string) error {
+	alert, err := as.GetAlert(id)
+	if err != nil {
+		return err
+	}
+
+	if !alert.CanUnacknowledge() {
+		return fmt.Errorf("la alerta no está reconocida")
+	}
+
+	if err := as.reactivateAlert(alert, nil); err != nil {
+		as.logger.Errorf("Error al retirar el reconocimiento de la alerta: %v", err)
+		return err
+	}
+
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID: alert.ID,
+		Type:    models.AlertEventUnacknowledged,
+		Message: notes,
+		ActorID: &userID,
+	})
+	as.afterReactivation(alert, false)
+
+	as.logger.Infof("Reconocimiento de la alerta %d retirado por usuario %d", id, userID)
+	return nil
+}
+
+// ReopenAlert vuelve a abrir una alerta resuelta por error: pasa a estar activa, se notifica
+// de nuevo y reinicia su escalado
+func (as *AlertService) ReopenAlert(id, userID uint, notes string) error {
+	alert, err := as.GetAlert(id)
+	if err != nil {
+		return err
+	}
+
+	if !alert.CanReopen() {
+		return fmt.Errorf("solo se pueden reabrir alertas resueltas")
+	}
+
+	// La alerta deja de estar inhibida aunque se hubiera resuelto estándolo
+	if err := as.reactivateAlert(alert, map[string]interface{}{
+		"inhibited_by_id":    nil,
+		"inhibition_rule_id": nil,
+		"inhibition_reason":  "",
+	}); err != nil {
+		as.logger.Errorf("Error al reabrir alerta: %v", err)
+		return err
+	}
+	alert.InhibitedByID = nil
+	alert.InhibitionRuleID = nil
+	alert.InhibitionReason = ""
+
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID: alert.ID,
+		Type:    models.AlertEventReopened,
+		Message: notes,
+		ActorID: &userID,
+	})
+	as.afterReactivation(alert, true)
+
+	as.logger.Infof("Alerta %d reabierta por usuario %d", id, userID)
+	return nil
+}
+
+// SnoozeAlert pospone una alerta durante los minutos indicados: queda suprimida, sin
+// escalado, y vuelve a estar activa al terminar el plazo si no se ha resuelto
+func (as *AlertService) SnoozeAlert(id, userID uint, minutes int, notes string) (*models.Alert, error) {
+	if minutes <= 0 || minutes > MaxSnoozeMinutes {
+		return nil, fmt.Errorf("la duración debe estar entre 1 y %d minutos", MaxSnoozeMinutes)
+	}
+
+	alert, err := as.GetAlert(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !alert.CanSnooze() {
+		return nil, fmt.Errorf("la alerta no puede posponerse")
+	}
+
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+	result := as.db.Model(&models.Alert{}).
+		Where("id = ? AND status = ?", alert.ID, alert.Status).
+		Updates(map[string]interface{}{
+			"status":             models.AlertStatusSuppressed,
+			"snoozed_until":      until,
+			"snoozed_by":         userID,
+			"next_escalation_at": nil, // Detener el escalado mientras esté pospuesta
+		})
+	if result.Error != nil {
+		as.logger.Errorf("Error al posponer alerta: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("la alerta cambió de estado, inténtelo de nuevo")
+	}
+
+	alert.Status = models.AlertStatusSuppressed
+	alert.SnoozedUntil = &until
+	alert.SnoozedBy = &userID
+	alert.NextEscalationAt = nil
+
+	message := fmt.Sprintf("Pospuesta hasta %s", until.Format(time.RFC3339))
+	if notes != "" {
+		message += ": " + notes
+	}
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID: alert.ID,
+		Type:    models.AlertEventSnoozed,
+		Message: message,
+		ActorID: &userID,
+	})
+
+	as.logger.Infof("Alerta %d pospuesta %d minutos por usuario %d", id, minutes, userID)
+	return alert, nil
+}
+
+// Start inicia la comprobación periódica de las alertas pospuestas
+func (as *AlertService) Start(interval time.Duration) {
+	as.stop = make(chan struct{})
+	as.wg.Add(1)
+
+	go func() {
+		defer as.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				as.ProcessExpiredSnoozes(time.Now())
+			case <-as.stop:
+				return
+			}
+		}
+	}()
+
+	as.logger.Infof("Planificador de alertas pospuestas iniciado (intervalo %s)", interval)
+}
+
+// Stop detiene el planificador de alertas pospuestas
+func (as *AlertService) Stop() {
+	if as.stop == nil {
+		return
+	}
+
+	close(as.stop)
+	as.wg.Wait()
+	as.stop = nil
+	as.logger.Info("Planificador de alertas pospuestas detenido")
+}
+
+// ProcessExpiredSnoozes reactiva las alertas cuyo plazo de posposición ha terminado. Si
+// entretanto coinciden con un silencio o una regla de inhibición siguen suprimidas
+func (as *AlertService) ProcessExpiredSnoozes(now time.Time) {
+	var alerts []models.Alert
+	if err := as.db.Where("status = ? AND snoozed_until IS NOT NULL AND snoozed_until <= ?",
+		models.AlertStatusSuppressed, now).Preload("Server").Find(&alerts).Error; err != nil {
+		as.logger.Errorf("Error al obtener alertas pospuestas vencidas: %v", err)
+		return
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+
+		if updates, reason := as.suppressionFor(alert); updates != nil {
+			updates["snoozed_until"] = nil
+			updates["snoozed_by"] = nil
+			if err := as.db.Model(alert).Updates(updates).Error; err != nil {
+				as.logger.Errorf("Error al actualizar la alerta pospuesta %d: %v", alert.ID, err)
+				continue
+			}
+			recordAlertEvent(as.db, as.logger, models.AlertEvent{
+				AlertID: alert.ID,
+				Type:    models.AlertEventSuppressed,
+				Message: reason,
+			})
+			continue
+		}
+
+		if err := as.reactivateAlert(alert, nil); err != nil {
+			as.logger.Errorf("Error al reactivar la alerta pospuesta %d: %v", alert.ID, err)
+			continue
+		}
+
+		recordAlertEvent(as.db, as.logger, models.AlertEvent{
+			AlertID: alert.ID,
+			Type:    models.AlertEventReopened,
+			Message: "Reactivada al terminar el plazo de posposición",
+		})
+		as.afterReactivation(alert, true)
+
+		as.logger.Infof("Alerta %d reactivada al terminar el plazo de posposición", alert.ID)
+	}
+}
+
+// reactivateAlert devuelve la alerta al estado activo borrando el reconocimiento, la
+// resolución y la posposición. La actualización solo se aplica si la alerta sigue en el
+// estado leído, para no pisar cambios concurrentes
+func (as *AlertService) reactivateAlert(alert *models.Alert, extra map[string]interface{}) error {
+	updates := map[string]interface{}{
+		"status":             models.AlertStatusActive,
+		"resolved_at":        nil,
+		"acknowledged_at":    nil,
+		"acknowledged_by":    nil,
+		"snoozed_until":      nil,
+		"snoozed_by":         nil,
+		"next_escalation_at": nil,
+	}
+	for field, value := range extra {
+		updates[field] = value
+	}
+
+	result := as.db.Model(&models.Alert{}).
+		Where("id = ? AND status = ?", alert.ID, alert.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("la alerta cambió de estado, inténtelo de nuevo")
+	}
+
+	alert.Status = models.AlertStatusActive
+	alert.ResolvedAt = nil
+	alert.AcknowledgedAt = nil
+	alert.AcknowledgedBy = nil
+	alert.SnoozedUntil = nil
+	alert.SnoozedBy = nil
+	alert.NextEscalationAt = nil
+	return nil
+}
+
+// afterReactivation actualiza el incidente de una alerta que vuelve a estar activa, la
+// notifica de nuevo si se indica y reinicia su escalado
+func (as *AlertService) afterReactivation(alert *models.Alert, notify bool) {
+	if as.incidentService != nil {
+		as.incidentService.OnAlertReactivated(alert)
+	}
+
+	threshold, ok := as.notificationThreshold(alert)
+	if !ok {
+		return
+	}
+
+	if notify {
+		if alert.Server.ID == 0 {
+			as.db.First(&alert.Server, alert.ServerID)
+		}
+
+		if err := as.notifyManager.NotifyAlert(alert, threshold); err != nil {
+			as.logger.Errorf("Error al enviar notificaciones para alerta %d: %v", alert.ID, err)
+		} else {
+			now := time.Now()
+			alert.NotifiedAt = &now
+			// Los canales se serializan como JSON: con un mapa en Updates no se aplicaría el serializador
+			if err := as.db.Model(alert).Select("notified_at", "notify_channels").Updates(alert).Error; err != nil {
+				as.logger.Errorf("Error al registrar la notificación de la alerta %d: %v", alert.ID, err)
+			}
+			recordNotifiedEvents(as.db, as.logger, alert.ID, alert.NotifyChannels, "")
+		}
+	}
+
+	if as.escalationService != nil {
+		as.escalationService.RestartEscalation(alert, threshold)
+	}
+}
+
+// suppressionFor comprueba si una alerta que va a reactivarse debe seguir suprimida por una
+// regla de inhibición o por un silencio. Devuelve los campos a guardar y el motivo, o nil
+func (as *AlertService) suppressionFor(alert *models.Alert) (map[string]interface{}, string) {
+	if as.inhibitionService != nil {
+		rule, source, err := as.inhibitionService.FindInhibitingAlert(alert)
+		if err != nil {
+			as.logger.Warnf("Error al comprobar reglas de inhibición para la alerta %d: %v", alert.ID, err)
+		} else if rule != nil {
+			reason := rule.Reason(source)
+			return map[string]interface{}{
+				"inhibited_by_id":    source.ID,
+				"inhibition_rule_id": rule.ID,
+				"inhibition_reason":  reason,
+			}, reason
+		}
+	}
+
+	if as.silenceService != nil {
+		silence, err := as.silenceService.FindMatchingSilence(alert, time.Now())
+		if err != nil {
+			as.logger.Warnf("Error al comprobar silencios para la alerta %d: %v", alert.ID, err)
+		} else if silence != nil {
+			return map[string]interface{}{
+				"silence_id": silence.ID,
+			}, fmt.Sprintf("Suprimida por el silencio %d", silence.ID)
+		}
+	}
+
+	return nil, ""
+}
+
+// BulkAcknowledge reconoce las alertas activas que cumplen el filtro
+func (as *AlertService) BulkAcknowledge(filter AlertBulkFilter, userID uint, notes string) (*AlertBulkResult, error) {
+	return as.applyBulk(filter, []models.AlertStatus{models.AlertStatusActive}, func(id uint) error {
+		return as.AcknowledgeAlert(id, userID, notes)
+	})
+}
+
+// BulkResolve resuelve las alertas pendientes que cumplen el filtro
+func (as *AlertService) BulkResolve(filter AlertBulkFilter, userID uint, notes string) (*AlertBulkResult, error) {
+	statuses := []models.AlertStatus{models.AlertStatusActive, models.AlertStatusAcknowledged, models.AlertStatusSuppressed}
+	return as.applyBulk(filter, statuses, func(id uint) error {
+		return as.ResolveAlert(id, userID, notes)
+	})
+}
+
+// BulkSnooze pospone las alertas activas o reconocidas que cumplen el filtro
+func (as *AlertService) BulkSnooze(filter AlertBulkFilter, userID uint, minutes int, notes string) (*AlertBulkResult, error) {
+	if minutes <= 0 || minutes > MaxSnoozeMinutes {
+		return nil, fmt.Errorf("la duración debe estar entre 1 y %d minutos", MaxSnoozeMinutes)
+	}
+
+	statuses := []models.AlertStatus{models.AlertStatusActive, models.AlertStatusAcknowledged}
+	return as.applyBulk(filter, statuses, func(id uint) error {
+		_, err := as.SnoozeAlert(id, userID, minutes, notes)
+		return err
+	})
+}
+
+// applyBulk aplica una acción a cada alerta que cumple el filtro y está en alguno de los estados
+func (as *AlertService) applyBulk(filter AlertBulkFilter, statuses []models.AlertStatus, action func(id uint) error) (*AlertBulkResult, error) {
+	ids, err := as.bulkAlertIDs(filter, statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AlertBulkResult{Matched: len(ids), Updated: []uint{}}
+	for _, id := range ids {
+		if err := action(id); err != nil {
+			result.Failed = append(result.Failed, AlertBulkFailure{AlertID: id, Error: err.Error()})
+			continue
+		}
+		result.Updated = append(result.Updated, id)
+	}
+
+	as.logger.Infof("Acción masiva sobre alertas: %d seleccionadas, %d modificadas", result.Matched, len(result.Updated))
+	return result, nil
+}
+
+// bulkAlertIDs IDs de las alertas que cumplen el filtro y están en alguno de los estados
+func (as *AlertService) bulkAlertIDs(filter AlertBulkFilter, statuses []models.AlertStatus) ([]uint, error) {
+	if filter.IsEmpty() {
+		return nil, fmt.Errorf("debe indicar al menos un criterio: alertas, servidor, grupo o severidad")
+	}
+	if filter.Severity != "" && models.SeverityRank(filter.Severity) == 0 {
+		return nil, fmt.Errorf("severidad inválida: %s", filter.Severity)
+	}
+
+	query := as.db.Model(&models.Alert{}).Where("status IN ?", statuses)
+
+	if len(filter.AlertIDs) > 0 {
+		query = query.Where("id IN ?", filter.AlertIDs)
+	}
+
+	if filter.ServerID != nil {
+		query = query.Where("server_id = ?", *filter.ServerID)
+	}
+
+	if filter.GroupID != nil {
+		serverIDs, err := serverIDsInGroupTree(as.db, *filter.GroupID)
+		if err != nil {
+			as.logger.Errorf("Error al obtener los servidores del grupo %d: %v", *filter.GroupID, err)
+			return nil, err
+		}
+		if len(serverIDs) == 0 {
+			return nil, nil
+		}
+		query = query.Where("server_id IN ?", serverIDs)
+	}
+
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+
+	if filter.Scope != nil {
+		if serverIDs, restricted := filter.Scope.ServerIDs(models.RoleUser); restricted {
+			if len(serverIDs) == 0 {
+				return nil, nil
+			}
+			query = query.Where("server_id IN ?", serverIDs)
+		}
+	}
+
+	var ids []uint
+	if err := query.Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		as.logger.Errorf("Error al seleccionar alertas para la acción masiva: %v", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+)
+
+// MaxBacktestRange rango máximo de tiempo de un backtest
+const MaxBacktestRange = 31 * 24 * time.Hour
+
+// MaxBacktestHistorySamples muestras que el backtest puede mantener en memoria a la vez: las de
+// la ventana que necesita la regla en todos sus servidores
+const MaxBacktestHistorySamples = 250000
+
+// backtestPageSize métricas que se leen en cada consulta del backtest
+const backtestPageSize = 5000
+
+// ErrBacktestTooLarge indica que la ventana de la regla en sus servidores no cabe en memoria
+var ErrBacktestTooLarge = fmt.Errorf("el backtest necesita más de %d muestras a la vez: limite los servidores (server_ids) o reduzca la ventana de la regla", MaxBacktestHistorySamples)
+
+// BacktestRequest regla sin guardar y rango de tiempo a reproducir
+type BacktestRequest struct {
+	Threshold models.AlertThreshold `json:"threshold"`
+	Start     time.Time             `json:"start" binding:"required"`
+	End       time.Time             `json:"end" binding:"required"`
+	ServerIDs []uint                `json:"server_ids"` // Opcional: limita los servidores dentro del alcance de la regla
+}
+
+// BacktestAlert alerta que se habría generado
+type BacktestAlert struct {
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`         // Nulo si seguía abierta al final del rango
+	DurationSeconds *int64     `json:"duration_seconds"` // Nulo si seguía abierta al final del rango
+	MetricValue     float64    `json:"metric_value"`
+	Threshold       float64    `json:"threshold"`
+	Operator        string     `json:"operator"`
+	Summary         string     `json:"summary,omitempty"`
+}
+
+// BacktestServerResult alertas que se habrían generado en un servidor
+type BacktestServerResult struct {
+	ServerID         uint            `json:"server_id"`
+	Hostname         string          `json:"hostname"`
+	Evaluations      int             `json:"evaluations"`
+	EvaluationErrors int             `json:"evaluation_errors"` // Evaluaciones fallidas, tratadas como condición no cumplida
+	AlertCount       int             `json:"alert_count"`
+	Alerts           []BacktestAlert `json:"alerts"`
+}
+
+// BacktestResult resultado de reproducir el historial a través de una regla
+type BacktestResult struct {
+	Start             time.Time              `json:"start"`
+	End               time.Time              `json:"end"`
+	ServersEvaluated  int                    `json:"servers_evaluated"`
+	ServersWithAlerts int                    `json:"servers_with_alerts"`
+	Evaluations       int                    `json:"evaluations"`
+	EvaluationErrors  int                    `json:"evaluation_errors"` // Evaluaciones fallidas, tratadas como condición no cumplida
+	TotalAlerts       int                    `json:"total_alerts"`
+	Servers           []BacktestServerResult `json:"servers"` // Solo servidores con alertas, de más a menos ruidosos
+}
+
+// BacktestThreshold reproduce las métricas almacenadas del rango a través de la misma evaluación
+// que CheckMetricAgainstThresholds (condición, duración y cooldown de la regla) sin escribir nada.
+// El cooldown, como en el motor, es común a todos los servidores de la regla.
+func (as *AlertService) BacktestThreshold(req *BacktestRequest) (*BacktestResult, error) {
+	threshold := req.Threshold
+	if !threshold.ValidateThreshold() {
+		return nil, fmt.Errorf("umbral de alerta inválido")
+	}
+	if err := threshold.ValidateCondition(); err != nil {
+		return nil, err
+	}
+	if threshold.IsExpression() {
+		if _, err := CompileThresholdExpression(threshold.Expression); err != nil {
+			return nil, err
+		}
+	}
+	if !req.End.After(req.Start) {
+		return nil, fmt.Errorf("el fin del rango debe ser posterior al inicio")
+	}
+	if req.End.Sub(req.Start) > MaxBacktestRange {
+		return nil, fmt.Errorf("el rango no puede superar %d días", int(MaxBacktestRange.Hours()/24))
+	}
+
+	servers, err := as.backtestServers(&threshold, req.ServerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := EvaluationSources{}
+	if as.baselineService != nil {
+		sources.Baselines = newCachedBaselines(as.baselineService)
+	}
+	replay := newBacktestReplay(&threshold, servers, sources, req.Start, req.End)
+
+	// Las métricas se leen por páginas en orden cronológico, empezando antes del rango para tener
+	// el historial que necesitan las ventanas y la duración
+	serverIDs := make([]uint, len(servers))
+	for i, server := range servers {
+		serverIDs[i] = server.ID
+	}
+	if err := as.streamBacktestMetrics(serverIDs, req.Start.Add(-replay.lookback), req.End, replay.addPage); err != nil {
+		return nil, err
+	}
+
+	return replay.finish(), nil
+}
+
+// backtestReplay reproduce en orden cronológico las muestras de todos los servidores de la regla.
+// Solo conserva en memoria el historial que aún puede necesitar la evaluación
+type backtestReplay struct {
+	threshold *models.AlertThreshold
+	sources   EvaluationSources
+	history   *memoryHistory
+	lookback  time.Duration
+	start     time.Time
+	end       time.Time
+
+	servers       []models.Server
+	results       map[uint]*BacktestServerResult
+	open          map[uint][]int // Índices de las alertas abiertas por servidor
+	lastTriggered *time.Time
+	result        *BacktestResult
+}
+
+// newBacktestReplay prepara la reproducción de la regla en los servidores
+func newBacktestReplay(threshold *models.AlertThreshold, servers []models.Server, sources EvaluationSources, start, end time.Time) *backtestReplay {
+	history := &memoryHistory{byServer: make(map[uint][]*models.Metric, len(servers))}
+	sources.History = history
+
+	results := make(map[uint]*BacktestServerResult, len(servers))
+	for _, server := range servers {
+		results[server.ID] = &BacktestServerResult{ServerID: server.ID, Hostname: server.Hostname, Alerts: []BacktestAlert{}}
+	}
+
+	return &backtestReplay{
+		threshold: threshold,
+		sources:   sources,
+		history:   history,
+		lookback:  backtestHistoryNeeded(threshold),
+		start:     start,
+		end:       end,
+		servers:   servers,
+		results:   results,
+		open:      make(map[uint][]int),
+		result:    &BacktestResult{Start: start, End: end, ServersEvaluated: len(servers)},
+	}
+}
+
+// addPage añade una página de métricas (en orden cronológico) al historial, evalúa las del rango
+// y descarta el historial que ya no necesitará ninguna evaluación posterior
+func (r *backtestReplay) addPage(page []models.Metric) error {
+	if len(page) == 0 {
+		return nil
+	}
+
+	// Cada muestra se copia por separado para no retener la página completa en el historial
+	added := make([]*models.Metric, len(page))
+	for i := range page {
+		metric := page[i]
+		added[i] = &metric
+		r.history.byServer[metric.ServerID] = append(r.history.byServer[metric.ServerID], &metric)
+	}
+	for _, metric := range added {
+		if !metric.Timestamp.Before(r.start) {
+			r.evaluate(metric)
+		}
+	}
+
+	if r.history.trim(page[len(page)-1].Timestamp.Add(-r.lookback)) > MaxBacktestHistorySamples {
+		return ErrBacktestTooLarge
+	}
+	return nil
+}
+
+// evaluate aplica la regla a una muestra como lo hace CheckMetricAgainstThresholds
+func (r *backtestReplay) evaluate(metric *models.Metric) {
+	if r.lastTriggered != nil {
+		cooldownEnds := r.lastTriggered.Add(time.Duration(r.threshold.CooldownMinutes) * time.Minute)
+		if metric.Timestamp.Before(cooldownEnds) {
+			return
+		}
+	}
+
+	serverResult := r.results[metric.ServerID]
+
+	eval, err := EvaluateAlertCondition(r.threshold, metric, r.sources)
+	if errors.Is(err, ErrInsufficientHistory) {
+		return
+	}
+	if err != nil {
+		// Como en el motor, una evaluación fallida cuenta como condición no cumplida
+		serverResult.EvaluationErrors++
+		r.result.EvaluationErrors++
+		eval = &ThresholdEvaluation{MetricName: r.threshold.Name}
+	}
+
+	serverResult.Evaluations++
+	r.result.Evaluations++
+
+	if eval.Pending {
+		return
+	}
+
+	if eval.Triggered {
+		serverResult.Alerts = append(serverResult.Alerts, BacktestAlert{
+			StartedAt:   metric.Timestamp,
+			MetricValue: eval.Value,
+			Threshold:   eval.Limit,
+			Operator:    eval.Operator,
+			Summary:     eval.Summary,
+		})
+		r.open[metric.ServerID] = append(r.open[metric.ServerID], len(serverResult.Alerts)-1)
+		triggeredAt := metric.Timestamp
+		r.lastTriggered = &triggeredAt
+		return
+	}
+
+	// La condición ya no se cumple: se resolverían las alertas abiertas del servidor
+	for _, i := range r.open[metric.ServerID] {
+		endedAt := metric.Timestamp
+		duration := int64(endedAt.Sub(serverResult.Alerts[i].StartedAt).Seconds())
+		serverResult.Alerts[i].EndedAt = &endedAt
+		serverResult.Alerts[i].DurationSeconds = &duration
+	}
+	delete(r.open, metric.ServerID)
+}
+
+// finish resume las alertas que se habrían generado, de los servidores más ruidosos a los menos
+func (r *backtestReplay) finish() *BacktestResult {
+	result := r.result
+	for _, server := range r.servers {
+		serverResult := r.results[server.ID]
+		serverResult.AlertCount = len(serverResult.Alerts)
+		if serverResult.AlertCount == 0 {
+			continue
+		}
+		result.TotalAlerts += serverResult.AlertCount
+		result.Servers = append(result.Servers, *serverResult)
+	}
+	result.ServersWithAlerts = len(result.Servers)
+	sort.SliceStable(result.Servers, func(i, j int) bool {
+		return result.Servers[i].AlertCount > result.Servers[j].AlertCount
+	})
+	if result.Servers == nil {
+		result.Servers = []BacktestServerResult{}
+	}
+
+	return result
+}
+
+// backtestServers servidores a los que se aplicaría la regla, opcionalmente filtrados
+func (as *AlertService) backtestServers(threshold *models.AlertThreshold, requested []uint) ([]models.Server, error) {
+	query := as.db.Model(&models.Server{})
+
+	switch {
+	case threshold.ServerID != nil:
+		query = query.Where("id = ?", *threshold.ServerID)
+	case threshold.GroupID != nil:
+		query = query.Where("id IN (?)", as.db.Table("server_group_servers").
+			Select("server_id").Where("server_group_id = ?", *threshold.GroupID))
+	}
+	if len(requested) > 0 {
+		query = query.Where("id IN ?", requested)
+	}
+
+	var servers []models.Server
+	if err := query.Order("id ASC").Find(&servers).Error; err != nil {
+		as.logger.Errorf("Error al obtener servidores para el backtest: %v", err)
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("ningún servidor dentro del alcance de la regla")
+	}
+
+	return servers, nil
+}
+
+// streamBacktestMetrics lee las métricas de los servidores en el rango, en orden cronológico y
+// por páginas, y pasa cada página a fn
+func (as *AlertService) streamBacktestMetrics(serverIDs []uint, start, end time.Time, fn func([]models.Metric) error) error {
+	var lastTimestamp time.Time
+	var lastID uint
+
+	for first := true; ; first = false {
+		query := as.db.Where("server_id IN ? AND timestamp <= ?", serverIDs, end)
+		if first {
+			query = query.Where("timestamp >= ?", start)
+		} else {
+			query = query.Where("(timestamp > ? OR (timestamp = ? AND id > ?))", lastTimestamp, lastTimestamp, lastID)
+		}
+
+		var page []models.Metric
+		if err := query.Order("timestamp ASC, id ASC").Limit(backtestPageSize).Find(&page).Error; err != nil {
+			as.logger.Errorf("Error al cargar métricas para el backtest: %v", err)
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if len(page) < backtestPageSize {
+			return nil
+		}
+
+		lastTimestamp = page[len(page)-1].Timestamp
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// backtestHistoryNeeded historial anterior al inicio que necesita la regla para evaluarse
+func backtestHistoryNeeded(threshold *models.AlertThreshold) time.Duration {
+	needed := time.Duration(threshold.WindowMinutes+threshold.CompareOffsetMinutes) * time.Minute
+	if threshold.Duration > 0 {
+		needed += time.Duration(threshold.Duration)*time.Second + durationLookbackSlack
+	}
+	return needed
+}
+
+// memoryHistory historial de métricas en memoria (ordenado por fecha) para el backtest
+type memoryHistory struct {
+	byServer map[uint][]*models.Metric
+}
+
+// GetMetricsByTimeRange implementa MetricHistory sobre las métricas cargadas
+func (h *memoryHistory) GetMetricsByTimeRange(serverID uint, startTime, endTime time.Time) ([]models.Metric, error) {
+	metrics := h.byServer[serverID]
+	from := sort.Search(len(metrics), func(i int) bool { return !metrics[i].Timestamp.Before(startTime) })
+	to := sort.Search(len(metrics), func(i int) bool { return metrics[i].Timestamp.After(endTime) })
+
+	if from >= to {
+		return nil, nil
+	}
+	result := make([]models.Metric, 0, to-from)
+	for _, metric := range metrics[from:to] {
+		result = append(result, *metric)
+	}
+	return result, nil
+}
+
+// trim descarta las muestras anteriores a before y devuelve las que quedan en total
+func (h *memoryHistory) trim(before time.Time) int {
+	total := 0
+	for serverID, metrics := range h.byServer {
+		from := sort.Search(len(metrics), func(i int) bool { return !metrics[i].Timestamp.Before(before) })
+		if from > 0 {
+			// Copiar para liberar el array con las muestras descartadas
+			metrics = append([]*models.Metric(nil), metrics[from:]...)
+			h.byServer[serverID] = metrics
+		}
+		total += len(metrics)
+	}
+	return total
+}
+
+// cachedBaselines evita consultar la misma franja de línea base repetidamente durante el backtest
+type cachedBaselines struct {
+	provider BaselineProvider
+	cache    map[string]*models.MetricBaseline
+}
+
+func newCachedBaselines(provider BaselineProvider) *cachedBaselines {
+	return &cachedBaselines{provider: provider, cache: make(map[string]*models.MetricBaseline)}
+}
+
+// GetBaseline implementa BaselineProvider
+func (c *cachedBaselines) GetBaseline(serverID uint, metric string, at time.Time) (*models.MetricBaseline, error) {
+	key := fmt.Sprintf("%d/%s/%d", serverID, metric, models.HourOfWeek(at))
+	if baseline, ok := c.cache[key]; ok {
+		return baseline, nil
+	}
+
+	baseline, err := c.provider.GetBaseline(serverID, metric, at)
+	if err != nil {
+		return nil, err
+	}
+	c.cache[key] = baseline
+	return baseline, nil
+}
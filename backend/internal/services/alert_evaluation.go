@@ -0,0 +1,452 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/expression"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/forecast"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+)
+
+// ErrInsufficientHistory indica que no hay suficientes muestras para evaluar una condición de tendencia
+var ErrInsufficientHistory = errors.New("historial de métricas insuficiente")
+
+// MetricHistory proporciona el historial de métricas de un servidor (ordenado por fecha ascendente)
+type MetricHistory interface {
+	GetMetricsByTimeRange(serverID uint, startTime, endTime time.Time) ([]models.Metric, error)
+}
+
+// BaselineProvider proporciona la línea base aprendida de una variable para un instante
+type BaselineProvider interface {
+	GetBaseline(serverID uint, metric string, at time.Time) (*models.MetricBaseline, error)
+}
+
+// EvaluationSources datos adicionales que necesitan las condiciones de tendencia y anomalía
+type EvaluationSources struct {
+	History   MetricHistory
+	Baselines BaselineProvider
+}
+
+// ThresholdEvaluation resultado de evaluar una regla de alerta contra una métrica
+type ThresholdEvaluation struct {
+	Triggered  bool
+	Value      float64 // Valor observado (lado izquierdo de la primera comparación en expresiones)
+	Limit      float64 // Límite con el que se compara
+	Operator   string
+	MetricName string
+	Summary    string // Descripción de la ventana y la comparación (condiciones de tendencia)
+	Pending    bool   // La condición se cumple pero aún no durante el tiempo requerido (Duration)
+
+	// Banda esperada (condición anomaly)
+	ExpectedMin  *float64
+	ExpectedMax  *float64
+	ExpectedMean *float64
+	ZScore       *float64
+}
+
+// CompileThresholdExpression valida la expresión de una regla y devuelve el error detallado
+func CompileThresholdExpression(source string) (*expression.Expression, error) {
+	expr, err := expression.Compile(source, models.IsMetricVariable)
+	if err != nil {
+		return nil, fmt.Errorf("expresión inválida: %v", err)
+	}
+	return expr, nil
+}
+
+// thresholdExpressions expresiones compiladas de los umbrales, por ID, para no volver a
+// compilarlas con cada métrica. Se descartan al actualizar o eliminar el umbral
+var thresholdExpressions = struct {
+	sync.Mutex
+	byID map[uint]compiledExpression
+}{byID: make(map[uint]compiledExpression)}
+
+// compiledExpression expresión compilada y el texto del que se obtuvo
+type compiledExpression struct {
+	source string
+	expr   *expression.Expression
+}
+
+// thresholdExpression expresión compilada de un umbral. Los umbrales sin guardar (simulaciones)
+// se compilan cada vez
+func thresholdExpression(threshold *models.AlertThreshold) (*expression.Expression, error) {
+	if threshold.ID == 0 {
+		return CompileThresholdExpression(threshold.Expression)
+	}
+
+	thresholdExpressions.Lock()
+	cached, ok := thresholdExpressions.byID[threshold.ID]
+	thresholdExpressions.Unlock()
+	// Se comprueba también el texto por si el umbral se modificó sin pasar por el servicio
+	if ok && cached.source == threshold.Expression {
+		return cached.expr, nil
+	}
+
+	expr, err := CompileThresholdExpression(threshold.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholdExpressions.Lock()
+	thresholdExpressions.byID[threshold.ID] = compiledExpression{source: threshold.Expression, expr: expr}
+	thresholdExpressions.Unlock()
+
+	return expr, nil
+}
+
+// forgetThresholdExpression descarta la expresión compilada de un umbral
+func forgetThresholdExpression(id uint) {
+	thresholdExpressions.Lock()
+	delete(thresholdExpressions.byID, id)
+	thresholdExpressions.Unlock()
+}
+
+// durationLookbackSlack historial adicional anterior a la ventana de duración que se consulta para
+// comprobar que la condición ya se cumplía al empezar la ventana
+const durationLookbackSlack = 15 * time.Minute
+
+// EvaluateAlertCondition evalúa una regla como lo hace el motor de alertas: si la condición se
+// cumple y la regla tiene duración, solo se considera disparada cuando se ha cumplido en todas
+// las muestras de los últimos Duration segundos (en otro caso queda pendiente)
+func EvaluateAlertCondition(threshold *models.AlertThreshold, metric *models.Metric, sources EvaluationSources) (*ThresholdEvaluation, error) {
+	eval, err := EvaluateThreshold(threshold, metric, sources)
+	if err != nil || !eval.Triggered || threshold.Duration <= 0 {
+		return eval, err
+	}
+
+	held, err := conditionHeld(threshold, metric, sources)
+	if err != nil {
+		return nil, err
+	}
+	if !held {
+		eval.Triggered = false
+		eval.Pending = true
+	}
+
+	return eval, nil
+}
+
+// conditionHeld comprueba que la condición se cumplía en todas las muestras anteriores de la
+// ventana de duración y en la última muestra previa a ella
+func conditionHeld(threshold *models.AlertThreshold, metric *models.Metric, sources EvaluationSources) (bool, error) {
+	if sources.History == nil {
+		return false, fmt.Errorf("no hay historial de métricas disponible")
+	}
+
+	now := metricTime(metric)
+	since := now.Add(-time.Duration(threshold.Duration) * time.Second)
+
+	samples, err := sources.History.GetMetricsByTimeRange(metric.ServerID, since.Add(-durationLookbackSlack), now)
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(samples) - 1; i >= 0; i-- {
+		if !samples[i].Timestamp.Before(now) {
+			continue
+		}
+
+		eval, err := EvaluateThreshold(threshold, &samples[i], sources)
+		if err != nil || !eval.Triggered {
+			return false, nil
+		}
+		if !samples[i].Timestamp.After(since) {
+			return true, nil
+		}
+	}
+
+	// No hay ninguna muestra lo bastante antigua para confirmar la duración
+	return false, nil
+}
+
+// metricTime instante de una métrica (ahora si no tiene marca de tiempo)
+func metricTime(metric *models.Metric) time.Time {
+	if metric.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return metric.Timestamp
+}
+
+// EvaluateThreshold evalúa una regla (simple, de expresión, de tendencia o de anomalía) contra una métrica
+func EvaluateThreshold(threshold *models.AlertThreshold, metric *models.Metric, sources EvaluationSources) (*ThresholdEvaluation, error) {
+	if threshold.IsExpression() {
+		return evaluateExpressionThreshold(threshold, metric)
+	}
+	if threshold.Condition == models.ThresholdConditionAnomaly {
+		return evaluateAnomalyThreshold(threshold, metric, sources.Baselines)
+	}
+	if threshold.IsTrend() {
+		return evaluateTrendThreshold(threshold, metric, sources.History)
+	}
+
+	value, name, ok := thresholdSourceValue(threshold, metric)
+	if !ok {
+		return nil, fmt.Errorf("tipo de métrica no soportado: %s", threshold.MetricType)
+	}
+
+	return &ThresholdEvaluation{
+		Triggered:  compareValues(value, threshold.Operator, threshold.Value),
+		Value:      value,
+		Limit:      threshold.Value,
+		Operator:   threshold.Operator,
+		MetricName: name,
+	}, nil
+}
+
+// evaluateExpressionThreshold evalúa una regla definida mediante una expresión
+func evaluateExpressionThreshold(threshold *models.AlertThreshold, metric *models.Metric) (*ThresholdEvaluation, error) {
+	expr, err := thresholdExpression(threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := models.MetricVariables(metric)
+	triggered, err := expr.Evaluate(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	eval := &ThresholdEvaluation{
+		Triggered:  triggered,
+		MetricName: threshold.Name,
+	}
+	if cmp, ok := expr.PrimaryComparison(vars); ok {
+		eval.Value, eval.Limit, eval.Operator = cmp.Left, cmp.Right, cmp.Operator
+	}
+
+	return eval, nil
+}
+
+// evaluateTrendThreshold evalúa una condición sobre la ventana de historial que termina en la métrica
+func evaluateTrendThreshold(threshold *models.AlertThreshold, metric *models.Metric, history MetricHistory) (*ThresholdEvaluation, error) {
+	if history == nil {
+		return nil, fmt.Errorf("no hay historial de métricas disponible")
+	}
+
+	current, name, ok := thresholdSourceValue(threshold, metric)
+	if !ok {
+		return nil, fmt.Errorf("tipo de métrica no soportado: %s", threshold.MetricType)
+	}
+
+	now := metricTime(metric)
+	window := time.Duration(threshold.WindowMinutes) * time.Minute
+
+	samples, err := history.GetMetricsByTimeRange(metric.ServerID, now.Add(-window), now)
+	if err != nil {
+		return nil, err
+	}
+	values := sourceValues(threshold, samples)
+
+	var observed float64
+	var summary string
+
+	switch threshold.Condition {
+	case models.ThresholdConditionDelta:
+		if len(values) < 2 {
+			return nil, ErrInsufficientHistory
+		}
+		observed = values[len(values)-1] - values[0]
+		summary = fmt.Sprintf("%s ha variado %+.2f en los últimos %d minutos (de %.2f a %.2f)",
+			name, observed, threshold.WindowMinutes, values[0], values[len(values)-1])
+
+	case models.ThresholdConditionPercentChange:
+		offset := time.Duration(threshold.CompareOffsetMinutes) * time.Minute
+		previousSamples, err := history.GetMetricsByTimeRange(metric.ServerID, now.Add(-offset-window), now.Add(-offset))
+		if err != nil {
+			return nil, err
+		}
+		previous := sourceValues(threshold, previousSamples)
+		if len(values) == 0 || len(previous) == 0 {
+			return nil, ErrInsufficientHistory
+		}
+
+		currentMean, previousMean := mean(values), mean(previous)
+		if previousMean == 0 {
+			return nil, ErrInsufficientHistory
+		}
+		observed = (currentMean - previousMean) / math.Abs(previousMean) * 100
+		summary = fmt.Sprintf("la media de %s en los últimos %d minutos (%.2f) ha cambiado un %+.2f%% respecto a la misma ventana de hace %s (%.2f)",
+			name, threshold.WindowMinutes, currentMean, observed, formatMinutes(threshold.CompareOffsetMinutes), previousMean)
+
+	case models.ThresholdConditionMovingAverage:
+		if len(values) < 2 {
+			return nil, ErrInsufficientHistory
+		}
+		average := mean(values)
+		observed = current - average
+		summary = fmt.Sprintf("%s (%.2f) se desvía %+.2f de su media móvil de %d minutos (%.2f)",
+			name, current, observed, threshold.WindowMinutes, average)
+
+	case models.ThresholdConditionForecast:
+		prediction, err := ForecastResource(samples, forecastResourceFor(threshold.MetricType), forecast.MethodLinear)
+		if err != nil {
+			return nil, ErrInsufficientHistory
+		}
+
+		// Sin crecimiento previsto o con un ajuste poco fiable la condición no se cumple
+		if prediction.TimeToFullHours == nil || prediction.Confidence < MinForecastConfidence {
+			return &ThresholdEvaluation{
+				Limit:      threshold.Value,
+				Operator:   threshold.Operator,
+				MetricName: name,
+			}, nil
+		}
+
+		observed = *prediction.TimeToFullHours
+		summary = fmt.Sprintf("se prevé que %s se llene en %.1f h (hacia %s, confianza %.0f%%, crecimiento %s/h en los últimos %d minutos)",
+			name, observed, prediction.EstimatedFullAt.Format("2006-01-02 15:04"), prediction.Confidence*100,
+			notifications.FormatBytes(int64(prediction.GrowthPerHour)), threshold.WindowMinutes)
+
+	default:
+		return nil, fmt.Errorf("condición desconocida: %s", threshold.Condition)
+	}
+
+	return &ThresholdEvaluation{
+		Triggered:  compareValues(observed, threshold.Operator, threshold.Value),
+		Value:      observed,
+		Limit:      threshold.Value,
+		Operator:   threshold.Operator,
+		MetricName: name,
+		Summary:    fmt.Sprintf("%s; condición: %s %.2f", summary, threshold.Operator, threshold.Value),
+	}, nil
+}
+
+// conditionMessage mensaje de la alerta de una condición sobre el historial, según su tipo
+func conditionMessage(condition models.ThresholdCondition, summary string) string {
+	switch condition {
+	case models.ThresholdConditionForecast:
+		return "Previsión de agotamiento: " + summary
+	case models.ThresholdConditionAnomaly:
+		return "Anomalía detectada: " + summary
+	default:
+		return "Tendencia detectada: " + summary
+	}
+}
+
+// evaluateAnomalyThreshold compara la muestra con la línea base de su hora de la semana
+func evaluateAnomalyThreshold(threshold *models.AlertThreshold, metric *models.Metric, baselines BaselineProvider) (*ThresholdEvaluation, error) {
+	if baselines == nil {
+		return nil, fmt.Errorf("no hay líneas base disponibles")
+	}
+
+	value, name, ok := thresholdSourceValue(threshold, metric)
+	if !ok {
+		return nil, fmt.Errorf("tipo de métrica no soportado: %s", threshold.MetricType)
+	}
+
+	at := metricTime(metric)
+
+	baseline, err := baselines.GetBaseline(metric.ServerID, threshold.SourceVariable(), at)
+	if err != nil {
+		return nil, err
+	}
+	if baseline == nil || !baseline.IsReliable() {
+		return nil, ErrInsufficientHistory
+	}
+
+	z := baseline.ZScore(value)
+	expected := baseline.Mean
+	lower, upper := baseline.Band(threshold.Value)
+	triggered := compareValues(math.Abs(z), threshold.Operator, threshold.Value)
+
+	// El límite mostrado es el extremo de la banda del lado por el que se sale la muestra
+	limit, operator := upper, ">"
+	if z < 0 {
+		limit, operator = lower, "<"
+	}
+
+	return &ThresholdEvaluation{
+		Triggered:  triggered,
+		Value:      value,
+		Limit:      limit,
+		Operator:   operator,
+		MetricName: name,
+		Summary: fmt.Sprintf("%s = %.2f fuera de la banda esperada [%.2f, %.2f] para %s %02d:00 UTC (media %.2f, z-score %+.2f); condición: |z| %s %.2f",
+			name, value, lower, upper, weekdayName(at.UTC().Weekday()), at.UTC().Hour(), expected, z, threshold.Operator, threshold.Value),
+		ExpectedMin:  &lower,
+		ExpectedMax:  &upper,
+		ExpectedMean: &expected,
+		ZScore:       &z,
+	}, nil
+}
+
+// weekdayName nombre del día de la semana en español
+func weekdayName(day time.Weekday) string {
+	return [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}[day]
+}
+
+// thresholdSourceValue valor de la variable que compara la regla: metric_field si está indicada
+// o la equivalente al tipo de métrica (porcentajes para memoria y disco, MB para red)
+func thresholdSourceValue(threshold *models.AlertThreshold, metric *models.Metric) (float64, string, bool) {
+	variable := threshold.SourceVariable()
+	if variable == "" {
+		return 0, "", false
+	}
+
+	name := variable
+	if threshold.MetricField == "" {
+		name = threshold.MetricType.DisplayName()
+	}
+
+	value, ok := models.MetricVariables(metric)[variable]
+	return value, name, ok
+}
+
+// sourceValues valores de la regla para una serie de métricas
+func sourceValues(threshold *models.AlertThreshold, metrics []models.Metric) []float64 {
+	values := make([]float64, 0, len(metrics))
+	for i := range metrics {
+		if value, _, ok := thresholdSourceValue(threshold, &metrics[i]); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// compareValues aplica el operador de comparación de una regla simple
+func compareValues(value float64, operator string, limit float64) bool {
+	switch operator {
+	case ">":
+		return value > limit
+	case "<":
+		return value < limit
+	case ">=":
+		return value >= limit
+	case "<=":
+		return value <= limit
+	case "==":
+		return value == limit
+	}
+	return false
+}
+
+// mean media aritmética (0 si no hay valores)
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// formatMinutes expresa una cantidad de minutos de forma legible (p. ej. "1 h", "1 día")
+func formatMinutes(minutes int) string {
+	switch {
+	case minutes%1440 == 0:
+		if minutes == 1440 {
+			return "1 día"
+		}
+		return fmt.Sprintf("%d días", minutes/1440)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d h", minutes/60)
+	default:
+		return fmt.Sprintf("%d min", minutes)
+	}
+}
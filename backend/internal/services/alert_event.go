@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// MaxCommentLength longitud máxima de un comentario de alerta
+const MaxCommentLength = 4000
+
+// recordAlertEvent guarda un evento en el historial de una alerta. Si tiene actor se guarda
+// también su nombre de usuario. Los errores solo se registran: el historial no debe impedir
+// la acción que lo origina
+func recordAlertEvent(db *gorm.DB, log logger.Logger, event models.AlertEvent) {
+	if event.ActorID != nil && event.ActorName == "" {
+		var user models.User
+		if err := db.Select("username").First(&user, *event.ActorID).Error; err == nil {
+			event.ActorName = user.Username
+		}
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		log.Errorf("Error al registrar el evento %s de la alerta %d: %v", event.Type, event.AlertID, err)
+	}
+}
+
+// recordNotifiedEvents registra un evento notified por cada canal que recibió la alerta
+func recordNotifiedEvents(db *gorm.DB, log logger.Logger, alertID uint, channels []string, message string) {
+	for _, channel := range channels {
+		recordAlertEvent(db, log, models.AlertEvent{
+			AlertID: alertID,
+			Type:    models.AlertEventNotified,
+			Channel: channel,
+			Message: message,
+		})
+	}
+}
+
+// suppressionMessage motivo legible por el que una alerta nueva se guardó suprimida
+func suppressionMessage(alert *models.Alert) string {
+	if alert.IsInhibited() {
+		return alert.InhibitionReason
+	}
+	if alert.SilenceID != nil {
+		return fmt.Sprintf("Suprimida por el silencio %d", *alert.SilenceID)
+	}
+	return ""
+}
+
+// GetAlertTimeline obtiene el historial de una alerta en orden cronológico
+func (as *AlertService) GetAlertTimeline(alertID uint) ([]models.AlertEvent, error) {
+	return as.alertEvents(alertID, "")
+}
+
+// GetAlertComments obtiene los comentarios de una alerta en orden cronológico
+func (as *AlertService) GetAlertComments(alertID uint) ([]models.AlertEvent, error) {
+	return as.alertEvents(alertID, models.AlertEventCommented)
+}
+
+// alertEvents eventos de una alerta, opcionalmente de un solo tipo
+func (as *AlertService) alertEvents(alertID uint, eventType models.AlertEventType) ([]models.AlertEvent, error) {
+	if _, err := as.GetAlert(alertID); err != nil {
+		return nil, err
+	}
+
+	query := as.db.Where("alert_id = ?", alertID)
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+
+	var events []models.AlertEvent
+	if err := query.Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		as.logger.Errorf("Error al obtener el historial de la alerta %d: %v", alertID, err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// AddComment añade un comentario de un usuario al historial de una alerta
+func (as *AlertService) AddComment(alertID, userID uint, text string) (*models.AlertEvent, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("el comentario no puede estar vacío")
+	}
+	if len(text) > MaxCommentLength {
+		return nil, fmt.Errorf("el comentario no puede superar los %d caracteres", MaxCommentLength)
+	}
+
+	if _, err := as.GetAlert(alertID); err != nil {
+		return nil, err
+	}
+
+	event := models.AlertEvent{
+		AlertID:   alertID,
+		Type:      models.AlertEventCommented,
+		Message:   text,
+		ActorID:   &userID,
+		CreatedAt: time.Now(),
+	}
+
+	var user models.User
+	if err := as.db.Select("username").First(&user, userID).Error; err == nil {
+		event.ActorName = user.Username
+	}
+
+	if err := as.db.Create(&event).Error; err != nil {
+		as.logger.Errorf("Error al guardar comentario en la alerta %d: %v", alertID, err)
+		return nil, err
+	}
+
+	as.logger.Infof("Comentario añadido a la alerta %d por usuario %d", alertID, userID)
+	return &event, nil
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
@@ -12,10 +14,19 @@ import (
 
 // AlertService servicio para gestionar alertas y umbrales
 type AlertService struct {
-	db            *gorm.DB
-	logger        logger.Logger
-	notifyManager *notifications.NotificationManager
-	metricService *MetricService // Añadir para evitar dependencias circulares
+	db                *gorm.DB
+	logger            logger.Logger
+	notifyManager     *notifications.NotificationManager
+	metricService     *MetricService     // Añadir para evitar dependencias circulares
+	templateService   *TemplateService   // Plantillas para el título y mensaje de las alertas
+	silenceService    *SilenceService    // Silencios y ventanas de mantenimiento
+	escalationService *EscalationService // Escalado de alertas sin reconocer
+	baselineService   *BaselineService   // Líneas base para las reglas de anomalía
+	incidentService   *IncidentService   // Agrupación de alertas relacionadas en incidentes
+	inhibitionService *InhibitionService // Reglas de inhibición entre alertas
+
+	stop chan struct{} // Planificador que reactiva las alertas pospuestas
+	wg   sync.WaitGroup
 }
 
 // NewAlertService crea un nuevo servicio de alertas
@@ -34,12 +45,46 @@ func (as *AlertService) SetMetricService(metricService *MetricService) {
 	as.logger.Info("Servicio de métricas configurado en el servicio de alertas")
 }
 
+// SetTemplateService establece el servicio de plantillas usado para generar el texto de las alertas
+func (as *AlertService) SetTemplateService(templateService *TemplateService) {
+	as.templateService = templateService
+}
+
+// SetSilenceService establece el servicio de silencios que decide si una alerta se suprime
+func (as *AlertService) SetSilenceService(silenceService *SilenceService) {
+	as.silenceService = silenceService
+}
+
+// SetBaselineService establece el servicio de líneas base usado por las reglas de anomalía
+func (as *AlertService) SetBaselineService(baselineService *BaselineService) {
+	as.baselineService = baselineService
+}
+
+// SetEscalationService establece el servicio que programa el escalado de las alertas nuevas
+func (as *AlertService) SetEscalationService(escalationService *EscalationService) {
+	as.escalationService = escalationService
+}
+
+// SetIncidentService establece el servicio que agrupa las alertas nuevas en incidentes
+func (as *AlertService) SetIncidentService(incidentService *IncidentService) {
+	as.incidentService = incidentService
+}
+
+// SetInhibitionService establece el servicio que decide si una alerta nueva queda inhibida por otra
+func (as *AlertService) SetInhibitionService(inhibitionService *InhibitionService) {
+	as.inhibitionService = inhibitionService
+}
+
 // CreateThreshold crea un nuevo umbral de alerta
 func (as *AlertService) CreateThreshold(threshold *models.AlertThreshold) error {
 	if !threshold.ValidateThreshold() {
 		return fmt.Errorf("umbral de alerta inválido")
 	}
 
+	if err := as.validateThresholdReferences(threshold); err != nil {
+		return err
+	}
+
 	if err := as.db.Create(threshold).Error; err != nil {
 		as.logger.Errorf("Error al crear umbral de alerta: %v", err)
 		return err
@@ -55,21 +100,50 @@ func (as *AlertService) UpdateThreshold(threshold *models.AlertThreshold) error
 		return fmt.Errorf("umbral de alerta inválido")
 	}
 
+	if err := as.validateThresholdReferences(threshold); err != nil {
+		return err
+	}
+
 	if err := as.db.Save(threshold).Error; err != nil {
 		as.logger.Errorf("Error al actualizar umbral de alerta: %v", err)
 		return err
 	}
+	forgetThresholdExpression(threshold.ID)
 
 	as.logger.Infof("Umbral de alerta actualizado: %s", threshold.Name)
 	return nil
 }
 
+// validateThresholdReferences comprueba la condición o expresión del umbral y que las entidades referenciadas existen
+func (as *AlertService) validateThresholdReferences(threshold *models.AlertThreshold) error {
+	if err := threshold.ValidateCondition(); err != nil {
+		return err
+	}
+
+	if threshold.IsExpression() {
+		if _, err := CompileThresholdExpression(threshold.Expression); err != nil {
+			return err
+		}
+	}
+
+	if threshold.EscalationPolicyID != nil {
+		var count int64
+		as.db.Model(&models.EscalationPolicy{}).Where("id = ?", *threshold.EscalationPolicyID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("la política de escalado no existe")
+		}
+	}
+
+	return validateOwnerTeam(as.db, threshold.OwnerTeamID)
+}
+
 // DeleteThreshold elimina un umbral
 func (as *AlertService) DeleteThreshold(id uint) error {
 	if err := as.db.Delete(&models.AlertThreshold{}, id).Error; err != nil {
 		as.logger.Errorf("Error al eliminar umbral de alerta: %v", err)
 		return err
 	}
+	forgetThresholdExpression(id)
 
 	as.logger.Infof("Umbral de alerta eliminado: %d", id)
 	return nil
@@ -131,6 +205,40 @@ func (as *AlertService) GetThresholdsByServer(serverID uint) ([]models.AlertThre
 
 // CreateAlert crea una nueva alerta
 func (as *AlertService) CreateAlert(alert *models.Alert) error {
+	// Las alertas que coinciden con un silencio en vigor se guardan como suprimidas
+	if as.silenceService != nil && alert.Status == models.AlertStatusActive {
+		silence, err := as.silenceService.FindMatchingSilence(alert, time.Now())
+		if err != nil {
+			as.logger.Warnf("Error al comprobar silencios para la alerta: %v", err)
+		} else if silence != nil {
+			alert.Status = models.AlertStatusSuppressed
+			alert.SilenceID = &silence.ID
+		}
+	}
+
+	// Las alertas que coinciden con una regla de inhibición mientras la alerta origen sigue
+	// activa se guardan como suprimidas con el motivo
+	if as.inhibitionService != nil && alert.Status == models.AlertStatusActive {
+		rule, source, err := as.inhibitionService.FindInhibitingAlert(alert)
+		if err != nil {
+			as.logger.Warnf("Error al comprobar reglas de inhibición para la alerta: %v", err)
+		} else if rule != nil {
+			alert.Status = models.AlertStatusSuppressed
+			alert.InhibitedByID = &source.ID
+			alert.InhibitionRuleID = &rule.ID
+			alert.InhibitionReason = rule.Reason(source)
+		}
+	}
+
+	// El equipo propietario se fija al disparar la alerta para filtrar y enrutar por equipo
+	if alert.OwnerTeamID == nil {
+		ownerTeamID, err := resolveOwnerTeamID(as.db, alert)
+		if err != nil {
+			as.logger.Warnf("Error al determinar el equipo propietario de la alerta: %v", err)
+		}
+		alert.OwnerTeamID = ownerTeamID
+	}
+
 	// Transacción para crear la alerta y actualizar el umbral
 	err := as.db.Transaction(func(tx *gorm.DB) error {
 		// Crear la alerta
@@ -154,26 +262,146 @@ func (as *AlertService) CreateAlert(alert *models.Alert) error {
 		return err
 	}
 
-	// Enviar notificaciones si hay un umbral asociado
-	if alert.ThresholdID != 0 {
-		threshold, err := as.GetThreshold(alert.ThresholdID)
-		if err == nil && threshold.Enabled {
-			if err := as.notifyManager.NotifyAlert(alert, threshold); err != nil {
-				as.logger.Errorf("Error al enviar notificaciones para alerta %d: %v", alert.ID, err)
-			} else {
-				// Actualizar la alerta con la información de notificación
-				as.db.Model(alert).Updates(map[string]interface{}{
-					"notified_at":     time.Now(),
-					"notify_channels": alert.NotifyChannels,
-				})
-			}
-		}
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID:   alert.ID,
+		Type:      models.AlertEventTriggered,
+		Message:   alert.Message,
+		CreatedAt: alert.TriggeredAt,
+	})
+	if alert.IsSuppressed() {
+		recordAlertEvent(as.db, as.logger, models.AlertEvent{
+			AlertID: alert.ID,
+			Type:    models.AlertEventSuppressed,
+			Message: suppressionMessage(alert),
+		})
+	}
+
+	if alert.IsInhibited() {
+		as.logger.Infof("Alerta inhibida por la alerta %d: %s (ID: %d)", *alert.InhibitedByID, alert.Title, alert.ID)
+		return nil
 	}
+	if alert.IsSuppressed() {
+		as.logger.Infof("Alerta suprimida por el silencio %d: %s (ID: %d)", *alert.SilenceID, alert.Title, alert.ID)
+		return nil
+	}
+
+	as.notifyNewAlert(alert)
 
 	as.logger.Infof("Alerta creada: %s (ID: %d)", alert.Title, alert.ID)
 	return nil
 }
 
+// notifyNewAlert agrupa la alerta en un incidente o, si no se agrupa, la notifica y programa su escalado
+func (as *AlertService) notifyNewAlert(alert *models.Alert) {
+	// Las alertas relacionadas se agrupan en un incidente que se notifica con un único resumen
+	if as.incidentService != nil {
+		grouped, err := as.incidentService.AttachAlert(alert)
+		if err != nil {
+			as.logger.Warnf("Error al agrupar la alerta en un incidente, se notifica por separado: %v", err)
+		} else if grouped {
+			return
+		}
+	}
+
+	threshold, ok := as.notificationThreshold(alert)
+	if !ok {
+		return
+	}
+
+	// Cargar el servidor para que los notificadores dispongan de hostname e IP
+	if alert.Server.ID == 0 {
+		as.db.First(&alert.Server, alert.ServerID)
+	}
+
+	if err := as.notifyManager.NotifyAlert(alert, threshold); err != nil {
+		as.logger.Errorf("Error al enviar notificaciones para alerta %d: %v", alert.ID, err)
+	} else {
+		// Actualizar la alerta con la información de notificación
+		as.db.Model(alert).Updates(map[string]interface{}{
+			"notified_at":     time.Now(),
+			"notify_channels": alert.NotifyChannels,
+		})
+		recordNotifiedEvents(as.db, as.logger, alert.ID, alert.NotifyChannels, "")
+	}
+
+	// Programar el escalado mientras la alerta no se reconozca
+	if as.escalationService != nil {
+		as.escalationService.StartEscalation(alert, threshold)
+	}
+}
+
+// notificationThreshold devuelve el umbral con el que se notifica la alerta e indica si debe
+// notificarse: las alertas de umbrales deshabilitados no se notifican y las externas no tienen
+// umbral, por lo que solo se notifican según las reglas de enrutamiento
+func (as *AlertService) notificationThreshold(alert *models.Alert) (*models.AlertThreshold, bool) {
+	if alert.ThresholdID == 0 {
+		return nil, alert.IsExternal()
+	}
+
+	threshold, err := as.GetThreshold(alert.ThresholdID)
+	if err != nil || !threshold.Enabled {
+		return nil, false
+	}
+
+	return threshold, true
+}
+
+// releaseInhibitedAlerts reevalúa las alertas inhibidas por una alerta que acaba de resolverse:
+// pasan a otra alerta origen si alguna sigue activa, a un silencio si coinciden con uno, o
+// vuelven a estar activas y se notifican
+func (as *AlertService) releaseInhibitedAlerts(source *models.Alert) {
+	if as.inhibitionService == nil {
+		return
+	}
+
+	var inhibited []models.Alert
+	if err := as.db.Where("inhibited_by_id = ? AND status = ?", source.ID, models.AlertStatusSuppressed).
+		Find(&inhibited).Error; err != nil {
+		as.logger.Errorf("Error al obtener las alertas inhibidas por la alerta %d: %v", source.ID, err)
+		return
+	}
+
+	for i := range inhibited {
+		alert := &inhibited[i]
+		updates := map[string]interface{}{
+			"inhibited_by_id":    nil,
+			"inhibition_rule_id": nil,
+			"inhibition_reason":  "",
+		}
+
+		if suppression, reason := as.suppressionFor(alert); suppression != nil {
+			for field, value := range suppression {
+				updates[field] = value
+			}
+			as.db.Model(alert).Updates(updates)
+			recordAlertEvent(as.db, as.logger, models.AlertEvent{
+				AlertID: alert.ID,
+				Type:    models.AlertEventSuppressed,
+				Message: reason,
+			})
+			continue
+		}
+
+		updates["status"] = models.AlertStatusActive
+		if err := as.db.Model(alert).Updates(updates).Error; err != nil {
+			as.logger.Errorf("Error al reactivar la alerta inhibida %d: %v", alert.ID, err)
+			continue
+		}
+		alert.Status = models.AlertStatusActive
+		alert.InhibitedByID = nil
+		alert.InhibitionRuleID = nil
+		alert.InhibitionReason = ""
+
+		recordAlertEvent(as.db, as.logger, models.AlertEvent{
+			AlertID: alert.ID,
+			Type:    models.AlertEventReopened,
+			Message: fmt.Sprintf("Reactivada al resolverse la alerta %d que la inhibía", source.ID),
+		})
+		as.logger.Infof("Alerta %d reactivada al resolverse la alerta %d que la inhibía", alert.ID, source.ID)
+		as.notifyNewAlert(alert)
+	}
+}
+
 // GetAllAlerts obtiene todas las alertas con filtrado opcional
 func (as *AlertService) GetAllAlerts(params map[string]interface{}) ([]models.Alert, error) {
 	var alerts []models.Alert
@@ -184,6 +412,16 @@ func (as *AlertService) GetAllAlerts(params map[string]interface{}) ([]models.Al
 		query = query.Where("server_id = ?", serverID)
 	}
 
+	// Servidores visibles para el usuario (permisos de grupo)
+	if serverIDs, ok := params["server_ids"].([]uint); ok {
+		query = query.Where("server_id IN ?", serverIDs)
+	}
+
+	// Equipos propietarios (filtro "mis equipos" o un equipo concreto)
+	if teamIDs, ok := params["team_ids"].([]uint); ok {
+		query = query.Where("owner_team_id IN ?", teamIDs)
+	}
+
 	if status, ok := params["status"].(models.AlertStatus); ok {
 		query = query.Where("status = ?", status)
 	}
@@ -200,6 +438,14 @@ func (as *AlertService) GetAllAlerts(params map[string]interface{}) ([]models.Al
 		query = query.Where("triggered_at <= ?", endTime)
 	}
 
+	if inhibited, ok := params["inhibited"].(bool); ok {
+		if inhibited {
+			query = query.Where("inhibited_by_id IS NOT NULL")
+		} else {
+			query = query.Where("inhibited_by_id IS NULL")
+		}
+	}
+
 	// Ejecutar consulta con preload de relaciones
 	if err := query.Preload("Server").Find(&alerts).Error; err != nil {
 		as.logger.Errorf("Error al obtener alertas: %v", err)
@@ -248,15 +494,38 @@ func (as *AlertService) AcknowledgeAlert(id, userID uint, notes string) error {
 
 	now := time.Now()
 	if err := as.db.Model(alert).Updates(map[string]interface{}{
-		"status":          models.AlertStatusAcknowledged,
-		"acknowledged_at": now,
-		"acknowledged_by": userID,
-		"notes":           notes,
+		"status":             models.AlertStatusAcknowledged,
+		"acknowledged_at":    now,
+		"acknowledged_by":    userID,
+		"notes":              notes,
+		"next_escalation_at": nil, // Detener el escalado
 	}).Error; err != nil {
 		as.logger.Errorf("Error al reconocer alerta: %v", err)
 		return err
 	}
 
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID: alert.ID,
+		Type:    models.AlertEventAcknowledged,
+		Message: notes,
+		ActorID: &userID,
+	})
+
+	// Avisar del reconocimiento por los canales que recibieron la alerta
+	if len(alert.NotifyChannels) > 0 {
+		alert.Status = models.AlertStatusAcknowledged
+		alert.AcknowledgedAt = &now
+		alert.AcknowledgedBy = &userID
+		alert.Notes = notes
+		if err := as.notifyManager.NotifyAcknowledgedAlert(alert); err != nil {
+			as.logger.Errorf("Error al enviar notificación de reconocimiento: %v", err)
+		}
+	}
+
+	if as.incidentService != nil {
+		as.incidentService.OnAlertAcknowledged(alert, userID)
+	}
+
 	as.logger.Infof("Alerta %d reconocida por usuario %d", id, userID)
 	return nil
 }
@@ -274,14 +543,22 @@ func (as *AlertService) ResolveAlert(id, userID uint, notes string) error {
 
 	now := time.Now()
 	if err := as.db.Model(alert).Updates(map[string]interface{}{
-		"status":      models.AlertStatusResolved,
-		"resolved_at": now,
-		"notes":       notes,
+		"status":             models.AlertStatusResolved,
+		"resolved_at":        now,
+		"notes":              notes,
+		"next_escalation_at": nil, // Detener el escalado
 	}).Error; err != nil {
 		as.logger.Errorf("Error al resolver alerta: %v", err)
 		return err
 	}
 
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID: alert.ID,
+		Type:    models.AlertEventResolved,
+		Message: notes,
+		ActorID: &userID,
+	})
+
 	// Enviar notificación de resolución si la alerta fue notificada
 	if len(alert.NotifyChannels) > 0 {
 		alert.ResolvedAt = &now // Actualizar para que el tiempo esté disponible
@@ -290,6 +567,15 @@ func (as *AlertService) ResolveAlert(id, userID uint, notes string) error {
 		}
 	}
 
+	// Cerrar el incidente si era su última alerta pendiente
+	if as.incidentService != nil {
+		alert.Status = models.AlertStatusResolved
+		as.incidentService.OnAlertResolved(alert)
+	}
+
+	// Las alertas que inhibía vuelven a evaluarse
+	as.releaseInhibitedAlerts(alert)
+
 	as.logger.Infof("Alerta %d resuelta por usuario %d", id, userID)
 	return nil
 }
@@ -301,20 +587,57 @@ func (as *AlertService) AutoResolveAlert(id uint) error {
 		return err
 	}
 
-	if !alert.IsActive() {
+	if !alert.IsActive() && !alert.IsSuppressed() {
 		return nil // Ignorar si ya no está activa
 	}
 
-	now := time.Now()
+	if err := as.autoResolve(alert, "Resuelta automáticamente al normalizarse los valores", time.Now()); err != nil {
+		return err
+	}
+
+	as.logger.Infof("Alerta %d resuelta automáticamente", id)
+	return nil
+}
+
+// ResolveAlertFromSource resuelve una alerta externa porque su fuente la dio por terminada
+// (endsAt), aunque esté reconocida
+func (as *AlertService) ResolveAlertFromSource(id uint, notes string, resolvedAt time.Time) error {
+	alert, err := as.GetAlert(id)
+	if err != nil {
+		return err
+	}
+
+	if !alert.CanResolve() {
+		return nil // Ya resuelta
+	}
+
+	if err := as.autoResolve(alert, notes, resolvedAt); err != nil {
+		return err
+	}
+
+	as.logger.Infof("Alerta externa %d resuelta por su fuente", id)
+	return nil
+}
+
+// autoResolve resuelve una alerta sin intervención de un usuario, avisa a los canales que la
+// recibieron y reevalúa su incidente y las alertas que inhibía
+func (as *AlertService) autoResolve(alert *models.Alert, notes string, now time.Time) error {
 	if err := as.db.Model(alert).Updates(map[string]interface{}{
-		"status":      models.AlertStatusResolved,
-		"resolved_at": now,
-		"notes":       "Resuelta automáticamente al normalizarse los valores",
+		"status":             models.AlertStatusResolved,
+		"resolved_at":        now,
+		"notes":              notes,
+		"next_escalation_at": nil, // Detener el escalado
 	}).Error; err != nil {
 		as.logger.Errorf("Error al resolver alerta automáticamente: %v", err)
 		return err
 	}
 
+	recordAlertEvent(as.db, as.logger, models.AlertEvent{
+		AlertID: alert.ID,
+		Type:    models.AlertEventResolved,
+		Message: notes,
+	})
+
 	// Enviar notificación de resolución si la alerta fue notificada
 	if len(alert.NotifyChannels) > 0 {
 		alert.ResolvedAt = &now // Actualizar para que el tiempo esté disponible
@@ -323,10 +646,30 @@ func (as *AlertService) AutoResolveAlert(id uint) error {
 		}
 	}
 
-	as.logger.Infof("Alerta %d resuelta automáticamente", id)
+	// Cerrar el incidente si era su última alerta pendiente
+	if as.incidentService != nil {
+		alert.Status = models.AlertStatusResolved
+		as.incidentService.OnAlertResolved(alert)
+	}
+
+	// Las alertas que inhibía vuelven a evaluarse
+	as.releaseInhibitedAlerts(alert)
+
 	return nil
 }
 
+// evaluationSources historial y líneas base disponibles para evaluar las reglas
+func (as *AlertService) evaluationSources() EvaluationSources {
+	var sources EvaluationSources
+	if as.metricService != nil {
+		sources.History = as.metricService
+	}
+	if as.baselineService != nil {
+		sources.Baselines = as.baselineService
+	}
+	return sources
+}
+
 // CheckMetricAgainstThresholds verifica una métrica contra los umbrales aplicables
 func (as *AlertService) CheckMetricAgainstThresholds(metric *models.Metric) error {
 	// Obtener umbrales aplicables a este servidor
@@ -348,54 +691,25 @@ func (as *AlertService) CheckMetricAgainstThresholds(metric *models.Metric) erro
 			}
 		}
 
-		// Verificar según el tipo de métrica
-		var metricValue float64
-		var metricName string
-
-		switch threshold.MetricType {
-		case models.MetricTypeCPU:
-			metricValue = metric.CPUUsage
-			metricName = "CPU"
-		case models.MetricTypeMemory:
-			// Calcular porcentaje de memoria usada
-			memoryPercent := float64(metric.MemoryUsed) / float64(metric.MemoryTotal) * 100
-			metricValue = memoryPercent
-			metricName = "Memoria"
-		case models.MetricTypeDisk:
-			// Calcular porcentaje de disco usado
-			diskPercent := float64(metric.DiskUsed) / float64(metric.DiskTotal) * 100
-			metricValue = diskPercent
-			metricName = "Disco"
-		case models.MetricTypeNetworkIn:
-			// Convertir a MB para mejor legibilidad
-			metricValue = float64(metric.NetDownload) / 1024 / 1024
-			metricName = "Red (entrada)"
-		case models.MetricTypeNetworkOut:
-			// Convertir a MB para mejor legibilidad
-			metricValue = float64(metric.NetUpload) / 1024 / 1024
-			metricName = "Red (salida)"
-		default:
+		eval, err := EvaluateAlertCondition(&threshold, metric, as.evaluationSources())
+		if errors.Is(err, ErrInsufficientHistory) {
 			continue
 		}
+		if err != nil {
+			// Una condición que no se puede evaluar (p. ej. división por cero) no se cumple, para
+			// que sus alertas abiertas se resuelvan
+			as.logger.Warnf("Error al evaluar el umbral %d, se considera no cumplido: %v", threshold.ID, err)
+			eval = &ThresholdEvaluation{MetricName: threshold.Name}
+		}
 
-		// Evaluar la condición
-		triggered := false
-
-		switch threshold.Operator {
-		case ">":
-			triggered = metricValue > threshold.Value
-		case "<":
-			triggered = metricValue < threshold.Value
-		case ">=":
-			triggered = metricValue >= threshold.Value
-		case "<=":
-			triggered = metricValue <= threshold.Value
-		case "==":
-			triggered = metricValue == threshold.Value
+		// La condición se cumple pero aún no durante el tiempo configurado
+		if eval.Pending {
+			continue
 		}
+		metricName := eval.MetricName
 
 		// Crear una alerta si se cumple la condición
-		if triggered {
+		if eval.Triggered {
 			var serverName string
 			var server models.Server
 
@@ -405,19 +719,39 @@ func (as *AlertService) CheckMetricAgainstThresholds(metric *models.Metric) erro
 				serverName = server.Hostname
 			}
 
+			message := fmt.Sprintf("La métrica %s ha alcanzado un valor de %.2f%%, superando el umbral establecido de %.2f%%",
+				metricName, eval.Value, eval.Limit)
+			if threshold.IsExpression() {
+				message = fmt.Sprintf("Se cumple la condición %s", threshold.Expression)
+			} else if eval.Summary != "" {
+				message = conditionMessage(threshold.Condition, eval.Summary)
+			}
+
 			alert := &models.Alert{
-				Title: fmt.Sprintf("Alerta: %s en %s", metricName, serverName),
-				Message: fmt.Sprintf("La métrica %s ha alcanzado un valor de %.2f%%, superando el umbral establecido de %.2f%%",
-					metricName, metricValue, threshold.Value),
-				MetricType:  threshold.MetricType,
-				MetricValue: metricValue,
-				Threshold:   threshold.Value,
-				Operator:    threshold.Operator,
-				Severity:    threshold.Severity,
-				Status:      models.AlertStatusActive,
-				ServerID:    metric.ServerID,
-				ThresholdID: threshold.ID,
-				TriggeredAt: time.Now(),
+				Title:        fmt.Sprintf("Alerta: %s en %s", metricName, serverName),
+				Message:      message,
+				MetricType:   threshold.MetricType,
+				MetricValue:  eval.Value,
+				Threshold:    eval.Limit,
+				Operator:     eval.Operator,
+				Severity:     threshold.Severity,
+				ExpectedMin:  eval.ExpectedMin,
+				ExpectedMax:  eval.ExpectedMax,
+				ExpectedMean: eval.ExpectedMean,
+				AnomalyScore: eval.ZScore,
+				Status:       models.AlertStatusActive,
+				ServerID:     metric.ServerID,
+				ThresholdID:  threshold.ID,
+				TriggeredAt:  time.Now(),
+			}
+
+			// Generar título y mensaje con la plantilla configurada (se mantiene el texto anterior si falla)
+			if as.templateService != nil {
+				if title, message, err := as.templateService.RenderAlertText(alert, &server, &threshold, metric); err != nil {
+					as.logger.Warnf("Error al aplicar plantilla de alerta, se usa el texto por defecto: %v", err)
+				} else {
+					alert.Title, alert.Message = title, message
+				}
 			}
 
 			if err := as.CreateAlert(alert); err != nil {
@@ -427,33 +761,16 @@ func (as *AlertService) CheckMetricAgainstThresholds(metric *models.Metric) erro
 		} else {
 			// Verificar si hay alertas activas que deban resolverse
 			var activeAlerts []models.Alert
-			if err := as.db.Where("server_id = ? AND metric_type = ? AND status = ? AND threshold_id = ?",
-				metric.ServerID, threshold.MetricType, models.AlertStatusActive, threshold.ID).
+			if err := as.db.Where("server_id = ? AND metric_type = ? AND status IN ? AND threshold_id = ?",
+				metric.ServerID, threshold.MetricType, []models.AlertStatus{models.AlertStatusActive, models.AlertStatusSuppressed}, threshold.ID).
 				Find(&activeAlerts).Error; err != nil {
 				continue
 			}
 
+			// La condición ya no se cumple: resolver las alertas abiertas
 			for _, activeAlert := range activeAlerts {
-				// Verificar si la condición ya no se cumple para resolverla
-				shouldResolve := false
-
-				switch threshold.Operator {
-				case ">":
-					shouldResolve = metricValue <= threshold.Value
-				case "<":
-					shouldResolve = metricValue >= threshold.Value
-				case ">=":
-					shouldResolve = metricValue < threshold.Value
-				case "<=":
-					shouldResolve = metricValue > threshold.Value
-				case "==":
-					shouldResolve = metricValue != threshold.Value
-				}
-
-				if shouldResolve {
-					if err := as.AutoResolveAlert(activeAlert.ID); err != nil {
-						as.logger.Errorf("Error al resolver automáticamente alerta %d: %v", activeAlert.ID, err)
-					}
+				if err := as.AutoResolveAlert(activeAlert.ID); err != nil {
+					as.logger.Errorf("Error al resolver automáticamente alerta %d: %v", activeAlert.ID, err)
 				}
 			}
 		}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// auditExportBatch entradas que se leen de cada vez al exportar
+const auditExportBatch = 500
+
+// auditIgnoredFields campos que no se comparan al calcular los cambios
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// auditSecretKeys campos que no se guardan nunca en la auditoría (por subcadena). Las URL se
+// ocultan porque las de los webhooks (Discord, Slack...) incluyen su token
+var auditSecretKeys = []string{"password", "token", "secret", "code", "url"}
+
+// auditResources modelos de los recursos cuyo estado se guarda antes y después de cada cambio,
+// por tipo de recurso (primer tramo de la ruta tras /api)
+var auditResources = map[string]func() interface{}{
+	"servers":                func() interface{} { return &models.Server{} },
+	"server-groups":          func() interface{} { return &models.ServerGroup{} },
+	"permissions":            func() interface{} { return &models.GroupPermission{} },
+	"teams":                  func() interface{} { return &models.Team{} },
+	"alerts":                 func() interface{} { return &models.Alert{} },
+	"alert-thresholds":       func() interface{} { return &models.AlertThreshold{} },
+	"silences":               func() interface{} { return &models.Silence{} },
+	"incidents":              func() interface{} { return &models.Incident{} },
+	"inhibition-rules":       func() interface{} { return &models.InhibitionRule{} },
+	"escalation-policies":    func() interface{} { return &models.EscalationPolicy{} },
+	"contact-points":         func() interface{} { return &models.ContactPoint{} },
+	"notification-routes":    func() interface{} { return &models.NotificationRoute{} },
+	"notification-templates": func() interface{} { return &models.NotificationTemplate{} },
+	"users":                  func() interface{} { return &models.User{} },
+	"users/lockouts":         func() interface{} { return &models.LoginThrottle{} },
+	"invitations":            func() interface{} { return &models.Invitation{} },
+	"auth/sessions":          func() interface{} { return &models.Session{} },
+}
+
+// AuditFilter filtros de la consulta del registro de auditoría
+type AuditFilter struct {
+	ActorID      uint
+	ActorName    string
+	Action       string // Acción exacta, o prefijo si termina en * (p. ej. users.*)
+	ResourceType string
+	ResourceID   string
+	Outcome      string
+	StartDate    time.Time
+	EndDate      time.Time
+	Limit        int
+	Offset       int
+}
+
+// AuditService registro de auditoría estructurado y de solo inserción de las acciones de
+// configuración y de seguridad
+type AuditService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewAuditService crea el servicio de auditoría
+func NewAuditService(db *gorm.DB, log logger.Logger) *AuditService {
+	return &AuditService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// EnsureAppendOnly instala en la base de datos un trigger que rechaza cualquier UPDATE, DELETE
+// o TRUNCATE de la tabla de auditoría, también los que no pasan por la aplicación
+func (s *AuditService) EnsureAppendOnly() error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'el registro de auditoría es de solo inserción';
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_entries_no_update ON audit_entries`,
+		`CREATE TRIGGER audit_entries_no_update BEFORE UPDATE OR DELETE ON audit_entries
+	FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only()`,
+		`DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries`,
+		`CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only()`,
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Record guarda una entrada de auditoría. Si tiene actor sin nombre se completa con su nombre de
+// usuario actual. Los errores solo se registran: la auditoría no debe impedir la acción
+func (s *AuditService) Record(entry *models.AuditEntry) {
+	if entry.ActorID != nil && entry.ActorName == "" {
+		var user models.User
+		if err := s.db.Unscoped().Select("username").First(&user, *entry.ActorID).Error; err == nil {
+			entry.ActorName = user.Username
+		}
+	}
+
+	entry.ActorName = truncateRunes(entry.ActorName, 100)
+	entry.UserAgent = truncateRunes(entry.UserAgent, 255)
+
+	if err := s.db.Create(entry).Error; err != nil {
+		s.logger.Errorf("Error al registrar la auditoría de %s %s: %v", entry.Action, entry.ResourceID, err)
+	}
+}
+
+// Tracks indica si se guarda el estado de un tipo de recurso antes y después de cada cambio
+func (s *AuditService) Tracks(resourceType string) bool {
+	_, ok := auditResources[resourceType]
+	return ok
+}
+
+// Snapshot obtiene el estado actual de un recurso como JSON (nil si no existe o no se sigue).
+// Los campos que no se devuelven por la API no se incluyen y los que pueden contener secretos
+// se ocultan
+func (s *AuditService) Snapshot(resourceType, id string) models.Metadata {
+	newModel, ok := auditResources[resourceType]
+	if !ok || id == "" {
+		return nil
+	}
+
+	model := newModel()
+	if err := s.db.Where("id = ?", id).Take(model).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warnf("Auditoría: no se pudo leer %s %s: %v", resourceType, id, err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+
+	var snapshot models.Metadata
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	RedactSecrets(map[string]interface{}(snapshot))
+
+	return snapshot
+}
+
+// GetEntries consulta el registro de auditoría, de la más reciente a la más antigua
+func (s *AuditService) GetEntries(filter AuditFilter) ([]models.AuditEntry, int64, error) {
+	query := s.filtered(filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		s.logger.Errorf("Error al contar las entradas de auditoría: %v", err)
+		return nil, 0, err
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var entries []models.AuditEntry
+	if err := query.Order("created_at DESC, id DESC").Find(&entries).Error; err != nil {
+		s.logger.Errorf("Error al consultar la auditoría: %v", err)
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Export escribe las entradas que cumplen el filtro como JSON lines (una entrada por línea), en
+// orden de ID y por lotes para no cargar todo el registro en memoria
+func (s *AuditService) Export(filter AuditFilter, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	written := 0
+
+	var batch []models.AuditEntry
+	result := s.filtered(filter).FindInBatches(&batch, auditExportBatch, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if filter.Limit > 0 && written >= filter.Limit {
+				return errAuditExportDone
+			}
+			if err := encoder.Encode(&batch[i]); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	})
+	if result.Error != nil && !errors.Is(result.Error, errAuditExportDone) {
+		s.logger.Errorf("Error al exportar la auditoría: %v", result.Error)
+		return written, result.Error
+	}
+
+	return written, nil
+}
+
+// errAuditExportDone detiene la exportación al alcanzar el límite
+var errAuditExportDone = errors.New("exportación completa")
+
+// filtered consulta con los filtros aplicados
+func (s *AuditService) filtered(filter AuditFilter) *gorm.DB {
+	query := s.db.Model(&models.AuditEntry{})
+
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ActorName != "" {
+		query = query.Where("actor_name = ?", filter.ActorName)
+	}
+	if filter.Action != "" {
+		if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+			query = query.Where("action LIKE ?", escapeLike(prefix)+"%")
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.StartDate.IsZero() {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if !filter.EndDate.IsZero() {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+
+	return query
+}
+
+// AuditDiff campos que cambian entre dos estados de un recurso: {"campo": {"before", "after"}}.
+// Con before nil (alta) o after nil (baja) se incluyen todos los campos del otro estado
+func AuditDiff(before, after models.Metadata) models.Metadata {
+	if before == nil && after == nil {
+		return nil
+	}
+
+	changes := models.Metadata{}
+	for field, value := range before {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if newValue, ok := after[field]; !ok || !reflect.DeepEqual(value, newValue) {
+			changes[field] = map[string]interface{}{"before": value, "after": after[field]}
+		}
+	}
+	for field, value := range after {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if _, ok := before[field]; !ok {
+			changes[field] = map[string]interface{}{"before": nil, "after": value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// RedactSecrets sustituye los valores de los campos que pueden contener secretos (contraseñas,
+// tokens, códigos, URL de webhooks), también en objetos anidados
+func RedactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if auditSecretKey(key) {
+				v[key] = "[oculto]"
+			} else {
+				v[key] = RedactSecrets(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = RedactSecrets(item)
+		}
+	}
+	return value
+}
+
+// auditSecretKey indica si un campo puede contener un secreto
+func auditSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range auditSecretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeLike escapa los comodines de LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
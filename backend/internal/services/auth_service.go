@@ -23,14 +23,18 @@ var (
 	ErrTokenExpired       = errors.New("token expirado")
 	ErrUserDisabled       = errors.New("usuario deshabilitado")
 	ErrInsufficientRole   = errors.New("permisos insuficientes")
+	ErrSessionRevoked     = errors.New("sesión revocada o caducada")
+	ErrRefreshTokenReused = errors.New("refresh token reutilizado")
+	ErrLocalLoginDisabled = errors.New("el inicio de sesión con contraseña está deshabilitado: use el inicio de sesión único")
 )
 
 // JWTClaims contiene los claims del token JWT
 type JWTClaims struct {
-	UserID   uint        `json:"user_id"`
-	Username string      `json:"username"`
-	Email    string      `json:"email"`
-	Role     models.Role `json:"role"`
+	UserID    uint        `json:"user_id"`
+	Username  string      `json:"username"`
+	Email     string      `json:"email"`
+	Role      models.Role `json:"role"`
+	SessionID uint        `json:"sid"` // Sesión a la que pertenece el token
 	jwt.RegisteredClaims
 }
 
@@ -39,69 +43,212 @@ type AuthService struct {
 	db         *gorm.DB
 	logger     logger.Logger
 	jwtSecret  []byte
-	jwtExpires time.Duration
+	accessTTL  time.Duration // Duración de los tokens de acceso
+	refreshTTL time.Duration // Duración de los refresh tokens (inactividad máxima de una sesión)
+	
+	// Con inicio de sesión único, limitar el login con contraseña a los administradores locales
+	localLoginAdminOnly bool
+	
+	// Autenticación contra el directorio para los usuarios LDAP
+	ldapService *LDAPService
+	
+	// Segundo paso del login (TOTP) para los usuarios que lo tienen activado
+	twoFactorService *TwoFactorService
+	
+	// Protección contra fuerza bruta (intentos fallidos por usuario e IP)
+	loginProtection *LoginProtectionService
 }
 
 // NewAuthService crea una nueva instancia del servicio de autenticación
-func NewAuthService(db *gorm.DB, logger logger.Logger, jwtSecret string, jwtExpireHours int) *AuthService {
-	if jwtExpireHours <= 0 {
-		jwtExpireHours = 24 // Por defecto 24 horas
+func NewAuthService(db *gorm.DB, logger logger.Logger, jwtSecret string, accessTTL, refreshTTL time.Duration) *AuthService {
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute // Por defecto 15 minutos
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = 7 * 24 * time.Hour // Por defecto 7 días
 	}
 	
 	return &AuthService{
 		db:         db,
 		logger:     logger,
 		jwtSecret:  []byte(jwtSecret),
-		jwtExpires: time.Duration(jwtExpireHours) * time.Hour,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// AccessTTL devuelve la duración de los tokens de acceso
+func (s *AuthService) AccessTTL() time.Duration {
+	return s.accessTTL
+}
+
+// RefreshTTL devuelve la duración de los refresh tokens
+func (s *AuthService) RefreshTTL() time.Duration {
+	return s.refreshTTL
+}
+
+// SetLocalLoginAdminOnly limita el login con contraseña a los administradores locales, que
+// quedan como acceso de emergencia cuando se usa el inicio de sesión único
+func (s *AuthService) SetLocalLoginAdminOnly(adminOnly bool) {
+	s.localLoginAdminOnly = adminOnly
+}
+
+// SetLDAPService habilita la autenticación contra el directorio LDAP
+func (s *AuthService) SetLDAPService(ldapService *LDAPService) {
+	s.ldapService = ldapService
+}
+
+// SetTwoFactorService habilita la verificación en dos pasos tras comprobar la contraseña
+func (s *AuthService) SetTwoFactorService(twoFactorService *TwoFactorService) {
+	s.twoFactorService = twoFactorService
+}
+
+// SetLoginProtection habilita el recuento de intentos fallidos y el bloqueo temporal del login
+func (s *AuthService) SetLoginProtection(loginProtection *LoginProtectionService) {
+	s.loginProtection = loginProtection
+}
+
+// LocalLoginAdminOnly indica si el login con contraseña está limitado a los administradores
+func (s *AuthService) LocalLoginAdminOnly() bool {
+	return s.localLoginAdminOnly
+}
+
+// Login autentica un usuario y abre una sesión para el cliente indicado. La contraseña se
+// comprueba con bcrypt o contra el directorio LDAP según el proveedor de cada usuario. Si el
+// usuario debe completar la verificación en dos pasos no se abre sesión: se devuelve el usuario
+// con ErrTwoFactorRequired o ErrTwoFactorSetupRequired
+func (s *AuthService) Login(username, password string, client ClientInfo) (*TokenPair, *models.User, error) {
+	// Rechazar sin comprobar la contraseña mientras el usuario o la IP estén bloqueados
+	if s.loginProtection != nil {
+		if err := s.loginProtection.Check(username, client.IPAddress); err != nil {
+			s.logger.Warnf("Intento de login bloqueado para %s desde %s", username, client.IPAddress)
+			return nil, nil, err
+		}
+	}
+	
+	user, err := s.verifyCredentials(username, password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) && s.loginProtection != nil {
+			s.loginProtection.RegisterFailure(username, client.IPAddress)
+		}
+		return nil, nil, err
+	}
+	if s.loginProtection != nil {
+		s.loginProtection.RegisterSuccess(username)
+	}
+	
+	if user.Disabled {
+		s.logger.Warnf("Intento de login de usuario deshabilitado: %s", username)
+		return nil, nil, ErrUserDisabled
+	}
+	
+	// Segundo paso: código TOTP o alta obligatoria de la verificación
+	if s.twoFactorService != nil {
+		if err := s.twoFactorService.CheckLogin(user); err != nil {
+			if !errors.Is(err, ErrTwoFactorRequired) && !errors.Is(err, ErrTwoFactorSetupRequired) {
+				s.logger.Errorf("Error al comprobar la verificación en dos pasos de %s: %v", username, err)
+				return nil, nil, err
+			}
+			return nil, user, err
+		}
+	}
+	
+	tokens, err := s.StartSession(user, client)
+	if err != nil {
+		return nil, nil, err
 	}
+	
+	return tokens, user, nil
 }
 
-// Login autentica un usuario y devuelve un token JWT
-func (s *AuthService) Login(username, password string) (string, *models.User, error) {
+// verifyCredentials comprueba la contraseña con bcrypt o contra el directorio LDAP según el
+// proveedor del usuario, y da de alta a los usuarios del directorio en su primer login
+func (s *AuthService) verifyCredentials(username, password string) (*models.User, error) {
 	var user models.User
 	
 	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Errorf("Error al buscar usuario en BD: %v", err)
+			return nil, err
+		}
+		
+		// Alta automática de los usuarios del directorio en su primer login
+		if s.ldapService == nil || !s.ldapService.AutoCreate() {
 			s.logger.Warnf("Intento de login para usuario inexistente: %s", username)
-			return "", nil, ErrInvalidCredentials
+			return nil, ErrInvalidCredentials
+		}
+		
+		created, err := s.ldapService.Login(username, password, nil)
+		if err != nil {
+			return nil, err
+		}
+		user = *created
+	} else {
+		switch user.AuthProvider {
+		case "", models.AuthProviderLocal:
+			if !user.CheckPassword(password) {
+				s.logger.Warnf("Contraseña incorrecta para usuario: %s", username)
+				return nil, ErrInvalidCredentials
+			}
+			
+			if s.localLoginAdminOnly && user.Role != models.RoleAdmin {
+				s.logger.Warnf("Login con contraseña rechazado para el usuario no administrador %s", username)
+				return nil, ErrLocalLoginDisabled
+			}
+			
+		case models.AuthProviderLDAP:
+			if s.ldapService == nil {
+				s.logger.Warnf("Intento de login LDAP para %s con LDAP deshabilitado", username)
+				return nil, ErrInvalidCredentials
+			}
+			
+			synced, err := s.ldapService.Login(username, password, &user)
+			if err != nil {
+				return nil, err
+			}
+			user = *synced
+			
+		default:
+			// Los usuarios de proveedores externos (OIDC) no tienen contraseña
+			s.logger.Warnf("Intento de login con contraseña para usuario %s de %s", username, user.AuthProvider)
+			return nil, ErrInvalidCredentials
 		}
-		s.logger.Errorf("Error al buscar usuario en BD: %v", err)
-		return "", nil, err
-	}
-	
-	if !user.CheckPassword(password) {
-		s.logger.Warnf("Contraseña incorrecta para usuario: %s", username)
-		return "", nil, ErrInvalidCredentials
 	}
 	
+	return &user, nil
+}
+
+// StartSession completa un login ya verificado: actualiza el último acceso del usuario y abre
+// su sesión
+func (s *AuthService) StartSession(user *models.User, client ClientInfo) (*TokenPair, error) {
 	// Actualizar último login
 	now := time.Now()
 	user.LastLogin = &now
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := s.db.Model(user).Update("last_login", now).Error; err != nil {
 		s.logger.Warnf("Error al actualizar último login: %v", err)
 		// No devolver error para no interrumpir el login
 	}
 	
-	// Generar token JWT
-	token, err := s.GenerateToken(&user)
+	// Abrir sesión y generar tokens
+	tokens, err := s.CreateSession(user, client)
 	if err != nil {
-		s.logger.Errorf("Error al generar token JWT: %v", err)
-		return "", nil, err
+		return nil, err
 	}
 	
-	s.logger.Infof("Login exitoso para usuario: %s", username)
-	return token, &user, nil
+	s.logger.Infof("Login exitoso para usuario: %s", user.Username)
+	return tokens, nil
 }
 
-// GenerateToken genera un token JWT para un usuario
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(s.jwtExpires)
+// GenerateToken genera un token JWT de acceso de corta duración para una sesión de un usuario
+func (s *AuthService) GenerateToken(user *models.User, sessionID uint) (string, error) {
+	expirationTime := time.Now().Add(s.accessTTL)
 	
 	claims := &JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -172,36 +319,14 @@ func (s *AuthService) CheckUserRole(userID uint, minRole models.Role) error {
 	return nil
 }
 
-// ValidateToken verifica y valida un token JWT
+// ValidateToken verifica un token JWT y que su sesión siga activa
 func (s *AuthService) ValidateToken(tokenString string) (*interfaces.TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verificar que se está usando el algoritmo correcto
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("método de firma inválido")
-		}
-		
-		return s.jwtSecret, nil
-	})
-	
+	claims, _, err := s.Authenticate(tokenString)
 	if err != nil {
 		s.logger.Warnf("Error al validar token: %v", err)
 		return nil, err
 	}
 	
-	if !token.Valid {
-		return nil, errors.New("token inválido")
-	}
-	
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
-	if !ok {
-		return nil, errors.New("claims inválidas")
-	}
-	
-	// Verificar expiración
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
-		return nil, errors.New("token expirado")
-	}
-
 	// Convertir a interfaces.TokenClaims
 	return &interfaces.TokenClaims{
 		Subject: claims.Subject,
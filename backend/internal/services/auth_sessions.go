@@ -0,0 +1,375 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval frecuencia máxima con la que se actualiza el último uso de una sesión
+const sessionTouchInterval = time.Minute
+
+// ClientInfo datos del dispositivo desde el que se abre o renueva una sesión
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// TokenPair token de acceso y refresh token emitidos para una sesión
+type TokenPair struct {
+	SessionID        uint
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// CreateSession abre una sesión para el usuario y emite sus primeros tokens
+func (s *AuthService) CreateSession(user *models.User, client ClientInfo) (*TokenPair, error) {
+	now := time.Now()
+	session := &models.Session{
+		UserID:     user.ID,
+		UserAgent:  truncateRunes(client.UserAgent, 500),
+		IPAddress:  truncateRunes(client.IPAddress, 45),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+
+	var tokens *TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = s.issueTokens(tx, user, session, now)
+		return err
+	})
+	if err != nil {
+		s.logger.Errorf("Error al crear sesión para el usuario %d: %v", user.ID, err)
+		return nil, err
+	}
+
+	s.purgeExpiredSessions(user.ID, now)
+
+	s.logger.Infof("Sesión %d abierta para el usuario %d desde %s", session.ID, user.ID, session.IPAddress)
+	return tokens, nil
+}
+
+// Refresh rota un refresh token: lo marca como usado y emite un nuevo par de tokens para su
+// sesión. Presentar un refresh token ya rotado revoca la sesión completa
+func (s *AuthService) Refresh(refreshToken string, client ClientInfo) (*TokenPair, *models.User, error) {
+	now := time.Now()
+
+	var token models.RefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(refreshToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrTokenInvalid
+		}
+		return nil, nil, err
+	}
+
+	var session models.Session
+	if err := s.db.First(&session, token.SessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrSessionRevoked
+		}
+		return nil, nil, err
+	}
+
+	if token.UsedAt != nil {
+		s.reportTokenReuse(&session)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	if !session.IsActive(now) || !now.Before(token.ExpiresAt) {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	user, err := s.GetUserByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, nil, ErrSessionRevoked
+		}
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrUserDisabled
+	}
+
+	var tokens *TokenPair
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Solo una renovación puede consumir el token: otra simultánea se trata como reutilización
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"user_agent":   truncateRunes(client.UserAgent, 500),
+			"ip_address":   truncateRunes(client.IPAddress, 45),
+			"last_used_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		tokens, err = s.issueTokens(tx, user, &session, now)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			s.reportTokenReuse(&session)
+			return nil, nil, err
+		}
+		s.logger.Errorf("Error al renovar la sesión %d: %v", session.ID, err)
+		return nil, nil, err
+	}
+
+	return tokens, user, nil
+}
+
+// Authenticate verifica un token de acceso y que su sesión siga activa. Devuelve el usuario
+// actual, cuyo rol prevalece sobre el del token
+func (s *AuthService) Authenticate(tokenString string) (*JWTClaims, *models.User, error) {
+	claims, err := s.VerifyToken(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if claims.SessionID == 0 {
+		return nil, nil, ErrTokenInvalid
+	}
+
+	now := time.Now()
+	var session models.Session
+	if err := s.db.First(&session, claims.SessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrSessionRevoked
+		}
+		return nil, nil, err
+	}
+
+	if session.UserID != claims.UserID || !session.IsActive(now) {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	user, err := s.GetUserByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, nil, ErrSessionRevoked
+		}
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrUserDisabled
+	}
+
+	if now.Sub(session.LastUsedAt) > sessionTouchInterval {
+		if err := s.db.Model(&session).Update("last_used_at", now).Error; err != nil {
+			s.logger.Warnf("Error al actualizar el último uso de la sesión %d: %v", session.ID, err)
+		}
+	}
+
+	return claims, user, nil
+}
+
+// GetSessions obtiene las sesiones activas de un usuario, marcando la actual
+func (s *AuthService) GetSessions(userID, currentSessionID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		s.logger.Errorf("Error al obtener sesiones del usuario %d: %v", userID, err)
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revoca una sesión de un usuario
+func (s *AuthService) RevokeSession(userID, sessionID uint, reason string) error {
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason})
+	if result.Error != nil {
+		s.logger.Errorf("Error al revocar la sesión %d: %v", sessionID, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("sesión no encontrada")
+	}
+
+	s.logger.Infof("Sesión %d del usuario %d revocada (%s)", sessionID, userID, reason)
+	return nil
+}
+
+// Logout revoca la sesión identificada por el token de acceso o, si éste ya no es válido, por
+// el refresh token. Devuelve el usuario y la sesión revocada (0 si no se identificó ninguna)
+func (s *AuthService) Logout(accessToken, refreshToken string) (uint, uint) {
+	var userID, sessionID uint
+
+	if claims, err := s.VerifyToken(accessToken); err == nil && claims.SessionID != 0 {
+		userID, sessionID = claims.UserID, claims.SessionID
+	} else if refreshToken != "" {
+		var token models.RefreshToken
+		if err := s.db.Where("token_hash = ?", hashToken(refreshToken)).First(&token).Error; err == nil {
+			var session models.Session
+			if err := s.db.First(&session, token.SessionID).Error; err == nil {
+				userID, sessionID = session.UserID, session.ID
+			}
+		}
+	}
+
+	if sessionID == 0 {
+		return 0, 0
+	}
+
+	if err := s.RevokeSession(userID, sessionID, models.SessionRevokedLogout); err != nil {
+		return 0, 0
+	}
+	return userID, sessionID
+}
+
+// RevokeOtherSessions revoca todas las sesiones de un usuario salvo la indicada
+func (s *AuthService) RevokeOtherSessions(userID, keepSessionID uint) (int64, error) {
+	count, err := revokeUserSessions(s.db, userID, models.SessionRevokedByUser, keepSessionID)
+	if err != nil {
+		s.logger.Errorf("Error al revocar las sesiones del usuario %d: %v", userID, err)
+		return 0, err
+	}
+
+	s.logger.Infof("%d sesiones del usuario %d revocadas", count, userID)
+	return count, nil
+}
+
+// issueTokens emite un token de acceso y un nuevo refresh token para la sesión
+func (s *AuthService) issueTokens(tx *gorm.DB, user *models.User, session *models.Session, now time.Time) (*TokenPair, error) {
+	accessToken, err := s.GenerateToken(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(s.refreshTTL)
+	if err := tx.Create(&models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	// La sesión caduca con su refresh token vigente
+	if err := tx.Model(session).Update("expires_at", expiresAt).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		SessionID:        session.ID,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+// reportTokenReuse revoca una sesión cuyo refresh token se ha presentado dos veces: el token
+// se ha filtrado y no se puede saber qué cliente es el legítimo
+func (s *AuthService) reportTokenReuse(session *models.Session) {
+	s.logger.Warnf("Reutilización de refresh token en la sesión %d del usuario %d: se revoca la sesión", session.ID, session.UserID)
+
+	if session.RevokedAt != nil {
+		return
+	}
+
+	if err := s.db.Model(session).Updates(map[string]interface{}{
+		"revoked_at":    time.Now(),
+		"revoke_reason": models.SessionRevokedTokenReuse,
+	}).Error; err != nil {
+		s.logger.Errorf("Error al revocar la sesión %d: %v", session.ID, err)
+	}
+}
+
+// purgeExpiredSessions elimina las sesiones caducadas o revocadas de un usuario, y sus refresh
+// tokens, una vez pasado el periodo de vida de un refresh token
+func (s *AuthService) purgeExpiredSessions(userID uint, now time.Time) {
+	cutoff := now.Add(-s.refreshTTL)
+
+	var ids []uint
+	if err := s.db.Model(&models.Session{}).
+		Where("user_id = ? AND (expires_at < ? OR revoked_at < ?)", userID, cutoff, cutoff).
+		Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		return
+	}
+
+	if err := s.db.Where("session_id IN ?", ids).Delete(&models.RefreshToken{}).Error; err != nil {
+		s.logger.Warnf("Error al eliminar refresh tokens caducados: %v", err)
+		return
+	}
+
+	if err := s.db.Delete(&models.Session{}, ids).Error; err != nil {
+		s.logger.Warnf("Error al eliminar sesiones caducadas: %v", err)
+	}
+}
+
+// revokeUserSessions revoca las sesiones activas de un usuario (salvo exceptID, si no es 0)
+// y devuelve cuántas se revocaron
+func revokeUserSessions(db *gorm.DB, userID uint, reason string, exceptID uint) (int64, error) {
+	query := db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != 0 {
+		query = query.Where("id <> ?", exceptID)
+	}
+
+	result := query.Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason})
+	return result.RowsAffected, result.Error
+}
+
+// generateRefreshToken genera un refresh token aleatorio de 256 bits
+func generateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// randomPassword genera una contraseña aleatoria para las cuentas de proveedores externos, que
+// no pueden iniciar sesión con contraseña local
+func randomPassword() (string, error) {
+	return generateRefreshToken()
+}
+
+// hashToken calcula el hash con el que se guarda un refresh token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncateRunes recorta un texto a un máximo de caracteres
+func truncateRunes(value string, max int) string {
+	if runes := []rune(value); len(runes) > max {
+		return string(runes[:max])
+	}
+	return value
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBaselineMetrics variables para las que siempre se aprende línea base
+var DefaultBaselineMetrics = []string{"cpu_usage", "memory_percent", "disk_percent", "net_in_mb", "net_out_mb", "load_avg_5"}
+
+// Anomaly muestra del historial que se sale de la banda esperada
+type Anomaly struct {
+	Timestamp time.Time `json:"timestamp"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Expected  float64   `json:"expected"` // Media de la franja
+	Lower     float64   `json:"lower"`
+	Upper     float64   `json:"upper"`
+	ZScore    float64   `json:"z_score"`
+}
+
+// BaselineService aprende, por servidor y variable de métrica, la media y desviación típica de
+// cada hora de la semana a partir del historial almacenado y detecta muestras anómalas
+type BaselineService struct {
+	db            *gorm.DB
+	logger        logger.Logger
+	lookback      time.Duration // Historial usado para aprender las líneas base
+	defaultZScore float64       // z-score por defecto para considerar anómala una muestra
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewBaselineService crea un nuevo servicio de líneas base
+func NewBaselineService(db *gorm.DB, log logger.Logger, lookback time.Duration, defaultZScore float64) *BaselineService {
+	return &BaselineService{
+		db:            db,
+		logger:        log,
+		lookback:      lookback,
+		defaultZScore: defaultZScore,
+	}
+}
+
+// DefaultZScore z-score por defecto para la detección de anomalías
+func (bs *BaselineService) DefaultZScore() float64 {
+	return bs.defaultZScore
+}
+
+// BaselineMetrics variables aprendidas: las predeterminadas y las usadas por reglas de anomalía
+func (bs *BaselineService) BaselineMetrics() []string {
+	metrics := append([]string(nil), DefaultBaselineMetrics...)
+
+	var thresholds []models.AlertThreshold
+	bs.db.Where("condition = ?", models.ThresholdConditionAnomaly).Find(&thresholds)
+	for _, threshold := range thresholds {
+		if variable := threshold.SourceVariable(); variable != "" && !slices.Contains(metrics, variable) {
+			metrics = append(metrics, variable)
+		}
+	}
+
+	return metrics
+}
+
+// baselineHourOfWeek franja de la semana de una muestra en SQL, como models.HourOfWeek
+const baselineHourOfWeek = `(EXTRACT(DOW FROM "timestamp" AT TIME ZONE 'UTC') * 24 + EXTRACT(HOUR FROM "timestamp" AT TIME ZONE 'UTC'))::int`
+
+// RecomputeServer recalcula las líneas base de un servidor con el historial más reciente
+func (bs *BaselineService) RecomputeServer(serverID uint, now time.Time) error {
+	return bs.recomputeServer(serverID, bs.BaselineMetrics(), now)
+}
+
+// RecomputeAll recalcula las líneas base de todos los servidores
+func (bs *BaselineService) RecomputeAll(now time.Time) {
+	var serverIDs []uint
+	if err := bs.db.Model(&models.Server{}).Pluck("id", &serverIDs).Error; err != nil {
+		bs.logger.Errorf("Error al obtener servidores para recalcular líneas base: %v", err)
+		return
+	}
+
+	variables := bs.BaselineMetrics()
+	for _, serverID := range serverIDs {
+		if err := bs.recomputeServer(serverID, variables, now); err != nil {
+			bs.logger.Errorf("Error al recalcular líneas base del servidor %d: %v", serverID, err)
+		}
+	}
+
+	bs.logger.Infof("Líneas base recalculadas para %d servidores", len(serverIDs))
+}
+
+// recomputeServer agrega el historial en la base de datos y actualiza las franjas del servidor.
+// Se eliminan las que ya no tienen muestras o son de variables que ya no se aprenden
+func (bs *BaselineService) recomputeServer(serverID uint, variables []string, now time.Time) error {
+	// Con precisión de milisegundos para que la fecha guardada coincida con la de la comparación
+	now = now.Truncate(time.Millisecond)
+
+	baselines, err := bs.aggregateBaselines(serverID, variables, now)
+	if err != nil {
+		return err
+	}
+
+	return bs.db.Transaction(func(tx *gorm.DB) error {
+		if len(baselines) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "server_id"}, {Name: "metric"}, {Name: "hour_of_week"}},
+				DoUpdates: clause.AssignmentColumns([]string{"samples", "mean", "std_dev", "updated_at"}),
+			}).CreateInBatches(baselines, 500).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("server_id = ? AND updated_at < ?", serverID, now).Delete(&models.MetricBaseline{}).Error
+	})
+}
+
+// aggregateBaselines calcula en la base de datos la media y la desviación típica de cada
+// variable por hora de la semana, sin cargar el historial en memoria
+func (bs *BaselineService) aggregateBaselines(serverID uint, variables []string, now time.Time) ([]models.MetricBaseline, error) {
+	columns := []string{baselineHourOfWeek + " AS hour_of_week", "COUNT(*) AS samples"}
+	var known []string
+	for _, variable := range variables {
+		expression, ok := models.MetricVariableSQL(variable)
+		if !ok {
+			bs.logger.Warnf("Variable de línea base desconocida: %s", variable)
+			continue
+		}
+		columns = append(columns, "AVG("+expression+")", "STDDEV_SAMP("+expression+")")
+		known = append(known, variable)
+	}
+	if len(known) == 0 {
+		return nil, nil
+	}
+
+	rows, err := bs.db.Model(&models.Metric{}).
+		Select(strings.Join(columns, ", ")).
+		Where("server_id = ? AND timestamp BETWEEN ? AND ?", serverID, now.Add(-bs.lookback), now).
+		Group("hour_of_week").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var baselines []models.MetricBaseline
+	means := make([]sql.NullFloat64, len(known))
+	stdDevs := make([]sql.NullFloat64, len(known))
+	for rows.Next() {
+		var hourOfWeek, samples int
+		dest := []interface{}{&hourOfWeek, &samples}
+		for i := range known {
+			dest = append(dest, &means[i], &stdDevs[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		for i, variable := range known {
+			if !means[i].Valid {
+				continue
+			}
+			// Con una sola muestra la desviación es nula: se guarda 0
+			baselines = append(baselines, models.MetricBaseline{
+				ServerID:   serverID,
+				Metric:     variable,
+				HourOfWeek: hourOfWeek,
+				Samples:    samples,
+				Mean:       means[i].Float64,
+				StdDev:     stdDevs[i].Float64,
+				UpdatedAt:  now,
+			})
+		}
+	}
+
+	return baselines, rows.Err()
+}
+
+// GetBaselines obtiene las franjas aprendidas de un servidor (opcionalmente de una sola variable)
+func (bs *BaselineService) GetBaselines(serverID uint, metric string) ([]models.MetricBaseline, error) {
+	var baselines []models.MetricBaseline
+	query := bs.db.Where("server_id = ?", serverID)
+	if metric != "" {
+		query = query.Where("metric = ?", metric)
+	}
+
+	if err := query.Order("metric ASC, hour_of_week ASC").Find(&baselines).Error; err != nil {
+		bs.logger.Errorf("Error al obtener líneas base del servidor %d: %v", serverID, err)
+		return nil, err
+	}
+
+	return baselines, nil
+}
+
+// GetBaseline obtiene la franja de una variable para un instante (nil si no se ha aprendido)
+func (bs *BaselineService) GetBaseline(serverID uint, metric string, at time.Time) (*models.MetricBaseline, error) {
+	var baselines []models.MetricBaseline
+	if err := bs.db.Where("server_id = ? AND metric = ? AND hour_of_week = ?", serverID, metric, models.HourOfWeek(at)).
+		Limit(1).Find(&baselines).Error; err != nil {
+		return nil, err
+	}
+
+	if len(baselines) == 0 {
+		return nil, nil
+	}
+	return &baselines[0], nil
+}
+
+// DetectAnomalies recorre el historial de un servidor en un rango y devuelve las muestras de la
+// variable cuyo z-score en valor absoluto supera zScore (si es 0 se usa el valor por defecto)
+func (bs *BaselineService) DetectAnomalies(serverID uint, metric string, start, end time.Time, zScore float64) ([]Anomaly, error) {
+	if !models.IsMetricVariable(metric) {
+		return nil, fmt.Errorf("variable de métrica desconocida: %s", metric)
+	}
+	if zScore <= 0 {
+		zScore = bs.defaultZScore
+	}
+
+	baselines, err := bs.GetBaselines(serverID, metric)
+	if err != nil {
+		return nil, err
+	}
+	byHour := make(map[int]*models.MetricBaseline, len(baselines))
+	for i := range baselines {
+		byHour[baselines[i].HourOfWeek] = &baselines[i]
+	}
+
+	var metrics []models.Metric
+	if err := bs.db.Where("server_id = ? AND timestamp BETWEEN ? AND ?", serverID, start, end).
+		Order("timestamp ASC").Find(&metrics).Error; err != nil {
+		return nil, err
+	}
+
+	anomalies := []Anomaly{}
+	for i := range metrics {
+		baseline, ok := byHour[models.HourOfWeek(metrics[i].Timestamp)]
+		if !ok || !baseline.IsReliable() {
+			continue
+		}
+
+		value := models.MetricVariables(&metrics[i])[metric]
+		z := baseline.ZScore(value)
+		if math.Abs(z) <= zScore {
+			continue
+		}
+
+		lower, upper := baseline.Band(zScore)
+		anomalies = append(anomalies, Anomaly{
+			Timestamp: metrics[i].Timestamp,
+			Metric:    metric,
+			Value:     value,
+			Expected:  baseline.Mean,
+			Lower:     lower,
+			Upper:     upper,
+			ZScore:    z,
+		})
+	}
+
+	return anomalies, nil
+}
+
+// Start recalcula las líneas base al arrancar y después periódicamente
+func (bs *BaselineService) Start(interval time.Duration) {
+	bs.stop = make(chan struct{})
+	bs.wg.Add(1)
+
+	go func() {
+		defer bs.wg.Done()
+
+		bs.RecomputeAll(time.Now())
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				bs.RecomputeAll(time.Now())
+			case <-bs.stop:
+				return
+			}
+		}
+	}()
+
+	bs.logger.Infof("Recálculo de líneas base iniciado (intervalo %s, historial %s)", interval, bs.lookback)
+}
+
+// Stop detiene el recálculo periódico de líneas base
+func (bs *BaselineService) Stop() {
+	if bs.stop == nil {
+		return
+	}
+
+	close(bs.stop)
+	bs.wg.Wait()
+	bs.stop = nil
+	bs.logger.Info("Recálculo de líneas base detenido")
+}
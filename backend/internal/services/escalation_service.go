@@ -0,0 +1,398 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+// EscalationService gestiona las políticas de escalado y ejecuta sus pasos en segundo plano
+// para las alertas que siguen sin reconocerse
+type EscalationService struct {
+	db            *gorm.DB
+	logger        logger.Logger
+	notifyManager *notifications.NotificationManager
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewEscalationService crea un nuevo servicio de escalado
+func NewEscalationService(db *gorm.DB, log logger.Logger, notifyManager *notifications.NotificationManager) *EscalationService {
+	return &EscalationService{
+		db:            db,
+		logger:        log,
+		notifyManager: notifyManager,
+	}
+}
+
+// GetAllPolicies obtiene todas las políticas con sus pasos
+func (es *EscalationService) GetAllPolicies() ([]models.EscalationPolicy, error) {
+	var policies []models.EscalationPolicy
+	if err := es.db.Preload("Steps", orderStepsByPosition).Order("name ASC").Find(&policies).Error; err != nil {
+		es.logger.Errorf("Error al obtener políticas de escalado: %v", err)
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// GetPolicy obtiene una política por ID con sus pasos ordenados
+func (es *EscalationService) GetPolicy(id uint) (*models.EscalationPolicy, error) {
+	var policy models.EscalationPolicy
+	if err := es.db.Preload("Steps", orderStepsByPosition).First(&policy, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("política de escalado no encontrada")
+		}
+		es.logger.Errorf("Error al obtener política de escalado: %v", err)
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// CreatePolicy crea una nueva política de escalado
+func (es *EscalationService) CreatePolicy(policy *models.EscalationPolicy) error {
+	if err := es.validatePolicy(policy); err != nil {
+		return err
+	}
+
+	if err := es.db.Create(policy).Error; err != nil {
+		es.logger.Errorf("Error al crear política de escalado: %v", err)
+		return err
+	}
+
+	es.logger.Infof("Política de escalado creada: %s (%d pasos)", policy.Name, len(policy.Steps))
+	return nil
+}
+
+// UpdatePolicy actualiza una política y reemplaza sus pasos
+func (es *EscalationService) UpdatePolicy(policy *models.EscalationPolicy) error {
+	existing, err := es.GetPolicy(policy.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := es.validatePolicy(policy); err != nil {
+		return err
+	}
+
+	policy.CreatedBy = existing.CreatedBy
+	policy.CreatedAt = existing.CreatedAt
+
+	err = es.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+
+		for i := range policy.Steps {
+			policy.Steps[i].ID = 0
+			policy.Steps[i].PolicyID = policy.ID
+		}
+
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(policy).Error
+	})
+	if err != nil {
+		es.logger.Errorf("Error al actualizar política de escalado: %v", err)
+		return err
+	}
+
+	es.logger.Infof("Política de escalado actualizada: %s", policy.Name)
+	return nil
+}
+
+// DeletePolicy elimina una política si ningún umbral la utiliza
+func (es *EscalationService) DeletePolicy(id uint) error {
+	var count int64
+	es.db.Model(&models.AlertThreshold{}).Where("escalation_policy_id = ?", id).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("la política está asignada a %d umbral(es)", count)
+	}
+
+	err := es.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", id).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.EscalationPolicy{}, id).Error
+	})
+	if err != nil {
+		es.logger.Errorf("Error al eliminar política de escalado: %v", err)
+		return err
+	}
+
+	// Detener los escalados en curso que usaban la política
+	es.db.Model(&models.Alert{}).Where("escalation_policy_id = ? AND next_escalation_at IS NOT NULL", id).
+		Update("next_escalation_at", nil)
+
+	es.logger.Infof("Política de escalado eliminada: %d", id)
+	return nil
+}
+
+// GetAlertEscalations obtiene los pasos de escalado ejecutados sobre una alerta
+func (es *EscalationService) GetAlertEscalations(alertID uint) ([]models.AlertEscalation, error) {
+	var escalations []models.AlertEscalation
+	if err := es.db.Where("alert_id = ?", alertID).Order("escalated_at ASC").Find(&escalations).Error; err != nil {
+		return nil, err
+	}
+
+	return escalations, nil
+}
+
+// validatePolicy valida la política, normaliza las posiciones y comprueba los puntos de contacto
+func (es *EscalationService) validatePolicy(policy *models.EscalationPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	for i := range policy.Steps {
+		policy.Steps[i].Position = i
+		for _, id := range policy.Steps[i].ContactPointIDs {
+			ids[id] = true
+		}
+	}
+
+	contactPointIDs := make([]uint, 0, len(ids))
+	for id := range ids {
+		contactPointIDs = append(contactPointIDs, id)
+	}
+
+	var count int64
+	if err := es.db.Model(&models.ContactPoint{}).Where("id IN ?", contactPointIDs).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if int(count) != len(contactPointIDs) {
+		return fmt.Errorf("uno o más puntos de contacto no existen")
+	}
+
+	return nil
+}
+
+// StartEscalation asigna a una alerta recién creada la política del umbral o, si no tiene,
+// la primera política habilitada para su severidad, y programa el primer paso
+func (es *EscalationService) StartEscalation(alert *models.Alert, threshold *models.AlertThreshold) {
+	es.scheduleEscalation(alert, threshold, alert.TriggeredAt)
+}
+
+// RestartEscalation vuelve a empezar el escalado desde el primer paso, contando el retardo
+// desde ahora (alertas reabiertas, con el reconocimiento retirado o que vuelven de posponerse)
+func (es *EscalationService) RestartEscalation(alert *models.Alert, threshold *models.AlertThreshold) {
+	es.scheduleEscalation(alert, threshold, time.Now())
+}
+
+// scheduleEscalation asigna la política de la alerta y programa su primer paso a partir de start
+func (es *EscalationService) scheduleEscalation(alert *models.Alert, threshold *models.AlertThreshold, start time.Time) {
+	policy, err := es.policyForAlert(alert, threshold)
+	if err != nil {
+		es.logger.Warnf("Error al obtener política de escalado para la alerta %d: %v", alert.ID, err)
+		return
+	}
+	if policy == nil {
+		return
+	}
+
+	step, ok := policy.StepAt(0)
+	if !ok {
+		return
+	}
+
+	next := start.Add(time.Duration(step.DelayMinutes) * time.Minute)
+	if err := es.db.Model(alert).Updates(map[string]interface{}{
+		"escalation_policy_id": policy.ID,
+		"escalation_level":     0,
+		"next_escalation_at":   next,
+	}).Error; err != nil {
+		es.logger.Errorf("Error al programar escalado de la alerta %d: %v", alert.ID, err)
+		return
+	}
+
+	es.logger.Infof("Alerta %d asignada a la política de escalado %s (primer paso a las %s)",
+		alert.ID, policy.Name, next.Format(time.RFC3339))
+}
+
+// policyForAlert determina la política de escalado aplicable a una alerta
+func (es *EscalationService) policyForAlert(alert *models.Alert, threshold *models.AlertThreshold) (*models.EscalationPolicy, error) {
+	if threshold != nil && threshold.EscalationPolicyID != nil {
+		policy, err := es.GetPolicy(*threshold.EscalationPolicyID)
+		if err != nil {
+			return nil, err
+		}
+		if !policy.Enabled {
+			return nil, nil
+		}
+		return policy, nil
+	}
+
+	policies, err := es.GetAllPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range policies {
+		if policies[i].AppliesToSeverity(alert.Severity) {
+			return &policies[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Start inicia la comprobación periódica de escalados pendientes
+func (es *EscalationService) Start(interval time.Duration) {
+	es.stop = make(chan struct{})
+	es.wg.Add(1)
+
+	go func() {
+		defer es.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				es.ProcessDueEscalations(time.Now())
+			case <-es.stop:
+				return
+			}
+		}
+	}()
+
+	es.logger.Infof("Planificador de escalado iniciado (intervalo %s)", interval)
+}
+
+// Stop detiene el planificador de escalado
+func (es *EscalationService) Stop() {
+	if es.stop == nil {
+		return
+	}
+
+	close(es.stop)
+	es.wg.Wait()
+	es.stop = nil
+	es.logger.Info("Planificador de escalado detenido")
+}
+
+// ProcessDueEscalations ejecuta los pasos de escalado vencidos de las alertas sin reconocer
+func (es *EscalationService) ProcessDueEscalations(now time.Time) {
+	var alerts []models.Alert
+	if err := es.db.Where("status = ? AND acknowledged_at IS NULL AND next_escalation_at IS NOT NULL AND next_escalation_at <= ?",
+		models.AlertStatusActive, now).Preload("Server").Find(&alerts).Error; err != nil {
+		es.logger.Errorf("Error al obtener alertas pendientes de escalado: %v", err)
+		return
+	}
+
+	for i := range alerts {
+		es.escalate(&alerts[i], now)
+	}
+}
+
+// escalate ejecuta el siguiente paso de escalado de una alerta y programa el posterior
+func (es *EscalationService) escalate(alert *models.Alert, now time.Time) {
+	if alert.EscalationPolicyID == nil {
+		return
+	}
+
+	policy, err := es.GetPolicy(*alert.EscalationPolicyID)
+	if err != nil {
+		es.logger.Warnf("Escalado de la alerta %d detenido: %v", alert.ID, err)
+		es.db.Model(alert).Update("next_escalation_at", nil)
+		return
+	}
+
+	level := alert.EscalationLevel
+	step, ok := policy.StepAt(level)
+	if !ok {
+		es.db.Model(alert).Update("next_escalation_at", nil)
+		return
+	}
+
+	// Calcular el siguiente paso (nulo si la política se ha agotado)
+	var next *time.Time
+	if nextStep, ok := policy.StepAt(level + 1); ok {
+		t := now.Add(time.Duration(nextStep.DelayMinutes) * time.Minute)
+		next = &t
+	}
+
+	// Reclamar el paso de forma atómica: si la alerta se reconoció o resolvió entretanto,
+	// o si otra instancia ya ejecutó el paso, no se notifica
+	result := es.db.Model(&models.Alert{}).
+		Where("id = ? AND escalation_level = ? AND status = ? AND acknowledged_at IS NULL", alert.ID, level, models.AlertStatusActive).
+		Updates(map[string]interface{}{
+			"escalation_level":   level + 1,
+			"next_escalation_at": next,
+		})
+	if result.Error != nil {
+		es.logger.Errorf("Error al actualizar escalado de la alerta %d: %v", alert.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	channels := es.notifyManager.NotifyContactPoints(alert, step.ContactPointIDs)
+
+	record := models.AlertEscalation{
+		AlertID:         alert.ID,
+		PolicyID:        policy.ID,
+		Level:           level,
+		StepPosition:    step.Position,
+		Repetition:      level / len(policy.Steps),
+		ContactPointIDs: step.ContactPointIDs,
+		NotifyChannels:  channels,
+		EscalatedAt:     now,
+	}
+	if err := es.db.Create(&record).Error; err != nil {
+		es.logger.Errorf("Error al registrar escalado de la alerta %d: %v", alert.ID, err)
+	}
+
+	recordAlertEvent(es.db, es.logger, models.AlertEvent{
+		AlertID:   alert.ID,
+		Type:      models.AlertEventEscalated,
+		Channel:   strings.Join(channels, ","),
+		Message:   fmt.Sprintf("Política %s, paso %d, repetición %d", policy.Name, step.Position+1, record.Repetition),
+		CreatedAt: now,
+	})
+
+	// Añadir los nuevos canales para que también reciban la resolución
+	if added := mergeChannels(alert.NotifyChannels, channels); len(added) != len(alert.NotifyChannels) {
+		alert.NotifyChannels = added
+		// Los canales se serializan como JSON: con Update(columna, valor) no se aplicaría el serializador
+		if err := es.db.Model(alert).Select("notify_channels").Updates(alert).Error; err != nil {
+			es.logger.Errorf("Error al guardar los canales de escalado de la alerta %d: %v", alert.ID, err)
+		}
+	}
+
+	es.logger.Infof("Alerta %d escalada (política %s, paso %d, repetición %d) a %d canal(es)",
+		alert.ID, policy.Name, step.Position+1, record.Repetition, len(channels))
+}
+
+// mergeChannels añade a existing los canales que aún no contiene
+func mergeChannels(existing, channels []string) []string {
+	merged := append([]string{}, existing...)
+	seen := make(map[string]bool, len(existing))
+	for _, channel := range existing {
+		seen[channel] = true
+	}
+
+	for _, channel := range channels {
+		if !seen[channel] {
+			seen[channel] = true
+			merged = append(merged, channel)
+		}
+	}
+
+	return merged
+}
+
+// orderStepsByPosition ordena los pasos precargados de una política
+func orderStepsByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ExternalAlertCheckInterval frecuencia con la que se resuelven las alertas externas vencidas
+const ExternalAlertCheckInterval = 30 * time.Second
+
+// ErrInvalidExternalAlert indica que un envío contiene alguna alerta mal formada
+var ErrInvalidExternalAlert = errors.New("alerta externa inválida")
+
+// labelNamePattern nombres de etiqueta válidos en Prometheus
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// PostableAlert alerta en el formato de POST /api/v2/alerts de Alertmanager
+type PostableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+// ExternalAlertResult resumen de un envío de alertas externas
+type ExternalAlertResult struct {
+	Created  int `json:"created"`  // Alertas nuevas
+	Updated  int `json:"updated"`  // Alertas abiertas renovadas por la fuente
+	Resolved int `json:"resolved"` // Alertas resueltas por su endsAt
+	Ignored  int `json:"ignored"`  // Sin servidor conocido o resueltas sin alerta abierta
+	Failed   int `json:"failed"`   // No se pudieron procesar (el resto del envío sí se aplica)
+
+	Errors []string `json:"errors,omitempty"` // Motivo de cada fallo, con la posición en el envío
+}
+
+// ExternalAlertService recibe alertas de Prometheus/Alertmanager, las asocia a un servidor por
+// la etiqueta instance y las gestiona como alertas propias: silencios, inhibición, incidentes,
+// enrutamiento y notificaciones. Se resuelven cuando llega su endsAt
+type ExternalAlertService struct {
+	db             *gorm.DB
+	logger         logger.Logger
+	alertService   *AlertService
+	resolveTimeout time.Duration // Vencimiento de las alertas recibidas sin endsAt
+
+	mu   sync.Mutex // Serializa los envíos para no duplicar alertas con la misma huella
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewExternalAlertService crea un nuevo servicio de alertas externas
+func NewExternalAlertService(db *gorm.DB, log logger.Logger, alertService *AlertService, resolveTimeout time.Duration) *ExternalAlertService {
+	if resolveTimeout <= 0 {
+		resolveTimeout = 5 * time.Minute
+	}
+
+	return &ExternalAlertService{
+		db:             db,
+		logger:         log,
+		alertService:   alertService,
+		resolveTimeout: resolveTimeout,
+	}
+}
+
+// ValidatePostableAlert comprueba una alerta recibida con las mismas reglas que Alertmanager
+func ValidatePostableAlert(alert *PostableAlert) error {
+	if len(alert.Labels) == 0 {
+		return fmt.Errorf("la alerta debe tener al menos una etiqueta")
+	}
+
+	for name, value := range alert.Labels {
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("nombre de etiqueta inválido: %q", name)
+		}
+		if value == "" {
+			return fmt.Errorf("la etiqueta %s está vacía", name)
+		}
+	}
+
+	if !alert.StartsAt.IsZero() && !alert.EndsAt.IsZero() && alert.EndsAt.Before(alert.StartsAt) {
+		return fmt.Errorf("endsAt no puede ser anterior a startsAt")
+	}
+
+	return nil
+}
+
+// Ingest procesa un envío de alertas. Todas se validan antes de aplicar ninguna; un fallo al
+// aplicar una alerta no impide procesar las demás. Solo se devuelve error si fallan todas
+func (es *ExternalAlertService) Ingest(alerts []PostableAlert) (*ExternalAlertResult, error) {
+	for i := range alerts {
+		if err := ValidatePostableAlert(&alerts[i]); err != nil {
+			return nil, fmt.Errorf("%w (posición %d): %v", ErrInvalidExternalAlert, i, err)
+		}
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	result := &ExternalAlertResult{}
+	servers := make(map[string]*models.Server)
+
+	for i := range alerts {
+		if err := es.ingestAlert(&alerts[i], servers, result); err != nil {
+			es.logger.Errorf("Error al procesar la alerta externa %s (posición %d): %v",
+				alerts[i].Labels["alertname"], i, err)
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("posición %d: no se pudo procesar la alerta", i))
+		}
+	}
+
+	if result.Failed > 0 && result.Failed == len(alerts) {
+		return result, fmt.Errorf("no se pudo procesar ninguna de las %d alertas", len(alerts))
+	}
+
+	return result, nil
+}
+
+// ingestAlert crea, renueva o resuelve la alerta correspondiente a una alerta recibida
+func (es *ExternalAlertService) ingestAlert(incoming *PostableAlert, servers map[string]*models.Server, result *ExternalAlertResult) error {
+	now := time.Now()
+	fingerprint := labelsFingerprint(incoming.Labels)
+
+	endsAt := incoming.EndsAt
+	resolved := !endsAt.IsZero() && !endsAt.After(now)
+	if endsAt.IsZero() {
+		endsAt = now.Add(es.resolveTimeout)
+	}
+
+	existing, err := es.openAlert(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if resolved {
+		if existing == nil {
+			result.Ignored++
+			return nil
+		}
+		if err := es.alertService.ResolveAlertFromSource(existing.ID, "Resuelta por la fuente externa", endsAt); err != nil {
+			return err
+		}
+		result.Resolved++
+		return nil
+	}
+
+	// La fuente reenvía las alertas activas periódicamente: se renueva el vencimiento
+	if existing != nil {
+		existing.EndsAt = &endsAt
+		existing.Annotations = incoming.Annotations
+		existing.GeneratorURL = incoming.GeneratorURL
+		// Las anotaciones se serializan como JSON: con un mapa en Updates no se aplicaría el serializador
+		if err := es.db.Model(existing).Select("ends_at", "annotations", "generator_url").Updates(existing).Error; err != nil {
+			es.logger.Errorf("Error al renovar la alerta externa %d: %v", existing.ID, err)
+			return err
+		}
+		result.Updated++
+		return nil
+	}
+
+	instance := incoming.Labels["instance"]
+	server, ok := servers[instance]
+	if !ok {
+		server, err = es.serverForInstance(instance)
+		if err != nil {
+			return err
+		}
+		servers[instance] = server
+	}
+	if server == nil {
+		es.logger.Warnf("Alerta externa %s ignorada: ningún servidor coincide con instance=%q",
+			incoming.Labels["alertname"], instance)
+		result.Ignored++
+		return nil
+	}
+
+	triggeredAt := incoming.StartsAt
+	if triggeredAt.IsZero() {
+		triggeredAt = now
+	}
+
+	title, message := externalAlertText(incoming, server)
+	alert := &models.Alert{
+		Title:        title,
+		Message:      message,
+		MetricType:   models.MetricTypeExternal,
+		Severity:     externalAlertSeverity(incoming.Labels["severity"]),
+		Status:       models.AlertStatusActive,
+		ServerID:     server.ID,
+		TriggeredAt:  triggeredAt,
+		Fingerprint:  fingerprint,
+		Labels:       incoming.Labels,
+		Annotations:  incoming.Annotations,
+		GeneratorURL: incoming.GeneratorURL,
+		EndsAt:       &endsAt,
+	}
+
+	if err := es.alertService.CreateAlert(alert); err != nil {
+		return err
+	}
+	result.Created++
+	return nil
+}
+
+// openAlert alerta externa sin resolver con la huella indicada, o nil si no hay
+func (es *ExternalAlertService) openAlert(fingerprint string) (*models.Alert, error) {
+	var alert models.Alert
+	err := es.db.Where("fingerprint = ? AND metric_type = ? AND status <> ?", fingerprint,
+		models.MetricTypeExternal, models.AlertStatusResolved).
+		Order("id DESC").First(&alert).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		es.logger.Errorf("Error al buscar la alerta externa %s: %v", fingerprint, err)
+		return nil, err
+	}
+
+	return &alert, nil
+}
+
+// serverForInstance busca el servidor de la etiqueta instance ("host:puerto"): por hostname o
+// IP exactos y, si no hay, por el primer componente del nombre (web01.example.com -> web01).
+// Este último solo se usa si identifica a un único servidor
+func (es *ExternalAlertService) serverForInstance(instance string) (*models.Server, error) {
+	host := instance
+	if h, _, err := net.SplitHostPort(instance); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "" {
+		return nil, nil
+	}
+
+	var server models.Server
+	err := es.db.Where("LOWER(hostname) = LOWER(?) OR ip = ?", host, host).First(&server).Error
+	if err == nil {
+		return &server, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		es.logger.Errorf("Error al buscar el servidor de instance=%q: %v", instance, err)
+		return nil, err
+	}
+
+	short, _, found := strings.Cut(host, ".")
+	if !found || net.ParseIP(host) != nil {
+		return nil, nil
+	}
+
+	// Un nombre corto compartido por varios servidores no identifica a ninguno
+	var servers []models.Server
+	if err := es.db.Where("LOWER(hostname) = LOWER(?)", short).Limit(2).Find(&servers).Error; err != nil {
+		es.logger.Errorf("Error al buscar el servidor de instance=%q: %v", instance, err)
+		return nil, err
+	}
+	if len(servers) != 1 {
+		if len(servers) > 1 {
+			es.logger.Warnf("instance=%q coincide con varios servidores por nombre corto, se ignora", instance)
+		}
+		return nil, nil
+	}
+
+	return &servers[0], nil
+}
+
+// Start inicia la resolución periódica de las alertas externas vencidas
+func (es *ExternalAlertService) Start(interval time.Duration) {
+	es.stop = make(chan struct{})
+	es.wg.Add(1)
+
+	go func() {
+		defer es.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				es.ProcessExpiredAlerts(time.Now())
+			case <-es.stop:
+				return
+			}
+		}
+	}()
+
+	es.logger.Infof("Planificador de alertas externas iniciado (intervalo %s)", interval)
+}
+
+// Stop detiene el planificador de alertas externas
+func (es *ExternalAlertService) Stop() {
+	if es.stop == nil {
+		return
+	}
+
+	close(es.stop)
+	es.wg.Wait()
+	es.stop = nil
+	es.logger.Info("Planificador de alertas externas detenido")
+}
+
+// ProcessExpiredAlerts resuelve las alertas externas que la fuente ha dejado de renovar
+func (es *ExternalAlertService) ProcessExpiredAlerts(now time.Time) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	var ids []uint
+	if err := es.db.Model(&models.Alert{}).
+		Where("metric_type = ? AND status <> ? AND ends_at IS NOT NULL AND ends_at <= ?",
+			models.MetricTypeExternal, models.AlertStatusResolved, now).
+		Pluck("id", &ids).Error; err != nil {
+		es.logger.Errorf("Error al obtener alertas externas vencidas: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := es.alertService.ResolveAlertFromSource(id, "Resuelta al vencer sin que la fuente externa la renovara", now); err != nil {
+			es.logger.Errorf("Error al resolver la alerta externa vencida %d: %v", id, err)
+		}
+	}
+}
+
+// labelsFingerprint huella de un conjunto de etiquetas, independiente de su orden
+func labelsFingerprint(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := fnv.New64a()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0xff})
+		hash.Write([]byte(labels[name]))
+		hash.Write([]byte{0xff})
+	}
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// externalAlertSeverity traduce la etiqueta severity de Prometheus (warning por defecto)
+func externalAlertSeverity(label string) models.AlertSeverity {
+	switch strings.ToLower(label) {
+	case "critical", "page", "error":
+		return models.AlertSeverityCritical
+	case "info", "informational", "none":
+		return models.AlertSeverityInfo
+	default:
+		return models.AlertSeverityWarning
+	}
+}
+
+// externalAlertText título y mensaje de una alerta externa a partir de alertname y de las
+// anotaciones summary y description
+func externalAlertText(alert *PostableAlert, server *models.Server) (string, string) {
+	name := alert.Labels["alertname"]
+	if name == "" {
+		name = "Alerta externa"
+	}
+
+	title := fmt.Sprintf("%s en %s", name, server.Hostname)
+	if summary := alert.Annotations["summary"]; summary != "" {
+		title = summary
+	}
+	if runes := []rune(title); len(runes) > 200 {
+		title = string(runes[:200])
+	}
+
+	message := alert.Annotations["description"]
+	if message == "" {
+		message = alert.Annotations["summary"]
+	}
+	if message == "" {
+		message = fmt.Sprintf("Alerta %s recibida de una fuente externa", name)
+	}
+
+	return title, message
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/forecast"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// DefaultForecastWindow historial utilizado por defecto para ajustar las previsiones
+const DefaultForecastWindow = 24 * time.Hour
+
+// MaxForecastWindow historial máximo que se carga para una previsión
+const MaxForecastWindow = 7 * 24 * time.Hour
+
+// ErrForecastServerNotFound el servidor de la previsión no existe
+var ErrForecastServerNotFound = errors.New("servidor no encontrado")
+
+// MinForecastConfidence confianza mínima para que una previsión dispare una alerta predictiva
+const MinForecastConfidence = 0.5
+
+// Recursos con previsión de agotamiento
+const (
+	ForecastResourceDisk   = "disk"
+	ForecastResourceMemory = "memory"
+)
+
+// ResourceForecast previsión de agotamiento de un recurso
+type ResourceForecast struct {
+	Resource        string     `json:"resource"`
+	Method          string     `json:"method"`
+	Used            int64      `json:"used"`               // Uso en la última muestra (bytes)
+	Total           int64      `json:"total"`              // Capacidad en la última muestra (bytes)
+	UsedPercent     float64    `json:"used_percent"`       // Porcentaje de uso en la última muestra
+	GrowthPerHour   float64    `json:"growth_per_hour"`    // Crecimiento estimado en bytes por hora
+	Trend           string     `json:"trend"`              // growing, stable o decreasing
+	Confidence      float64    `json:"confidence"`         // Bondad del ajuste (0-1)
+	Samples         int        `json:"samples"`            // Muestras utilizadas
+	TimeToFullHours *float64   `json:"time_to_full_hours"` // Nulo si no se prevé que se llene
+	EstimatedFullAt *time.Time `json:"estimated_full_at"`
+	EarliestFullAt  *time.Time `json:"earliest_full_at,omitempty"` // Intervalo aprox. del 95% (solo lineal)
+	LatestFullAt    *time.Time `json:"latest_full_at,omitempty"`
+}
+
+// ServerForecast previsiones de disco y memoria de un servidor
+type ServerForecast struct {
+	ServerID    uint              `json:"server_id"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	WindowHours float64           `json:"window_hours"`
+	Disk        *ResourceForecast `json:"disk"`   // Nulo si no hay historial suficiente
+	Memory      *ResourceForecast `json:"memory"` // Nulo si no hay historial suficiente
+}
+
+// ForecastService calcula previsiones de agotamiento de disco y memoria
+type ForecastService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewForecastService crea una nueva instancia del servicio de previsiones
+func NewForecastService(db *gorm.DB, log logger.Logger) *ForecastService {
+	return &ForecastService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// GetServerForecast calcula las previsiones de un servidor con el historial de la ventana indicada
+func (fs *ForecastService) GetServerForecast(serverID uint, window time.Duration, method forecast.Method) (*ServerForecast, error) {
+	if window <= 0 {
+		window = DefaultForecastWindow
+	}
+	if window > MaxForecastWindow {
+		window = MaxForecastWindow
+	}
+
+	var count int64
+	if err := fs.db.Model(&models.Server{}).Where("id = ?", serverID).Count(&count).Error; err != nil {
+		fs.logger.Errorf("Error al comprobar el servidor %d para la previsión: %v", serverID, err)
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrForecastServerNotFound
+	}
+
+	now := time.Now()
+	var metrics []models.Metric
+	if err := fs.db.Where("server_id = ? AND timestamp BETWEEN ? AND ?", serverID, now.Add(-window), now).
+		Order("timestamp ASC").Find(&metrics).Error; err != nil {
+		fs.logger.Errorf("Error al obtener historial para la previsión del servidor %d: %v", serverID, err)
+		return nil, err
+	}
+
+	result := &ServerForecast{
+		ServerID:    serverID,
+		GeneratedAt: now,
+		WindowHours: window.Hours(),
+	}
+
+	if disk, err := ForecastResource(metrics, ForecastResourceDisk, method); err == nil {
+		result.Disk = disk
+	}
+	if memory, err := ForecastResource(metrics, ForecastResourceMemory, method); err == nil {
+		result.Memory = memory
+	}
+
+	return result, nil
+}
+
+// ForecastResource ajusta el modelo sobre el uso de disco o memoria de una serie de métricas
+// (ordenada por fecha ascendente) y estima cuándo se alcanzará la capacidad
+func ForecastResource(metrics []models.Metric, resource string, method forecast.Method) (*ResourceForecast, error) {
+	points := make([]forecast.Point, 0, len(metrics))
+	var used, total int64
+
+	for _, m := range metrics {
+		u, t := resourceUsage(&m, resource)
+		if t == 0 {
+			continue
+		}
+		points = append(points, forecast.Point{Time: m.Timestamp, Value: float64(u)})
+		used, total = u, t
+	}
+
+	model, err := forecast.Fit(method, points)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ResourceForecast{
+		Resource:      resource,
+		Method:        string(model.Method),
+		Used:          used,
+		Total:         total,
+		UsedPercent:   float64(used) / float64(total) * 100,
+		GrowthPerHour: model.Slope,
+		Confidence:    model.Confidence,
+		Samples:       model.Samples,
+		Trend:         trendLabel(model.Slope, float64(total)),
+	}
+
+	capacity := float64(total)
+	if ttf, ok := model.TimeToReach(capacity); ok {
+		hours := ttf.Hours()
+		fullAt := model.LastTime.Add(ttf)
+		result.TimeToFullHours = &hours
+		result.EstimatedFullAt = &fullAt
+	}
+
+	earliest, latest, okEarliest, okLatest := model.TimeToReachRange(capacity)
+	if okEarliest {
+		at := model.LastTime.Add(earliest)
+		result.EarliestFullAt = &at
+	}
+	if okLatest {
+		at := model.LastTime.Add(latest)
+		result.LatestFullAt = &at
+	}
+
+	return result, nil
+}
+
+// resourceUsage uso y capacidad de un recurso en una métrica
+func resourceUsage(m *models.Metric, resource string) (used, total int64) {
+	if resource == ForecastResourceMemory {
+		return m.MemoryUsed, m.MemoryTotal
+	}
+	return m.DiskUsed, m.DiskTotal
+}
+
+// forecastResourceFor recurso previsto por un tipo de métrica ("" si no admite previsión)
+func forecastResourceFor(metricType models.MetricType) string {
+	switch metricType {
+	case models.MetricTypeDisk:
+		return ForecastResourceDisk
+	case models.MetricTypeMemory:
+		return ForecastResourceMemory
+	}
+	return ""
+}
+
+// trendLabel clasifica la tendencia: se considera estable si varía menos de un 0.1% de la capacidad por hora
+func trendLabel(slope, capacity float64) string {
+	switch {
+	case slope > capacity*0.001:
+		return "growing"
+	case slope < -capacity*0.001:
+		return "decreasing"
+	default:
+		return "stable"
+	}
+}
@@ -0,0 +1,849 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+// IncidentNotifyInterval frecuencia con la que se envían los resúmenes de incidentes pendientes
+const IncidentNotifyInterval = 5 * time.Second
+
+// maxIncidentSummaryAlerts alertas que se enumeran en el resumen de un incidente
+const maxIncidentSummaryAlerts = 10
+
+// IncidentSettings configuración de la agrupación de alertas en incidentes
+type IncidentSettings struct {
+	Enabled bool
+	GroupBy []string      // Claves de agrupación, ver models.IncidentKey*
+	Window  time.Duration // Tiempo tras la última alerta durante el que se añaden nuevas al incidente
+	Wait    time.Duration // Espera antes de enviar el resumen
+}
+
+// IncidentService agrupa las alertas relacionadas en incidentes, envía una única notificación
+// resumen por incidente y gestiona su ciclo de vida (abierto, reconocido, resuelto)
+type IncidentService struct {
+	db                *gorm.DB
+	logger            logger.Logger
+	notifyManager     *notifications.NotificationManager
+	escalationService *EscalationService
+	settings          IncidentSettings
+
+	mu   sync.Mutex // Serializa la asignación de alertas para no crear incidentes duplicados
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewIncidentService crea un nuevo servicio de incidentes. Las claves de agrupación desconocidas se ignoran
+func NewIncidentService(db *gorm.DB, log logger.Logger, notifyManager *notifications.NotificationManager, settings IncidentSettings) *IncidentService {
+	var groupBy []string
+	for _, key := range settings.GroupBy {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if err := models.ValidateIncidentGroupKey(key); err != nil {
+			log.Warnf("Agrupación de incidentes: %v", err)
+			continue
+		}
+		groupBy = append(groupBy, key)
+	}
+	settings.GroupBy = groupBy
+
+	return &IncidentService{
+		db:            db,
+		logger:        log,
+		notifyManager: notifyManager,
+		settings:      settings,
+	}
+}
+
+// SetEscalationService establece el servicio que programa el escalado de los incidentes notificados
+func (is *IncidentService) SetEscalationService(escalationService *EscalationService) {
+	is.escalationService = escalationService
+}
+
+// Enabled indica si las alertas nuevas se agrupan en incidentes
+func (is *IncidentService) Enabled() bool {
+	return is.settings.Enabled && len(is.settings.GroupBy) > 0
+}
+
+// GetIncidents obtiene los incidentes, con filtro opcional por estado. Si serverIDs no es nil
+// solo se devuelven los incidentes con alguna alerta de esos servidores
+func (is *IncidentService) GetIncidents(status models.IncidentStatus, serverIDs []uint) ([]models.Incident, error) {
+	incidents := []models.Incident{}
+	query := is.db.Order("opened_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if serverIDs != nil {
+		if len(serverIDs) == 0 {
+			return incidents, nil
+		}
+		query = query.Where("id IN (?)", is.db.Model(&models.Alert{}).
+			Select("incident_id").
+			Where("incident_id IS NOT NULL AND server_id IN ?", serverIDs))
+	}
+
+	if err := query.Find(&incidents).Error; err != nil {
+		is.logger.Errorf("Error al obtener incidentes: %v", err)
+		return nil, err
+	}
+
+	return incidents, nil
+}
+
+// GetIncident obtiene un incidente con sus alertas
+func (is *IncidentService) GetIncident(id uint) (*models.Incident, error) {
+	var incident models.Incident
+	if err := is.db.Preload("Alerts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("triggered_at ASC")
+	}).Preload("Alerts.Server").First(&incident, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("incidente no encontrado")
+		}
+		is.logger.Errorf("Error al obtener incidente: %v", err)
+		return nil, err
+	}
+
+	return &incident, nil
+}
+
+// AttachAlert añade una alerta recién creada al incidente abierto con su misma clave de
+// agrupación o crea uno nuevo. Devuelve false si la alerta no se agrupa (agrupación
+// deshabilitada o el servidor no tiene valor para alguna de las claves) y debe notificarse sola
+func (is *IncidentService) AttachAlert(alert *models.Alert) (bool, error) {
+	if !is.Enabled() {
+		return false, nil
+	}
+
+	labels, err := is.groupLabels(alert)
+	if err != nil {
+		return false, err
+	}
+	if labels == nil {
+		return false, nil
+	}
+	key := is.groupKey(labels)
+
+	now := alert.TriggeredAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	var incident models.Incident
+	err = is.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("group_key = ? AND status IN ? AND last_alert_at >= ?", key,
+			[]models.IncidentStatus{models.IncidentStatusOpen, models.IncidentStatusAcknowledged},
+			now.Add(-is.settings.Window)).
+			Order("id DESC").First(&incident).Error
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			notifyAt := now.Add(is.settings.Wait)
+			incident = models.Incident{
+				Title:       fmt.Sprintf("Incidente en %s", key),
+				Status:      models.IncidentStatusOpen,
+				Severity:    alert.Severity,
+				GroupKey:    key,
+				GroupLabels: labels,
+				AlertCount:  1,
+				OpenedAt:    now,
+				LastAlertAt: now,
+				NotifyAt:    &notifyAt,
+			}
+			if err := tx.Create(&incident).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			updates := map[string]interface{}{
+				"alert_count":   gorm.Expr("alert_count + 1"),
+				"last_alert_at": now,
+			}
+			if models.SeverityRank(alert.Severity) > models.SeverityRank(incident.Severity) {
+				updates["severity"] = alert.Severity
+			}
+			if err := tx.Model(&incident).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(alert).Update("incident_id", incident.ID).Error
+	})
+	if err != nil {
+		is.logger.Errorf("Error al agrupar la alerta %d en un incidente: %v", alert.ID, err)
+		return false, err
+	}
+
+	alert.IncidentID = &incident.ID
+	is.logger.Infof("Alerta %d agrupada en el incidente %d (%s)", alert.ID, incident.ID, key)
+	return true, nil
+}
+
+// groupLabels valores de las claves de agrupación para una alerta, o nil si falta alguno
+func (is *IncidentService) groupLabels(alert *models.Alert) (map[string]string, error) {
+	if alert.Server.ID == 0 {
+		if err := is.db.First(&alert.Server, alert.ServerID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	labels := make(map[string]string, len(is.settings.GroupBy))
+	for _, key := range is.settings.GroupBy {
+		var value string
+
+		switch key {
+		case models.IncidentKeyLocation:
+			value = alert.Server.Location
+		case models.IncidentKeyServer:
+			value = strconv.FormatUint(uint64(alert.ServerID), 10)
+		case models.IncidentKeySeverity:
+			value = string(alert.Severity)
+		case models.IncidentKeyMetricType:
+			value = string(alert.MetricType)
+		case models.IncidentKeyGroup:
+			var groupIDs []uint
+			if err := is.db.Table("server_group_servers").Where("server_id = ?", alert.ServerID).
+				Order("server_group_id").Limit(1).Pluck("server_group_id", &groupIDs).Error; err != nil {
+				return nil, err
+			}
+			if len(groupIDs) > 0 {
+				value = strconv.FormatUint(uint64(groupIDs[0]), 10)
+			}
+		default:
+			prefix := strings.TrimPrefix(key, models.IncidentKeyTagPrefix)
+			for _, tag := range alert.Server.Tags {
+				if strings.HasPrefix(tag, prefix) {
+					value = tag
+					break
+				}
+			}
+		}
+
+		if value == "" {
+			return nil, nil
+		}
+		labels[key] = value
+	}
+
+	return labels, nil
+}
+
+// groupKey clave de agrupación en el orden configurado, p. ej. "location=Madrid-DC1,severity=critical"
+func (is *IncidentService) groupKey(labels map[string]string) string {
+	parts := make([]string, 0, len(is.settings.GroupBy))
+	for _, key := range is.settings.GroupBy {
+		parts = append(parts, key+"="+labels[key])
+	}
+	return strings.Join(parts, ",")
+}
+
+// Start inicia el envío periódico de los resúmenes de incidentes
+func (is *IncidentService) Start(interval time.Duration) {
+	is.stop = make(chan struct{})
+	is.wg.Add(1)
+
+	go func() {
+		defer is.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				is.ProcessPendingNotifications(time.Now())
+			case <-is.stop:
+				return
+			}
+		}
+	}()
+
+	is.logger.Infof("Agrupación de alertas en incidentes iniciada (claves %s, ventana %s, espera %s)",
+		strings.Join(is.settings.GroupBy, ","), is.settings.Window, is.settings.Wait)
+}
+
+// Stop detiene el envío de resúmenes
+func (is *IncidentService) Stop() {
+	if is.stop == nil {
+		return
+	}
+
+	close(is.stop)
+	is.wg.Wait()
+	is.stop = nil
+	is.logger.Info("Agrupación de alertas en incidentes detenida")
+}
+
+// ProcessPendingNotifications notifica los incidentes abiertos cuya espera ha terminado
+func (is *IncidentService) ProcessPendingNotifications(now time.Time) {
+	var incidents []models.Incident
+	if err := is.db.Where("status = ? AND notified_at IS NULL AND notify_at IS NOT NULL AND notify_at <= ?",
+		models.IncidentStatusOpen, now).Find(&incidents).Error; err != nil {
+		is.logger.Errorf("Error al obtener incidentes pendientes de notificar: %v", err)
+		return
+	}
+
+	for i := range incidents {
+		is.notifyIncident(&incidents[i], now)
+	}
+}
+
+// notifyIncident envía la notificación del incidente: la propia alerta si solo tiene una,
+// o un resumen con sus alertas. El escalado sigue a la primera alerta del incidente
+func (is *IncidentService) notifyIncident(incident *models.Incident, now time.Time) {
+	members, err := is.incidentAlerts(incident.ID)
+	if err != nil {
+		is.logger.Errorf("Error al obtener las alertas del incidente %d: %v", incident.ID, err)
+		return
+	}
+
+	if len(members) == 0 {
+		is.db.Model(incident).Update("notify_at", nil)
+		return
+	}
+
+	lead := &members[0]
+	var threshold *models.AlertThreshold
+	if lead.ThresholdID != 0 {
+		var t models.AlertThreshold
+		if err := is.db.First(&t, lead.ThresholdID).Error; err == nil {
+			threshold = &t
+		}
+	}
+
+	if len(members) == 1 {
+		// Un incidente con una sola alerta se notifica como la alerta original
+		if err := is.notifyManager.NotifyAlert(lead, threshold); err != nil {
+			is.logger.Errorf("Error al enviar notificaciones para alerta %d: %v", lead.ID, err)
+		} else {
+			lead.NotifiedAt = &now
+			// Los canales se serializan como JSON: con un mapa en Updates no se aplicaría el serializador
+			if err := is.db.Model(lead).Select("notified_at", "notify_channels").Updates(lead).Error; err != nil {
+				is.logger.Errorf("Error al registrar la notificación de la alerta %d: %v", lead.ID, err)
+			}
+			recordNotifiedEvents(is.db, is.logger, lead.ID, lead.NotifyChannels, "")
+		}
+	} else {
+		summary := is.summaryAlert(incident, members)
+		if err := is.notifyManager.NotifyAlert(summary, threshold); err != nil {
+			is.logger.Errorf("Error al enviar notificaciones para el incidente %d: %v", incident.ID, err)
+		} else {
+			incident.NotifyChannels = summary.NotifyChannels
+			message := fmt.Sprintf("Notificada en el resumen del incidente %d", incident.ID)
+			for _, member := range members {
+				recordNotifiedEvents(is.db, is.logger, member.ID, summary.NotifyChannels, message)
+			}
+		}
+	}
+
+	incident.NotifyAt = nil
+	incident.NotifiedAt = &now
+	if err := is.db.Model(incident).Select("notify_at", "notified_at", "notify_channels").Updates(incident).Error; err != nil {
+		is.logger.Errorf("Error al actualizar el incidente %d: %v", incident.ID, err)
+		return
+	}
+
+	if is.escalationService != nil && lead.IsActive() {
+		is.escalationService.StartEscalation(lead, threshold)
+	}
+
+	is.logger.Infof("Incidente %d notificado (%d alertas)", incident.ID, len(members))
+}
+
+// incidentAlerts alertas de un incidente en orden de llegada
+func (is *IncidentService) incidentAlerts(incidentID uint) ([]models.Alert, error) {
+	var alerts []models.Alert
+	err := is.db.Where("incident_id = ?", incidentID).Order("triggered_at ASC, id ASC").
+		Preload("Server").Find(&alerts).Error
+	return alerts, err
+}
+
+// summaryAlert construye la alerta (no almacenada) que representa al incidente en las notificaciones
+func (is *IncidentService) summaryAlert(incident *models.Incident, members []models.Alert) *models.Alert {
+	lead := members[0]
+
+	var lines []string
+	for i, member := range members {
+		if i == maxIncidentSummaryAlerts {
+			lines = append(lines, fmt.Sprintf("… y %d más", len(members)-maxIncidentSummaryAlerts))
+			break
+		}
+		hostname := member.Server.Hostname
+		if hostname == "" {
+			hostname = fmt.Sprintf("Servidor #%d", member.ServerID)
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s [%s]", hostname, member.Title, member.Severity))
+	}
+
+	incidentID := incident.ID
+	return &models.Alert{
+		Title:          fmt.Sprintf("Incidente #%d: %d alertas en %s", incident.ID, len(members), incident.GroupKey),
+		Message:        strings.Join(lines, "\n"),
+		MetricType:     lead.MetricType,
+		Severity:       incident.Severity,
+		Status:         models.AlertStatusActive,
+		ServerID:       lead.ServerID,
+		Server:         lead.Server,
+		ThresholdID:    lead.ThresholdID,
+		IncidentID:     &incidentID,
+		OwnerTeamID:    lead.OwnerTeamID,
+		Incident:       incident,
+		TriggeredAt:    incident.OpenedAt,
+		ResolvedAt:     incident.ResolvedAt,
+		AcknowledgedAt: incident.AcknowledgedAt,
+		AcknowledgedBy: incident.AcknowledgedBy,
+		NotifyChannels: incident.NotifyChannels,
+		Notes:          incident.Notes,
+	}
+}
+
+// AcknowledgeIncident reconoce el incidente y todas sus alertas activas
+func (is *IncidentService) AcknowledgeIncident(id, userID uint, notes string) error {
+	incident, err := is.GetIncident(id)
+	if err != nil {
+		return err
+	}
+
+	if !incident.CanAcknowledge() {
+		return fmt.Errorf("el incidente no puede ser reconocido")
+	}
+
+	now := time.Now()
+	err = is.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(incident).Updates(map[string]interface{}{
+			"status":          models.IncidentStatusAcknowledged,
+			"acknowledged_at": now,
+			"acknowledged_by": userID,
+			"notes":           notes,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Alert{}).
+			Where("incident_id = ? AND status = ?", id, models.AlertStatusActive).
+			Updates(map[string]interface{}{
+				"status":             models.AlertStatusAcknowledged,
+				"acknowledged_at":    now,
+				"acknowledged_by":    userID,
+				"next_escalation_at": nil, // Detener el escalado
+			}).Error
+	})
+	if err != nil {
+		is.logger.Errorf("Error al reconocer incidente: %v", err)
+		return err
+	}
+
+	is.recordMemberEvents(incident, models.AlertStatusActive, models.AlertEventAcknowledged, userID, notes)
+
+	incident.Status = models.IncidentStatusAcknowledged
+	incident.AcknowledgedAt = &now
+	incident.AcknowledgedBy = &userID
+	incident.Notes = notes
+	is.notifyFollowUp(incident, models.AlertStatusActive, models.TemplateStateAcknowledged)
+
+	is.logger.Infof("Incidente %d reconocido por usuario %d", id, userID)
+	return nil
+}
+
+// ResolveIncident resuelve el incidente y todas sus alertas pendientes
+func (is *IncidentService) ResolveIncident(id, userID uint, notes string) error {
+	incident, err := is.GetIncident(id)
+	if err != nil {
+		return err
+	}
+
+	if incident.IsClosed() {
+		return fmt.Errorf("el incidente no puede ser resuelto")
+	}
+
+	now := time.Now()
+	err = is.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(incident).Updates(map[string]interface{}{
+			"status":      models.IncidentStatusResolved,
+			"resolved_at": now,
+			"notify_at":   nil,
+			"notes":       notes,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Alert{}).
+			Where("incident_id = ? AND status <> ?", id, models.AlertStatusResolved).
+			Updates(map[string]interface{}{
+				"status":             models.AlertStatusResolved,
+				"resolved_at":        now,
+				"next_escalation_at": nil, // Detener el escalado
+			}).Error
+	})
+	if err != nil {
+		is.logger.Errorf("Error al resolver incidente: %v", err)
+		return err
+	}
+
+	is.recordMemberEvents(incident, "", models.AlertEventResolved, userID, notes)
+
+	incident.Status = models.IncidentStatusResolved
+	incident.ResolvedAt = &now
+	incident.Notes = notes
+	is.notifyFollowUp(incident, "", models.TemplateStateResolved)
+
+	is.logger.Infof("Incidente %d resuelto por usuario %d", id, userID)
+	return nil
+}
+
+// recordMemberEvents registra en el historial de las alertas del incidente la acción de un
+// usuario. Con fromStatus solo se registra en las alertas que estaban en ese estado
+func (is *IncidentService) recordMemberEvents(incident *models.Incident, fromStatus models.AlertStatus, eventType models.AlertEventType, userID uint, notes string) {
+	message := fmt.Sprintf("Incidente %d", incident.ID)
+	if notes != "" {
+		message += ": " + notes
+	}
+
+	for _, alert := range incident.Alerts {
+		if alert.Status == models.AlertStatusResolved || (fromStatus != "" && alert.Status != fromStatus) {
+			continue
+		}
+		recordAlertEvent(is.db, is.logger, models.AlertEvent{
+			AlertID: alert.ID,
+			Type:    eventType,
+			Message: message,
+			ActorID: &userID,
+		})
+	}
+}
+
+// notifyFollowUp avisa del reconocimiento o resolución de un incidente por los canales que
+// recibieron su resumen, y por los suyos a las alertas que se notificaron por separado.
+// Con fromStatus solo se avisa de las alertas que estaban en ese estado
+func (is *IncidentService) notifyFollowUp(incident *models.Incident, fromStatus models.AlertStatus, state models.TemplateState) {
+	if len(incident.NotifyChannels) > 0 && len(incident.Alerts) > 0 {
+		summary := is.summaryAlert(incident, incident.Alerts)
+		is.sendFollowUp(summary, state)
+	}
+
+	for i := range incident.Alerts {
+		alert := &incident.Alerts[i]
+		if len(alert.NotifyChannels) == 0 || alert.Status == models.AlertStatusResolved {
+			continue
+		}
+		if fromStatus != "" && alert.Status != fromStatus {
+			continue
+		}
+
+		switch state {
+		case models.TemplateStateAcknowledged:
+			alert.Status = models.AlertStatusAcknowledged
+			alert.AcknowledgedAt = incident.AcknowledgedAt
+			alert.AcknowledgedBy = incident.AcknowledgedBy
+		case models.TemplateStateResolved:
+			alert.Status = models.AlertStatusResolved
+			alert.ResolvedAt = incident.ResolvedAt
+		}
+		is.sendFollowUp(alert, state)
+	}
+}
+
+// sendFollowUp envía una notificación de reconocimiento o resolución
+func (is *IncidentService) sendFollowUp(alert *models.Alert, state models.TemplateState) {
+	var err error
+	if state == models.TemplateStateAcknowledged {
+		err = is.notifyManager.NotifyAcknowledgedAlert(alert)
+	} else {
+		err = is.notifyManager.NotifyResolvedAlert(alert)
+	}
+
+	if err != nil {
+		is.logger.Errorf("Error al enviar notificación de seguimiento del incidente: %v", err)
+	}
+}
+
+// OnAlertAcknowledged marca el incidente como reconocido cuando no le quedan alertas activas
+func (is *IncidentService) OnAlertAcknowledged(alert *models.Alert, userID uint) {
+	if alert.IncidentID == nil {
+		return
+	}
+
+	var active int64
+	if err := is.db.Model(&models.Alert{}).Where("incident_id = ? AND status = ?", *alert.IncidentID,
+		models.AlertStatusActive).Count(&active).Error; err != nil || active > 0 {
+		return
+	}
+
+	now := time.Now()
+	is.db.Model(&models.Incident{}).
+		Where("id = ? AND status = ?", *alert.IncidentID, models.IncidentStatusOpen).
+		Updates(map[string]interface{}{
+			"status":          models.IncidentStatusAcknowledged,
+			"acknowledged_at": now,
+			"acknowledged_by": userID,
+		})
+}
+
+// OnAlertResolved resuelve el incidente cuando todas sus alertas están resueltas. Si el
+// resumen aún no se había enviado, se descarta
+func (is *IncidentService) OnAlertResolved(alert *models.Alert) {
+	if alert.IncidentID == nil {
+		return
+	}
+
+	var pending int64
+	if err := is.db.Model(&models.Alert{}).Where("incident_id = ? AND status <> ?", *alert.IncidentID,
+		models.AlertStatusResolved).Count(&pending).Error; err != nil || pending > 0 {
+		return
+	}
+
+	incident, err := is.GetIncident(*alert.IncidentID)
+	if err != nil || incident.IsClosed() {
+		return
+	}
+
+	now := time.Now()
+	if err := is.db.Model(incident).Updates(map[string]interface{}{
+		"status":      models.IncidentStatusResolved,
+		"resolved_at": now,
+		"notify_at":   nil,
+	}).Error; err != nil {
+		is.logger.Errorf("Error al resolver el incidente %d: %v", incident.ID, err)
+		return
+	}
+
+	// Las alertas notificadas por separado ya avisaron de su resolución
+	if len(incident.NotifyChannels) > 0 && len(incident.Alerts) > 0 {
+		incident.Status = models.IncidentStatusResolved
+		incident.ResolvedAt = &now
+		is.sendFollowUp(is.summaryAlert(incident, incident.Alerts), models.TemplateStateResolved)
+	}
+
+	is.logger.Infof("Incidente %d resuelto automáticamente", incident.ID)
+}
+
+// OnAlertReactivated recalcula el estado del incidente de una alerta que vuelve a estar activa
+// (reabierta, con el reconocimiento retirado o al terminar de posponerse), lo que reabre el
+// incidente si estaba reconocido o resuelto
+func (is *IncidentService) OnAlertReactivated(alert *models.Alert) {
+	if alert.IncidentID == nil {
+		return
+	}
+
+	incident, err := is.GetIncident(*alert.IncidentID)
+	if err != nil || incident.Status == models.IncidentStatusMerged {
+		return
+	}
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if err := is.db.Transaction(func(tx *gorm.DB) error {
+		return is.refreshIncident(tx, incident, time.Now())
+	}); err != nil {
+		is.logger.Errorf("Error al actualizar el incidente %d: %v", incident.ID, err)
+	}
+}
+
+// MergeIncidents mueve las alertas de los incidentes indicados al incidente destino y marca
+// los de origen como fusionados
+func (is *IncidentService) MergeIncidents(targetID uint, sourceIDs []uint) (*models.Incident, error) {
+	if len(sourceIDs) == 0 {
+		return nil, fmt.Errorf("debe indicar al menos un incidente a fusionar")
+	}
+
+	target, err := is.GetIncident(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Status == models.IncidentStatusMerged {
+		return nil, fmt.Errorf("el incidente %d ya fue fusionado en otro", targetID)
+	}
+
+	seen := make(map[uint]bool)
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return nil, fmt.Errorf("un incidente no puede fusionarse consigo mismo")
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("el incidente %d está repetido", id)
+		}
+		seen[id] = true
+	}
+
+	var sources []models.Incident
+	if err := is.db.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	if len(sources) != len(sourceIDs) {
+		return nil, fmt.Errorf("uno o más incidentes no existen")
+	}
+	for _, source := range sources {
+		if source.Status == models.IncidentStatusMerged {
+			return nil, fmt.Errorf("el incidente %d ya fue fusionado en otro", source.ID)
+		}
+	}
+
+	now := time.Now()
+	err = is.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Alert{}).Where("incident_id IN ?", sourceIDs).
+			Update("incident_id", targetID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Incident{}).Where("id IN ?", sourceIDs).Updates(map[string]interface{}{
+			"status":         models.IncidentStatusMerged,
+			"merged_into_id": targetID,
+			"alert_count":    0,
+			"resolved_at":    now,
+			"notify_at":      nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		return is.refreshIncident(tx, target, now)
+	})
+	if err != nil {
+		is.logger.Errorf("Error al fusionar incidentes en %d: %v", targetID, err)
+		return nil, err
+	}
+
+	is.logger.Infof("Incidentes %v fusionados en el incidente %d", sourceIDs, targetID)
+	return is.GetIncident(targetID)
+}
+
+// SplitIncident mueve las alertas indicadas a un incidente nuevo con la misma clave de agrupación
+func (is *IncidentService) SplitIncident(id uint, alertIDs []uint) (*models.Incident, error) {
+	if len(alertIDs) == 0 {
+		return nil, fmt.Errorf("debe indicar al menos una alerta a separar")
+	}
+
+	source, err := is.GetIncident(id)
+	if err != nil {
+		return nil, err
+	}
+	if source.Status == models.IncidentStatusMerged {
+		return nil, fmt.Errorf("el incidente %d ya fue fusionado en otro", id)
+	}
+
+	members := make(map[uint]bool, len(source.Alerts))
+	for _, alert := range source.Alerts {
+		members[alert.ID] = true
+	}
+
+	selected := make(map[uint]bool)
+	for _, alertID := range alertIDs {
+		if !members[alertID] {
+			return nil, fmt.Errorf("la alerta %d no pertenece al incidente %d", alertID, id)
+		}
+		selected[alertID] = true
+	}
+	if len(selected) == len(members) {
+		return nil, fmt.Errorf("el incidente debe conservar al menos una alerta")
+	}
+
+	now := time.Now()
+	split := &models.Incident{
+		Title:          source.Title,
+		Status:         models.IncidentStatusOpen,
+		Severity:       source.Severity,
+		GroupKey:       source.GroupKey,
+		GroupLabels:    source.GroupLabels,
+		OpenedAt:       now,
+		LastAlertAt:    now,
+		NotifyAt:       source.NotifyAt,
+		NotifiedAt:     source.NotifiedAt, // Las alertas ya se notificaron con el incidente original
+		NotifyChannels: source.NotifyChannels,
+	}
+
+	err = is.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(split).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Alert{}).Where("id IN ? AND incident_id = ?", alertIDs, id).
+			Update("incident_id", split.ID).Error; err != nil {
+			return err
+		}
+
+		if err := is.refreshIncident(tx, source, now); err != nil {
+			return err
+		}
+		return is.refreshIncident(tx, split, now)
+	})
+	if err != nil {
+		is.logger.Errorf("Error al separar alertas del incidente %d: %v", id, err)
+		return nil, err
+	}
+
+	is.logger.Infof("Alertas %v separadas del incidente %d en el incidente %d", alertIDs, id, split.ID)
+	return is.GetIncident(split.ID)
+}
+
+// refreshIncident recalcula el número de alertas, la severidad, las fechas y el estado de un
+// incidente a partir de sus alertas (tras fusionar o separar)
+func (is *IncidentService) refreshIncident(tx *gorm.DB, incident *models.Incident, now time.Time) error {
+	var alerts []models.Alert
+	if err := tx.Where("incident_id = ?", incident.ID).Find(&alerts).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"alert_count": len(alerts)}
+
+	var severity models.AlertSeverity
+	var opened, last time.Time
+	pending, active := 0, 0
+	for _, alert := range alerts {
+		if models.SeverityRank(alert.Severity) > models.SeverityRank(severity) {
+			severity = alert.Severity
+		}
+		if opened.IsZero() || alert.TriggeredAt.Before(opened) {
+			opened = alert.TriggeredAt
+		}
+		if alert.TriggeredAt.After(last) {
+			last = alert.TriggeredAt
+		}
+		if alert.Status != models.AlertStatusResolved {
+			pending++
+		}
+		if alert.Status == models.AlertStatusActive || alert.Status == models.AlertStatusSuppressed {
+			active++
+		}
+	}
+
+	if len(alerts) > 0 {
+		updates["severity"] = severity
+		updates["opened_at"] = opened
+		updates["last_alert_at"] = last
+	}
+
+	switch {
+	case pending == 0:
+		updates["status"] = models.IncidentStatusResolved
+		updates["notify_at"] = nil
+		if incident.ResolvedAt == nil {
+			updates["resolved_at"] = now
+		}
+	case active > 0:
+		// Un incidente resuelto o reconocido que recibe alertas sin reconocer se reabre
+		updates["status"] = models.IncidentStatusOpen
+		updates["resolved_at"] = nil
+	default:
+		updates["status"] = models.IncidentStatusAcknowledged
+		updates["resolved_at"] = nil
+		if incident.AcknowledgedAt == nil {
+			updates["acknowledged_at"] = now
+		}
+	}
+
+	return tx.Model(incident).Updates(updates).Error
+}
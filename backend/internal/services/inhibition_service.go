@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// InhibitionService gestiona las reglas de inhibición y decide qué alerta activa inhibe a una nueva
+type InhibitionService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewInhibitionService crea un nuevo servicio de reglas de inhibición
+func NewInhibitionService(db *gorm.DB, log logger.Logger) *InhibitionService {
+	return &InhibitionService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// GetAllRules obtiene todas las reglas de inhibición
+func (is *InhibitionService) GetAllRules() ([]models.InhibitionRule, error) {
+	var rules []models.InhibitionRule
+	if err := is.db.Order("id").Find(&rules).Error; err != nil {
+		is.logger.Errorf("Error al obtener reglas de inhibición: %v", err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// GetRule obtiene una regla de inhibición por ID
+func (is *InhibitionService) GetRule(id uint) (*models.InhibitionRule, error) {
+	var rule models.InhibitionRule
+	if err := is.db.First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("regla de inhibición no encontrada")
+		}
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// CreateRule crea una nueva regla de inhibición
+func (is *InhibitionService) CreateRule(rule *models.InhibitionRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	if err := is.db.Create(rule).Error; err != nil {
+		is.logger.Errorf("Error al crear regla de inhibición: %v", err)
+		return err
+	}
+
+	is.logger.Infof("Regla de inhibición creada: %s (ID: %d)", rule.Name, rule.ID)
+	return nil
+}
+
+// UpdateRule actualiza una regla de inhibición existente
+func (is *InhibitionService) UpdateRule(rule *models.InhibitionRule) error {
+	existing, err := is.GetRule(rule.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	rule.CreatedBy = existing.CreatedBy
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := is.db.Save(rule).Error; err != nil {
+		is.logger.Errorf("Error al actualizar regla de inhibición: %v", err)
+		return err
+	}
+
+	is.logger.Infof("Regla de inhibición actualizada: %s (ID: %d)", rule.Name, rule.ID)
+	return nil
+}
+
+// DeleteRule elimina una regla de inhibición. Las alertas ya inhibidas conservan su estado
+// hasta que se resuelvan o se resuelva la alerta que las inhibe
+func (is *InhibitionService) DeleteRule(id uint) error {
+	result := is.db.Delete(&models.InhibitionRule{}, id)
+	if result.Error != nil {
+		is.logger.Errorf("Error al eliminar regla de inhibición: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("regla de inhibición no encontrada")
+	}
+
+	is.logger.Infof("Regla de inhibición eliminada: %d", id)
+	return nil
+}
+
+// GetInhibitedAlerts obtiene las alertas inhibidas en este momento por una regla
+func (is *InhibitionService) GetInhibitedAlerts(ruleID uint) ([]models.Alert, error) {
+	var alerts []models.Alert
+	if err := is.db.Where("inhibition_rule_id = ? AND status = ?", ruleID, models.AlertStatusSuppressed).
+		Order("triggered_at DESC").Preload("Server").Find(&alerts).Error; err != nil {
+		is.logger.Errorf("Error al obtener alertas inhibidas: %v", err)
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// FindInhibitingAlert devuelve la primera regla habilitada que inhibe la alerta y la alerta
+// origen (activa o reconocida) que lo provoca, o nil si ninguna la inhibe. Las alertas ya
+// suprimidas no inhiben a otras, lo que evita cadenas y ciclos de inhibición
+func (is *InhibitionService) FindInhibitingAlert(alert *models.Alert) (*models.InhibitionRule, *models.Alert, error) {
+	var rules []models.InhibitionRule
+	if err := is.db.Where("enabled = ?", true).Order("id").Find(&rules).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	// Trabajar sobre una copia para no asociar el servidor a una alerta aún no guardada
+	candidate := *alert
+	if candidate.Server.ID == 0 {
+		is.db.First(&candidate.Server, alert.ServerID)
+	}
+
+	groups := newServerGroupCache(is.db)
+	targetGroups := groups.withAncestors(candidate.ServerID)
+
+	var applicable []models.InhibitionRule
+	for _, rule := range rules {
+		if rule.Target.Matches(&candidate, targetGroups) {
+			applicable = append(applicable, rule)
+		}
+	}
+
+	if len(applicable) == 0 {
+		return nil, nil, nil
+	}
+
+	var sources []models.Alert
+	query := is.db.Where("status IN ?", []models.AlertStatus{models.AlertStatusActive, models.AlertStatusAcknowledged})
+	if candidate.ID != 0 {
+		query = query.Where("id <> ?", candidate.ID)
+	}
+	if err := query.Order("triggered_at").Preload("Server").Find(&sources).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for i := range applicable {
+		rule := &applicable[i]
+		for j := range sources {
+			source := &sources[j]
+
+			// Una alerta no se inhibe con otra de la misma regla en el mismo servidor
+			if source.SameRule(&candidate) {
+				continue
+			}
+
+			if !rule.Source.Matches(source, groups.withAncestors(source.ServerID)) {
+				continue
+			}
+
+			if rule.SharesLabels(source, &candidate, groups.direct(source.ServerID), targetGroups) {
+				return rule, source, nil
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// serverGroupCache grupos de cada servidor consultados durante una evaluación
+type serverGroupCache struct {
+	db        *gorm.DB
+	ancestors map[uint][]uint
+	directIDs map[uint][]uint
+}
+
+func newServerGroupCache(db *gorm.DB) *serverGroupCache {
+	return &serverGroupCache{
+		db:        db,
+		ancestors: make(map[uint][]uint),
+		directIDs: make(map[uint][]uint),
+	}
+}
+
+// withAncestors grupos del servidor incluidos sus ancestros
+func (c *serverGroupCache) withAncestors(serverID uint) []uint {
+	if ids, ok := c.ancestors[serverID]; ok {
+		return ids
+	}
+
+	ids, _ := serverGroupIDsWithAncestors(c.db, serverID)
+	c.ancestors[serverID] = ids
+	return ids
+}
+
+// direct grupos a los que pertenece el servidor directamente
+func (c *serverGroupCache) direct(serverID uint) []uint {
+	if ids, ok := c.directIDs[serverID]; ok {
+		return ids
+	}
+
+	var ids []uint
+	c.db.Table("server_group_servers").Where("server_id = ?", serverID).Pluck("server_group_id", &ids)
+	c.directIDs[serverID] = ids
+	return ids
+}
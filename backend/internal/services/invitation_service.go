@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+// Errores de las invitaciones y el autorregistro
+var (
+	ErrInvitationInvalid  = errors.New("la invitación no es válida o ha caducado")
+	ErrInvitationNotFound = errors.New("invitación no encontrada")
+	ErrEmailInUse         = errors.New("ya existe un usuario con ese email")
+	ErrInvitationPending  = errors.New("el email ya tiene una invitación pendiente de un administrador")
+	ErrSignupDisabled     = errors.New("el registro está deshabilitado: solicite una invitación a un administrador")
+	ErrSignupDomain       = errors.New("el dominio del email no admite el autorregistro")
+)
+
+// InvitationSettings configuración de las invitaciones y del autorregistro
+type InvitationSettings struct {
+	TTL           time.Duration // Validez de las invitaciones
+	AppURL        string        // Página del frontend que recibe el token (?invite=)
+	SignupDomains []string      // Dominios de email con autorregistro como VIEWER (vacío = deshabilitado)
+}
+
+// InvitationResult invitación emitida y cómo se ha entregado
+type InvitationResult struct {
+	Invitation *models.Invitation
+	Link       string // Enlace con el token para aceptarla
+	Emailed    bool   // Se ha enviado por correo al invitado
+}
+
+// InvitationService gestiona las invitaciones de un solo uso con las que se dan de alta los
+// usuarios, en lugar del registro abierto
+type InvitationService struct {
+	db          *gorm.DB
+	logger      logger.Logger
+	userService *UserService
+	notifier    *notifications.NotificationManager
+	settings    InvitationSettings
+}
+
+// NewInvitationService crea el servicio de invitaciones. Los dominios de autorregistro se
+// normalizan; el autorregistro necesita el envío de correo para comprobar el email
+func NewInvitationService(db *gorm.DB, log logger.Logger, userService *UserService, notifier *notifications.NotificationManager, settings InvitationSettings) *InvitationService {
+	if settings.TTL <= 0 {
+		settings.TTL = 72 * time.Hour
+	}
+
+	var domains []string
+	for _, domain := range settings.SignupDomains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	settings.SignupDomains = domains
+
+	if len(domains) > 0 && !notifier.EmailEnabled() {
+		log.Warn("Autorregistro por dominio deshabilitado: requiere el envío de correo (EMAIL_ENABLED)")
+	}
+
+	return &InvitationService{
+		db:          db,
+		logger:      log,
+		userService: userService,
+		notifier:    notifier,
+		settings:    settings,
+	}
+}
+
+// SignupEnabled indica si se admite el autorregistro por dominio
+func (s *InvitationService) SignupEnabled() bool {
+	return len(s.settings.SignupDomains) > 0 && s.notifier.EmailEnabled()
+}
+
+// CreateInvitation invita a un email con un rol. Sustituye a las invitaciones pendientes del
+// mismo email. Si el correo está configurado se envía el enlace al invitado
+func (s *InvitationService) CreateInvitation(email string, role models.Role, invitedBy uint) (*InvitationResult, error) {
+	if models.RoleRank(role) == 0 {
+		return nil, fmt.Errorf("rol inválido: %s", role)
+	}
+
+	result, err := s.issue(email, role, &invitedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifier.EmailEnabled() {
+		if err := s.sendInvitation(result); err != nil {
+			s.logger.Warnf("No se pudo enviar la invitación a %s: %v", result.Invitation.Email, err)
+		} else {
+			result.Emailed = true
+		}
+	}
+
+	s.logger.Infof("Invitación %d emitida para %s con rol %s", result.Invitation.ID, result.Invitation.Email, role)
+	return result, nil
+}
+
+// SignUp autorregistro con un email de un dominio permitido: envía al email una invitación con
+// rol VIEWER, que demuestra que el usuario controla el buzón. Si el email ya tiene cuenta o una
+// invitación de un admin pendiente no se hace nada, para no revelar qué emails están registrados
+// ni permitir que cualquiera anule una invitación ajena
+func (s *InvitationService) SignUp(email string) error {
+	if !s.SignupEnabled() {
+		return ErrSignupDisabled
+	}
+
+	email = normalizeEmail(email)
+	if !s.allowedDomain(email) {
+		return ErrSignupDomain
+	}
+
+	result, err := s.issue(email, models.RoleViewer, nil)
+	if err != nil {
+		if errors.Is(err, ErrEmailInUse) {
+			s.logger.Warnf("Autorregistro solicitado para un email ya registrado: %s", email)
+			return nil
+		}
+		if errors.Is(err, ErrInvitationPending) {
+			s.logger.Warnf("Autorregistro solicitado para un email con una invitación pendiente: %s", email)
+			return nil
+		}
+		return err
+	}
+
+	if err := s.sendInvitation(result); err != nil {
+		s.logger.Errorf("No se pudo enviar la invitación de autorregistro a %s: %v", email, err)
+		return err
+	}
+
+	s.logger.Infof("Invitación de autorregistro enviada a %s", email)
+	return nil
+}
+
+// GetInvitations obtiene las invitaciones (solo las pendientes si pendingOnly)
+func (s *InvitationService) GetInvitations(pendingOnly bool) ([]models.Invitation, error) {
+	query := s.db.Order("created_at DESC")
+	if pendingOnly {
+		query = query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	var invitations []models.Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		s.logger.Errorf("Error al obtener las invitaciones: %v", err)
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation anula una invitación pendiente
+func (s *InvitationService) RevokeInvitation(id uint) error {
+	result := s.db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		s.logger.Errorf("Error al anular la invitación %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+
+	s.logger.Infof("Invitación %d anulada", id)
+	return nil
+}
+
+// GetByToken obtiene la invitación pendiente de un token (para mostrar el email y el rol antes
+// de aceptarla)
+func (s *InvitationService) GetByToken(token string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := s.db.Where("token_hash = ?", hashToken(token)).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	if !invitation.IsPending(time.Now()) {
+		return nil, ErrInvitationInvalid
+	}
+
+	return &invitation, nil
+}
+
+// Accept acepta una invitación: crea el usuario local con el email y el rol de la invitación
+// y la contraseña elegida. La invitación queda consumida
+func (s *InvitationService) Accept(token, username, password string) (*models.User, error) {
+	invitation, err := s.GetByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Comprobar la contraseña antes de consumir la invitación
+	if err := s.userService.ValidatePassword(password, username); err != nil {
+		return nil, err
+	}
+
+	// Reservar la invitación: solo una petición puede aceptarla
+	now := time.Now()
+	claim := s.db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", invitation.ID, now).
+		Update("accepted_at", now)
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, ErrInvitationInvalid
+	}
+
+	user := &models.User{
+		Username:     username,
+		Email:        invitation.Email,
+		Role:         invitation.Role,
+		AuthProvider: models.AuthProviderLocal,
+	}
+	if err := s.userService.CreateUser(user, password); err != nil {
+		// Liberar la invitación para que pueda reintentarse (p. ej. con otro nombre de usuario)
+		if releaseErr := s.db.Model(&models.Invitation{}).Where("id = ?", invitation.ID).
+			Update("accepted_at", nil).Error; releaseErr != nil {
+			s.logger.Errorf("Error al liberar la invitación %d: %v", invitation.ID, releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := s.db.Model(&models.Invitation{}).Where("id = ?", invitation.ID).
+		Update("accepted_user_id", user.ID).Error; err != nil {
+		s.logger.Warnf("Error al vincular la invitación %d con el usuario %d: %v", invitation.ID, user.ID, err)
+	}
+
+	s.logger.Infof("Invitación %d aceptada: usuario %s creado con rol %s", invitation.ID, user.Username, user.Role)
+	return user, nil
+}
+
+// issue crea la invitación con un token aleatorio y anula las pendientes del mismo email. Las
+// de autorregistro (invitedBy nil) solo sustituyen a otras de autorregistro y no se emiten si
+// hay una invitación de un admin pendiente
+func (s *InvitationService) issue(email string, role models.Role, invitedBy *uint) (*InvitationResult, error) {
+	email = normalizeEmail(email)
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrEmailInUse
+	}
+
+	token, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation := &models.Invitation{
+		Email:     email,
+		Role:      role,
+		TokenHash: hashToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(s.settings.TTL),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		pending := tx.Model(&models.Invitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email)
+
+		if invitedBy == nil {
+			var adminInvitations int64
+			if err := tx.Model(&models.Invitation{}).
+				Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+				Where("invited_by IS NOT NULL AND expires_at > ?", now).
+				Count(&adminInvitations).Error; err != nil {
+				return err
+			}
+			if adminInvitations > 0 {
+				return ErrInvitationPending
+			}
+			pending = pending.Where("invited_by IS NULL")
+		}
+
+		if err := pending.Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if errors.Is(err, ErrInvitationPending) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Errorf("Error al crear la invitación para %s: %v", email, err)
+		return nil, err
+	}
+
+	return &InvitationResult{
+		Invitation: invitation,
+		Link:       appLink(s.settings.AppURL, "invite", token),
+	}, nil
+}
+
+// sendInvitation envía el enlace de la invitación por correo
+func (s *InvitationService) sendInvitation(result *InvitationResult) error {
+	body := fmt.Sprintf("Ha recibido una invitación para acceder al panel de monitorización de servidores con el rol %s.\n\n"+
+		"Para crear su cuenta abra este enlace antes del %s:\n\n%s\n\n"+
+		"Si no esperaba esta invitación puede ignorar este correo.\n",
+		result.Invitation.Role, result.Invitation.ExpiresAt.Format("02/01/2006 15:04"), result.Link)
+
+	return s.notifier.SendEmail(result.Invitation.Email, "Invitación al panel de monitorización", body)
+}
+
+// allowedDomain indica si el dominio del email admite el autorregistro
+func (s *InvitationService) allowedDomain(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := email[at+1:]
+	for _, allowed := range s.settings.SignupDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeEmail normaliza un email para compararlo
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// appLink construye el enlace del frontend con un token en el parámetro indicado
+func appLink(base, param, token string) string {
+	target, err := url.Parse(base)
+	if err != nil {
+		return base + "?" + param + "=" + url.QueryEscape(token)
+	}
+
+	query := target.Query()
+	query.Set(param, token)
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/ldapauth"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Errores de la autenticación contra el directorio
+var (
+	ErrLDAPUnavailable   = errors.New("el directorio LDAP no está disponible")
+	ErrLDAPNotAuthorized = errors.New("el usuario no pertenece a ningún grupo autorizado del directorio")
+)
+
+// LDAPSettings configuración de la autenticación contra LDAP / Active Directory
+type LDAPSettings struct {
+	Enabled     bool
+	Directory   ldapauth.Config
+	RoleMapping []string    // "dnDelGrupo:rol": grupos del directorio que conceden un rol global
+	DefaultRole models.Role // Rol si ningún grupo coincide (vacío = denegar el acceso)
+	AutoCreate  bool        // Dar de alta en su primer login a los usuarios del directorio
+}
+
+// ldapRoleMapping grupo del directorio que concede un rol
+type ldapRoleMapping struct {
+	GroupDN string
+	Role    models.Role
+}
+
+// LDAPService autentica a los usuarios con AuthProvider "ldap" contra el directorio, les asigna
+// el rol según sus grupos y sincroniza periódicamente las cuentas deshabilitadas
+type LDAPService struct {
+	db       *gorm.DB
+	logger   logger.Logger
+	client   *ldapauth.Client
+	settings LDAPSettings
+	roles    []ldapRoleMapping
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewLDAPService crea el servicio de LDAP. Si la configuración del directorio no es válida el
+// servicio queda deshabilitado; las entradas de mapeo inválidas se ignoran con un aviso
+func NewLDAPService(db *gorm.DB, log logger.Logger, settings LDAPSettings) *LDAPService {
+	settings.DefaultRole = models.Role(strings.ToUpper(strings.TrimSpace(string(settings.DefaultRole))))
+	if settings.DefaultRole != "" && models.RoleRank(settings.DefaultRole) == 0 {
+		log.Warnf("LDAP: rol por defecto inválido %q, se denegará el acceso sin grupo mapeado", settings.DefaultRole)
+		settings.DefaultRole = ""
+	}
+
+	s := &LDAPService{
+		db:       db,
+		logger:   log,
+		settings: settings,
+	}
+
+	for _, entry := range settings.RoleMapping {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// El DN contiene "=" y ",", por lo que el rol se separa con el último ":"
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			log.Warnf("LDAP: mapeo de rol ignorado: entrada inválida %q (formato dnDelGrupo:rol)", entry)
+			continue
+		}
+		role := models.Role(strings.ToUpper(strings.TrimSpace(entry[i+1:])))
+		if models.RoleRank(role) == 0 {
+			log.Warnf("LDAP: mapeo de rol ignorado: rol inválido en %q", entry)
+			continue
+		}
+		s.roles = append(s.roles, ldapRoleMapping{GroupDN: strings.TrimSpace(entry[:i]), Role: role})
+	}
+
+	if settings.Enabled {
+		client, err := ldapauth.NewClient(settings.Directory)
+		if err != nil {
+			log.Errorf("LDAP: configuración del directorio inválida, autenticación LDAP deshabilitada: %v", err)
+			s.settings.Enabled = false
+		} else {
+			s.client = client
+		}
+	}
+
+	return s
+}
+
+// Enabled indica si la autenticación LDAP está habilitada
+func (s *LDAPService) Enabled() bool {
+	return s.settings.Enabled
+}
+
+// AutoCreate indica si los usuarios del directorio se dan de alta en su primer login
+func (s *LDAPService) AutoCreate() bool {
+	return s.settings.Enabled && s.settings.AutoCreate
+}
+
+// Login comprueba la contraseña contra el directorio y sincroniza el email y el rol del usuario.
+// Si user es nil (usuario aún no registrado), lo da de alta
+func (s *LDAPService) Login(username, password string, user *models.User) (*models.User, error) {
+	if !s.Enabled() {
+		return nil, ErrInvalidCredentials
+	}
+
+	entry, err := s.client.Authenticate(username, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ldapauth.ErrInvalidCredentials):
+			s.logger.Warnf("LDAP: credenciales incorrectas para el usuario %s", username)
+			return nil, ErrInvalidCredentials
+		case errors.Is(err, ldapauth.ErrUnavailable):
+			s.logger.Errorf("LDAP: %v", err)
+			return nil, ErrLDAPUnavailable
+		default:
+			s.logger.Errorf("LDAP: error al autenticar a %s: %v", username, err)
+			return nil, err
+		}
+	}
+
+	if entry.Disabled {
+		if user != nil {
+			s.disableUser(user, "cuenta deshabilitada en el directorio")
+		}
+		return nil, ErrUserDisabled
+	}
+
+	role := s.mapRole(entry.Groups)
+	if role == "" {
+		s.logger.Warnf("LDAP: acceso denegado a %s, ningún grupo mapeado (%v)", entry.DN, entry.Groups)
+		return nil, ErrLDAPNotAuthorized
+	}
+
+	if user == nil {
+		return s.createUser(entry, role)
+	}
+
+	if err := s.syncUser(user, entry, role); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createUser da de alta al usuario del directorio en su primer login
+func (s *LDAPService) createUser(entry *ldapauth.Entry, role models.Role) (*models.User, error) {
+	if !s.settings.AutoCreate {
+		return nil, ErrInvalidCredentials
+	}
+
+	// El nombre canónico del directorio puede diferir del escrito (mayúsculas)
+	var existing models.User
+	err := s.db.Where("username = ?", entry.Username).First(&existing).Error
+	if err == nil {
+		if existing.AuthProvider != models.AuthProviderLDAP {
+			return nil, ErrInvalidCredentials
+		}
+		if err := s.syncUser(&existing, entry, role); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if entry.Email == "" {
+		return nil, fmt.Errorf("el directorio no devolvió el email del usuario")
+	}
+
+	var count int64
+	if err := s.db.Unscoped().Model(&models.User{}).Where("username = ? OR email = ?", entry.Username, entry.Email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("el nombre de usuario o email ya está en uso")
+	}
+
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	dn := entry.DN
+	user := &models.User{
+		Username:     truncateRunes(entry.Username, 50),
+		Email:        entry.Email,
+		Role:         role,
+		AuthProvider: models.AuthProviderLDAP,
+		ExternalID:   &dn,
+	}
+	// Contraseña aleatoria: la contraseña real se comprueba siempre contra el directorio
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("LDAP: usuario %s dado de alta con rol %s", user.Username, role)
+	return user, nil
+}
+
+// syncUser actualiza el DN, el email, el rol y el estado del usuario con los datos del directorio.
+// Un cambio de rol revoca sus sesiones anteriores
+func (s *LDAPService) syncUser(user *models.User, entry *ldapauth.Entry, role models.Role) error {
+	updates := map[string]interface{}{}
+
+	if user.ExternalID == nil || *user.ExternalID != entry.DN {
+		dn := entry.DN
+		updates["external_id"] = dn
+		user.ExternalID = &dn
+	}
+
+	if entry.Email != "" && entry.Email != user.Email {
+		var count int64
+		if err := s.db.Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", entry.Email, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			updates["email"] = entry.Email
+			user.Email = entry.Email
+		} else {
+			s.logger.Warnf("LDAP: el email %s ya está en uso, no se actualiza el del usuario %s", entry.Email, user.Username)
+		}
+	}
+
+	if user.Disabled {
+		updates["disabled"] = false
+		user.Disabled = false
+	}
+
+	roleChanged := user.Role != role
+	if roleChanged {
+		updates["role"] = role
+		user.Role = role
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		s.logger.Errorf("LDAP: error al sincronizar el usuario %s: %v", user.Username, err)
+		return err
+	}
+
+	if roleChanged {
+		if _, err := revokeUserSessions(s.db, user.ID, models.SessionRevokedRoleChange, 0); err != nil {
+			s.logger.Errorf("Error al revocar las sesiones del usuario %d: %v", user.ID, err)
+		}
+		s.logger.Infof("LDAP: rol del usuario %s actualizado a %s", user.Username, role)
+	}
+
+	return nil
+}
+
+// disableUser deshabilita al usuario y revoca sus sesiones
+func (s *LDAPService) disableUser(user *models.User, reason string) {
+	if user.Disabled {
+		return
+	}
+
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Update("disabled", true).Error; err != nil {
+		s.logger.Errorf("LDAP: error al deshabilitar el usuario %s: %v", user.Username, err)
+		return
+	}
+	user.Disabled = true
+
+	if _, err := revokeUserSessions(s.db, user.ID, models.SessionRevokedUserDisabled, 0); err != nil {
+		s.logger.Errorf("Error al revocar las sesiones del usuario %d: %v", user.ID, err)
+	}
+
+	s.logger.Warnf("LDAP: usuario %s deshabilitado (%s)", user.Username, reason)
+}
+
+// SyncUsers revisa en el directorio a todos los usuarios LDAP: deshabilita los que ya no
+// existen o están deshabilitados o sin grupo autorizado, y actualiza el rol y el email del resto.
+// Si el directorio no responde no se modifica ningún usuario
+func (s *LDAPService) SyncUsers() {
+	if !s.Enabled() {
+		return
+	}
+
+	var users []models.User
+	if err := s.db.Where("auth_provider = ?", models.AuthProviderLDAP).Find(&users).Error; err != nil {
+		s.logger.Errorf("LDAP: error al obtener los usuarios a sincronizar: %v", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+
+		entry, err := s.client.Lookup(user.Username)
+		switch {
+		case errors.Is(err, ldapauth.ErrUserNotFound):
+			s.disableUser(user, "no encontrado en el directorio")
+			continue
+		case err != nil:
+			s.logger.Errorf("LDAP: sincronización interrumpida: %v", err)
+			return
+		case entry.Disabled:
+			s.disableUser(user, "cuenta deshabilitada en el directorio")
+			continue
+		}
+
+		role := s.mapRole(entry.Groups)
+		if role == "" {
+			s.disableUser(user, "sin grupo autorizado")
+			continue
+		}
+
+		if err := s.syncUser(user, entry, role); err != nil {
+			continue
+		}
+	}
+}
+
+// Start inicia la sincronización periódica de los usuarios del directorio
+func (s *LDAPService) Start(interval time.Duration) {
+	if !s.Enabled() {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		s.SyncUsers()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.SyncUsers()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+
+	s.logger.Infof("Sincronización de usuarios LDAP iniciada (intervalo %s)", interval)
+}
+
+// Stop detiene la sincronización periódica
+func (s *LDAPService) Stop() {
+	if s.stop == nil {
+		return
+	}
+
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+	s.logger.Info("Sincronización de usuarios LDAP detenida")
+}
+
+// mapRole obtiene el rol de mayor privilegio entre los grupos del usuario, o el rol por defecto
+func (s *LDAPService) mapRole(groups []string) models.Role {
+	var role models.Role
+	for _, mapping := range s.roles {
+		if models.RoleRank(mapping.Role) <= models.RoleRank(role) {
+			continue
+		}
+		for _, group := range groups {
+			if ldapauth.SameDN(group, mapping.GroupDN) {
+				role = mapping.Role
+				break
+			}
+		}
+	}
+
+	if role == "" {
+		return s.settings.DefaultRole
+	}
+	return role
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginThrottlePurgeInterval frecuencia de limpieza de los recuentos de fallos caducados
+const LoginThrottlePurgeInterval = 10 * time.Minute
+
+// securityLogSource origen con el que se guardan los eventos de seguridad en la tabla de logs
+const securityLogSource = "security"
+
+// ErrAccountLocked se devuelve (envuelto en LoginLockedError) mientras dura un bloqueo
+var ErrAccountLocked = errors.New("demasiados intentos fallidos: inténtelo de nuevo más tarde")
+
+// ErrLoginThrottleNotFound el bloqueo indicado no existe
+var ErrLoginThrottleNotFound = errors.New("bloqueo no encontrado")
+
+// LoginLockedError bloqueo temporal de un usuario o una IP por intentos fallidos
+type LoginLockedError struct {
+	Scope string    // models.LoginThrottleUser o models.LoginThrottleIP
+	Until time.Time // Momento en que se levanta el bloqueo
+}
+
+// Error implementa la interfaz error
+func (e *LoginLockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+// Is permite comparar con errors.Is(err, ErrAccountLocked)
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// LoginProtectionSettings límites de la protección contra fuerza bruta
+type LoginProtectionSettings struct {
+	Enabled         bool
+	MaxUserFailures int           // Fallos de un mismo usuario antes del bloqueo
+	MaxIPFailures   int           // Fallos desde una misma IP (con cualquier usuario) antes del bloqueo
+	FailureWindow   time.Duration // Los fallos más antiguos que esto dejan de contar
+	LockoutDuration time.Duration // Duración del bloqueo temporal
+	BaseDelay       time.Duration // Retraso tras el primer fallo; se duplica con cada fallo
+	MaxDelay        time.Duration // Retraso máximo de la respuesta a un intento fallido
+}
+
+// LoginProtectionService cuenta los intentos de login fallidos por usuario y por IP, retrasa
+// progresivamente las respuestas y bloquea temporalmente al superar los límites
+type LoginProtectionService struct {
+	db         *gorm.DB
+	logger     logger.Logger
+	logService *LogService
+	settings   LoginProtectionSettings
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewLoginProtectionService crea el servicio de protección del login aplicando los valores por
+// defecto a los límites no configurados
+func NewLoginProtectionService(db *gorm.DB, log logger.Logger, logService *LogService, settings LoginProtectionSettings) *LoginProtectionService {
+	if settings.MaxUserFailures <= 0 {
+		settings.MaxUserFailures = 5
+	}
+	if settings.MaxIPFailures <= 0 {
+		settings.MaxIPFailures = 20
+	}
+	if settings.FailureWindow <= 0 {
+		settings.FailureWindow = 15 * time.Minute
+	}
+	if settings.LockoutDuration <= 0 {
+		settings.LockoutDuration = 15 * time.Minute
+	}
+	if settings.BaseDelay < 0 {
+		settings.BaseDelay = 0
+	}
+	if settings.MaxDelay < settings.BaseDelay {
+		settings.MaxDelay = settings.BaseDelay
+	}
+
+	return &LoginProtectionService{
+		db:         db,
+		logger:     log,
+		logService: logService,
+		settings:   settings,
+	}
+}
+
+// Enabled indica si la protección está activa
+func (s *LoginProtectionService) Enabled() bool {
+	return s.settings.Enabled
+}
+
+// Check comprueba antes de verificar la contraseña que ni el usuario ni la IP estén bloqueados
+func (s *LoginProtectionService) Check(username, ip string) error {
+	now := time.Now()
+
+	var throttles []models.LoginThrottle
+	if err := s.db.
+		Where("(scope = ? AND identifier = ?) OR (scope = ? AND identifier = ?)",
+			models.LoginThrottleUser, userIdentifier(username), models.LoginThrottleIP, ip).
+		Where("locked_until > ?", now).
+		Find(&throttles).Error; err != nil {
+		s.logger.Errorf("Error al comprobar los bloqueos de login: %v", err)
+		return nil // No impedir el login por un fallo de la comprobación
+	}
+
+	var locked *LoginLockedError
+	for _, throttle := range throttles {
+		if locked == nil || throttle.LockedUntil.After(locked.Until) {
+			locked = &LoginLockedError{Scope: throttle.Scope, Until: *throttle.LockedUntil}
+		}
+	}
+	if locked != nil {
+		return locked
+	}
+
+	return nil
+}
+
+// RegisterFailure cuenta un intento fallido para el usuario y la IP, bloquea al alcanzar los
+// límites y retrasa la respuesta de forma progresiva según los fallos acumulados
+func (s *LoginProtectionService) RegisterFailure(username, ip string) {
+	now := time.Now()
+	failures := 0
+
+	if throttle := s.countFailure(models.LoginThrottleUser, userIdentifier(username), s.settings.MaxUserFailures, now); throttle != nil {
+		failures = throttle.Failures
+	}
+	if ip != "" {
+		if throttle := s.countFailure(models.LoginThrottleIP, ip, s.settings.MaxIPFailures, now); throttle != nil && throttle.Failures > failures {
+			failures = throttle.Failures
+		}
+	}
+
+	if delay := s.delayFor(failures); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// RegisterSuccess olvida los fallos del usuario tras comprobar su contraseña. Los de la IP se
+// mantienen hasta que caducan para que una cuenta válida no sirva para reiniciarlos
+func (s *LoginProtectionService) RegisterSuccess(username string) {
+	if err := s.db.Where("scope = ? AND identifier = ?", models.LoginThrottleUser, userIdentifier(username)).
+		Delete(&models.LoginThrottle{}).Error; err != nil {
+		s.logger.Warnf("Error al reiniciar los intentos fallidos de %s: %v", username, err)
+	}
+}
+
+// GetLockouts obtiene los bloqueos vigentes
+func (s *LoginProtectionService) GetLockouts() ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	if err := s.db.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&throttles).Error; err != nil {
+		s.logger.Errorf("Error al obtener los bloqueos de login: %v", err)
+		return nil, err
+	}
+
+	return throttles, nil
+}
+
+// Unlock levanta un bloqueo (de un usuario o de una IP) antes de que caduque
+func (s *LoginProtectionService) Unlock(id, adminID uint) error {
+	var throttle models.LoginThrottle
+	if err := s.db.First(&throttle, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLoginThrottleNotFound
+		}
+		return err
+	}
+
+	return s.unlock(&throttle, adminID)
+}
+
+// UnlockUser levanta el bloqueo de un usuario
+func (s *LoginProtectionService) UnlockUser(username string, adminID uint) error {
+	var throttle models.LoginThrottle
+	if err := s.db.Where("scope = ? AND identifier = ?", models.LoginThrottleUser, userIdentifier(username)).
+		First(&throttle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLoginThrottleNotFound
+		}
+		return err
+	}
+
+	return s.unlock(&throttle, adminID)
+}
+
+// Start inicia la limpieza periódica de los recuentos caducados
+func (s *LoginProtectionService) Start(interval time.Duration) {
+	if !s.Enabled() {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.purge()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+
+	s.logger.Infof("Protección del login contra fuerza bruta iniciada (%d fallos por usuario, %d por IP, bloqueo de %s)",
+		s.settings.MaxUserFailures, s.settings.MaxIPFailures, s.settings.LockoutDuration)
+}
+
+// Stop detiene la limpieza periódica
+func (s *LoginProtectionService) Stop() {
+	if s.stop == nil {
+		return
+	}
+
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+	s.logger.Info("Protección del login detenida")
+}
+
+// countFailure suma un fallo al recuento (reiniciándolo si el anterior cayó fuera de la
+// ventana) y bloquea al llegar al máximo. Devuelve el recuento actualizado
+func (s *LoginProtectionService) countFailure(scope, identifier string, max int, now time.Time) *models.LoginThrottle {
+	windowStart := now.Add(-s.settings.FailureWindow)
+
+	throttle := models.LoginThrottle{
+		Scope:          scope,
+		Identifier:     identifier,
+		Failures:       1,
+		FirstFailureAt: now,
+		LastFailureAt:  now,
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "identifier"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":         gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END", windowStart),
+			"first_failure_at": gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? THEN ? ELSE login_throttles.first_failure_at END", windowStart, now),
+			"last_failure_at":  now,
+			"updated_at":       now,
+		}),
+	}).Create(&throttle).Error
+	if err == nil {
+		err = s.db.Where("scope = ? AND identifier = ?", scope, identifier).First(&throttle).Error
+	}
+	if err != nil {
+		s.logger.Errorf("Error al registrar el intento de login fallido (%s %s): %v", scope, identifier, err)
+		return nil
+	}
+
+	if throttle.Failures < max || throttle.IsLocked(now) {
+		return &throttle
+	}
+
+	// Bloquear solo si nadie lo ha hecho ya (peticiones simultáneas)
+	lockedUntil := now.Add(s.settings.LockoutDuration)
+	result := s.db.Model(&models.LoginThrottle{}).
+		Where("id = ? AND (locked_until IS NULL OR locked_until <= ?)", throttle.ID, now).
+		Updates(map[string]interface{}{"locked_until": lockedUntil, "failures": 0})
+	if result.Error != nil {
+		s.logger.Errorf("Error al bloquear %s %s: %v", scope, identifier, result.Error)
+		return &throttle
+	}
+	if result.RowsAffected > 0 {
+		s.recordEvent(models.LogLevelWarn,
+			fmt.Sprintf("Login bloqueado para %s %s hasta %s tras %d intentos fallidos", scopeName(scope), identifier, lockedUntil.Format(time.RFC3339), throttle.Failures),
+			models.Metadata{
+				"event":        "login_lockout",
+				"scope":        scope,
+				"identifier":   identifier,
+				"failures":     throttle.Failures,
+				"locked_until": lockedUntil,
+			})
+	}
+
+	return &throttle
+}
+
+// unlock elimina el recuento y registra el desbloqueo
+func (s *LoginProtectionService) unlock(throttle *models.LoginThrottle, adminID uint) error {
+	if err := s.db.Delete(throttle).Error; err != nil {
+		s.logger.Errorf("Error al desbloquear %s %s: %v", throttle.Scope, throttle.Identifier, err)
+		return err
+	}
+
+	s.recordEvent(models.LogLevelInfo,
+		fmt.Sprintf("Login desbloqueado para %s %s por un administrador", scopeName(throttle.Scope), throttle.Identifier),
+		models.Metadata{
+			"event":      "login_unlock",
+			"scope":      throttle.Scope,
+			"identifier": throttle.Identifier,
+			"admin_id":   adminID,
+		})
+
+	return nil
+}
+
+// purge elimina los recuentos sin bloqueo vigente cuyo último fallo quedó fuera de la ventana
+func (s *LoginProtectionService) purge() {
+	now := time.Now()
+	result := s.db.
+		Where("last_failure_at < ?", now.Add(-s.settings.FailureWindow)).
+		Where("locked_until IS NULL OR locked_until <= ?", now).
+		Delete(&models.LoginThrottle{})
+	if result.Error != nil {
+		s.logger.Warnf("Error al limpiar los intentos de login caducados: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		s.logger.Debugf("%d recuentos de intentos de login caducados eliminados", result.RowsAffected)
+	}
+}
+
+// delayFor calcula el retraso de la respuesta tras un fallo: BaseDelay, duplicado en cada fallo
+// consecutivo hasta MaxDelay
+func (s *LoginProtectionService) delayFor(failures int) time.Duration {
+	if failures <= 0 || s.settings.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := s.settings.BaseDelay
+	for i := 1; i < failures && delay < s.settings.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.settings.MaxDelay {
+		delay = s.settings.MaxDelay
+	}
+
+	return delay
+}
+
+// recordEvent guarda un evento de seguridad en la tabla de logs
+func (s *LoginProtectionService) recordEvent(level models.LogLevel, message string, metadata models.Metadata) {
+	if s.logService == nil {
+		s.logger.Warn(message)
+		return
+	}
+	if err := s.logService.CreateLog(level, message, securityLogSource, metadata); err != nil {
+		s.logger.Warnf("Error al registrar el evento de seguridad: %v", err)
+	}
+}
+
+// userIdentifier normaliza el nombre de usuario con el que se cuentan los fallos
+func userIdentifier(username string) string {
+	return truncateRunes(strings.ToLower(strings.TrimSpace(username)), 255)
+}
+
+// scopeName nombre legible del ámbito de un bloqueo
+func scopeName(scope string) string {
+	if scope == models.LoginThrottleIP {
+		return "la IP"
+	}
+	return "el usuario"
+}
@@ -0,0 +1,524 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/oidc"
+	"gorm.io/gorm"
+)
+
+// OIDCStateTTL tiempo que tiene el usuario para completar el inicio de sesión en el IdP
+const OIDCStateTTL = 10 * time.Minute
+
+// Errores del inicio de sesión único
+var (
+	ErrOIDCDisabled      = errors.New("el inicio de sesión único no está habilitado")
+	ErrOIDCState         = errors.New("estado de inicio de sesión inválido o caducado")
+	ErrOIDCNotAuthorized = errors.New("el usuario no pertenece a ningún grupo autorizado del proveedor de identidad")
+	ErrOIDCAccount       = errors.New("ya existe una cuenta local con el mismo email")
+)
+
+// OIDCSettings configuración del inicio de sesión único con OpenID Connect
+type OIDCSettings struct {
+	Enabled       bool
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string      // Claim con el nombre de usuario (p. ej. preferred_username)
+	GroupsClaim   string      // Claim con los grupos del usuario; admite rutas con puntos
+	RoleMapping   []string    // "grupo=rol": grupos del IdP que conceden un rol global
+	DefaultRole   models.Role // Rol si ningún grupo coincide (vacío = denegar el acceso)
+	GroupMapping  []string    // "grupo=idGrupoServidores:rol": permisos sobre grupos de servidores
+	StateSecret   string      // Clave para firmar la cookie con el estado del flujo
+}
+
+// oidcGroupGrant permiso sobre un grupo de servidores concedido por un grupo del IdP
+type oidcGroupGrant struct {
+	GroupID uint
+	Role    models.Role
+}
+
+// OIDCFlow estado de un inicio de sesión en curso, guardado en una cookie firmada
+type OIDCFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+	jwt.RegisteredClaims
+}
+
+// OIDCService gestiona el inicio de sesión único: el flujo authorization code con PKCE, el
+// alta automática de los usuarios y la asignación de roles y permisos según sus grupos del IdP
+type OIDCService struct {
+	db          *gorm.DB
+	logger      logger.Logger
+	authService *AuthService
+	provider    *oidc.Provider
+	settings    OIDCSettings
+	stateKey    []byte
+	roles       map[string]models.Role
+	groups      map[string][]oidcGroupGrant
+}
+
+// NewOIDCService crea el servicio de inicio de sesión único. Las entradas de mapeo inválidas
+// se ignoran con un aviso
+func NewOIDCService(db *gorm.DB, log logger.Logger, authService *AuthService, settings OIDCSettings) *OIDCService {
+	if settings.UsernameClaim == "" {
+		settings.UsernameClaim = "preferred_username"
+	}
+	if settings.GroupsClaim == "" {
+		settings.GroupsClaim = "groups"
+	}
+	settings.DefaultRole = models.Role(strings.ToUpper(strings.TrimSpace(string(settings.DefaultRole))))
+	if settings.DefaultRole != "" && models.RoleRank(settings.DefaultRole) == 0 {
+		log.Warnf("OIDC: rol por defecto inválido %q, se denegará el acceso sin grupo mapeado", settings.DefaultRole)
+		settings.DefaultRole = ""
+	}
+
+	s := &OIDCService{
+		db:          db,
+		logger:      log,
+		authService: authService,
+		settings:    settings,
+		stateKey:    []byte("oidc-state:" + settings.StateSecret),
+		roles:       make(map[string]models.Role),
+		groups:      make(map[string][]oidcGroupGrant),
+	}
+
+	for _, entry := range settings.RoleMapping {
+		group, role, err := parseOIDCRoleEntry(entry)
+		if err != nil {
+			log.Warnf("OIDC: mapeo de rol ignorado: %v", err)
+			continue
+		}
+		if group != "" {
+			s.roles[group] = role
+		}
+	}
+
+	for _, entry := range settings.GroupMapping {
+		group, grant, err := parseOIDCGroupEntry(entry)
+		if err != nil {
+			log.Warnf("OIDC: mapeo de grupo ignorado: %v", err)
+			continue
+		}
+		if group != "" {
+			s.groups[group] = append(s.groups[group], grant)
+		}
+	}
+
+	if settings.Enabled {
+		s.provider = oidc.NewProvider(oidc.Config{
+			IssuerURL:    settings.IssuerURL,
+			ClientID:     settings.ClientID,
+			ClientSecret: settings.ClientSecret,
+			RedirectURL:  settings.RedirectURL,
+			Scopes:       settings.Scopes,
+		})
+	}
+
+	return s
+}
+
+// Enabled indica si el inicio de sesión único está habilitado
+func (s *OIDCService) Enabled() bool {
+	return s.settings.Enabled
+}
+
+// BeginLogin inicia el flujo: genera state, nonce y verificador PKCE y devuelve la URL de
+// autorización del IdP junto con el estado firmado que se guarda en la cookie
+func (s *OIDCService) BeginLogin(ctx context.Context, returnTo string) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+
+	flow := &OIDCFlow{ReturnTo: returnTo}
+	var err error
+	if flow.State, err = oidc.RandomString(32); err != nil {
+		return "", "", err
+	}
+	if flow.Nonce, err = oidc.RandomString(32); err != nil {
+		return "", "", err
+	}
+	if flow.Verifier, err = oidc.RandomString(48); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	flow.ExpiresAt = jwt.NewNumericDate(time.Now().Add(OIDCStateTTL))
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, flow).SignedString(s.stateKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, signed, nil
+}
+
+// CompleteLogin valida el state contra la cookie, canjea el código, verifica el ID token
+// (firma, emisor, audiencia, caducidad y nonce), da de alta o actualiza al usuario y abre
+// una sesión. Devuelve también la ruta a la que volver
+func (s *OIDCService) CompleteLogin(ctx context.Context, code, state, stateCookie string, client ClientInfo) (*TokenPair, *models.User, string, error) {
+	if !s.Enabled() {
+		return nil, nil, "", ErrOIDCDisabled
+	}
+
+	flow, err := s.parseFlow(stateCookie)
+	if err != nil || state == "" || flow.State != state {
+		return nil, nil, "", ErrOIDCState
+	}
+	if code == "" {
+		return nil, nil, flow.ReturnTo, fmt.Errorf("falta el código de autorización")
+	}
+
+	tokens, err := s.provider.Exchange(ctx, code, flow.Verifier)
+	if err != nil {
+		return nil, nil, flow.ReturnTo, err
+	}
+
+	idToken, err := s.provider.VerifyIDToken(ctx, tokens.IDToken, flow.Nonce)
+	if err != nil {
+		return nil, nil, flow.ReturnTo, err
+	}
+
+	claims := idToken.Claims
+	// Los IdP que no incluyen los grupos en el ID token suelen publicarlos en userinfo
+	if _, ok := oidc.StringsClaim(claims, s.settings.GroupsClaim); !ok {
+		if info, err := s.provider.UserInfo(ctx, tokens.AccessToken); err != nil {
+			s.logger.Warnf("OIDC: %v", err)
+		} else if info["sub"] == idToken.Subject {
+			for key, value := range info {
+				if _, exists := claims[key]; !exists {
+					claims[key] = value
+				}
+			}
+		}
+	}
+
+	user, err := s.provisionUser(idToken.Subject, claims)
+	if err != nil {
+		return nil, nil, flow.ReturnTo, err
+	}
+
+	pair, err := s.authService.CreateSession(user, client)
+	if err != nil {
+		return nil, nil, flow.ReturnTo, err
+	}
+
+	s.logger.Infof("Login OIDC exitoso para usuario: %s", user.Username)
+	return pair, user, flow.ReturnTo, nil
+}
+
+// parseFlow verifica la firma y la caducidad de la cookie de estado
+func (s *OIDCService) parseFlow(signed string) (*OIDCFlow, error) {
+	if signed == "" {
+		return nil, ErrOIDCState
+	}
+
+	flow := &OIDCFlow{}
+	_, err := jwt.ParseWithClaims(signed, flow, func(token *jwt.Token) (interface{}, error) {
+		return s.stateKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrOIDCState
+	}
+
+	return flow, nil
+}
+
+// provisionUser busca al usuario por su sub en el IdP y lo crea si no existe (alta "just in
+// time"). En cada inicio de sesión se sincronizan su email, su rol y sus permisos de grupo
+func (s *OIDCService) provisionUser(subject string, claims map[string]interface{}) (*models.User, error) {
+	idpGroups, _ := oidc.StringsClaim(claims, s.settings.GroupsClaim)
+	role := s.mapRole(idpGroups)
+	if role == "" {
+		s.logger.Warnf("OIDC: acceso denegado a %s, ningún grupo mapeado (%v)", subject, idpGroups)
+		return nil, ErrOIDCNotAuthorized
+	}
+
+	email := strings.TrimSpace(oidc.StringClaim(claims, "email"))
+	if email == "" {
+		return nil, fmt.Errorf("el proveedor de identidad no devolvió el email del usuario")
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Incluir usuarios eliminados: un usuario borrado no se vuelve a crear en el siguiente login
+		err := tx.Unscoped().Where("auth_provider = ? AND external_id = ?", models.AuthProviderOIDC, subject).First(&user).Error
+		switch {
+		case err == nil:
+			if user.DeletedAt.Valid || user.Disabled {
+				return ErrUserDisabled
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.createOrLinkUser(tx, &user, subject, email, claims, role); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return s.syncUser(tx, &user, email, role, idpGroups)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// createOrLinkUser crea el usuario del IdP. Si ya existe una cuenta local no administradora con
+// el mismo email y el IdP lo ha verificado, la cuenta se vincula al IdP en lugar de duplicarla
+func (s *OIDCService) createOrLinkUser(tx *gorm.DB, user *models.User, subject, email string, claims map[string]interface{}, role models.Role) error {
+	var existing models.User
+	err := tx.Unscoped().Where("email = ?", email).First(&existing).Error
+	if err == nil {
+		if existing.DeletedAt.Valid || !existing.IsLocal() || existing.Role == models.RoleAdmin || !oidc.BoolClaim(claims, "email_verified") {
+			return ErrOIDCAccount
+		}
+
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"auth_provider": models.AuthProviderOIDC,
+			"external_id":   subject,
+		}).Error; err != nil {
+			return err
+		}
+		existing.AuthProvider = models.AuthProviderOIDC
+		existing.ExternalID = &subject
+		*user = existing
+
+		s.logger.Infof("OIDC: cuenta local %s vinculada al proveedor de identidad", existing.Username)
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	username, err := s.availableUsername(tx, oidc.StringClaim(claims, s.settings.UsernameClaim), email)
+	if err != nil {
+		return err
+	}
+
+	*user = models.User{
+		Username:     username,
+		Email:        email,
+		Role:         role,
+		AuthProvider: models.AuthProviderOIDC,
+		ExternalID:   &subject,
+	}
+
+	// Contraseña aleatoria: estos usuarios no pueden iniciar sesión con contraseña
+	password, err := randomPassword()
+	if err != nil {
+		return err
+	}
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+
+	s.logger.Infof("OIDC: usuario %s dado de alta con rol %s", username, role)
+	return nil
+}
+
+// availableUsername elige un nombre de usuario libre a partir del claim configurado o del email
+func (s *OIDCService) availableUsername(tx *gorm.DB, preferred, email string) (string, error) {
+	base := strings.TrimSpace(preferred)
+	if base == "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = truncateRunes(base, 45)
+	if len([]rune(base)) < 3 {
+		base += "-sso"
+	}
+
+	for i := 1; i <= 20; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no se encontró un nombre de usuario libre para %s", base)
+}
+
+// syncUser actualiza el email, el rol y los permisos de grupo del usuario según el IdP
+func (s *OIDCService) syncUser(tx *gorm.DB, user *models.User, email string, role models.Role, idpGroups []string) error {
+	now := time.Now()
+	updates := map[string]interface{}{"last_login": now}
+	user.LastLogin = &now
+
+	if email != user.Email {
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", email, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			updates["email"] = email
+			user.Email = email
+		} else {
+			s.logger.Warnf("OIDC: el email %s ya está en uso, no se actualiza el del usuario %s", email, user.Username)
+		}
+	}
+
+	roleChanged := user.Role != role
+	if roleChanged {
+		updates["role"] = role
+		user.Role = role
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// Un cambio de rol invalida las sesiones anteriores, igual que al cambiarlo a mano
+	if roleChanged {
+		if _, err := revokeUserSessions(tx, user.ID, models.SessionRevokedRoleChange, 0); err != nil {
+			return err
+		}
+		s.logger.Infof("OIDC: rol del usuario %s actualizado a %s", user.Username, role)
+	}
+
+	return s.syncGroupPermissions(tx, user.ID, idpGroups)
+}
+
+// syncGroupPermissions concede los permisos de grupo mapeados desde los grupos del IdP y retira
+// los sincronizados anteriormente que ya no correspondan. Los permisos manuales no se tocan
+func (s *OIDCService) syncGroupPermissions(tx *gorm.DB, userID uint, idpGroups []string) error {
+	desired := make(map[uint]models.Role)
+	for _, group := range idpGroups {
+		for _, grant := range s.groups[group] {
+			if models.RoleRank(grant.Role) > models.RoleRank(desired[grant.GroupID]) {
+				desired[grant.GroupID] = grant.Role
+			}
+		}
+	}
+
+	var existing []models.GroupPermission
+	if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	for _, permission := range existing {
+		role, wanted := desired[permission.GroupID]
+		delete(desired, permission.GroupID)
+
+		if permission.Source != models.PermissionSourceOIDC {
+			continue
+		}
+
+		switch {
+		case !wanted:
+			if err := tx.Delete(&models.GroupPermission{}, permission.ID).Error; err != nil {
+				return err
+			}
+		case role != permission.Role:
+			if err := tx.Model(&models.GroupPermission{}).Where("id = ?", permission.ID).Update("role", role).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for groupID, role := range desired {
+		var count int64
+		if err := tx.Model(&models.ServerGroup{}).Where("id = ?", groupID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			s.logger.Warnf("OIDC: el grupo de servidores %d del mapeo no existe", groupID)
+			continue
+		}
+
+		uid := userID
+		if err := tx.Create(&models.GroupPermission{
+			UserID:  &uid,
+			GroupID: groupID,
+			Role:    role,
+			Source:  models.PermissionSourceOIDC,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapRole obtiene el rol de mayor privilegio entre los grupos del IdP del usuario, o el rol
+// por defecto si ninguno está mapeado
+func (s *OIDCService) mapRole(idpGroups []string) models.Role {
+	var role models.Role
+	for _, group := range idpGroups {
+		if mapped, ok := s.roles[group]; ok && models.RoleRank(mapped) > models.RoleRank(role) {
+			role = mapped
+		}
+	}
+
+	if role == "" {
+		return s.settings.DefaultRole
+	}
+	return role
+}
+
+// parseOIDCRoleEntry interpreta una entrada "grupo=rol"
+func parseOIDCRoleEntry(entry string) (string, models.Role, error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return "", "", nil
+	}
+
+	group, value, found := strings.Cut(entry, "=")
+	role := models.Role(strings.ToUpper(strings.TrimSpace(value)))
+	if !found || strings.TrimSpace(group) == "" || models.RoleRank(role) == 0 {
+		return "", "", fmt.Errorf("entrada inválida %q (formato grupo=rol)", entry)
+	}
+
+	return strings.TrimSpace(group), role, nil
+}
+
+// parseOIDCGroupEntry interpreta una entrada "grupo=idGrupoServidores:rol"
+func parseOIDCGroupEntry(entry string) (string, oidcGroupGrant, error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return "", oidcGroupGrant{}, nil
+	}
+
+	invalid := fmt.Errorf("entrada inválida %q (formato grupo=idGrupo:rol)", entry)
+
+	group, value, found := strings.Cut(entry, "=")
+	if !found || strings.TrimSpace(group) == "" {
+		return "", oidcGroupGrant{}, invalid
+	}
+
+	idText, roleText, found := strings.Cut(value, ":")
+	id, err := strconv.ParseUint(strings.TrimSpace(idText), 10, 32)
+	role := models.Role(strings.ToUpper(strings.TrimSpace(roleText)))
+	if !found || err != nil || id == 0 || models.RoleRank(role) == 0 {
+		return "", oidcGroupGrant{}, invalid
+	}
+
+	return strings.TrimSpace(group), oidcGroupGrant{GroupID: uint(id), Role: role}, nil
+}
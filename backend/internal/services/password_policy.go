@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// passwordMaxBytes longitud máxima que bcrypt tiene en cuenta
+const passwordMaxBytes = 72
+
+// ErrPasswordPolicy la contraseña no cumple la política configurada
+var ErrPasswordPolicy = errors.New("la contraseña no cumple la política de seguridad")
+
+// PasswordPolicy requisitos de las contraseñas locales
+type PasswordPolicy struct {
+	MinLength      int  // Caracteres mínimos
+	RequireUpper   bool // Al menos una mayúscula
+	RequireLower   bool // Al menos una minúscula
+	RequireDigit   bool // Al menos un número
+	RequireSymbol  bool // Al menos un carácter que no sea letra ni número
+	RejectUsername bool // No puede contener el nombre de usuario
+}
+
+// Validate comprueba una contraseña contra la política
+func (p PasswordPolicy) Validate(password, username string) error {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("debe tener al menos %d caracteres", p.MinLength))
+	}
+	if len(password) > passwordMaxBytes {
+		problems = append(problems, fmt.Sprintf("no puede superar los %d bytes", passwordMaxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "debe incluir una mayúscula")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "debe incluir una minúscula")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "debe incluir un número")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "debe incluir un símbolo")
+	}
+
+	if p.RejectUsername && len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "no puede contener el nombre de usuario")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrPasswordPolicy, strings.Join(problems, ", "))
+	}
+
+	return nil
+}
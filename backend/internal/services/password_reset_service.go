@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+// Errores del restablecimiento de contraseña
+var (
+	ErrResetTokenInvalid = errors.New("el enlace para restablecer la contraseña no es válido o ha caducado")
+	ErrResetNotLocal     = errors.New("la contraseña de este usuario la gestiona su proveedor de identidad")
+)
+
+// PasswordResetSettings configuración del restablecimiento de contraseña
+type PasswordResetSettings struct {
+	TTL    time.Duration // Validez de los enlaces
+	AppURL string        // Página del frontend que recibe el token (?reset_token=)
+}
+
+// PasswordResetClaims token firmado para restablecer la contraseña. La huella de la contraseña
+// actual hace que el token deje de valer en cuanto la contraseña cambia (un solo uso)
+type PasswordResetClaims struct {
+	Fingerprint string `json:"fp"`
+	jwt.RegisteredClaims
+}
+
+// PasswordResetService restablece contraseñas locales con enlaces de un solo uso enviados por
+// correo o generados por un admin
+type PasswordResetService struct {
+	db          *gorm.DB
+	logger      logger.Logger
+	userService *UserService
+	notifier    *notifications.NotificationManager
+	settings    PasswordResetSettings
+	key         []byte
+}
+
+// NewPasswordResetService crea el servicio de restablecimiento. La clave de firma se deriva del
+// secreto JWT para que los tokens no sirvan como tokens de acceso
+func NewPasswordResetService(db *gorm.DB, log logger.Logger, userService *UserService, notifier *notifications.NotificationManager, secret string, settings PasswordResetSettings) *PasswordResetService {
+	if settings.TTL <= 0 {
+		settings.TTL = time.Hour
+	}
+
+	return &PasswordResetService{
+		db:          db,
+		logger:      log,
+		userService: userService,
+		notifier:    notifier,
+		settings:    settings,
+		key:         []byte("password-reset:" + secret),
+	}
+}
+
+// EmailEnabled indica si los enlaces pueden enviarse por correo
+func (s *PasswordResetService) EmailEnabled() bool {
+	return s.notifier.EmailEnabled()
+}
+
+// RequestReset envía por correo un enlace de restablecimiento al usuario local con ese email.
+// No informa de si el email existe, para no revelar qué cuentas hay registradas
+func (s *PasswordResetService) RequestReset(email string) error {
+	if !s.notifier.EmailEnabled() {
+		return notifications.ErrEmailDisabled
+	}
+
+	email = normalizeEmail(email)
+
+	var user models.User
+	if err := s.db.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warnf("Restablecimiento solicitado para un email no registrado: %s", email)
+			return nil
+		}
+		return err
+	}
+	if user.Disabled || !user.IsLocal() {
+		s.logger.Warnf("Restablecimiento solicitado para el usuario %s, que no admite contraseña local", user.Username)
+		return nil
+	}
+
+	link, expiresAt, err := s.issue(&user)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Se ha solicitado restablecer la contraseña del usuario %s en el panel de monitorización de servidores.\n\n"+
+		"Para elegir una nueva contraseña abra este enlace antes del %s:\n\n%s\n\n"+
+		"Si no ha sido usted puede ignorar este correo: su contraseña no cambiará.\n",
+		user.Username, expiresAt.Format("02/01/2006 15:04"), link)
+
+	if err := s.notifier.SendEmail(user.Email, "Restablecer la contraseña", body); err != nil {
+		s.logger.Errorf("No se pudo enviar el enlace de restablecimiento a %s: %v", user.Email, err)
+		return err
+	}
+
+	s.logger.Infof("Enlace de restablecimiento enviado al usuario %s", user.Username)
+	return nil
+}
+
+// IssueResetLink genera un enlace de restablecimiento para que un admin se lo haga llegar al
+// usuario
+func (s *PasswordResetService) IssueResetLink(userID uint) (string, time.Time, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", time.Time{}, ErrUserNotFound
+		}
+		return "", time.Time{}, err
+	}
+	if !user.IsLocal() {
+		return "", time.Time{}, ErrResetNotLocal
+	}
+
+	link, expiresAt, err := s.issue(&user)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	s.logger.Infof("Enlace de restablecimiento generado para el usuario %s", user.Username)
+	return link, expiresAt, nil
+}
+
+// ResetPassword establece una nueva contraseña con un token de restablecimiento. El cambio
+// revoca todas las sesiones del usuario e invalida el token. Devuelve el usuario
+func (s *PasswordResetService) ResetPassword(token, newPassword string) (*models.User, error) {
+	claims := &PasswordResetClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrResetTokenInvalid
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, ErrResetTokenInvalid
+	}
+
+	var user models.User
+	if err := s.db.First(&user, uint(userID)).Error; err != nil {
+		return nil, ErrResetTokenInvalid
+	}
+	if user.Disabled || !user.IsLocal() {
+		return nil, ErrResetTokenInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Fingerprint), []byte(passwordFingerprint(&user))) != 1 {
+		return nil, ErrResetTokenInvalid
+	}
+
+	if err := s.userService.ChangePassword(user.ID, newPassword); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Contraseña restablecida para el usuario %s", user.Username)
+	return &user, nil
+}
+
+// issue firma un token de restablecimiento y construye el enlace del frontend
+func (s *PasswordResetService) issue(user *models.User) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.settings.TTL)
+
+	claims := &PasswordResetClaims{
+		Fingerprint: passwordFingerprint(user),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.key)
+	if err != nil {
+		s.logger.Errorf("Error al firmar el token de restablecimiento: %v", err)
+		return "", time.Time{}, err
+	}
+
+	return appLink(s.settings.AppURL, "reset_token", signed), expiresAt, nil
+}
+
+// passwordFingerprint huella de la contraseña actual (cambia con cada cambio de contraseña)
+func passwordFingerprint(user *models.User) string {
+	sum := sha256.Sum256([]byte(strconv.FormatUint(uint64(user.ID), 10) + ":" + user.Password))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/interfaces"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Asegurar que PermissionService implementa la interfaz ServerAccessInterface
+var _ interfaces.ServerAccessInterface = &PermissionService{}
+
+// AccessScope servidores y grupos a los que puede acceder un usuario y con qué rol. Los
+// administradores y los usuarios sin permisos de grupo (propios o de sus equipos) no están
+// restringidos: se aplica su rol global a todos los servidores. El resto solo accede a los
+// servidores de sus grupos (incluidos los subgrupos) con el mayor rol que le concedan
+type AccessScope struct {
+	UserID  uint
+	Role    models.Role // Rol global
+	TeamIDs []uint      // Equipos de los que el usuario es miembro
+
+	restricted bool
+	groups     map[uint]models.Role // Rol efectivo por grupo (solo si restricted)
+	servers    map[uint]models.Role // Rol efectivo por servidor (solo si restricted)
+}
+
+// Restricted indica si el acceso del usuario está limitado por permisos de grupo
+func (s *AccessScope) Restricted() bool {
+	return s.restricted
+}
+
+// ServerRole rol efectivo del usuario sobre un servidor ("" si no puede verlo)
+func (s *AccessScope) ServerRole(serverID uint) models.Role {
+	if !s.restricted {
+		return s.Role
+	}
+	return s.servers[serverID]
+}
+
+// GroupRole rol efectivo del usuario sobre un grupo ("" si no puede verlo)
+func (s *AccessScope) GroupRole(groupID uint) models.Role {
+	if !s.restricted {
+		return s.Role
+	}
+	return s.groups[groupID]
+}
+
+// CanView indica si el usuario puede ver un servidor, sus métricas y sus alertas
+func (s *AccessScope) CanView(serverID uint) bool {
+	return s.Allows(serverID, models.RoleViewer)
+}
+
+// Allows indica si el usuario tiene al menos minRole sobre un servidor
+func (s *AccessScope) Allows(serverID uint, minRole models.Role) bool {
+	return models.RoleIncludes(s.ServerRole(serverID), minRole)
+}
+
+// AllowsGroup indica si el usuario tiene al menos minRole sobre un grupo
+func (s *AccessScope) AllowsGroup(groupID uint, minRole models.Role) bool {
+	return models.RoleIncludes(s.GroupRole(groupID), minRole)
+}
+
+// AllowsGlobal indica si el usuario tiene al menos minRole sobre todos los servidores, lo
+// que exigen los recursos que no pertenecen a ningún grupo
+func (s *AccessScope) AllowsGlobal(minRole models.Role) bool {
+	return !s.restricted && models.RoleIncludes(s.Role, minRole)
+}
+
+// AllowsAny indica si el usuario tiene al menos minRole sobre algún grupo o servidor
+func (s *AccessScope) AllowsAny(minRole models.Role) bool {
+	if !s.restricted {
+		return models.RoleIncludes(s.Role, minRole)
+	}
+
+	for _, role := range s.groups {
+		if models.RoleIncludes(role, minRole) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsThreshold indica si el usuario tiene al menos minRole sobre el alcance de un umbral:
+// su servidor, su grupo o, en los umbrales que aplican a todos los servidores, todos ellos
+func (s *AccessScope) AllowsThreshold(threshold *models.AlertThreshold, minRole models.Role) bool {
+	switch {
+	case threshold.ServerID != nil:
+		return s.Allows(*threshold.ServerID, minRole)
+	case threshold.GroupID != nil:
+		return s.AllowsGroup(*threshold.GroupID, minRole)
+	default:
+		return s.AllowsGlobal(minRole)
+	}
+}
+
+// AllowsSilence indica si el usuario tiene al menos minRole sobre todo lo que puede silenciar un
+// silencio: cada uno de sus servidores y grupos o, si no se limita a ninguno, todos los servidores
+func (s *AccessScope) AllowsSilence(silence *models.Silence, minRole models.Role) bool {
+	if len(silence.ServerIDs) == 0 && len(silence.GroupIDs) == 0 {
+		return s.AllowsGlobal(minRole)
+	}
+
+	for _, serverID := range silence.ServerIDs {
+		if !s.Allows(serverID, minRole) {
+			return false
+		}
+	}
+	for _, groupID := range silence.GroupIDs {
+		if !s.AllowsGroup(groupID, minRole) {
+			return false
+		}
+	}
+	return true
+}
+
+// ServerIDs servidores sobre los que el usuario tiene al menos minRole, ordenados. El segundo
+// valor es false si el usuario no está restringido y la lista no aplica
+func (s *AccessScope) ServerIDs(minRole models.Role) ([]uint, bool) {
+	if !s.restricted {
+		return nil, false
+	}
+
+	ids := []uint{}
+	for id, role := range s.servers {
+		if models.RoleIncludes(role, minRole) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, true
+}
+
+// PermissionService gestiona los permisos de los usuarios sobre grupos de servidores y calcula
+// el alcance de acceso de cada usuario
+type PermissionService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewPermissionService crea un nuevo servicio de permisos por grupo
+func NewPermissionService(db *gorm.DB, log logger.Logger) *PermissionService {
+	return &PermissionService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// GetPermissions obtiene los permisos de grupo, opcionalmente de un usuario, de un equipo o
+// de un grupo
+func (ps *PermissionService) GetPermissions(userID, teamID, groupID *uint) ([]models.GroupPermission, error) {
+	query := ps.db.Order("id").Preload("User").Preload("Team").Preload("Group")
+
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if teamID != nil {
+		query = query.Where("team_id = ?", *teamID)
+	}
+
+	if groupID != nil {
+		query = query.Where("group_id = ?", *groupID)
+	}
+
+	var permissions []models.GroupPermission
+	if err := query.Find(&permissions).Error; err != nil {
+		ps.logger.Errorf("Error al obtener permisos de grupo: %v", err)
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetUserPermissions obtiene los permisos de grupo que se aplican a un usuario: los suyos y
+// los de sus equipos
+func (ps *PermissionService) GetUserPermissions(userID uint, teamIDs []uint) ([]models.GroupPermission, error) {
+	permissions, err := ps.bindingsFor(ps.db.Order("id").Preload("User").Preload("Team").Preload("Group"), userID, teamIDs)
+	if err != nil {
+		ps.logger.Errorf("Error al obtener permisos del usuario %d: %v", userID, err)
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetPermission obtiene un permiso de grupo por ID
+func (ps *PermissionService) GetPermission(id uint) (*models.GroupPermission, error) {
+	var permission models.GroupPermission
+	if err := ps.db.Preload("User").Preload("Team").Preload("Group").First(&permission, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("permiso no encontrado")
+		}
+		return nil, err
+	}
+
+	return &permission, nil
+}
+
+// CreatePermission concede a un usuario o a un equipo un rol sobre un grupo
+func (ps *PermissionService) CreatePermission(permission *models.GroupPermission) error {
+	if err := permission.Validate(); err != nil {
+		return err
+	}
+
+	grantee := "usuario"
+	granteeQuery := ps.db.Model(&models.GroupPermission{})
+	if permission.UserID != nil {
+		if err := ps.db.First(&models.User{}, *permission.UserID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("usuario no encontrado")
+			}
+			return err
+		}
+		granteeQuery = granteeQuery.Where("user_id = ?", *permission.UserID)
+	} else {
+		grantee = "equipo"
+		if err := ps.db.First(&models.Team{}, *permission.TeamID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("equipo no encontrado")
+			}
+			return err
+		}
+		granteeQuery = granteeQuery.Where("team_id = ?", *permission.TeamID)
+	}
+
+	if err := ps.db.First(&models.ServerGroup{}, permission.GroupID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("grupo no encontrado")
+		}
+		return err
+	}
+
+	var count int64
+	if err := granteeQuery.Where("group_id = ?", permission.GroupID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("el %s ya tiene un permiso sobre el grupo %d", grantee, permission.GroupID)
+	}
+
+	if err := ps.db.Create(permission).Error; err != nil {
+		ps.logger.Errorf("Error al crear permiso de grupo: %v", err)
+		return err
+	}
+
+	ps.logger.Infof("Permiso concedido: %s %d con rol %s en el grupo %d", grantee, permission.GranteeID(), permission.Role, permission.GroupID)
+	return nil
+}
+
+// UpdatePermissionRole cambia el rol de un permiso de grupo. Un permiso sincronizado desde el
+// IdP pasa a ser manual y deja de sincronizarse
+func (ps *PermissionService) UpdatePermissionRole(id uint, role models.Role) (*models.GroupPermission, error) {
+	permission, err := ps.GetPermission(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if models.RoleRank(role) == 0 {
+		return nil, fmt.Errorf("rol inválido: %s", role)
+	}
+
+	if err := ps.db.Model(permission).Updates(map[string]interface{}{"role": role, "source": ""}).Error; err != nil {
+		ps.logger.Errorf("Error al actualizar permiso de grupo %d: %v", id, err)
+		return nil, err
+	}
+	permission.Role = role
+	permission.Source = ""
+
+	ps.logger.Infof("Permiso %d actualizado: rol %s", id, role)
+	return permission, nil
+}
+
+// DeletePermission retira un permiso de grupo
+func (ps *PermissionService) DeletePermission(id uint) error {
+	result := ps.db.Delete(&models.GroupPermission{}, id)
+	if result.Error != nil {
+		ps.logger.Errorf("Error al eliminar permiso de grupo: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("permiso no encontrado")
+	}
+
+	ps.logger.Infof("Permiso de grupo eliminado: %d", id)
+	return nil
+}
+
+// ScopeFor calcula el alcance de acceso de un usuario con el rol global indicado
+func (ps *PermissionService) ScopeFor(userID uint, role models.Role) (*AccessScope, error) {
+	teamIDs, err := teamIDsForUser(ps.db, userID)
+	if err != nil {
+		ps.logger.Errorf("Error al obtener equipos del usuario %d: %v", userID, err)
+		return nil, err
+	}
+
+	scope := &AccessScope{UserID: userID, Role: role, TeamIDs: teamIDs}
+	if role == models.RoleAdmin {
+		return scope, nil
+	}
+
+	permissions, err := ps.bindingsFor(ps.db, userID, teamIDs)
+	if err != nil {
+		ps.logger.Errorf("Error al obtener permisos del usuario %d: %v", userID, err)
+		return nil, err
+	}
+	if len(permissions) == 0 {
+		return scope, nil
+	}
+
+	scope.restricted = true
+	scope.groups = make(map[uint]models.Role)
+	scope.servers = make(map[uint]models.Role)
+
+	// Cada permiso se hereda a todos los subgrupos; prevalece el mayor rol
+	for _, permission := range permissions {
+		groupIDs, err := groupTreeIDs(ps.db, permission.GroupID)
+		if err != nil {
+			ps.logger.Errorf("Error al obtener los subgrupos del grupo %d: %v", permission.GroupID, err)
+			return nil, err
+		}
+		for _, id := range groupIDs {
+			grantRole(scope.groups, id, permission.Role)
+		}
+	}
+
+	groupIDs := make([]uint, 0, len(scope.groups))
+	for id := range scope.groups {
+		groupIDs = append(groupIDs, id)
+	}
+
+	var links []struct {
+		ServerGroupID uint
+		ServerID      uint
+	}
+	if err := ps.db.Table("server_group_servers").
+		Select("server_group_id, server_id").
+		Where("server_group_id IN ?", groupIDs).
+		Scan(&links).Error; err != nil {
+		ps.logger.Errorf("Error al obtener los servidores de los grupos del usuario %d: %v", userID, err)
+		return nil, err
+	}
+
+	for _, link := range links {
+		grantRole(scope.servers, link.ServerID, scope.groups[link.ServerGroupID])
+	}
+
+	return scope, nil
+}
+
+// CanViewServer indica si un usuario puede ver un servidor. Lo usan las conexiones WebSocket,
+// que no llevan el rol en el contexto
+func (ps *PermissionService) CanViewServer(userID, serverID uint) (bool, error) {
+	var user models.User
+	if err := ps.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	scope, err := ps.ScopeFor(user.ID, user.Role)
+	if err != nil {
+		return false, err
+	}
+
+	return scope.CanView(serverID), nil
+}
+
+// bindingsFor obtiene los permisos de grupo concedidos a un usuario o a alguno de sus equipos
+func (ps *PermissionService) bindingsFor(query *gorm.DB, userID uint, teamIDs []uint) ([]models.GroupPermission, error) {
+	if len(teamIDs) > 0 {
+		query = query.Where("user_id = ? OR team_id IN ?", userID, teamIDs)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	permissions := []models.GroupPermission{}
+	if err := query.Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// grantRole asigna role a la clave si supera el rol que ya tenía
+func grantRole(roles map[uint]models.Role, id uint, role models.Role) {
+	if models.RoleRank(role) > models.RoleRank(roles[id]) {
+		roles[id] = role
+	}
+}
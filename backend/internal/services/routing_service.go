@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+// Asegurar que RoutingService implementa la interfaz notifications.RouteProvider
+var _ notifications.RouteProvider = &RoutingService{}
+
+// RoutingService gestiona los puntos de contacto y las reglas de enrutamiento de notificaciones
+type RoutingService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewRoutingService crea un nuevo servicio de enrutamiento de notificaciones
+func NewRoutingService(db *gorm.DB, log logger.Logger) *RoutingService {
+	return &RoutingService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// CreateContactPoint crea un nuevo punto de contacto
+func (rs *RoutingService) CreateContactPoint(cp *models.ContactPoint) error {
+	if err := cp.Validate(); err != nil {
+		return err
+	}
+
+	if err := validateOwnerTeam(rs.db, cp.OwnerTeamID); err != nil {
+		return err
+	}
+
+	if err := rs.db.Create(cp).Error; err != nil {
+		rs.logger.Errorf("Error al crear punto de contacto: %v", err)
+		return err
+	}
+
+	rs.logger.Infof("Punto de contacto creado: %s (%s)", cp.Name, cp.Type)
+	return nil
+}
+
+// UpdateContactPoint actualiza un punto de contacto existente
+func (rs *RoutingService) UpdateContactPoint(cp *models.ContactPoint) error {
+	if err := cp.Validate(); err != nil {
+		return err
+	}
+
+	if err := validateOwnerTeam(rs.db, cp.OwnerTeamID); err != nil {
+		return err
+	}
+
+	existing, err := rs.GetContactPoint(cp.ID)
+	if err != nil {
+		return err
+	}
+	cp.CreatedBy = existing.CreatedBy
+	cp.CreatedAt = existing.CreatedAt
+
+	if err := rs.db.Save(cp).Error; err != nil {
+		rs.logger.Errorf("Error al actualizar punto de contacto: %v", err)
+		return err
+	}
+
+	rs.logger.Infof("Punto de contacto actualizado: %s", cp.Name)
+	return nil
+}
+
+// DeleteContactPoint elimina un punto de contacto si ninguna regla ni política de escalado lo utiliza
+func (rs *RoutingService) DeleteContactPoint(id uint) error {
+	var routes []models.NotificationRoute
+	if err := rs.db.Find(&routes).Error; err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		for _, cpID := range route.ContactPointIDs {
+			if cpID == id {
+				return fmt.Errorf("el punto de contacto está en uso por la regla '%s'", route.Name)
+			}
+		}
+	}
+
+	var steps []models.EscalationStep
+	if err := rs.db.Find(&steps).Error; err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		for _, cpID := range step.ContactPointIDs {
+			if cpID == id {
+				return fmt.Errorf("el punto de contacto está en uso por una política de escalado")
+			}
+		}
+	}
+
+	if err := rs.db.Delete(&models.ContactPoint{}, id).Error; err != nil {
+		rs.logger.Errorf("Error al eliminar punto de contacto: %v", err)
+		return err
+	}
+
+	rs.logger.Infof("Punto de contacto eliminado: %d", id)
+	return nil
+}
+
+// GetContactPoint obtiene un punto de contacto por ID
+func (rs *RoutingService) GetContactPoint(id uint) (*models.ContactPoint, error) {
+	var cp models.ContactPoint
+	if err := rs.db.First(&cp, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("punto de contacto no encontrado")
+		}
+		rs.logger.Errorf("Error al obtener punto de contacto: %v", err)
+		return nil, err
+	}
+
+	return &cp, nil
+}
+
+// GetAllContactPoints obtiene todos los puntos de contacto
+func (rs *RoutingService) GetAllContactPoints() ([]models.ContactPoint, error) {
+	var contactPoints []models.ContactPoint
+	if err := rs.db.Order("name ASC").Find(&contactPoints).Error; err != nil {
+		rs.logger.Errorf("Error al obtener puntos de contacto: %v", err)
+		return nil, err
+	}
+
+	return contactPoints, nil
+}
+
+// CreateRoute crea una nueva regla de enrutamiento
+func (rs *RoutingService) CreateRoute(route *models.NotificationRoute) error {
+	if err := rs.validateRoute(route); err != nil {
+		return err
+	}
+
+	if err := rs.db.Create(route).Error; err != nil {
+		rs.logger.Errorf("Error al crear regla de enrutamiento: %v", err)
+		return err
+	}
+
+	rs.logger.Infof("Regla de enrutamiento creada: %s (posición %d)", route.Name, route.Position)
+	return nil
+}
+
+// UpdateRoute actualiza una regla de enrutamiento existente
+func (rs *RoutingService) UpdateRoute(route *models.NotificationRoute) error {
+	if err := rs.validateRoute(route); err != nil {
+		return err
+	}
+
+	existing, err := rs.GetRoute(route.ID)
+	if err != nil {
+		return err
+	}
+	route.CreatedBy = existing.CreatedBy
+	route.CreatedAt = existing.CreatedAt
+
+	if err := rs.db.Save(route).Error; err != nil {
+		rs.logger.Errorf("Error al actualizar regla de enrutamiento: %v", err)
+		return err
+	}
+
+	rs.logger.Infof("Regla de enrutamiento actualizada: %s", route.Name)
+	return nil
+}
+
+// DeleteRoute elimina una regla de enrutamiento
+func (rs *RoutingService) DeleteRoute(id uint) error {
+	if err := rs.db.Delete(&models.NotificationRoute{}, id).Error; err != nil {
+		rs.logger.Errorf("Error al eliminar regla de enrutamiento: %v", err)
+		return err
+	}
+
+	rs.logger.Infof("Regla de enrutamiento eliminada: %d", id)
+	return nil
+}
+
+// GetRoute obtiene una regla de enrutamiento por ID
+func (rs *RoutingService) GetRoute(id uint) (*models.NotificationRoute, error) {
+	var route models.NotificationRoute
+	if err := rs.db.First(&route, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("regla de enrutamiento no encontrada")
+		}
+		rs.logger.Errorf("Error al obtener regla de enrutamiento: %v", err)
+		return nil, err
+	}
+
+	return &route, nil
+}
+
+// GetAllRoutes obtiene todas las reglas en orden de evaluación
+func (rs *RoutingService) GetAllRoutes() ([]models.NotificationRoute, error) {
+	var routes []models.NotificationRoute
+	if err := rs.db.Order("position ASC, id ASC").Find(&routes).Error; err != nil {
+		rs.logger.Errorf("Error al obtener reglas de enrutamiento: %v", err)
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// GetActiveRoutes obtiene las reglas habilitadas en orden de evaluación
+func (rs *RoutingService) GetActiveRoutes() ([]models.NotificationRoute, error) {
+	var routes []models.NotificationRoute
+	if err := rs.db.Where("enabled = ?", true).Order("position ASC, id ASC").Find(&routes).Error; err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// GetServerGroupIDs devuelve los grupos de un servidor, incluidos sus ancestros
+func (rs *RoutingService) GetServerGroupIDs(serverID uint) ([]uint, error) {
+	return serverGroupIDsWithAncestors(rs.db, serverID)
+}
+
+// GetTeamContactPointIDs devuelve los puntos de contacto habilitados de un equipo
+func (rs *RoutingService) GetTeamContactPointIDs(teamID uint) ([]uint, error) {
+	var ids []uint
+	if err := rs.db.Model(&models.ContactPoint{}).
+		Where("owner_team_id = ? AND enabled = ?", teamID, true).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// validateRoute valida la regla y comprueba que sus puntos de contacto existen
+func (rs *RoutingService) validateRoute(route *models.NotificationRoute) error {
+	if err := route.Validate(); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := rs.db.Model(&models.ContactPoint{}).Where("id IN ?", route.ContactPointIDs).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if int(count) != len(route.ContactPointIDs) {
+		return fmt.Errorf("uno o más puntos de contacto no existen")
+	}
+
+	return nil
+}
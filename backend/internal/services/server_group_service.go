@@ -24,6 +24,10 @@ func NewServerGroupService(db *gorm.DB, log logger.Logger) *ServerGroupService {
 
 // CreateGroup crea un nuevo grupo de servidores
 func (sgs *ServerGroupService) CreateGroup(group *models.ServerGroup) error {
+	if err := validateOwnerTeam(sgs.db, group.OwnerTeamID); err != nil {
+		return err
+	}
+
 	// Verificar recursividad en grupos si se especifica un padre
 	if group.ParentID != nil {
 		if err := sgs.checkGroupRecursion(*group.ParentID, 0); err != nil {
@@ -51,6 +55,10 @@ func (sgs *ServerGroupService) UpdateGroup(group *models.ServerGroup) error {
 		return err
 	}
 
+	if err := validateOwnerTeam(sgs.db, group.OwnerTeamID); err != nil {
+		return err
+	}
+
 	// Verificar recursividad en grupos si se cambia el padre
 	if group.ParentID != nil && (existingGroup.ParentID == nil || *existingGroup.ParentID != *group.ParentID) {
 		if err := sgs.checkGroupRecursion(*group.ParentID, group.ID); err != nil {
@@ -93,6 +101,13 @@ func (sgs *ServerGroupService) DeleteGroup(id uint) error {
 		return err
 	}
 
+	// Eliminar los permisos concedidos sobre el grupo
+	if err := tx.Where("group_id = ?", id).Delete(&models.GroupPermission{}).Error; err != nil {
+		tx.Rollback()
+		sgs.logger.Errorf("Error al eliminar permisos del grupo: %v", err)
+		return err
+	}
+
 	// Eliminar el grupo
 	if err := tx.Delete(&models.ServerGroup{}, id).Error; err != nil {
 		tx.Rollback()
@@ -192,6 +207,29 @@ func (sgs *ServerGroupService) GetRootGroups(includeChildren bool, includeServer
 	return groups, nil
 }
 
+// GetSubtrees obtiene los grupos indicados con sus subgrupos, con la misma profundidad que
+// GetRootGroups. Se usa para mostrar el árbol a los usuarios con permisos de grupo
+func (sgs *ServerGroupService) GetSubtrees(ids []uint, includeServers bool) ([]models.ServerGroup, error) {
+	var groups []models.ServerGroup
+	if len(ids) == 0 {
+		return groups, nil
+	}
+
+	query := sgs.db.Model(&models.ServerGroup{}).Where("id IN ?", ids).
+		Preload("Children").Preload("Children.Children")
+
+	if includeServers {
+		query = query.Preload("Servers").Preload("Children.Servers")
+	}
+
+	if err := query.Find(&groups).Error; err != nil {
+		sgs.logger.Errorf("Error al obtener subárboles de grupos: %v", err)
+		return nil, err
+	}
+
+	return groups, nil
+}
+
 // AddServerToGroup añade un servidor a un grupo
 func (sgs *ServerGroupService) AddServerToGroup(groupID, serverID uint) error {
 	// Comprobar que el grupo existe
@@ -277,3 +315,90 @@ func (sgs *ServerGroupService) checkGroupRecursion(parentID, groupID uint) error
 
 	return nil
 }
+
+// serverGroupIDsWithAncestors devuelve los IDs de los grupos de un servidor junto con todos sus ancestros
+func serverGroupIDsWithAncestors(db *gorm.DB, serverID uint) ([]uint, error) {
+	var directIDs []uint
+	if err := db.Table("server_group_servers").
+		Where("server_id = ?", serverID).
+		Pluck("server_group_id", &directIDs).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool)
+	var groupIDs []uint
+	pending := directIDs
+
+	for len(pending) > 0 {
+		var next []uint
+		for _, id := range pending {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			groupIDs = append(groupIDs, id)
+		}
+
+		var parentIDs []uint
+		if err := db.Model(&models.ServerGroup{}).
+			Where("id IN ? AND parent_id IS NOT NULL", pending).
+			Pluck("parent_id", &parentIDs).Error; err != nil {
+			return nil, err
+		}
+
+		for _, id := range parentIDs {
+			if !seen[id] {
+				next = append(next, id)
+			}
+		}
+		pending = next
+	}
+
+	return groupIDs, nil
+}
+
+// serverIDsInGroupTree devuelve los IDs de los servidores de un grupo y de todos sus subgrupos
+func serverIDsInGroupTree(db *gorm.DB, groupID uint) ([]uint, error) {
+	groupIDs, err := groupTreeIDs(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var serverIDs []uint
+	if err := db.Table("server_group_servers").
+		Where("server_group_id IN ?", groupIDs).
+		Distinct().
+		Pluck("server_id", &serverIDs).Error; err != nil {
+		return nil, err
+	}
+
+	return serverIDs, nil
+}
+
+// groupTreeIDs devuelve el ID de un grupo junto con los de todos sus subgrupos
+func groupTreeIDs(db *gorm.DB, groupID uint) ([]uint, error) {
+	seen := map[uint]bool{groupID: true}
+	groupIDs := []uint{groupID}
+	pending := []uint{groupID}
+
+	for len(pending) > 0 {
+		var childIDs []uint
+		if err := db.Model(&models.ServerGroup{}).
+			Where("parent_id IN ?", pending).
+			Pluck("id", &childIDs).Error; err != nil {
+			return nil, err
+		}
+
+		var next []uint
+		for _, id := range childIDs {
+			if !seen[id] {
+				seen[id] = true
+				groupIDs = append(groupIDs, id)
+				next = append(next, id)
+			}
+		}
+		pending = next
+	}
+
+	return groupIDs, nil
+}
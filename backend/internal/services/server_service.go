@@ -14,8 +14,9 @@ var _ interfaces.ServerServiceInterface = &ServerService{}
 
 // ServerService maneja la lógica de negocio relacionada con servidores
 type ServerService struct {
-	db     *gorm.DB
-	logger logger.Logger
+	db             *gorm.DB
+	logger         logger.Logger
+	silenceService *SilenceService // Silencios del mantenimiento programado
 }
 
 // NewServerService crea una nueva instancia del servicio de servidores
@@ -26,6 +27,35 @@ func NewServerService(db *gorm.DB, logger logger.Logger) *ServerService {
 	}
 }
 
+// SetSilenceService establece el servicio de silencios usado para el mantenimiento programado
+func (s *ServerService) SetSilenceService(silenceService *SilenceService) {
+	s.silenceService = silenceService
+}
+
+// syncMaintenanceSilence programa el silencio del próximo mantenimiento del servidor
+func (s *ServerService) syncMaintenanceSilence(server *models.Server) {
+	if s.silenceService == nil {
+		return
+	}
+
+	if err := s.silenceService.SyncMaintenanceSilence(server); err != nil {
+		s.logger.Errorf("Error al programar el silencio de mantenimiento del servidor %d: %v", server.ID, err)
+	}
+}
+
+// SyncMaintenanceSilences programa los silencios de los servidores con mantenimiento pendiente
+func (s *ServerService) SyncMaintenanceSilences() {
+	var servers []models.Server
+	if err := s.db.Where("next_maintenance_date IS NOT NULL").Find(&servers).Error; err != nil {
+		s.logger.Errorf("Error al obtener servidores con mantenimiento programado: %v", err)
+		return
+	}
+
+	for i := range servers {
+		s.syncMaintenanceSilence(&servers[i])
+	}
+}
+
 // GetAllServers obtiene todos los servidores activos
 func (s *ServerService) GetAllServers() ([]models.Server, error) {
 	var servers []models.Server
@@ -70,24 +100,48 @@ func (s *ServerService) GetServerByHostname(hostname string) (*models.Server, er
 	return &server, nil
 }
 
+// GetServersByIP obtiene los servidores registrados con una IP (puede haber varios tras NAT)
+func (s *ServerService) GetServersByIP(ip string) ([]models.Server, error) {
+	var servers []models.Server
+	
+	if err := s.db.Where("ip = ?", ip).Find(&servers).Error; err != nil {
+		s.logger.Errorf("Error al obtener servidores con IP %s: %v", ip, err)
+		return nil, err
+	}
+	
+	return servers, nil
+}
+
 // CreateServer crea un nuevo servidor
 func (s *ServerService) CreateServer(server *models.Server) error {
+	if err := validateOwnerTeam(s.db, server.OwnerTeamID); err != nil {
+		return err
+	}
+
 	if err := s.db.Create(server).Error; err != nil {
 		s.logger.Errorf("Error al crear servidor: %v", err)
 		return err
 	}
 	
+	s.syncMaintenanceSilence(server)
+
 	s.logger.Infof("Servidor creado exitosamente: ID=%d, Hostname=%s", server.ID, server.Hostname)
 	return nil
 }
 
 // UpdateServer actualiza un servidor existente
 func (s *ServerService) UpdateServer(server *models.Server) error {
+	if err := validateOwnerTeam(s.db, server.OwnerTeamID); err != nil {
+		return err
+	}
+
 	if err := s.db.Save(server).Error; err != nil {
 		s.logger.Errorf("Error al actualizar servidor con ID %d: %v", server.ID, err)
 		return err
 	}
 	
+	s.syncMaintenanceSilence(server)
+
 	s.logger.Infof("Servidor actualizado exitosamente: ID=%d, Hostname=%s", server.ID, server.Hostname)
 	return nil
 }
@@ -99,6 +153,12 @@ func (s *ServerService) DeleteServer(id uint) error {
 		return err
 	}
 	
+	if s.silenceService != nil {
+		if err := s.silenceService.RemoveMaintenanceSilences(id); err != nil {
+			s.logger.Warnf("Error al eliminar silencios de mantenimiento del servidor %d: %v", id, err)
+		}
+	}
+
 	s.logger.Infof("Servidor eliminado exitosamente: ID=%d", id)
 	return nil
 } 
\ No newline at end of file
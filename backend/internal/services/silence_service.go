@@ -0,0 +1,258 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// SilenceService gestiona los silencios y ventanas de mantenimiento que suprimen alertas
+type SilenceService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewSilenceService crea un nuevo servicio de silencios
+func NewSilenceService(db *gorm.DB, log logger.Logger) *SilenceService {
+	return &SilenceService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// GetSilences obtiene los silencios, opcionalmente filtrados por estado (pending, active, expired)
+func (ss *SilenceService) GetSilences(state models.SilenceState) ([]models.Silence, error) {
+	var silences []models.Silence
+	query := ss.db.Order("starts_at DESC")
+
+	now := time.Now()
+	switch state {
+	case models.SilenceStatePending:
+		query = query.Where("starts_at > ?", now)
+	case models.SilenceStateActive:
+		query = query.Where("starts_at <= ? AND ends_at > ?", now, now)
+	case models.SilenceStateExpired:
+		query = query.Where("ends_at <= ?", now)
+	}
+
+	if err := query.Find(&silences).Error; err != nil {
+		ss.logger.Errorf("Error al obtener silencios: %v", err)
+		return nil, err
+	}
+
+	return silences, nil
+}
+
+// GetSilence obtiene un silencio por ID
+func (ss *SilenceService) GetSilence(id uint) (*models.Silence, error) {
+	var silence models.Silence
+	if err := ss.db.First(&silence, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("silencio no encontrado")
+		}
+		return nil, err
+	}
+
+	return &silence, nil
+}
+
+// CreateSilence crea un nuevo silencio manual
+func (ss *SilenceService) CreateSilence(silence *models.Silence) error {
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	silence.Source = models.SilenceSourceManual
+	silence.MaintenanceServerID = nil
+
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	if err := ss.db.Create(silence).Error; err != nil {
+		ss.logger.Errorf("Error al crear silencio: %v", err)
+		return err
+	}
+
+	ss.logger.Infof("Silencio %d creado por usuario %d hasta %s: %s",
+		silence.ID, silence.CreatedBy, silence.EndsAt.Format(time.RFC3339), silence.Comment)
+	return nil
+}
+
+// UpdateSilence actualiza un silencio existente
+func (ss *SilenceService) UpdateSilence(silence *models.Silence) error {
+	existing, err := ss.GetSilence(silence.ID)
+	if err != nil {
+		return err
+	}
+
+	if existing.Source == models.SilenceSourceMaintenance {
+		return fmt.Errorf("los silencios de mantenimiento se gestionan desde el servidor")
+	}
+
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	silence.Source = existing.Source
+	silence.CreatedBy = existing.CreatedBy
+	silence.CreatedAt = existing.CreatedAt
+	silence.MaintenanceServerID = nil
+
+	if err := ss.db.Save(silence).Error; err != nil {
+		ss.logger.Errorf("Error al actualizar silencio: %v", err)
+		return err
+	}
+
+	ss.logger.Infof("Silencio %d actualizado", silence.ID)
+	return nil
+}
+
+// ExpireSilence finaliza un silencio en este instante conservándolo en el historial
+func (ss *SilenceService) ExpireSilence(id uint) (*models.Silence, error) {
+	silence, err := ss.GetSilence(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if silence.State(now) == models.SilenceStateExpired {
+		return silence, nil
+	}
+
+	// Un silencio pendiente se expira sin llegar a comenzar
+	updates := map[string]interface{}{"ends_at": now}
+	if now.Before(silence.StartsAt) {
+		updates["starts_at"] = now
+		silence.StartsAt = now
+	}
+
+	if err := ss.db.Model(silence).Updates(updates).Error; err != nil {
+		ss.logger.Errorf("Error al expirar silencio %d: %v", id, err)
+		return nil, err
+	}
+	silence.EndsAt = now
+
+	ss.logger.Infof("Silencio %d expirado", id)
+	return silence, nil
+}
+
+// DeleteSilence elimina un silencio
+func (ss *SilenceService) DeleteSilence(id uint) error {
+	result := ss.db.Delete(&models.Silence{}, id)
+	if result.Error != nil {
+		ss.logger.Errorf("Error al eliminar silencio: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("silencio no encontrado")
+	}
+
+	ss.logger.Infof("Silencio eliminado: %d", id)
+	return nil
+}
+
+// FindMatchingSilence devuelve el primer silencio en vigor que suprime la alerta, o nil si no hay
+func (ss *SilenceService) FindMatchingSilence(alert *models.Alert, now time.Time) (*models.Silence, error) {
+	var silences []models.Silence
+	if err := ss.db.Where("starts_at <= ? AND ends_at > ?", now, now).
+		Order("starts_at").Find(&silences).Error; err != nil {
+		return nil, err
+	}
+
+	if len(silences) == 0 {
+		return nil, nil
+	}
+
+	// Trabajar sobre una copia para no asociar el servidor a una alerta aún no guardada
+	candidate := *alert
+	if candidate.Server.ID == 0 {
+		ss.db.First(&candidate.Server, alert.ServerID)
+	}
+
+	groupIDs, err := serverGroupIDsWithAncestors(ss.db, alert.ServerID)
+	if err != nil {
+		ss.logger.Warnf("Error al obtener grupos del servidor %d para silencios: %v", alert.ServerID, err)
+	}
+
+	for i := range silences {
+		if silences[i].Matches(&candidate, groupIDs, now) {
+			return &silences[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SyncMaintenanceSilence crea, actualiza o elimina el silencio asociado al mantenimiento
+// programado de un servidor según su NextMaintenanceDate
+func (ss *SilenceService) SyncMaintenanceSilence(server *models.Server) error {
+	var existing models.Silence
+	err := ss.db.Where("maintenance_server_id = ? AND source = ? AND ends_at > ?",
+		server.ID, models.SilenceSourceMaintenance, time.Now()).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	found := err == nil
+
+	// Sin mantenimiento pendiente: eliminar el silencio que quedara programado
+	if server.NextMaintenanceDate == nil {
+		if found {
+			return ss.db.Delete(&existing).Error
+		}
+		return nil
+	}
+
+	duration := maintenanceDuration(server)
+	startsAt := *server.NextMaintenanceDate
+	endsAt := startsAt.Add(duration)
+
+	if !endsAt.After(time.Now()) {
+		return nil // El mantenimiento ya terminó
+	}
+
+	comment := fmt.Sprintf("Mantenimiento programado de %s", server.Hostname)
+	if server.MaintenanceNotes != "" {
+		comment = fmt.Sprintf("%s: %s", comment, server.MaintenanceNotes)
+	}
+
+	serverID := server.ID
+	silence := models.Silence{
+		Comment:             comment,
+		Source:              models.SilenceSourceMaintenance,
+		StartsAt:            startsAt,
+		EndsAt:              endsAt,
+		ServerIDs:           []uint{server.ID},
+		MaintenanceServerID: &serverID,
+	}
+
+	if found {
+		silence.ID = existing.ID
+		silence.CreatedAt = existing.CreatedAt
+	}
+
+	if err := ss.db.Save(&silence).Error; err != nil {
+		ss.logger.Errorf("Error al guardar silencio de mantenimiento del servidor %d: %v", server.ID, err)
+		return err
+	}
+
+	ss.logger.Infof("Silencio de mantenimiento del servidor %s programado de %s a %s",
+		server.Hostname, startsAt.Format(time.RFC3339), endsAt.Format(time.RFC3339))
+	return nil
+}
+
+// RemoveMaintenanceSilences elimina los silencios de mantenimiento pendientes o activos de un servidor
+func (ss *SilenceService) RemoveMaintenanceSilences(serverID uint) error {
+	return ss.db.Where("maintenance_server_id = ? AND source = ? AND ends_at > ?",
+		serverID, models.SilenceSourceMaintenance, time.Now()).Delete(&models.Silence{}).Error
+}
+
+// maintenanceDuration devuelve la duración del mantenimiento de un servidor
+func maintenanceDuration(server *models.Server) time.Duration {
+	if server.MaintenanceDuration > 0 {
+		return time.Duration(server.MaintenanceDuration) * time.Minute
+	}
+	return models.DefaultMaintenanceDuration
+}
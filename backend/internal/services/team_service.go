@@ -0,0 +1,397 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// teamOwnedTables tablas con un equipo propietario (owner_team_id)
+var teamOwnedTables = []interface{}{
+	&models.Server{},
+	&models.ServerGroup{},
+	&models.AlertThreshold{},
+	&models.ContactPoint{},
+	&models.Alert{},
+}
+
+// TeamService gestiona los equipos y sus miembros
+type TeamService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewTeamService crea un nuevo servicio de equipos
+func NewTeamService(db *gorm.DB, log logger.Logger) *TeamService {
+	return &TeamService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// GetTeams obtiene todos los equipos con sus miembros
+func (ts *TeamService) GetTeams() ([]models.Team, error) {
+	var teams []models.Team
+	if err := ts.db.Preload("Members").Order("name ASC").Find(&teams).Error; err != nil {
+		ts.logger.Errorf("Error al obtener equipos: %v", err)
+		return nil, err
+	}
+
+	return teams, nil
+}
+
+// GetTeamsForUser obtiene los equipos de los que un usuario es miembro
+func (ts *TeamService) GetTeamsForUser(userID uint) ([]models.Team, error) {
+	teamIDs, err := teamIDsForUser(ts.db, userID)
+	if err != nil {
+		ts.logger.Errorf("Error al obtener equipos del usuario %d: %v", userID, err)
+		return nil, err
+	}
+
+	teams := []models.Team{}
+	if len(teamIDs) == 0 {
+		return teams, nil
+	}
+
+	if err := ts.db.Preload("Members").Where("id IN ?", teamIDs).Order("name ASC").Find(&teams).Error; err != nil {
+		ts.logger.Errorf("Error al obtener equipos del usuario %d: %v", userID, err)
+		return nil, err
+	}
+
+	return teams, nil
+}
+
+// GetTeam obtiene un equipo por ID con sus miembros
+func (ts *TeamService) GetTeam(id uint) (*models.Team, error) {
+	var team models.Team
+	if err := ts.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Members.User").First(&team, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("equipo no encontrado")
+		}
+		ts.logger.Errorf("Error al obtener equipo %d: %v", id, err)
+		return nil, err
+	}
+
+	return &team, nil
+}
+
+// CreateTeam crea un equipo y añade a su creador como administrador del equipo
+func (ts *TeamService) CreateTeam(team *models.Team) error {
+	team.Name = strings.TrimSpace(team.Name)
+	if err := team.Validate(); err != nil {
+		return err
+	}
+
+	if err := ts.checkNameAvailable(team.Name, 0); err != nil {
+		return err
+	}
+
+	team.Members = nil
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+
+		if team.CreatedBy == 0 {
+			return nil
+		}
+
+		return tx.Create(&models.TeamMember{
+			TeamID: team.ID,
+			UserID: team.CreatedBy,
+			Role:   models.TeamRoleAdmin,
+		}).Error
+	})
+	if err != nil {
+		ts.logger.Errorf("Error al crear equipo: %v", err)
+		return err
+	}
+
+	ts.logger.Infof("Equipo creado: %s (ID: %d)", team.Name, team.ID)
+	return nil
+}
+
+// UpdateTeam actualiza el nombre y la descripción de un equipo
+func (ts *TeamService) UpdateTeam(team *models.Team) error {
+	team.Name = strings.TrimSpace(team.Name)
+	if err := team.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := ts.GetTeam(team.ID); err != nil {
+		return err
+	}
+
+	if err := ts.checkNameAvailable(team.Name, team.ID); err != nil {
+		return err
+	}
+
+	if err := ts.db.Model(&models.Team{}).Where("id = ?", team.ID).Updates(map[string]interface{}{
+		"name":        team.Name,
+		"description": team.Description,
+	}).Error; err != nil {
+		ts.logger.Errorf("Error al actualizar equipo %d: %v", team.ID, err)
+		return err
+	}
+
+	ts.logger.Infof("Equipo actualizado: %s (ID: %d)", team.Name, team.ID)
+	return nil
+}
+
+// DeleteTeam elimina un equipo, sus miembros y sus permisos de grupo. Los recursos de los que
+// era propietario quedan sin equipo
+func (ts *TeamService) DeleteTeam(id uint) error {
+	if _, err := ts.GetTeam(id); err != nil {
+		return err
+	}
+
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range teamOwnedTables {
+			if err := tx.Unscoped().Model(model).Where("owner_team_id = ?", id).Update("owner_team_id", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("team_id = ?", id).Delete(&models.GroupPermission{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("team_id = ?", id).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Team{}, id).Error
+	})
+	if err != nil {
+		ts.logger.Errorf("Error al eliminar equipo %d: %v", id, err)
+		return err
+	}
+
+	ts.logger.Infof("Equipo eliminado: %d", id)
+	return nil
+}
+
+// AddMember añade un usuario a un equipo con el rol indicado
+func (ts *TeamService) AddMember(teamID, userID uint, role models.TeamRole) (*models.TeamMember, error) {
+	if role == "" {
+		role = models.TeamRoleMember
+	}
+	if !models.ValidTeamRole(role) {
+		return nil, fmt.Errorf("rol de equipo inválido: %s", role)
+	}
+
+	if _, err := ts.GetTeam(teamID); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := ts.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("usuario no encontrado")
+		}
+		return nil, err
+	}
+
+	var count int64
+	if err := ts.db.Model(&models.TeamMember{}).Where("team_id = ? AND user_id = ?", teamID, userID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("el usuario ya es miembro del equipo")
+	}
+
+	member := &models.TeamMember{TeamID: teamID, UserID: userID, Role: role}
+	if err := ts.db.Create(member).Error; err != nil {
+		ts.logger.Errorf("Error al añadir miembro al equipo %d: %v", teamID, err)
+		return nil, err
+	}
+	member.User = &user
+
+	ts.logger.Infof("Usuario %d añadido al equipo %d con rol %s", userID, teamID, role)
+	return member, nil
+}
+
+// UpdateMemberRole cambia el rol de un miembro del equipo
+func (ts *TeamService) UpdateMemberRole(teamID, userID uint, role models.TeamRole) (*models.TeamMember, error) {
+	if !models.ValidTeamRole(role) {
+		return nil, fmt.Errorf("rol de equipo inválido: %s", role)
+	}
+
+	member, err := ts.getMember(teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.Role == models.TeamRoleAdmin && role != models.TeamRoleAdmin {
+		if err := ts.checkRemainingAdmin(teamID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ts.db.Model(member).Update("role", role).Error; err != nil {
+		ts.logger.Errorf("Error al actualizar miembro %d del equipo %d: %v", userID, teamID, err)
+		return nil, err
+	}
+	member.Role = role
+
+	ts.logger.Infof("Rol del usuario %d en el equipo %d cambiado a %s", userID, teamID, role)
+	return member, nil
+}
+
+// RemoveMember retira a un usuario de un equipo. El equipo debe conservar algún administrador
+func (ts *TeamService) RemoveMember(teamID, userID uint) error {
+	member, err := ts.getMember(teamID, userID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == models.TeamRoleAdmin {
+		if err := ts.checkRemainingAdmin(teamID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := ts.db.Delete(member).Error; err != nil {
+		ts.logger.Errorf("Error al retirar al usuario %d del equipo %d: %v", userID, teamID, err)
+		return err
+	}
+
+	ts.logger.Infof("Usuario %d retirado del equipo %d", userID, teamID)
+	return nil
+}
+
+// IsTeamAdmin indica si un usuario es administrador de un equipo
+func (ts *TeamService) IsTeamAdmin(teamID, userID uint) (bool, error) {
+	var count int64
+	if err := ts.db.Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id = ? AND role = ?", teamID, userID, models.TeamRoleAdmin).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// getMember obtiene la pertenencia de un usuario a un equipo
+func (ts *TeamService) getMember(teamID, userID uint) (*models.TeamMember, error) {
+	var member models.TeamMember
+	if err := ts.db.Where("team_id = ? AND user_id = ?", teamID, userID).Preload("User").First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("el usuario no es miembro del equipo")
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// checkRemainingAdmin comprueba que el equipo conserve otro administrador además de userID
+func (ts *TeamService) checkRemainingAdmin(teamID, userID uint) error {
+	var count int64
+	if err := ts.db.Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id <> ? AND role = ?", teamID, userID, models.TeamRoleAdmin).
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("el equipo debe conservar al menos un administrador")
+	}
+
+	return nil
+}
+
+// checkNameAvailable comprueba que ningún otro equipo use el nombre
+func (ts *TeamService) checkNameAvailable(name string, excludeID uint) error {
+	var count int64
+	if err := ts.db.Model(&models.Team{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("ya existe un equipo con el nombre '%s'", name)
+	}
+
+	return nil
+}
+
+// teamIDsForUser devuelve los equipos de los que un usuario es miembro
+func teamIDsForUser(db *gorm.DB, userID uint) ([]uint, error) {
+	teamIDs := []uint{}
+	if err := db.Model(&models.TeamMember{}).Where("user_id = ?", userID).Order("team_id").Pluck("team_id", &teamIDs).Error; err != nil {
+		return nil, err
+	}
+
+	return teamIDs, nil
+}
+
+// validateOwnerTeam comprueba que el equipo propietario indicado (si lo hay) existe
+func validateOwnerTeam(db *gorm.DB, teamID *uint) error {
+	if teamID == nil {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Team{}).Where("id = ?", *teamID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("el equipo propietario no existe")
+	}
+
+	return nil
+}
+
+// resolveOwnerTeamID determina el equipo propietario de una alerta: el de su umbral, el de su
+// servidor o el del grupo más cercano del servidor (sus grupos directos antes que sus ancestros)
+func resolveOwnerTeamID(db *gorm.DB, alert *models.Alert) (*uint, error) {
+	if alert.ThresholdID != 0 {
+		var threshold models.AlertThreshold
+		err := db.Select("id", "owner_team_id").First(&threshold, alert.ThresholdID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if threshold.OwnerTeamID != nil {
+			return threshold.OwnerTeamID, nil
+		}
+	}
+
+	var server models.Server
+	err := db.Select("id", "owner_team_id").First(&server, alert.ServerID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if server.OwnerTeamID != nil {
+		return server.OwnerTeamID, nil
+	}
+
+	groupIDs, err := serverGroupIDsWithAncestors(db, alert.ServerID)
+	if err != nil || len(groupIDs) == 0 {
+		return nil, err
+	}
+
+	var groups []models.ServerGroup
+	if err := db.Select("id", "owner_team_id").Where("id IN ? AND owner_team_id IS NOT NULL", groupIDs).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	owners := make(map[uint]*uint, len(groups))
+	for _, group := range groups {
+		owners[group.ID] = group.OwnerTeamID
+	}
+
+	for _, id := range groupIDs {
+		if owner, ok := owners[id]; ok {
+			return owner, nil
+		}
+	}
+
+	return nil, nil
+}
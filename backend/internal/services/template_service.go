@@ -0,0 +1,239 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
+	"gorm.io/gorm"
+)
+
+// Asegurar que TemplateService implementa la interfaz notifications.TemplateProvider
+var _ notifications.TemplateProvider = &TemplateService{}
+
+// TemplateService gestiona las plantillas de los mensajes de notificación
+type TemplateService struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewTemplateService crea un nuevo servicio de plantillas de notificación
+func NewTemplateService(db *gorm.DB, log logger.Logger) *TemplateService {
+	return &TemplateService{
+		db:     db,
+		logger: log,
+	}
+}
+
+// GetAllTemplates obtiene todas las plantillas almacenadas
+func (ts *TemplateService) GetAllTemplates() ([]models.NotificationTemplate, error) {
+	var templates []models.NotificationTemplate
+	if err := ts.db.Order("channel, state").Find(&templates).Error; err != nil {
+		ts.logger.Errorf("Error al obtener plantillas de notificación: %v", err)
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// GetTemplateByID obtiene una plantilla por ID
+func (ts *TemplateService) GetTemplateByID(id uint) (*models.NotificationTemplate, error) {
+	var tmpl models.NotificationTemplate
+	if err := ts.db.First(&tmpl, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("plantilla no encontrada")
+		}
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+// GetTemplate devuelve la plantilla habilitada para un canal y estado, o nil si no existe
+func (ts *TemplateService) GetTemplate(channel string, state models.TemplateState) (*models.NotificationTemplate, error) {
+	var tmpl models.NotificationTemplate
+	err := ts.db.Where("channel = ? AND state = ? AND enabled = ?", channel, state, true).First(&tmpl).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+// CreateTemplate crea una nueva plantilla tras validarla
+func (ts *TemplateService) CreateTemplate(tmpl *models.NotificationTemplate) error {
+	if err := ts.validateTemplate(tmpl); err != nil {
+		return err
+	}
+
+	var count int64
+	ts.db.Model(&models.NotificationTemplate{}).
+		Where("channel = ? AND state = ?", tmpl.Channel, tmpl.State).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("ya existe una plantilla para el canal %s y estado %s", tmpl.Channel, tmpl.State)
+	}
+
+	if err := ts.db.Create(tmpl).Error; err != nil {
+		ts.logger.Errorf("Error al crear plantilla de notificación: %v", err)
+		return err
+	}
+
+	ts.logger.Infof("Plantilla de notificación creada: %s (%s/%s)", tmpl.Name, tmpl.Channel, tmpl.State)
+	return nil
+}
+
+// UpdateTemplate actualiza una plantilla existente tras validarla
+func (ts *TemplateService) UpdateTemplate(tmpl *models.NotificationTemplate) error {
+	existing, err := ts.GetTemplateByID(tmpl.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.validateTemplate(tmpl); err != nil {
+		return err
+	}
+
+	var count int64
+	ts.db.Model(&models.NotificationTemplate{}).
+		Where("channel = ? AND state = ? AND id <> ?", tmpl.Channel, tmpl.State, tmpl.ID).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("ya existe una plantilla para el canal %s y estado %s", tmpl.Channel, tmpl.State)
+	}
+
+	tmpl.CreatedBy = existing.CreatedBy
+	tmpl.CreatedAt = existing.CreatedAt
+
+	if err := ts.db.Save(tmpl).Error; err != nil {
+		ts.logger.Errorf("Error al actualizar plantilla de notificación: %v", err)
+		return err
+	}
+
+	ts.logger.Infof("Plantilla de notificación actualizada: %s", tmpl.Name)
+	return nil
+}
+
+// DeleteTemplate elimina una plantilla; el canal vuelve a usar el formato integrado
+func (ts *TemplateService) DeleteTemplate(id uint) error {
+	result := ts.db.Delete(&models.NotificationTemplate{}, id)
+	if result.Error != nil {
+		ts.logger.Errorf("Error al eliminar plantilla de notificación: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("plantilla no encontrada")
+	}
+
+	ts.logger.Infof("Plantilla de notificación eliminada: %d", id)
+	return nil
+}
+
+// Preview renderiza una plantilla con una alerta de ejemplo
+func (ts *TemplateService) Preview(tmpl *models.NotificationTemplate) (*notifications.RenderedMessage, error) {
+	if tmpl.Channel == "" {
+		tmpl.Channel = models.TemplateChannelDefault
+	}
+	if !models.IsValidTemplateState(tmpl.State) {
+		return nil, fmt.Errorf("estado de plantilla inválido: %s", tmpl.State)
+	}
+
+	return notifications.RenderMessage(tmpl.TitleTemplate, tmpl.BodyTemplate, notifications.SampleTemplateData(tmpl.State))
+}
+
+// validateTemplate comprueba canal, estado y que la plantilla se renderice con datos de ejemplo
+func (ts *TemplateService) validateTemplate(tmpl *models.NotificationTemplate) error {
+	if tmpl.Name == "" {
+		return fmt.Errorf("el nombre de la plantilla es obligatorio")
+	}
+
+	if tmpl.Channel == "" {
+		tmpl.Channel = models.TemplateChannelDefault
+	}
+	if !models.IsValidTemplateChannel(tmpl.Channel) {
+		return fmt.Errorf("canal de plantilla inválido: %s", tmpl.Channel)
+	}
+
+	if !models.IsValidTemplateState(tmpl.State) {
+		return fmt.Errorf("estado de plantilla inválido: %s", tmpl.State)
+	}
+
+	if tmpl.BodyTemplate == "" {
+		return fmt.Errorf("el cuerpo de la plantilla es obligatorio")
+	}
+
+	// Renderizar contra una alerta de ejemplo para detectar campos inexistentes
+	if _, err := notifications.RenderMessage(tmpl.TitleTemplate, tmpl.BodyTemplate, notifications.SampleTemplateData(tmpl.State)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BuildTemplateData reúne servidor, umbral, última métrica y usuario que reconoció la alerta
+func (ts *TemplateService) BuildTemplateData(alert *models.Alert, state models.TemplateState) (*notifications.TemplateData, error) {
+	if alert.Server.ID == 0 {
+		ts.db.First(&alert.Server, alert.ServerID)
+	}
+
+	data := notifications.NewTemplateData(alert, state)
+
+	if alert.ThresholdID != 0 {
+		var threshold models.AlertThreshold
+		if err := ts.db.First(&threshold, alert.ThresholdID).Error; err == nil {
+			data.Threshold = &threshold
+			if threshold.IsExpression() {
+				data.MetricName = threshold.Name
+			}
+		}
+	}
+
+	var metric models.Metric
+	if err := ts.db.Where("server_id = ?", alert.ServerID).Order("timestamp DESC").First(&metric).Error; err == nil {
+		data.Metric = &metric
+	}
+
+	if alert.AcknowledgedBy != nil {
+		var user models.User
+		if err := ts.db.Select("username").First(&user, *alert.AcknowledgedBy).Error; err == nil {
+			data.AcknowledgedBy = user.Username
+		}
+	}
+
+	return data, nil
+}
+
+// RenderAlertText genera el título y mensaje almacenados en una alerta nueva usando la
+// plantilla "default" de estado firing, o la plantilla integrada si no hay ninguna
+func (ts *TemplateService) RenderAlertText(alert *models.Alert, server *models.Server, threshold *models.AlertThreshold, metric *models.Metric) (string, string, error) {
+	def := notifications.DefaultTemplates[models.TemplateStateFiring]
+	titleTemplate, bodyTemplate := def.Title, def.Body
+
+	stored, err := ts.GetTemplate(models.TemplateChannelDefault, models.TemplateStateFiring)
+	if err != nil {
+		return "", "", err
+	}
+	if stored != nil {
+		titleTemplate, bodyTemplate = stored.TitleTemplate, stored.BodyTemplate
+	}
+
+	data := notifications.NewTemplateData(alert, models.TemplateStateFiring)
+	if server != nil && server.ID != 0 {
+		data.Server = server
+		data.ServerName = server.Hostname
+	}
+	data.Threshold = threshold
+	data.Metric = metric
+	if threshold != nil && threshold.IsExpression() {
+		data.MetricName = threshold.Name
+	}
+
+	msg, err := notifications.RenderMessage(titleTemplate, bodyTemplate, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	return msg.Title, msg.Body, nil
+}
@@ -0,0 +1,749 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/totp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Parámetros de la verificación en dos pasos
+const (
+	TwoFactorChallengeTTL  = 5 * time.Minute  // Tiempo para introducir el código tras la contraseña
+	TwoFactorMaxAttempts   = 5                // Códigos incorrectos consecutivos antes del bloqueo
+	TwoFactorLockout       = 15 * time.Minute // Duración del bloqueo tras demasiados códigos incorrectos
+	TwoFactorRecoveryCodes = 10               // Códigos de recuperación generados en cada alta
+	twoFactorSkew          = 1                // Periodos de desfase de reloj admitidos en cada sentido
+)
+
+// Propósitos del token de verificación emitido tras comprobar la contraseña
+const (
+	TwoFactorPurposeLogin = "login" // El usuario debe introducir su código
+	TwoFactorPurposeSetup = "setup" // El usuario debe dar de alta la verificación (obligatoria para su rol)
+)
+
+// recoveryCodeAlphabet caracteres de los códigos de recuperación (sin 0/o ni 1/l/i, que se confunden)
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// Errores de la verificación en dos pasos
+var (
+	ErrTwoFactorRequired       = errors.New("se requiere el código de verificación en dos pasos")
+	ErrTwoFactorSetupRequired  = errors.New("la verificación en dos pasos es obligatoria para su rol: debe configurarla para continuar")
+	ErrTwoFactorInvalidCode    = errors.New("código de verificación inválido")
+	ErrTwoFactorLocked         = errors.New("demasiados códigos incorrectos: inténtelo de nuevo más tarde")
+	ErrTwoFactorChallenge      = errors.New("la verificación ha caducado: inicie sesión de nuevo")
+	ErrTwoFactorNotEnabled     = errors.New("la verificación en dos pasos no está activada")
+	ErrTwoFactorAlreadyEnabled = errors.New("la verificación en dos pasos ya está activada")
+	ErrTwoFactorNotStarted     = errors.New("no hay ninguna configuración de la verificación en dos pasos pendiente")
+	ErrTwoFactorEnforced       = errors.New("la verificación en dos pasos es obligatoria para su rol")
+	ErrTwoFactorProvider       = errors.New("la verificación en dos pasos de este usuario se gestiona en su proveedor de identidad")
+)
+
+// TwoFactorChallenge token firmado que acredita que el usuario ya comprobó su contraseña y
+// debe completar el segundo paso. No abre sesión por sí mismo
+type TwoFactorChallenge struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// TwoFactorSetup datos para dar de alta la aplicación de autenticación
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`           // Para introducirlo a mano
+	ProvisioningURI string `json:"provisioning_uri"` // Contenido del código QR (otpauth://)
+}
+
+// TwoFactorStatus estado de la verificación en dos pasos de un usuario
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"` // Obligatoria para el rol del usuario
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
+
+// TwoFactorService gestiona la verificación en dos pasos con TOTP y códigos de recuperación
+type TwoFactorService struct {
+	db           *gorm.DB
+	logger       logger.Logger
+	authService  *AuthService
+	issuer       string // Nombre que muestra la aplicación de autenticación
+	secretKey    []byte // Clave AES-256 con la que se cifran los secretos TOTP
+	challengeKey []byte // Clave de firma de los tokens de verificación
+}
+
+// NewTwoFactorService crea el servicio de verificación en dos pasos. Las claves de cifrado y
+// de firma se derivan del secreto de la aplicación
+func NewTwoFactorService(db *gorm.DB, log logger.Logger, authService *AuthService, secret, issuer string) *TwoFactorService {
+	if issuer == "" {
+		issuer = "Dashboard Servers"
+	}
+	key := sha256.Sum256([]byte("totp:" + secret))
+
+	return &TwoFactorService{
+		db:           db,
+		logger:       log,
+		authService:  authService,
+		issuer:       issuer,
+		secretKey:    key[:],
+		challengeKey: []byte("2fa-challenge:" + secret),
+	}
+}
+
+// CheckLogin indica si el usuario que acaba de comprobar su contraseña debe completar un segundo
+// paso: ErrTwoFactorRequired si tiene la verificación activada y ErrTwoFactorSetupRequired si es
+// obligatoria para su rol y aún no la ha configurado
+func (s *TwoFactorService) CheckLogin(user *models.User) error {
+	var twoFactor models.TwoFactor
+	err := s.db.Where("user_id = ?", user.ID).First(&twoFactor).Error
+	if err == nil && twoFactor.Enabled {
+		return ErrTwoFactorRequired
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	required, err := s.IsRequired(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorSetupRequired
+	}
+
+	return nil
+}
+
+// IssueChallenge emite el token con el que el usuario completa el segundo paso del login
+func (s *TwoFactorService) IssueChallenge(user *models.User, purpose string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(TwoFactorChallengeTTL)
+
+	challenge := &TwoFactorChallenge{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, challenge).SignedString(s.challengeKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
+
+// VerifyLogin completa el login con el código TOTP (o un código de recuperación) y abre la sesión
+func (s *TwoFactorService) VerifyLogin(challenge, code string, client ClientInfo) (*TokenPair, *models.User, error) {
+	user, err := s.parseChallenge(challenge, TwoFactorPurposeLogin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usedRecovery, err := s.verify(user, code, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if usedRecovery {
+		s.logger.Warnf("El usuario %s ha iniciado sesión con un código de recuperación", user.Username)
+	}
+
+	tokens, err := s.authService.StartSession(user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, user, nil
+}
+
+// BeginSetup genera un secreto nuevo pendiente de verificar. Sustituye cualquier alta anterior
+// que no se llegara a completar
+func (s *TwoFactorService) BeginSetup(user *models.User) (*TwoFactorSetup, error) {
+	if user.AuthProvider == models.AuthProviderOIDC {
+		return nil, ErrTwoFactorProvider
+	}
+
+	var twoFactor models.TwoFactor
+	err := s.db.Where("user_id = ?", user.ID).First(&twoFactor).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor.UserID = user.ID
+	twoFactor.Secret = encrypted
+	twoFactor.Enabled = false
+	twoFactor.LastUsedStep = 0
+	twoFactor.FailedAttempts = 0
+	twoFactor.LockedUntil = nil
+	if err := s.db.Save(&twoFactor).Error; err != nil {
+		s.logger.Errorf("Error al guardar la configuración 2FA del usuario %d: %v", user.ID, err)
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// Enable activa la verificación tras comprobar un primer código de la aplicación, genera los
+// códigos de recuperación y cierra las demás sesiones del usuario (salvo keepSessionID)
+func (s *TwoFactorService) Enable(user *models.User, code string, keepSessionID uint) ([]string, error) {
+	var twoFactor models.TwoFactor
+	if err := s.db.Where("user_id = ?", user.ID).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorNotStarted
+		}
+		return nil, err
+	}
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	if err := s.checkCode(&twoFactor, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&twoFactor).Updates(map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = revokeUserSessions(tx, user.ID, models.SessionRevokedTwoFactor, keepSessionID)
+		return err
+	})
+	if err != nil {
+		s.logger.Errorf("Error al activar la verificación en dos pasos del usuario %d: %v", user.ID, err)
+		return nil, err
+	}
+
+	s.logger.Infof("Verificación en dos pasos activada para el usuario %s", user.Username)
+	return codes, nil
+}
+
+// BeginSetupWithChallenge inicia el alta obligatoria durante el login, antes de abrir sesión
+func (s *TwoFactorService) BeginSetupWithChallenge(challenge string) (*TwoFactorSetup, error) {
+	user, err := s.parseChallenge(challenge, TwoFactorPurposeSetup)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.BeginSetup(user)
+}
+
+// EnableWithChallenge completa el alta obligatoria durante el login y abre la sesión
+func (s *TwoFactorService) EnableWithChallenge(challenge, code string, client ClientInfo) (*TokenPair, *models.User, []string, error) {
+	user, err := s.parseChallenge(challenge, TwoFactorPurposeSetup)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	codes, err := s.Enable(user, code, 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tokens, err := s.authService.StartSession(user, client)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return tokens, user, codes, nil
+}
+
+// Disable desactiva la verificación del propio usuario, que debe confirmar con un código.
+// No se permite si es obligatoria para su rol
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	required, err := s.IsRequired(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorEnforced
+	}
+
+	if _, err := s.verify(user, code, true); err != nil {
+		return err
+	}
+
+	if err := s.deleteTwoFactor(user.ID); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Verificación en dos pasos desactivada por el usuario %s", user.Username)
+	return nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación del usuario y genera otros nuevos.
+// Requiere un código de la aplicación
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if _, err := s.verify(user, code, false); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Códigos de recuperación regenerados para el usuario %s", user.Username)
+	return codes, nil
+}
+
+// Reset elimina la verificación en dos pasos de un usuario (acción de administrador, p. ej. si
+// ha perdido el dispositivo y los códigos) y cierra sus sesiones
+func (s *TwoFactorService) Reset(userID uint) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	var twoFactor models.TwoFactor
+	if err := s.db.Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+
+	if err := s.deleteTwoFactor(userID); err != nil {
+		return err
+	}
+
+	s.logger.Warnf("Verificación en dos pasos restablecida por un administrador para el usuario %s", user.Username)
+	return nil
+}
+
+// Status devuelve el estado de la verificación en dos pasos de un usuario
+func (s *TwoFactorService) Status(user *models.User) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{}
+
+	var twoFactor models.TwoFactor
+	err := s.db.Where("user_id = ? AND enabled = ?", user.ID, true).First(&twoFactor).Error
+	switch {
+	case err == nil:
+		status.Enabled = true
+		status.EnabledAt = twoFactor.EnabledAt
+		if err := s.db.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Count(&status.RecoveryCodesLeft).Error; err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	required, err := s.IsRequired(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	status.Required = required
+
+	return status, nil
+}
+
+// IsRequired indica si la verificación en dos pasos es obligatoria para un rol
+func (s *TwoFactorService) IsRequired(role models.Role) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.TwoFactorPolicy{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetPolicy devuelve los roles para los que la verificación en dos pasos es obligatoria
+func (s *TwoFactorService) GetPolicy() ([]models.TwoFactorPolicy, error) {
+	var policies []models.TwoFactorPolicy
+	if err := s.db.Order("role").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// SetPolicy fija los roles para los que la verificación es obligatoria. Se cierran las sesiones
+// de los usuarios locales y LDAP de esos roles que aún no la tienen, que deberán configurarla
+// en su próximo login
+func (s *TwoFactorService) SetPolicy(roles []models.Role, adminID uint) ([]models.TwoFactorPolicy, error) {
+	unique := make(map[models.Role]bool)
+	for _, role := range roles {
+		if models.RoleRank(role) == 0 {
+			return nil, fmt.Errorf("rol inválido: %s", role)
+		}
+		unique[role] = true
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current []models.TwoFactorPolicy
+		if err := tx.Find(&current).Error; err != nil {
+			return err
+		}
+
+		enforced := make(map[models.Role]bool)
+		for _, policy := range current {
+			if !unique[policy.Role] {
+				if err := tx.Delete(&policy).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			enforced[policy.Role] = true
+		}
+
+		for role := range unique {
+			if enforced[role] {
+				continue
+			}
+			if err := tx.Create(&models.TwoFactorPolicy{Role: role, CreatedBy: adminID}).Error; err != nil {
+				return err
+			}
+
+			// Forzar el alta en el próximo login de quienes no la tienen
+			var userIDs []uint
+			if err := tx.Model(&models.User{}).
+				Where("role = ? AND auth_provider <> ?", role, models.AuthProviderOIDC).
+				Where("id NOT IN (?)", tx.Model(&models.TwoFactor{}).Select("user_id").Where("enabled = ?", true)).
+				Pluck("id", &userIDs).Error; err != nil {
+				return err
+			}
+			for _, userID := range userIDs {
+				if _, err := revokeUserSessions(tx, userID, models.SessionRevokedTwoFactor, 0); err != nil {
+					return err
+				}
+			}
+
+			s.logger.Infof("Verificación en dos pasos obligatoria para el rol %s (%d usuarios deberán configurarla)", role, len(userIDs))
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorf("Error al guardar la política de verificación en dos pasos: %v", err)
+		return nil, err
+	}
+
+	return s.GetPolicy()
+}
+
+// verify comprueba un código de la aplicación o, si allowRecovery, un código de recuperación.
+// Devuelve si se usó un código de recuperación
+func (s *TwoFactorService) verify(user *models.User, code string, allowRecovery bool) (bool, error) {
+	var twoFactor models.TwoFactor
+	if err := s.db.Where("user_id = ? AND enabled = ?", user.ID, true).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrTwoFactorNotEnabled
+		}
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if allowRecovery && len(code) > totp.Digits {
+		if err := s.checkLock(&twoFactor); err != nil {
+			return false, err
+		}
+		if err := s.useRecoveryCode(user.ID, code); err != nil {
+			if errors.Is(err, ErrTwoFactorInvalidCode) {
+				s.registerFailure(&twoFactor)
+			}
+			return false, err
+		}
+		s.resetFailures(&twoFactor)
+		return true, nil
+	}
+
+	return false, s.checkCode(&twoFactor, code)
+}
+
+// checkCode comprueba un código TOTP impidiendo reutilizar uno ya aceptado y bloqueando
+// temporalmente la verificación tras demasiados fallos
+func (s *TwoFactorService) checkCode(twoFactor *models.TwoFactor, code string) error {
+	if err := s.checkLock(twoFactor); err != nil {
+		return err
+	}
+
+	secret, err := s.decryptSecret(twoFactor.Secret)
+	if err != nil {
+		s.logger.Errorf("No se pudo descifrar el secreto 2FA del usuario %d: %v", twoFactor.UserID, err)
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), twoFactorSkew)
+	if !ok || step <= twoFactor.LastUsedStep {
+		s.registerFailure(twoFactor)
+		return ErrTwoFactorInvalidCode
+	}
+
+	// Marcar el periodo como usado solo si nadie lo ha hecho antes y la verificación no se ha
+	// bloqueado mientras tanto (peticiones simultáneas)
+	result := s.db.Model(&models.TwoFactor{}).
+		Where("id = ? AND last_used_step < ? AND (locked_until IS NULL OR locked_until <= ?)", twoFactor.ID, step, time.Now()).
+		Updates(map[string]interface{}{"last_used_step": step, "failed_attempts": 0, "locked_until": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	twoFactor.LastUsedStep = step
+
+	return nil
+}
+
+// checkLock rechaza la verificación mientras dure el bloqueo por fallos
+func (s *TwoFactorService) checkLock(twoFactor *models.TwoFactor) error {
+	if twoFactor.LockedUntil != nil && time.Now().Before(*twoFactor.LockedUntil) {
+		return ErrTwoFactorLocked
+	}
+	return nil
+}
+
+// registerFailure cuenta un código incorrecto y bloquea la verificación al llegar al máximo. El
+// recuento y la decisión se toman en la misma sentencia para que los intentos simultáneos no
+// superen el límite
+func (s *TwoFactorService) registerFailure(twoFactor *models.TwoFactor) {
+	now := time.Now()
+	reachesMax := gorm.Expr("failed_attempts + 1 >= ?", TwoFactorMaxAttempts)
+
+	var updated models.TwoFactor
+	err := s.db.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_attempts"}, {Name: "locked_until"}}}).
+		Where("id = ?", twoFactor.ID).
+		Updates(map[string]interface{}{
+			"failed_attempts": gorm.Expr("CASE WHEN ? THEN 0 ELSE failed_attempts + 1 END", reachesMax),
+			"locked_until":    gorm.Expr("CASE WHEN ? THEN ? ELSE locked_until END", reachesMax, now.Add(TwoFactorLockout)),
+		}).Error
+	if err != nil {
+		s.logger.Warnf("Error al registrar el fallo de verificación del usuario %d: %v", twoFactor.UserID, err)
+		return
+	}
+
+	twoFactor.FailedAttempts = updated.FailedAttempts
+	twoFactor.LockedUntil = updated.LockedUntil
+	if updated.FailedAttempts == 0 && updated.LockedUntil != nil && updated.LockedUntil.After(now) {
+		s.logger.Warnf("Verificación en dos pasos bloqueada para el usuario %d tras %d códigos incorrectos", twoFactor.UserID, TwoFactorMaxAttempts)
+	}
+}
+
+// resetFailures pone a cero el contador de fallos tras una verificación correcta
+func (s *TwoFactorService) resetFailures(twoFactor *models.TwoFactor) {
+	if twoFactor.FailedAttempts == 0 && twoFactor.LockedUntil == nil {
+		return
+	}
+	if err := s.db.Model(&models.TwoFactor{}).Where("id = ?", twoFactor.ID).
+		Updates(map[string]interface{}{"failed_attempts": 0, "locked_until": nil}).Error; err != nil {
+		s.logger.Warnf("Error al reiniciar los fallos de verificación del usuario %d: %v", twoFactor.UserID, err)
+	}
+}
+
+// useRecoveryCode consume un código de recuperación sin usar del usuario
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) error {
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+
+	return nil
+}
+
+// deleteTwoFactor elimina la configuración y los códigos de recuperación de un usuario y cierra
+// sus sesiones
+func (s *TwoFactorService) deleteTwoFactor(userID uint) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		_, err := revokeUserSessions(tx, userID, models.SessionRevokedTwoFactor, 0)
+		return err
+	})
+	if err != nil {
+		s.logger.Errorf("Error al eliminar la verificación en dos pasos del usuario %d: %v", userID, err)
+	}
+
+	return err
+}
+
+// parseChallenge valida el token de verificación y carga su usuario
+func (s *TwoFactorService) parseChallenge(signed, purpose string) (*models.User, error) {
+	if signed == "" {
+		return nil, ErrTwoFactorChallenge
+	}
+
+	challenge := &TwoFactorChallenge{}
+	_, err := jwt.ParseWithClaims(signed, challenge, func(token *jwt.Token) (interface{}, error) {
+		return s.challengeKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || challenge.Purpose != purpose {
+		return nil, ErrTwoFactorChallenge
+	}
+
+	userID, err := strconv.ParseUint(challenge.Subject, 10, 32)
+	if err != nil {
+		return nil, ErrTwoFactorChallenge
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorChallenge
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	return &user, nil
+}
+
+// encryptSecret cifra un secreto TOTP con AES-GCM (nonce || texto cifrado, en base64)
+func (s *TwoFactorService) encryptSecret(secret string) (string, error) {
+	block, err := aes.NewCipher(s.secretKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret descifra un secreto TOTP guardado con encryptSecret
+func (s *TwoFactorService) decryptSecret(encrypted string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(s.secretKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("secreto cifrado demasiado corto")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// replaceRecoveryCodes sustituye los códigos de recuperación de un usuario por otros nuevos y
+// los devuelve en claro (solo se muestran una vez)
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, TwoFactorRecoveryCodes)
+	for len(codes) < TwoFactorRecoveryCodes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		record := &models.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code))}
+		if err := tx.Create(record).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode genera un código de recuperación con el formato xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	var builder strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			builder.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+
+	return builder.String(), nil
+}
+
+// normalizeRecoveryCode admite el código con o sin guion, espacios o mayúsculas
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
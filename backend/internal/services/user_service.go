@@ -8,10 +8,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrUserExists ya existe un usuario con el mismo nombre o email
+var ErrUserExists = errors.New("el nombre de usuario o email ya está en uso")
+
 // UserService maneja la lógica de negocio relacionada con usuarios
 type UserService struct {
-	db     *gorm.DB
-	logger logger.Logger
+	db             *gorm.DB
+	logger         logger.Logger
+	passwordPolicy PasswordPolicy // Requisitos de las contraseñas locales
 }
 
 // NewUserService crea una nueva instancia del servicio de usuarios
@@ -22,6 +26,16 @@ func NewUserService(db *gorm.DB, logger logger.Logger) *UserService {
 	}
 }
 
+// SetPasswordPolicy establece los requisitos de las contraseñas al crear usuarios y al cambiarlas
+func (s *UserService) SetPasswordPolicy(policy PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
+// ValidatePassword comprueba una contraseña contra la política configurada
+func (s *UserService) ValidatePassword(password, username string) error {
+	return s.passwordPolicy.Validate(password, username)
+}
+
 // GetAllUsers obtiene todos los usuarios
 func (s *UserService) GetAllUsers() ([]models.User, error) {
 	var users []models.User
@@ -89,7 +103,19 @@ func (s *UserService) CreateUser(user *models.User, plainPassword string) error
 	s.db.Model(&models.User{}).Where("username = ? OR email = ?", user.Username, user.Email).Count(&count)
 	if count > 0 {
 		s.logger.Warnf("Intento de crear usuario con username o email duplicado: %s, %s", user.Username, user.Email)
-		return errors.New("el nombre de usuario o email ya está en uso")
+		return ErrUserExists
+	}
+	
+	// Los usuarios LDAP se autentican contra el directorio: contraseña local aleatoria
+	if plainPassword == "" && !user.IsLocal() {
+		password, err := randomPassword()
+		if err != nil {
+			return err
+		}
+		plainPassword = password
+	} else if err := s.passwordPolicy.Validate(plainPassword, user.Username); err != nil {
+		s.logger.Warnf("Contraseña rechazada al crear el usuario %s: %v", user.Username, err)
+		return err
 	}
 	
 	// Establecer contraseña
@@ -108,14 +134,30 @@ func (s *UserService) CreateUser(user *models.User, plainPassword string) error
 	return nil
 }
 
-// UpdateUser actualiza un usuario existente
+// UpdateUser actualiza un usuario existente. Un cambio de rol o de proveedor revoca sus sesiones
 func (s *UserService) UpdateUser(user *models.User) error {
+	var current models.User
+	if err := s.db.Select("id", "role", "auth_provider").First(&current, user.ID).Error; err != nil {
+		s.logger.Errorf("Error al obtener usuario para actualizar: %v", err)
+		return err
+	}
+	
+	// Al cambiar de proveedor se desvincula la identidad externa
+	providerChanged := current.AuthProvider != user.AuthProvider
+	if providerChanged {
+		user.ExternalID = nil
+	}
+	
 	// La contraseña se maneja en un método separado
 	if err := s.db.Omit("password").Save(user).Error; err != nil {
 		s.logger.Errorf("Error al actualizar usuario: %v", err)
 		return err
 	}
 	
+	if current.Role != user.Role || providerChanged {
+		s.revokeSessions(user.ID, models.SessionRevokedRoleChange)
+	}
+	
 	s.logger.Infof("Usuario actualizado exitosamente: ID=%d, Username=%s", user.ID, user.Username)
 	return nil
 }
@@ -127,17 +169,24 @@ func (s *UserService) DeleteUser(id uint) error {
 		return err
 	}
 	
+	s.revokeSessions(id, models.SessionRevokedUserDeleted)
+	
 	s.logger.Infof("Usuario eliminado exitosamente: ID=%d", id)
 	return nil
 }
 
-// ChangePassword cambia la contraseña de un usuario
+// ChangePassword cambia la contraseña de un usuario y revoca todas sus sesiones
 func (s *UserService) ChangePassword(id uint, newPassword string) error {
 	user, err := s.GetUserByID(id)
 	if err != nil {
 		return err
 	}
 	
+	if err := s.passwordPolicy.Validate(newPassword, user.Username); err != nil {
+		s.logger.Warnf("Contraseña rechazada para usuario ID=%d: %v", id, err)
+		return err
+	}
+	
 	if err := user.SetPassword(newPassword); err != nil {
 		s.logger.Errorf("Error al cifrar nueva contraseña: %v", err)
 		return err
@@ -148,6 +197,21 @@ func (s *UserService) ChangePassword(id uint, newPassword string) error {
 		return err
 	}
 	
+	s.revokeSessions(id, models.SessionRevokedPasswordChange)
+	
 	s.logger.Infof("Contraseña cambiada exitosamente para usuario ID=%d", id)
 	return nil
+}
+
+// revokeSessions revoca todas las sesiones de un usuario
+func (s *UserService) revokeSessions(userID uint, reason string) {
+	count, err := revokeUserSessions(s.db, userID, reason, 0)
+	if err != nil {
+		s.logger.Errorf("Error al revocar las sesiones del usuario %d: %v", userID, err)
+		return
+	}
+	
+	if count > 0 {
+		s.logger.Infof("%d sesiones del usuario %d revocadas (%s)", count, userID, reason)
+	}
 } 
\ No newline at end of file
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,7 +14,9 @@ import (
 	"github.com/jminat01/dashboard-servers-go/backend/internal/middleware"
 	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
 	"github.com/jminat01/dashboard-servers-go/backend/internal/services"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/agentca"
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/database"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/ldapauth"
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/notifications"
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/websocket"
@@ -27,6 +30,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Subcomando de la CA interna de los agentes (no arranca el servidor)
+	if len(os.Args) > 1 && os.Args[1] == "ca" {
+		os.Exit(runCA(&cfg.TLS, os.Args[2:]))
+	}
+
 	// Inicializar logger básico para el arranque
 	log := logger.NewLogger(cfg.Server.Env)
 	log.Info("Iniciando servidor de monitoreo...")
@@ -46,10 +54,33 @@ func main() {
 	if err := db.AutoMigrate(
 		&models.Server{},
 		&models.Metric{},
-		&models.Log{},            // Añadir tabla de logs
-		&models.User{},           // Añadir tabla de usuarios
-		&models.Alert{},          // Añadir tabla de alertas
-		&models.AlertThreshold{}, // Añadir tabla de umbrales de alertas
+		&models.Log{},                  // Añadir tabla de logs
+		&models.User{},                 // Añadir tabla de usuarios
+		&models.Alert{},                // Añadir tabla de alertas
+		&models.AlertThreshold{},       // Añadir tabla de umbrales de alertas
+		&models.ContactPoint{},         // Puntos de contacto para notificaciones
+		&models.NotificationRoute{},    // Reglas de enrutamiento de notificaciones
+		&models.NotificationTemplate{}, // Plantillas de mensajes de notificación
+		&models.Silence{},              // Silencios y ventanas de mantenimiento
+		&models.EscalationPolicy{},     // Políticas de escalado
+		&models.EscalationStep{},       // Pasos de las políticas de escalado
+		&models.AlertEscalation{},      // Historial de escalado de alertas
+		&models.MetricBaseline{},       // Líneas base para la detección de anomalías
+		&models.Incident{},             // Incidentes que agrupan alertas relacionadas
+		&models.InhibitionRule{},       // Reglas de inhibición entre alertas
+		&models.AlertEvent{},           // Historial de eventos de las alertas
+		&models.ServerGroup{},          // Grupos jerárquicos de servidores
+		&models.GroupPermission{},      // Permisos de los usuarios y equipos sobre grupos de servidores
+		&models.Team{},                 // Equipos propietarios de servidores, grupos, umbrales y canales
+		&models.TeamMember{},           // Miembros de los equipos
+		&models.Session{},              // Sesiones abiertas por los usuarios
+		&models.RefreshToken{},         // Refresh tokens (hash) de las sesiones
+		&models.TwoFactor{},            // Verificación en dos pasos (TOTP) de los usuarios
+		&models.RecoveryCode{},         // Códigos de recuperación (hash) de la verificación en dos pasos
+		&models.TwoFactorPolicy{},      // Roles con verificación en dos pasos obligatoria
+		&models.LoginThrottle{},        // Intentos de login fallidos y bloqueos por usuario e IP
+		&models.Invitation{},           // Invitaciones de alta de usuarios
+		&models.AuditEntry{},           // Registro de auditoría (solo inserción)
 	); err != nil {
 		log.Fatalf("Error en la migración automática: %v", err)
 	}
@@ -92,38 +123,225 @@ func main() {
 		SMTPUser:          cfg.Notifications.EmailUser,
 		SMTPPassword:      cfg.Notifications.EmailPassword,
 		EmailFrom:         cfg.Notifications.EmailFrom,
+		TelegramEnabled:   cfg.Notifications.TelegramEnabled,
+		TelegramBotToken:  cfg.Notifications.TelegramBotToken,
+		TelegramChatID:    cfg.Notifications.TelegramChatID,
+		NtfyEnabled:       cfg.Notifications.NtfyEnabled,
+		NtfyServerURL:     cfg.Notifications.NtfyServerURL,
+		NtfyTopic:         cfg.Notifications.NtfyTopic,
+		NtfyToken:         cfg.Notifications.NtfyToken,
 	}
 	notificationManager := notifications.NewNotificationManager(notifyConfig, log)
 
 	// Inicializar resto de servicios con el nuevo logger
 	serverService := services.NewServerService(db.DB, log)
+	serverGroupService := services.NewServerGroupService(db.DB, log)
+	permissionService := services.NewPermissionService(db.DB, log)
+	teamService := services.NewTeamService(db.DB, log)
 	metricService := services.NewMetricService(db.DB, log, wsHub, redisClient)
 	userService := services.NewUserService(db.DB, log)
-	authService := services.NewAuthService(db.DB, log, cfg.Auth.JWTSecret, 86400) // Token válido por 24 horas en segundos
+	userService.SetPasswordPolicy(services.PasswordPolicy{
+		MinLength:      cfg.Auth.PasswordMinLength,
+		RequireUpper:   cfg.Auth.PasswordRequireUpper,
+		RequireLower:   cfg.Auth.PasswordRequireLower,
+		RequireDigit:   cfg.Auth.PasswordRequireDigit,
+		RequireSymbol:  cfg.Auth.PasswordRequireSymbol,
+		RejectUsername: cfg.Auth.PasswordRejectUser,
+	})
+	authService := services.NewAuthService(db.DB, log, cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
 	alertService := services.NewAlertService(db.DB, log, notificationManager)
+	routingService := services.NewRoutingService(db.DB, log)
+	templateService := services.NewTemplateService(db.DB, log)
+	silenceService := services.NewSilenceService(db.DB, log)
+	escalationService := services.NewEscalationService(db.DB, log, notificationManager)
+	forecastService := services.NewForecastService(db.DB, log)
+	baselineService := services.NewBaselineService(db.DB, log, cfg.Alerting.BaselineLookback, cfg.Alerting.AnomalyZScore)
+	inhibitionService := services.NewInhibitionService(db.DB, log)
+	incidentService := services.NewIncidentService(db.DB, log, notificationManager, services.IncidentSettings{
+		Enabled: cfg.Alerting.IncidentGroupingEnabled,
+		GroupBy: cfg.Alerting.IncidentGroupBy,
+		Window:  cfg.Alerting.IncidentGroupWindow,
+		Wait:    cfg.Alerting.IncidentGroupWait,
+	})
+
+	// El gestor de notificaciones resuelve los destinos mediante las reglas de enrutamiento
+	// y da formato a los mensajes con las plantillas almacenadas
+	notificationManager.SetRouteProvider(routingService)
+	notificationManager.SetTemplateProvider(templateService)
+	alertService.SetTemplateService(templateService)
 
 	// Configurar las dependencias circulares entre servicios
 	metricService.SetAlertService(alertService)
 	alertService.SetMetricService(metricService)
 
+	// Los silencios suprimen alertas; el mantenimiento programado de un servidor genera el suyo
+	alertService.SetSilenceService(silenceService)
+	serverService.SetSilenceService(silenceService)
+	serverService.SyncMaintenanceSilences()
+
+	// Las alertas activas inhiben a las relacionadas según las reglas de inhibición
+	alertService.SetInhibitionService(inhibitionService)
+
+	// Escalar las alertas que sigan sin reconocerse
+	alertService.SetEscalationService(escalationService)
+	escalationService.Start(cfg.Alerting.EscalationInterval)
+
+	// Aprender las líneas base por hora de la semana para las reglas de anomalía
+	alertService.SetBaselineService(baselineService)
+	baselineService.Start(cfg.Alerting.BaselineInterval)
+
+	// Agrupar las alertas relacionadas en incidentes con una única notificación
+	alertService.SetIncidentService(incidentService)
+	incidentService.SetEscalationService(escalationService)
+	if incidentService.Enabled() {
+		incidentService.Start(services.IncidentNotifyInterval)
+	}
+
+	// Reactivar las alertas pospuestas cuando termina su plazo
+	alertService.Start(services.SnoozeCheckInterval)
+
+	// Alertas externas recibidas por la API compatible con Alertmanager
+	externalAlertService := services.NewExternalAlertService(db.DB, log, alertService, cfg.Alerting.AlertmanagerResolveTimeout)
+	externalAlertService.Start(services.ExternalAlertCheckInterval)
+
+	// Inicio de sesión único con el proveedor de identidad (OIDC); el admin local queda como
+	// acceso de emergencia
+	oidcService := services.NewOIDCService(db.DB, log, authService, services.OIDCSettings{
+		Enabled:       cfg.OIDC.Enabled,
+		IssuerURL:     cfg.OIDC.IssuerURL,
+		ClientID:      cfg.OIDC.ClientID,
+		ClientSecret:  cfg.OIDC.ClientSecret,
+		RedirectURL:   cfg.OIDC.RedirectURL,
+		Scopes:        cfg.OIDC.Scopes,
+		UsernameClaim: cfg.OIDC.UsernameClaim,
+		GroupsClaim:   cfg.OIDC.GroupsClaim,
+		RoleMapping:   cfg.OIDC.RoleMapping,
+		DefaultRole:   models.Role(cfg.OIDC.DefaultRole),
+		GroupMapping:  cfg.OIDC.GroupMapping,
+		StateSecret:   cfg.Auth.JWTSecret,
+	})
+	authService.SetLocalLoginAdminOnly(cfg.OIDC.Enabled && cfg.OIDC.LocalLogin == "admin")
+
+	// Autenticación contra LDAP / Active Directory para los usuarios con proveedor "ldap"
+	ldapService := services.NewLDAPService(db.DB, log, services.LDAPSettings{
+		Enabled: cfg.LDAP.Enabled,
+		Directory: ldapauth.Config{
+			URL:          cfg.LDAP.URL,
+			StartTLS:     cfg.LDAP.StartTLS,
+			SkipVerify:   cfg.LDAP.SkipVerify,
+			CACertFile:   cfg.LDAP.CACertFile,
+			BindDN:       cfg.LDAP.BindDN,
+			BindPassword: cfg.LDAP.BindPassword,
+			BaseDN:       cfg.LDAP.BaseDN,
+			UserFilter:   cfg.LDAP.UserFilter,
+			UsernameAttr: cfg.LDAP.UsernameAttr,
+			EmailAttr:    cfg.LDAP.EmailAttr,
+			MemberOfAttr: cfg.LDAP.MemberOfAttr,
+			GroupBaseDN:  cfg.LDAP.GroupBaseDN,
+			GroupFilter:  cfg.LDAP.GroupFilter,
+		},
+		RoleMapping: cfg.LDAP.RoleMapping,
+		DefaultRole: models.Role(cfg.LDAP.DefaultRole),
+		AutoCreate:  cfg.LDAP.AutoCreate,
+	})
+	if ldapService.Enabled() {
+		authService.SetLDAPService(ldapService)
+		ldapService.Start(cfg.LDAP.SyncInterval)
+	}
+
+	// Verificación en dos pasos (TOTP) tras comprobar la contraseña
+	twoFactorService := services.NewTwoFactorService(db.DB, log, authService, cfg.Auth.JWTSecret, cfg.Auth.TOTPIssuer)
+	authService.SetTwoFactorService(twoFactorService)
+
+	// Protección del login contra fuerza bruta: retrasos progresivos y bloqueo temporal por
+	// usuario e IP, con los bloqueos registrados en la tabla de logs
+	loginProtection := services.NewLoginProtectionService(db.DB, log, logService, services.LoginProtectionSettings{
+		Enabled:         cfg.Auth.LoginProtection,
+		MaxUserFailures: cfg.Auth.LoginMaxFailures,
+		MaxIPFailures:   cfg.Auth.LoginMaxIPFailures,
+		FailureWindow:   cfg.Auth.LoginFailureWindow,
+		LockoutDuration: cfg.Auth.LoginLockoutDuration,
+		BaseDelay:       cfg.Auth.LoginDelayBase,
+		MaxDelay:        cfg.Auth.LoginDelayMax,
+	})
+	if loginProtection.Enabled() {
+		authService.SetLoginProtection(loginProtection)
+		loginProtection.Start(services.LoginThrottlePurgeInterval)
+	}
+
+	// Alta de usuarios por invitación (el registro abierto queda deshabilitado) y restablecimiento
+	// de contraseña con enlaces firmados enviados por correo o mostrados al admin
+	invitationService := services.NewInvitationService(db.DB, log, userService, notificationManager, services.InvitationSettings{
+		TTL:           cfg.Auth.InviteTTL,
+		AppURL:        cfg.Auth.AppURL,
+		SignupDomains: cfg.Auth.SignupDomains,
+	})
+	passwordResetService := services.NewPasswordResetService(db.DB, log, userService, notificationManager, cfg.Auth.JWTSecret, services.PasswordResetSettings{
+		TTL:    cfg.Auth.PasswordResetTTL,
+		AppURL: cfg.Auth.AppURL,
+	})
+
+	// Registro de auditoría de las acciones de configuración y de seguridad. La base de datos
+	// rechaza cualquier modificación o borrado de las entradas
+	auditService := services.NewAuditService(db.DB, log)
+	if err := auditService.EnsureAppendOnly(); err != nil {
+		log.Warnf("No se pudo proteger la tabla de auditoría contra modificaciones: %v", err)
+	}
+
 	// Crear usuario admin por defecto si no existe
 	createDefaultAdmin(userService, log, cfg)
 
 	// Inicializar middleware de autenticación
 	authMiddleware := middleware.NewAuthMiddleware(authService, log)
 	wsAuthMiddleware := websocket.NewWSAuthMiddleware(authService, log)
+	auditMiddleware := middleware.NewAuditMiddleware(auditService, log)
+	agentMiddleware := middleware.NewAgentMiddleware(serverService, cfg.TLS.AgentCertRequired, log)
+
+	// Limitar el acceso a los servidores según los permisos de grupo de cada usuario
+	authMiddleware.SetPermissionService(permissionService)
+	wsAuthMiddleware.SetAccessChecker(permissionService)
 
 	// Inicializar handlers
 	serverHandler := handlers.NewServerHandler(serverService, log)
 	metricHandler := handlers.NewMetricHandler(metricService, serverService, log, wsAuthMiddleware, wsHub)
+	serverGroupHandler := handlers.NewServerGroupHandler(serverGroupService, log)
+	permissionHandler := handlers.NewPermissionHandler(permissionService, log)
+	teamHandler := handlers.NewTeamHandler(teamService, log)
 	logHandler := handlers.NewLogHandler(logService, log)
 	authHandler := handlers.NewAuthHandler(authService, userService, log)
+	authHandler.SetOIDCService(oidcService, cfg.OIDC.PostLoginURL)
+	authHandler.SetTwoFactorService(twoFactorService)
+	authHandler.SetInvitationService(invitationService)
+	authHandler.SetPasswordResetService(passwordResetService)
+	authHandler.SetAccountRateLimiter(middleware.NewRateLimiter(cfg.Auth.AccountRequestLimit, cfg.Auth.AccountRequestWindow))
 	userHandler := handlers.NewUserHandler(userService, log)
+	userHandler.SetTwoFactorService(twoFactorService)
+	userHandler.SetPasswordResetService(passwordResetService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService, log)
+	auditHandler := handlers.NewAuditHandler(auditService, log)
+	if loginProtection.Enabled() {
+		userHandler.SetLoginProtection(loginProtection)
+	}
 	alertHandler := handlers.NewAlertHandler(alertService, log)
+	routingHandler := handlers.NewRoutingHandler(routingService, log)
+	templateHandler := handlers.NewTemplateHandler(templateService, log)
+	silenceHandler := handlers.NewSilenceHandler(silenceService, log)
+	escalationHandler := handlers.NewEscalationHandler(escalationService, alertService, log)
+	forecastHandler := handlers.NewForecastHandler(forecastService, log)
+	baselineHandler := handlers.NewBaselineHandler(baselineService, log)
+	incidentHandler := handlers.NewIncidentHandler(incidentService, log)
+	inhibitionHandler := handlers.NewInhibitionHandler(inhibitionService, log)
+	alertmanagerHandler := handlers.NewAlertmanagerHandler(externalAlertService, cfg.Alerting.AlertmanagerAPIToken, log)
 
 	// Configurar router
 	router := gin.Default()
 
+	// La IP del cliente (bloqueos del login, sesiones, auditoría) solo se toma de X-Forwarded-For
+	// si la petición llega de un proxy de confianza
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES inválido: %v", err)
+	}
+
 	// Middleware para CORS
 	router.Use(func(c *gin.Context) {
 		// Usar el origen específico en lugar de "*"
@@ -131,6 +349,7 @@ func main() {
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Content-Disposition")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -140,6 +359,9 @@ func main() {
 		c.Next()
 	})
 
+	// Auditar las peticiones que modifican la configuración o afectan a la seguridad
+	router.Use(auditMiddleware.Record())
+
 	// Endpoint de salud
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -150,14 +372,24 @@ func main() {
 	// Registrar rutas
 	authHandler.RegisterRoutes(router, authMiddleware)
 	userHandler.RegisterRoutes(router, authMiddleware)
+	invitationHandler.RegisterRoutes(router, authMiddleware)
+	auditHandler.RegisterRoutes(router, authMiddleware)
+
+	// Ingesta de métricas: agentes con certificado de cliente o usuarios con sesión
+	metricHandler.RegisterIngestRoutes(router, agentMiddleware, authMiddleware)
 
 	// Registrar rutas protegidas con autenticación
 	serverRoutes := router.Group("/api")
 	serverRoutes.Use(authMiddleware.RequireAuth())
+	serverRoutes.Use(authMiddleware.LoadAccessScope())
 
 	// Registrar rutas de servidores y métricas (requieren autenticación)
 	serverHandler.RegisterRoutes(serverRoutes)
 	metricHandler.RegisterRoutes(serverRoutes)
+	forecastHandler.RegisterRoutes(serverRoutes)
+	serverGroupHandler.RegisterRoutes(serverRoutes, authMiddleware)
+	permissionHandler.RegisterRoutes(serverRoutes, authMiddleware)
+	teamHandler.RegisterRoutes(serverRoutes, authMiddleware)
 
 	// Ruta de logs (requiere rol de admin)
 	logRoutes := router.Group("/api")
@@ -168,27 +400,86 @@ func main() {
 	// Registrar rutas de alertas
 	alertRoutes := router.Group("/api")
 	alertRoutes.Use(authMiddleware.RequireAuth())
+	alertRoutes.Use(authMiddleware.LoadAccessScope())
 	alertHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	routingHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	templateHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	silenceHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	escalationHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	baselineHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	incidentHandler.RegisterRoutes(alertRoutes, authMiddleware)
+	inhibitionHandler.RegisterRoutes(alertRoutes, authMiddleware)
+
+	// API compatible con Alertmanager (autenticada con su propio token)
+	if cfg.Alerting.AlertmanagerAPIToken != "" {
+		alertmanagerHandler.RegisterRoutes(router)
+	} else {
+		log.Info("API compatible con Alertmanager deshabilitada (ALERTMANAGER_API_TOKEN vacío)")
+	}
 
 	// Manejar señales para apagado graceful
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	// Iniciar el servidor en una goroutine
-	go func() {
-		addr := fmt.Sprintf(":%s", cfg.Server.Port)
-		log.Infof("Servidor iniciado en http://localhost%s", addr)
-		log.Infof("WebSockets disponibles en ws://localhost%s/api/metrics/live/{server_id}", addr)
+	addr := fmt.Sprintf(":%s", cfg.Server.Port)
+	if cfg.TLS.Enabled {
+		clientCAFile, crlFile := "", ""
+		if cfg.TLS.AgentMTLS {
+			clientCAFile, crlFile = cfg.TLS.ClientCAFile, cfg.TLS.CRLFile
+		} else if cfg.TLS.AgentCertRequired {
+			log.Fatalf("AGENT_MTLS_REQUIRED requiere AGENT_MTLS_ENABLED")
+		}
 
-		if err := router.Run(addr); err != nil {
-			log.Fatalf("Error al iniciar servidor: %v", err)
+		tlsConfig, err := agentca.ServerTLSConfig(cfg.TLS.CertFile, cfg.TLS.KeyFile, clientCAFile, crlFile)
+		if err != nil {
+			log.Fatalf("Error en la configuración TLS: %v", err)
+		}
+
+		server := &http.Server{
+			Addr:      addr,
+			Handler:   router,
+			TLSConfig: tlsConfig,
+		}
+		go func() {
+			log.Infof("Servidor iniciado en https://localhost%s", addr)
+			log.Infof("WebSockets disponibles en wss://localhost%s/api/metrics/live/{server_id}", addr)
+			if clientCAFile != "" {
+				log.Infof("Agentes con certificado de cliente habilitados (CA %s, CRL %s)", clientCAFile, crlFile)
+			}
+
+			if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Error al iniciar servidor: %v", err)
+			}
+		}()
+	} else {
+		if cfg.TLS.AgentCertRequired {
+			log.Fatalf("AGENT_MTLS_REQUIRED requiere TLS_ENABLED")
 		}
-	}()
+
+		go func() {
+			log.Infof("Servidor iniciado en http://localhost%s", addr)
+			log.Infof("WebSockets disponibles en ws://localhost%s/api/metrics/live/{server_id}", addr)
+
+			if err := router.Run(addr); err != nil {
+				log.Fatalf("Error al iniciar servidor: %v", err)
+			}
+		}()
+	}
 
 	// Bloquear hasta que se reciba una señal de terminación
 	<-quit
 	log.Info("Apagando servidor...")
 
+	// Detener los procesos de alertas en segundo plano
+	escalationService.Stop()
+	baselineService.Stop()
+	incidentService.Stop()
+	alertService.Stop()
+	externalAlertService.Stop()
+	ldapService.Stop()
+	loginProtection.Stop()
+
 	// Detener el hub de WebSockets
 	if wsHub != nil {
 		wsHub.Stop()
@@ -0,0 +1,419 @@
+// Package agentca implementa una pequeña autoridad de certificación interna para los agentes:
+// emite certificados de cliente (y de servidor para el backend), los revoca y publica la lista
+// de revocación (CRL). El estado se guarda en un directorio: ca.crt, ca.key, index.json y crl.pem.
+package agentca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ficheros del directorio de la CA
+const (
+	CertFile  = "ca.crt"
+	keyFile   = "ca.key"
+	indexFile = "index.json"
+	CRLFile   = "crl.pem"
+)
+
+// Tipos de certificado emitidos
+const (
+	KindAgent  = "agent"  // Certificado de cliente de un agente
+	KindServer = "server" // Certificado de servidor del backend
+)
+
+// Errores de la CA
+var (
+	ErrNotInitialized = errors.New("la CA no está inicializada")
+	ErrAlreadyExists  = errors.New("la CA ya está inicializada")
+	ErrUnknownSerial  = errors.New("no hay ningún certificado emitido con ese número de serie")
+	ErrAlreadyRevoked = errors.New("el certificado ya está revocado")
+)
+
+// Record certificado emitido por la CA
+type Record struct {
+	Serial     string     `json:"serial"` // Número de serie en hexadecimal
+	Kind       string     `json:"kind"`
+	CommonName string     `json:"common_name"`
+	DNSNames   []string   `json:"dns_names,omitempty"`
+	IPs        []string   `json:"ips,omitempty"`
+	NotAfter   time.Time  `json:"not_after"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// index estado de la CA: certificados emitidos y número de la última CRL
+type index struct {
+	CRLNumber int64    `json:"crl_number"`
+	Records   []Record `json:"records"`
+}
+
+// CA autoridad de certificación cargada desde su directorio
+type CA struct {
+	dir   string
+	cert  *x509.Certificate
+	key   crypto.Signer
+	index index
+}
+
+// Issued certificado recién emitido y su clave, en PEM
+type Issued struct {
+	Record  Record
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// Init crea una CA nueva en dir con un certificado autofirmado válido durante validity
+func Init(dir, commonName string, validity time.Duration) (*CA, error) {
+	if _, err := os.Stat(filepath.Join(dir, keyFile)); err == nil {
+		return nil, ErrAlreadyExists
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, keyFile), keyPEM, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, CertFile), encodeCert(der), 0o644); err != nil {
+		return nil, err
+	}
+
+	ca := &CA{dir: dir, cert: cert, key: key}
+	if err := ca.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+// Load carga la CA de dir
+func Load(dir string) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CertFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotInitialized
+		}
+		return nil, err
+	}
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(dir, keyFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotInitialized
+		}
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("clave de la CA inválida")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("clave de la CA inválida: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("tipo de clave de la CA no soportado")
+	}
+
+	ca := &CA{dir: dir, cert: cert, key: key}
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &ca.index); err != nil {
+			return nil, fmt.Errorf("índice de la CA inválido: %w", err)
+		}
+	}
+
+	return ca, nil
+}
+
+// Certificate certificado de la CA
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// Records certificados emitidos, ordenados por fecha de emisión
+func (ca *CA) Records() []Record {
+	records := append([]Record(nil), ca.index.Records...)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].IssuedAt.Before(records[j].IssuedAt)
+	})
+	return records
+}
+
+// Issue emite un certificado. El nombre común de un agente es el hostname del servidor
+// registrado en el dashboard; names añade SAN (DNS o IP) adicionales
+func (ca *CA) Issue(kind, commonName string, names []string, validity time.Duration) (*Issued, error) {
+	commonName = strings.TrimSpace(commonName)
+	if commonName == "" {
+		return nil, fmt.Errorf("el nombre común es obligatorio")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	switch kind {
+	case KindAgent:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case KindServer:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	default:
+		return nil, fmt.Errorf("tipo de certificado desconocido: %s", kind)
+	}
+
+	// El nombre común también va como SAN: los clientes TLS solo comprueban los SAN
+	for _, name := range append([]string{commonName}, names...) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	record := Record{
+		Serial:     FormatSerial(serial),
+		Kind:       kind,
+		CommonName: commonName,
+		DNSNames:   template.DNSNames,
+		NotAfter:   notAfter,
+		IssuedAt:   now,
+	}
+	for _, ip := range template.IPAddresses {
+		record.IPs = append(record.IPs, ip.String())
+	}
+
+	ca.index.Records = append(ca.index.Records, record)
+	if err := ca.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	return &Issued{Record: record, CertPEM: encodeCert(der), KeyPEM: keyPEM}, nil
+}
+
+// Revoke revoca un certificado por su número de serie (hexadecimal, admite ":" y mayúsculas).
+// La CRL se debe regenerar después con WriteCRL
+func (ca *CA) Revoke(serial string) (*Record, error) {
+	serial = normalizeSerial(serial)
+	for i := range ca.index.Records {
+		record := &ca.index.Records[i]
+		if record.Serial != serial {
+			continue
+		}
+		if record.RevokedAt != nil {
+			return record, ErrAlreadyRevoked
+		}
+
+		now := time.Now()
+		record.RevokedAt = &now
+		if err := ca.saveIndex(); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	return nil, ErrUnknownSerial
+}
+
+// WriteCRL genera y guarda la lista de revocación firmada, válida durante validity. Los
+// certificados caducados se omiten: la validación ya los rechaza
+func (ca *CA) WriteCRL(validity time.Duration) (string, error) {
+	now := time.Now()
+
+	var revoked []x509.RevocationListEntry
+	for _, record := range ca.index.Records {
+		if record.RevokedAt == nil || record.NotAfter.Before(now) {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(record.Serial, 16)
+		if !ok {
+			continue
+		}
+		revoked = append(revoked, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: *record.RevokedAt,
+		})
+	}
+
+	ca.index.CRLNumber++
+	template := &x509.RevocationList{
+		Number:                    big.NewInt(ca.index.CRLNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(validity),
+		RevokedCertificateEntries: revoked,
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(ca.dir, CRLFile)
+	data := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return "", err
+	}
+	if err := ca.saveIndex(); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// saveIndex guarda el índice de certificados emitidos
+func (ca *CA) saveIndex() error {
+	data, err := json.MarshalIndent(ca.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(ca.dir, indexFile), data, 0o600)
+}
+
+// ParseCertificate lee el primer certificado de un PEM
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("certificado PEM inválido")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// FormatSerial número de serie en hexadecimal, como lo muestra la CA
+func FormatSerial(serial *big.Int) string {
+	return strings.ToLower(serial.Text(16))
+}
+
+// normalizeSerial admite los números de serie con separadores (openssl: "0A:1B:...")
+func normalizeSerial(serial string) string {
+	serial = strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(serial)))
+	return strings.TrimLeft(serial, "0")
+}
+
+// randomSerial número de serie aleatorio de 128 bits
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// encodeCert certificado DER a PEM
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// encodeKey clave privada a PEM (PKCS#8)
+func encodeKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// writeFileAtomic escribe un fichero completo de una vez, para que el backend nunca lea una CRL
+// a medio escribir
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
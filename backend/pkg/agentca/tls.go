@@ -0,0 +1,164 @@
+package agentca
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Errores de la validación de certificados de cliente
+var (
+	ErrCertificateRevoked = errors.New("certificado de cliente revocado")
+	ErrCRLExpired         = errors.New("la CRL ha caducado: regenérela con 'ca crl'")
+)
+
+// CRLChecker comprueba los certificados de cliente contra la CRL de la CA. El fichero se vuelve
+// a leer cuando cambia, así que una revocación se aplica en el siguiente handshake sin reiniciar
+type CRLChecker struct {
+	path string
+	ca   *x509.Certificate
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	list    *x509.RevocationList
+	revoked map[string]bool
+}
+
+// NewCRLChecker crea el comprobador de la CRL en path, firmada por ca. Falla si la CRL no se
+// puede leer o no es válida
+func NewCRLChecker(path string, ca *x509.Certificate) (*CRLChecker, error) {
+	checker := &CRLChecker{path: path, ca: ca}
+	if _, err := checker.current(); err != nil {
+		return nil, err
+	}
+	return checker, nil
+}
+
+// Check devuelve un error si el certificado está revocado o la CRL no es válida. Si la CRL no
+// se puede leer se rechaza el certificado: sin ella no se sabe si está revocado
+func (c *CRLChecker) Check(cert *x509.Certificate) error {
+	revoked, err := c.current()
+	if err != nil {
+		return err
+	}
+	if revoked[FormatSerial(cert.SerialNumber)] {
+		return ErrCertificateRevoked
+	}
+	return nil
+}
+
+// current números de serie revocados de la CRL vigente, releyendo el fichero si ha cambiado
+func (c *CRLChecker) current() (map[string]bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la CRL: %w", err)
+	}
+
+	if c.list == nil || !info.ModTime().Equal(c.modTime) || info.Size() != c.size {
+		list, err := c.load()
+		if err != nil {
+			return nil, err
+		}
+
+		revoked := make(map[string]bool, len(list.RevokedCertificateEntries))
+		for _, entry := range list.RevokedCertificateEntries {
+			revoked[FormatSerial(entry.SerialNumber)] = true
+		}
+
+		c.list = list
+		c.revoked = revoked
+		c.modTime = info.ModTime()
+		c.size = info.Size()
+	}
+
+	if !c.list.NextUpdate.IsZero() && time.Now().After(c.list.NextUpdate) {
+		return nil, ErrCRLExpired
+	}
+
+	return c.revoked, nil
+}
+
+// load lee la CRL (PEM o DER) y verifica su firma
+func (c *CRLChecker) load() (*x509.RevocationList, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la CRL: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	list, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("CRL inválida: %w", err)
+	}
+	if err := list.CheckSignatureFrom(c.ca); err != nil {
+		return nil, fmt.Errorf("la CRL no está firmada por la CA de los agentes: %w", err)
+	}
+
+	return list, nil
+}
+
+// ServerTLSConfig configuración TLS del backend. Con clientCAFile se aceptan certificados de
+// cliente firmados por esa CA: son opcionales en el handshake (los navegadores no los envían)
+// y, si se presentan, se verifican y se comprueban contra la CRL (si se indica crlFile) en cada
+// handshake, también en los que reanudan una sesión
+func ServerTLSConfig(certFile, keyFile, clientCAFile, crlFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo cargar el certificado del servidor: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la CA de los agentes: %w", err)
+	}
+	caCert, err := ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("CA de los agentes inválida: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+
+	if crlFile != "" {
+		checker, err := NewCRLChecker(crlFile, caCert)
+		if err != nil {
+			return nil, err
+		}
+		// VerifyConnection se ejecuta también al reanudar una sesión TLS (a diferencia de
+		// VerifyPeerCertificate), así que un certificado revocado no conserva el acceso
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, chain := range state.VerifiedChains {
+				if len(chain) == 0 {
+					continue
+				}
+				if err := checker.Check(chain[0]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,291 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+)
+
+// valueType tipo de un valor o subexpresión
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeBool
+)
+
+func (t valueType) String() string {
+	if t == typeBool {
+		return "booleano"
+	}
+	return "numérico"
+}
+
+// value resultado de evaluar un nodo
+type value struct {
+	typ valueType
+	num float64
+	b   bool
+}
+
+// node nodo del árbol sintáctico de una expresión
+type node interface {
+	// check comprueba tipos y variables, y devuelve el tipo del nodo
+	check(known func(string) bool) (valueType, error)
+
+	// eval evalúa el nodo con los valores de las variables
+	eval(vars map[string]float64) (value, error)
+}
+
+// numberNode literal numérico
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) check(known func(string) bool) (valueType, error) {
+	return typeNumber, nil
+}
+
+func (n *numberNode) eval(vars map[string]float64) (value, error) {
+	return value{typ: typeNumber, num: n.value}, nil
+}
+
+// boolNode literal booleano (true / false)
+type boolNode struct {
+	value bool
+}
+
+func (n *boolNode) check(known func(string) bool) (valueType, error) {
+	return typeBool, nil
+}
+
+func (n *boolNode) eval(vars map[string]float64) (value, error) {
+	return value{typ: typeBool, b: n.value}, nil
+}
+
+// varNode referencia a una variable (campo de métrica)
+type varNode struct {
+	name string
+	pos  int
+}
+
+func (n *varNode) check(known func(string) bool) (valueType, error) {
+	if known != nil && !known(n.name) {
+		return typeNumber, fmt.Errorf("variable desconocida '%s' en la posición %d", n.name, n.pos+1)
+	}
+	return typeNumber, nil
+}
+
+func (n *varNode) eval(vars map[string]float64) (value, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return value{}, fmt.Errorf("variable sin valor: %s", n.name)
+	}
+	return value{typ: typeNumber, num: v}, nil
+}
+
+// unaryNode operador unario (- numérico, ! lógico)
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) check(known func(string) bool) (valueType, error) {
+	t, err := n.operand.check(known)
+	if err != nil {
+		return t, err
+	}
+
+	if n.op == "!" {
+		if t != typeBool {
+			return typeBool, fmt.Errorf("el operador '!' requiere un valor booleano")
+		}
+		return typeBool, nil
+	}
+
+	if t != typeNumber {
+		return typeNumber, fmt.Errorf("el operador '%s' requiere un valor numérico", n.op)
+	}
+	return typeNumber, nil
+}
+
+func (n *unaryNode) eval(vars map[string]float64) (value, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return v, err
+	}
+
+	if n.op == "!" {
+		return value{typ: typeBool, b: !v.b}, nil
+	}
+	return value{typ: typeNumber, num: -v.num}, nil
+}
+
+// binaryNode operador binario aritmético, de comparación o lógico
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+// isComparison indica si el operador es de comparación
+func isComparison(op string) bool {
+	switch op {
+	case ">", "<", ">=", "<=", "==", "!=":
+		return true
+	}
+	return false
+}
+
+func (n *binaryNode) check(known func(string) bool) (valueType, error) {
+	lt, err := n.left.check(known)
+	if err != nil {
+		return lt, err
+	}
+	rt, err := n.right.check(known)
+	if err != nil {
+		return rt, err
+	}
+
+	switch {
+	case n.op == "&&" || n.op == "||":
+		if lt != typeBool || rt != typeBool {
+			return typeBool, fmt.Errorf("el operador '%s' requiere condiciones a ambos lados", n.op)
+		}
+		return typeBool, nil
+	case isComparison(n.op):
+		if lt != typeNumber || rt != typeNumber {
+			return typeBool, fmt.Errorf("el operador '%s' requiere valores numéricos a ambos lados", n.op)
+		}
+		return typeBool, nil
+	default:
+		if lt != typeNumber || rt != typeNumber {
+			return typeNumber, fmt.Errorf("el operador '%s' requiere valores numéricos", n.op)
+		}
+		return typeNumber, nil
+	}
+}
+
+func (n *binaryNode) eval(vars map[string]float64) (value, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return left, err
+	}
+
+	// Evaluación en cortocircuito de los operadores lógicos
+	switch n.op {
+	case "&&":
+		if !left.b {
+			return value{typ: typeBool, b: false}, nil
+		}
+	case "||":
+		if left.b {
+			return value{typ: typeBool, b: true}, nil
+		}
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return right, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return value{typ: typeBool, b: right.b}, nil
+	case ">":
+		return value{typ: typeBool, b: left.num > right.num}, nil
+	case "<":
+		return value{typ: typeBool, b: left.num < right.num}, nil
+	case ">=":
+		return value{typ: typeBool, b: left.num >= right.num}, nil
+	case "<=":
+		return value{typ: typeBool, b: left.num <= right.num}, nil
+	case "==":
+		return value{typ: typeBool, b: left.num == right.num}, nil
+	case "!=":
+		return value{typ: typeBool, b: left.num != right.num}, nil
+	case "+":
+		return value{typ: typeNumber, num: left.num + right.num}, nil
+	case "-":
+		return value{typ: typeNumber, num: left.num - right.num}, nil
+	case "*":
+		return value{typ: typeNumber, num: left.num * right.num}, nil
+	case "/":
+		if right.num == 0 {
+			return value{}, fmt.Errorf("división por cero")
+		}
+		return value{typ: typeNumber, num: left.num / right.num}, nil
+	case "%":
+		if right.num == 0 {
+			return value{}, fmt.Errorf("división por cero")
+		}
+		return value{typ: typeNumber, num: math.Mod(left.num, right.num)}, nil
+	}
+
+	return value{}, fmt.Errorf("operador desconocido: %s", n.op)
+}
+
+// callNode llamada a una función numérica
+type callNode struct {
+	name string
+	args []node
+	pos  int
+}
+
+// functions funciones disponibles en las expresiones y su número mínimo de argumentos
+var functions = map[string]int{
+	"abs": 1,
+	"min": 2,
+	"max": 2,
+}
+
+func (n *callNode) check(known func(string) bool) (valueType, error) {
+	minArgs, ok := functions[n.name]
+	if !ok {
+		return typeNumber, fmt.Errorf("función desconocida '%s' en la posición %d", n.name, n.pos+1)
+	}
+
+	if n.name == "abs" && len(n.args) != 1 {
+		return typeNumber, fmt.Errorf("la función abs requiere un argumento")
+	}
+	if len(n.args) < minArgs {
+		return typeNumber, fmt.Errorf("la función %s requiere al menos %d argumentos", n.name, minArgs)
+	}
+
+	for _, arg := range n.args {
+		t, err := arg.check(known)
+		if err != nil {
+			return typeNumber, err
+		}
+		if t != typeNumber {
+			return typeNumber, fmt.Errorf("la función %s requiere argumentos numéricos", n.name)
+		}
+	}
+
+	return typeNumber, nil
+}
+
+func (n *callNode) eval(vars map[string]float64) (value, error) {
+	values := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return v, err
+		}
+		values[i] = v.num
+	}
+
+	result := values[0]
+	switch n.name {
+	case "abs":
+		result = math.Abs(result)
+	case "min":
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+	case "max":
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+	}
+
+	return value{typ: typeNumber, num: result}, nil
+}
@@ -0,0 +1,141 @@
+// Package expression implementa el lenguaje de expresiones de las reglas de alerta.
+//
+// Una expresión es una condición sobre variables numéricas (campos de métrica) que admite
+// aritmética (+ - * / %), comparaciones (> < >= <= == !=), combinadores lógicos
+// (&& || ! o and, or, not), paréntesis y las funciones abs, min y max. Por ejemplo:
+//
+//	memory_percent > 90 && swap_percent > 50
+//	load_avg_5 > 8 or (cpu_temp >= 85 and cpu_usage > 70)
+package expression
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Expression expresión compilada y validada
+type Expression struct {
+	source string
+	root   node
+	vars   []string
+}
+
+// Compile analiza y valida una expresión. Si known no es nil, solo se admiten las
+// variables para las que devuelve true. La expresión debe producir un valor booleano.
+func Compile(source string, known func(string) bool) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := root.check(known)
+	if err != nil {
+		return nil, err
+	}
+	if t != typeBool {
+		return nil, fmt.Errorf("la expresión debe ser una condición (comparación o combinación lógica)")
+	}
+
+	return &Expression{
+		source: source,
+		root:   root,
+		vars:   collectVariables(root),
+	}, nil
+}
+
+// String devuelve el texto original de la expresión
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Variables devuelve las variables utilizadas, ordenadas alfabéticamente
+func (e *Expression) Variables() []string {
+	return append([]string(nil), e.vars...)
+}
+
+// Evaluate evalúa la condición con los valores de las variables
+func (e *Expression) Evaluate(vars map[string]float64) (bool, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return v.b, nil
+}
+
+// Comparison describe la primera comparación de la expresión con sus operandos evaluados.
+// Sirve para mostrar en la alerta el valor observado y el límite.
+type Comparison struct {
+	Operator string
+	Left     float64
+	Right    float64
+}
+
+// PrimaryComparison evalúa los operandos de la primera comparación de la expresión
+// (recorrido en profundidad de izquierda a derecha). ok es false si no hay ninguna.
+func (e *Expression) PrimaryComparison(vars map[string]float64) (cmp Comparison, ok bool) {
+	bin := firstComparison(e.root)
+	if bin == nil {
+		return cmp, false
+	}
+
+	left, errLeft := bin.left.eval(vars)
+	right, errRight := bin.right.eval(vars)
+	if errLeft != nil || errRight != nil {
+		return cmp, false
+	}
+
+	return Comparison{Operator: bin.op, Left: left.num, Right: right.num}, true
+}
+
+// firstComparison busca el primer nodo de comparación
+func firstComparison(n node) *binaryNode {
+	switch t := n.(type) {
+	case *binaryNode:
+		if isComparison(t.op) {
+			return t
+		}
+		if found := firstComparison(t.left); found != nil {
+			return found
+		}
+		return firstComparison(t.right)
+	case *unaryNode:
+		return firstComparison(t.operand)
+	}
+	return nil
+}
+
+// collectVariables devuelve las variables referenciadas por un nodo, sin duplicados
+func collectVariables(root node) []string {
+	seen := make(map[string]bool)
+
+	var walk func(n node)
+	walk = func(n node) {
+		switch t := n.(type) {
+		case *varNode:
+			seen[t.name] = true
+		case *unaryNode:
+			walk(t.operand)
+		case *binaryNode:
+			walk(t.left)
+			walk(t.right)
+		case *callNode:
+			for _, arg := range t.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(root)
+
+	vars := make([]string, 0, len(seen))
+	for name := range seen {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+	return vars
+}
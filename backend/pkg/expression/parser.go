@@ -0,0 +1,253 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parser analizador sintáctico descendente recursivo. Precedencia (de menor a mayor):
+// ||, &&, !, comparaciones, + -, * / %, - unario
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse analiza la expresión completa
+func (p *parser) parse() (node, error) {
+	if p.peek().kind == tokenEOF {
+		return nil, fmt.Errorf("la expresión está vacía")
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("símbolo inesperado '%s' en la posición %d", tok.text, tok.pos+1)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOperator consume el siguiente token si es uno de los operadores indicados
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.acceptOperator("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.acceptOperator("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.acceptOperator(">=", "<=", "==", "!=", ">", "<")
+	if !ok {
+		return left, nil
+	}
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	// Las comparaciones no se encadenan (a < b < c es ambiguo)
+	if tok := p.peek(); tok.kind == tokenOperator && isComparison(tok.text) {
+		return nil, fmt.Errorf("comparaciones encadenadas no permitidas en la posición %d; use && para combinarlas", tok.pos+1)
+	}
+
+	return &binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.acceptOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.acceptOperator("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptOperator("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+
+	if _, ok := p.acceptOperator("+"); ok {
+		return p.parseUnary()
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		return &numberNode{value: tok.num}, nil
+
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &boolNode{value: true}, nil
+		case "false":
+			return &boolNode{value: false}, nil
+		}
+
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(tok)
+		}
+		return &varNode{name: tok.text, pos: tok.pos}, nil
+
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("falta ')' en la posición %d", closing.pos+1)
+		}
+		return n, nil
+
+	case tokenEOF:
+		return nil, fmt.Errorf("la expresión termina de forma inesperada")
+	}
+
+	return nil, fmt.Errorf("símbolo inesperado '%s' en la posición %d", tok.text, tok.pos+1)
+}
+
+// parseCall analiza los argumentos de una llamada a función
+func (p *parser) parseCall(name token) (node, error) {
+	p.next() // (
+
+	call := &callNode{name: strings.ToLower(name.text), pos: name.pos}
+
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return call, nil
+	}
+
+	for {
+		arg, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+
+		tok := p.next()
+		switch tok.kind {
+		case tokenComma:
+			continue
+		case tokenRParen:
+			return call, nil
+		default:
+			return nil, fmt.Errorf("se esperaba ',' o ')' en la posición %d", tok.pos+1)
+		}
+	}
+}
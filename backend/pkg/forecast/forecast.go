@@ -0,0 +1,207 @@
+// Package forecast ajusta modelos de tendencia sobre series temporales para estimar
+// cuándo un recurso alcanzará su capacidad.
+package forecast
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// MinPoints número mínimo de puntos para ajustar un modelo
+const MinPoints = 3
+
+// ErrNotEnoughPoints indica que la serie no tiene puntos suficientes
+var ErrNotEnoughPoints = errors.New("no hay suficientes puntos para ajustar el modelo")
+
+// Method método de ajuste
+type Method string
+
+const (
+	MethodLinear Method = "linear" // Regresión lineal por mínimos cuadrados
+	MethodHolt   Method = "holt"   // Suavizado exponencial doble de Holt
+)
+
+// Default parámetros de suavizado de Holt
+const (
+	DefaultHoltAlpha = 0.5
+	DefaultHoltBeta  = 0.3
+)
+
+// Point muestra de la serie temporal
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Model modelo de tendencia ajustado
+type Model struct {
+	Method      Method
+	Level       float64   // Valor estimado en LastTime
+	Slope       float64   // Variación por hora
+	SlopeStdErr float64   // Error estándar de la pendiente (solo lineal)
+	Confidence  float64   // Bondad del ajuste (R², entre 0 y 1)
+	Samples     int       // Puntos utilizados
+	LastTime    time.Time // Instante del último punto
+}
+
+// Fit ajusta el modelo indicado (lineal si el método no se reconoce)
+func Fit(method Method, points []Point) (*Model, error) {
+	if method == MethodHolt {
+		return FitHolt(points, DefaultHoltAlpha, DefaultHoltBeta)
+	}
+	return FitLinear(points)
+}
+
+// FitLinear ajusta una recta por mínimos cuadrados (x en horas desde el primer punto)
+func FitLinear(points []Point) (*Model, error) {
+	n := len(points)
+	if n < MinPoints {
+		return nil, ErrNotEnoughPoints
+	}
+
+	origin := points[0].Time
+	xs := make([]float64, n)
+	var sumX, sumY float64
+	for i, p := range points {
+		xs[i] = p.Time.Sub(origin).Hours()
+		sumX += xs[i]
+		sumY += p.Value
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var sxx, sxy float64
+	for i, p := range points {
+		dx := xs[i] - meanX
+		sxx += dx * dx
+		sxy += dx * (p.Value - meanY)
+	}
+	if sxx == 0 {
+		return nil, ErrNotEnoughPoints
+	}
+
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	predictions := make([]float64, n)
+	var sse float64
+	for i := range points {
+		predictions[i] = intercept + slope*xs[i]
+		residual := points[i].Value - predictions[i]
+		sse += residual * residual
+	}
+
+	var stdErr float64
+	if n > 2 {
+		stdErr = math.Sqrt(sse/float64(n-2)) / math.Sqrt(sxx)
+	}
+
+	return &Model{
+		Method:      MethodLinear,
+		Level:       intercept + slope*xs[n-1],
+		Slope:       slope,
+		SlopeStdErr: stdErr,
+		Confidence:  rSquared(points, predictions),
+		Samples:     n,
+		LastTime:    points[n-1].Time,
+	}, nil
+}
+
+// FitHolt ajusta un suavizado exponencial doble de Holt. La tendencia se calcula por muestra
+// y se convierte a variación por hora con el intervalo medio entre muestras.
+func FitHolt(points []Point, alpha, beta float64) (*Model, error) {
+	n := len(points)
+	if n < MinPoints {
+		return nil, ErrNotEnoughPoints
+	}
+
+	span := points[n-1].Time.Sub(points[0].Time).Hours()
+	if span <= 0 {
+		return nil, ErrNotEnoughPoints
+	}
+	stepHours := span / float64(n-1)
+
+	level := points[0].Value
+	trend := points[1].Value - points[0].Value
+
+	// Predicciones a un paso para medir la bondad del ajuste
+	predictions := make([]float64, n)
+	predictions[0] = points[0].Value
+	for i := 1; i < n; i++ {
+		predictions[i] = level + trend
+		previousLevel := level
+		level = alpha*points[i].Value + (1-alpha)*(level+trend)
+		trend = beta*(level-previousLevel) + (1-beta)*trend
+	}
+
+	return &Model{
+		Method:     MethodHolt,
+		Level:      level,
+		Slope:      trend / stepHours,
+		Confidence: rSquared(points, predictions),
+		Samples:    n,
+		LastTime:   points[n-1].Time,
+	}, nil
+}
+
+// Predict estima el valor en un instante
+func (m *Model) Predict(at time.Time) float64 {
+	return m.Level + m.Slope*at.Sub(m.LastTime).Hours()
+}
+
+// TimeToReach tiempo desde LastTime hasta que el modelo alcance target. Devuelve false si
+// la tendencia no se acerca al objetivo. Si ya se alcanzó, devuelve 0.
+func (m *Model) TimeToReach(target float64) (time.Duration, bool) {
+	return timeToReach(m.Level, m.Slope, target)
+}
+
+// TimeToReachRange intervalo de tiempo hasta target usando la pendiente ± 1.96 errores estándar
+// (aprox. 95%). Solo disponible en el modelo lineal.
+func (m *Model) TimeToReachRange(target float64) (earliest, latest time.Duration, okEarliest, okLatest bool) {
+	if m.SlopeStdErr == 0 {
+		return 0, 0, false, false
+	}
+	margin := 1.96 * m.SlopeStdErr
+	earliest, okEarliest = timeToReach(m.Level, m.Slope+margin, target)
+	latest, okLatest = timeToReach(m.Level, m.Slope-margin, target)
+	return earliest, latest, okEarliest, okLatest
+}
+
+func timeToReach(level, slope, target float64) (time.Duration, bool) {
+	if level >= target {
+		return 0, true
+	}
+	if slope <= 0 {
+		return 0, false
+	}
+	hours := (target - level) / slope
+	if hours > math.MaxInt64/float64(time.Hour) {
+		return 0, false
+	}
+	return time.Duration(hours * float64(time.Hour)), true
+}
+
+// rSquared coeficiente de determinación de las predicciones (limitado a [0, 1])
+func rSquared(points []Point, predictions []float64) float64 {
+	var mean float64
+	for _, p := range points {
+		mean += p.Value
+	}
+	mean /= float64(len(points))
+
+	var sse, sst float64
+	for i, p := range points {
+		sse += (p.Value - predictions[i]) * (p.Value - predictions[i])
+		sst += (p.Value - mean) * (p.Value - mean)
+	}
+
+	if sst == 0 {
+		// Serie constante: el ajuste es perfecto si no hay residuos
+		if sse == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	return math.Max(0, math.Min(1, 1-sse/sst))
+}
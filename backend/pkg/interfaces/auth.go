@@ -14,3 +14,8 @@ type AuthServiceInterface interface {
 type ServerServiceInterface interface {
 	GetServerByID(id uint) (interface{}, error)
 }
+
+// ServerAccessInterface define la comprobación de acceso de un usuario a un servidor
+type ServerAccessInterface interface {
+	CanViewServer(userID, serverID uint) (bool, error)
+}
@@ -0,0 +1,308 @@
+// Package ldapauth autentica usuarios contra un directorio LDAP o Active Directory: se conecta
+// con una cuenta de servicio, busca al usuario con un filtro configurable y comprueba su
+// contraseña con un bind con su DN.
+package ldapauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Errores del directorio
+var (
+	ErrInvalidCredentials = errors.New("credenciales inválidas")
+	ErrUserNotFound       = errors.New("usuario no encontrado en el directorio")
+	ErrUnavailable        = errors.New("el directorio LDAP no está disponible")
+)
+
+// DefaultTimeout tiempo máximo de conexión y de cada operación
+const DefaultTimeout = 10 * time.Second
+
+// adAccountDisable bit de userAccountControl que marca una cuenta deshabilitada en Active Directory
+const adAccountDisable = 0x2
+
+// Config configuración de la conexión y del esquema del directorio
+type Config struct {
+	URL          string // ldap://host:389 o ldaps://host:636
+	StartTLS     bool   // Pasar a TLS tras conectar (solo con ldap://)
+	SkipVerify   bool   // No verificar el certificado del servidor (solo pruebas)
+	CACertFile   string // CA adicional para verificar el certificado del servidor
+	BindDN       string // Cuenta de servicio para las búsquedas
+	BindPassword string
+	BaseDN       string // Base de búsqueda de los usuarios
+	UserFilter   string // Filtro de usuario; %s se sustituye por el nombre escapado
+	UsernameAttr string
+	EmailAttr    string
+	MemberOfAttr string // Atributo con los DN de los grupos del usuario (p. ej. memberOf)
+	GroupBaseDN  string // Si se indica, los grupos se buscan aquí en lugar de leer MemberOfAttr
+	GroupFilter  string // Filtro de grupos; %s se sustituye por el DN escapado del usuario
+	Timeout      time.Duration
+}
+
+// Entry usuario encontrado en el directorio
+type Entry struct {
+	DN       string
+	Username string
+	Email    string
+	Groups   []string // DN de los grupos del usuario
+	Disabled bool     // Cuenta deshabilitada (userAccountControl de AD o bloqueo de ppolicy)
+}
+
+// Client cliente del directorio. Cada operación abre su propia conexión
+type Client struct {
+	config    Config
+	tlsConfig *tls.Config
+}
+
+// NewClient crea un cliente del directorio aplicando los valores por defecto del esquema
+func NewClient(config Config) (*Client, error) {
+	if config.URL == "" || config.BaseDN == "" {
+		return nil, fmt.Errorf("la URL y la base de búsqueda del directorio son obligatorias")
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = "(uid=%s)"
+	}
+	if !strings.Contains(config.UserFilter, "%s") {
+		return nil, fmt.Errorf("el filtro de usuario debe contener %%s")
+	}
+	if config.UsernameAttr == "" {
+		config.UsernameAttr = "uid"
+	}
+	if config.EmailAttr == "" {
+		config.EmailAttr = "mail"
+	}
+	if config.MemberOfAttr == "" {
+		config.MemberOfAttr = "memberOf"
+	}
+	if config.GroupFilter == "" {
+		config.GroupFilter = "(member=%s)"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{config: config, tlsConfig: tlsConfig}, nil
+}
+
+// Authenticate comprueba el usuario y la contraseña contra el directorio y devuelve su entrada
+func (c *Client) Authenticate(username, password string) (*Entry, error) {
+	// Un bind con contraseña vacía es un bind anónimo que el servidor puede aceptar
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := c.findUser(conn, username)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	// Volver a la cuenta de servicio para leer los grupos con sus permisos
+	if err := c.serviceBind(conn); err != nil {
+		return nil, err
+	}
+	if err := c.loadGroups(conn, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Lookup busca un usuario sin comprobar su contraseña (sincronización periódica)
+func (c *Client) Lookup(username string) (*Entry, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := c.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.loadGroups(conn, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// connect abre la conexión (con StartTLS si se configura) y se autentica con la cuenta de servicio
+func (c *Client) connect() (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.config.Timeout}
+	conn, err := ldap.DialURL(c.config.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(c.tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	conn.SetTimeout(c.config.Timeout)
+
+	if c.config.StartTLS && strings.HasPrefix(strings.ToLower(c.config.URL), "ldap://") {
+		if err := conn.StartTLS(c.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: StartTLS: %v", ErrUnavailable, err)
+		}
+	}
+
+	if err := c.serviceBind(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// serviceBind se autentica con la cuenta de servicio (o de forma anónima si no se configura)
+func (c *Client) serviceBind(conn *ldap.Conn) error {
+	var err error
+	if c.config.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(c.config.BindDN, c.config.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: bind de la cuenta de servicio: %v", ErrUnavailable, err)
+	}
+
+	return nil
+}
+
+// findUser busca al usuario con el filtro configurado; debe haber exactamente una entrada
+func (c *Client) findUser(conn *ldap.Conn, username string) (*Entry, error) {
+	filter := strings.ReplaceAll(c.config.UserFilter, "%s", ldap.EscapeFilter(username))
+	request := ldap.NewSearchRequest(
+		c.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(c.config.Timeout.Seconds()), false, filter,
+		[]string{"dn", c.config.UsernameAttr, c.config.EmailAttr, c.config.MemberOfAttr, "userAccountControl", "pwdAccountLockedTime"},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%w: búsqueda del usuario: %v", ErrUnavailable, err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("el filtro de usuario devuelve varias entradas para %s", username)
+	}
+
+	raw := result.Entries[0]
+	entry := &Entry{
+		DN:       raw.DN,
+		Username: raw.GetAttributeValue(c.config.UsernameAttr),
+		Email:    raw.GetAttributeValue(c.config.EmailAttr),
+		Groups:   raw.GetAttributeValues(c.config.MemberOfAttr),
+	}
+	if entry.Username == "" {
+		entry.Username = username
+	}
+
+	if uac, err := strconv.ParseInt(raw.GetAttributeValue("userAccountControl"), 10, 64); err == nil && uac&adAccountDisable != 0 {
+		entry.Disabled = true
+	}
+	if raw.GetAttributeValue("pwdAccountLockedTime") != "" {
+		entry.Disabled = true
+	}
+
+	return entry, nil
+}
+
+// loadGroups busca los grupos del usuario en GroupBaseDN, si se configura
+func (c *Client) loadGroups(conn *ldap.Conn, entry *Entry) error {
+	if c.config.GroupBaseDN == "" {
+		return nil
+	}
+
+	filter := strings.ReplaceAll(c.config.GroupFilter, "%s", ldap.EscapeFilter(entry.DN))
+	request := ldap.NewSearchRequest(
+		c.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(c.config.Timeout.Seconds()), false, filter, []string{"dn"}, nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return fmt.Errorf("%w: búsqueda de grupos: %v", ErrUnavailable, err)
+	}
+
+	entry.Groups = entry.Groups[:0]
+	for _, group := range result.Entries {
+		entry.Groups = append(entry.Groups, group.DN)
+	}
+
+	return nil
+}
+
+// SameDN compara dos DN sin distinguir mayúsculas ni espacios entre componentes
+func SameDN(a, b string) bool {
+	dnA, errA := ldap.ParseDN(a)
+	dnB, errB := ldap.ParseDN(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+
+	return dnA.EqualFold(dnB)
+}
+
+// buildTLSConfig prepara la configuración TLS para ldaps:// y StartTLS
+func buildTLSConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.SkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	host := config.URL
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	tlsConfig.ServerName = host
+
+	if config.CACertFile != "" {
+		pem, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo leer la CA del directorio: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("la CA del directorio no contiene certificados válidos")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
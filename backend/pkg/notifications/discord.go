@@ -11,6 +11,12 @@ import (
 	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
 )
 
+// Límites de Discord para el título y la descripción de un embed
+const (
+	discordMaxEmbedTitle       = 256
+	discordMaxEmbedDescription = 4096
+)
+
 // DiscordWebhook estructura para enviar mensajes a Discord
 type DiscordWebhook struct {
 	Content   string         `json:"content,omitempty"`
@@ -227,3 +233,42 @@ func getDurationText(start, end time.Time) string {
 		return fmt.Sprintf("%dd %dh", days, hours)
 	}
 }
+
+// SendRendered envía a Discord un mensaje generado a partir de una plantilla
+func (dc *DiscordClient) SendRendered(alert *models.Alert, state models.TemplateState, msg *RenderedMessage) error {
+	color := GetColorForSeverity(alert.Severity)
+	if state == models.TemplateStateResolved {
+		color = 3066993 // Verde
+	}
+
+	webhook := DiscordWebhook{
+		Username:  dc.botUsername,
+		AvatarURL: dc.avatarURL,
+		Embeds: []DiscordEmbed{{
+			Title:       truncateText(msg.Title, discordMaxEmbedTitle),
+			Description: truncateText(msg.Body, discordMaxEmbedDescription),
+			Color:       color,
+			Timestamp:   time.Now().Format(time.RFC3339),
+			Footer: &DiscordEmbedFooter{
+				Text: "Sistema de Monitoreo de Servidores",
+			},
+		}},
+	}
+
+	jsonData, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+
+	resp, err := dc.httpClient.Post(dc.webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error en respuesta de Discord. Código: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// ErrEmailDisabled el envío de correo no está configurado
+var ErrEmailDisabled = errors.New("el envío de correo no está configurado")
+
+// EmailClient cliente SMTP para enviar correos de texto plano
+type EmailClient struct {
+	server   string
+	port     int
+	user     string
+	password string
+	from     string
+	logger   logger.Logger
+}
+
+// NewEmailClient crea un nuevo cliente de correo. Si el servidor lo admite la conexión pasa a
+// TLS con STARTTLS antes de autenticarse
+func NewEmailClient(server string, port int, user, password, from string, log logger.Logger) *EmailClient {
+	if port <= 0 {
+		port = 587
+	}
+
+	return &EmailClient{
+		server:   server,
+		port:     port,
+		user:     user,
+		password: password,
+		from:     from,
+		logger:   log,
+	}
+}
+
+// SendMail envía un correo de texto plano a un destinatario
+func (ec *EmailClient) SendMail(to, subject, body string) error {
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("destinatario inválido: %w", err)
+	}
+	sender, err := mail.ParseAddress(ec.from)
+	if err != nil {
+		return fmt.Errorf("remitente inválido: %w", err)
+	}
+
+	// Las cabeceras no pueden contener saltos de línea (inyección de cabeceras)
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+
+	var message strings.Builder
+	message.WriteString("From: " + sender.String() + "\r\n")
+	message.WriteString("To: " + recipient.String() + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if ec.user != "" {
+		auth = smtp.PlainAuth("", ec.user, ec.password, ec.server)
+	}
+
+	addr := net.JoinHostPort(ec.server, strconv.Itoa(ec.port))
+	if err := smtp.SendMail(addr, auth, sender.Address, []string{recipient.Address}, []byte(message.String())); err != nil {
+		return fmt.Errorf("error al enviar el correo a %s: %w", recipient.Address, err)
+	}
+
+	ec.logger.Infof("Correo enviado a %s: %s", recipient.Address, subject)
+	return nil
+}
@@ -14,11 +14,19 @@ type Notifier interface {
 	SendResolvedAlert(alert *models.Alert) error
 }
 
+// legacyWebhookChannel canal del webhook configurado directamente en el umbral
+const legacyWebhookChannel = string(models.ContactPointWebhook)
+
 // NotificationManager gestiona diferentes proveedores de notificaciones
 type NotificationManager struct {
-	discordClient *DiscordClient
-	// Futuros proveedores: email, webhooks externos, etc
-	logger logger.Logger
+	discordClient  *DiscordClient
+	telegramClient *TelegramClient
+	ntfyClient     *NtfyClient
+	emailClient    *EmailClient // Correos a usuarios (invitaciones, restablecer contraseña)
+	// Futuros proveedores: webhooks externos, etc
+	routing   routingState
+	templates TemplateProvider
+	logger    logger.Logger
 }
 
 // NotificationConfig configuración para las notificaciones
@@ -29,7 +37,18 @@ type NotificationConfig struct {
 	DiscordBotName    string
 	DiscordAvatarURL  string
 
-	// Email (futuro)
+	// Telegram
+	TelegramEnabled  bool
+	TelegramBotToken string
+	TelegramChatID   string
+
+	// ntfy
+	NtfyEnabled   bool
+	NtfyServerURL string
+	NtfyTopic     string
+	NtfyToken     string
+
+	// Email
 	EmailEnabled bool
 	SMTPServer   string
 	SMTPPort     int
@@ -46,7 +65,8 @@ type NotificationConfig struct {
 // NewNotificationManager crea un nuevo gestor de notificaciones
 func NewNotificationManager(config *NotificationConfig, log logger.Logger) *NotificationManager {
 	manager := &NotificationManager{
-		logger: log,
+		routing: routingState{notifiers: make(map[uint]cachedNotifier)},
+		logger:  log,
 	}
 
 	// Inicializar cliente de Discord si está habilitado
@@ -60,44 +80,197 @@ func NewNotificationManager(config *NotificationConfig, log logger.Logger) *Noti
 		log.Info("Cliente de notificaciones Discord inicializado")
 	}
 
+	// Inicializar cliente de Telegram si está habilitado
+	if config.TelegramEnabled && config.TelegramBotToken != "" && config.TelegramChatID != "" {
+		manager.telegramClient = NewTelegramClient(
+			config.TelegramBotToken,
+			config.TelegramChatID,
+			log,
+		)
+		log.Info("Cliente de notificaciones Telegram inicializado")
+	}
+
+	// Inicializar cliente de ntfy si está habilitado
+	if config.NtfyEnabled && config.NtfyTopic != "" {
+		manager.ntfyClient = NewNtfyClient(
+			config.NtfyServerURL,
+			config.NtfyTopic,
+			config.NtfyToken,
+			log,
+		)
+		log.Info("Cliente de notificaciones ntfy inicializado")
+	}
+
+	// Inicializar cliente de correo si está habilitado
+	if config.EmailEnabled && config.SMTPServer != "" {
+		manager.emailClient = NewEmailClient(
+			config.SMTPServer,
+			config.SMTPPort,
+			config.SMTPUser,
+			config.SMTPPassword,
+			config.EmailFrom,
+			log,
+		)
+		log.Info("Cliente de correo inicializado")
+	}
+
 	return manager
 }
 
-// NotifyAlert envía una alerta a todos los canales configurados
+// EmailEnabled indica si se pueden enviar correos
+func (nm *NotificationManager) EmailEnabled() bool {
+	return nm.emailClient != nil
+}
+
+// SendEmail envía un correo de texto plano a un usuario
+func (nm *NotificationManager) SendEmail(to, subject, body string) error {
+	if nm.emailClient == nil {
+		return ErrEmailDisabled
+	}
+
+	return nm.emailClient.SendMail(to, subject, body)
+}
+
+// NotifyAlert envía una alerta a los destinos resueltos por las reglas de enrutamiento.
+// Si ninguna regla coincide (o la última coincidente tiene "continue"), se aplican además
+// los canales habilitados en el umbral como ruta heredada.
 func (nm *NotificationManager) NotifyAlert(alert *models.Alert, threshold *models.AlertThreshold) error {
+	contactPointIDs, useLegacy := nm.resolveRoutes(alert)
+
+	notifyChannels := nm.NotifyContactPoints(alert, contactPointIDs)
+
+	if useLegacy && threshold != nil {
+		notifyChannels = append(notifyChannels, nm.notifyLegacy(alert, threshold)...)
+	}
+
+	// Actualizar canales en la alerta
+	alert.NotifyChannels = notifyChannels
+
+	return nil
+}
+
+// notifyLegacy envía la alerta a los canales globales habilitados en el umbral
+func (nm *NotificationManager) notifyLegacy(alert *models.Alert, threshold *models.AlertThreshold) []string {
 	var notifyChannels []string
 
 	// Registrar canales utilizados
 	if threshold.EnableDiscord && nm.discordClient != nil {
-		if err := nm.discordClient.SendAlert(alert); err != nil {
+		if err := nm.deliver("discord", nm.discordClient, alert, models.TemplateStateFiring); err != nil {
 			nm.logger.Errorf("Error al enviar alerta a Discord: %v", err)
 		} else {
 			notifyChannels = append(notifyChannels, "discord")
 		}
 	}
 
-	// TODO: Implementar otros canales (email, webhook, etc)
+	if threshold.EnableTelegram && nm.telegramClient != nil {
+		if err := nm.deliver("telegram", nm.telegramClient, alert, models.TemplateStateFiring); err != nil {
+			nm.logger.Errorf("Error al enviar alerta a Telegram: %v", err)
+		} else {
+			notifyChannels = append(notifyChannels, "telegram")
+		}
+	}
 
-	// Actualizar canales en la alerta
-	alert.NotifyChannels = notifyChannels
+	if threshold.EnableNtfy && nm.ntfyClient != nil {
+		if err := nm.deliver("ntfy", nm.ntfyClient, alert, models.TemplateStateFiring); err != nil {
+			nm.logger.Errorf("Error al enviar alerta a ntfy: %v", err)
+		} else {
+			notifyChannels = append(notifyChannels, "ntfy")
+		}
+	}
 
-	return nil
+	if threshold.EnableWebhook && threshold.WebhookURL != "" {
+		webhook := NewWebhookClient(threshold.WebhookURL, "", nm.logger)
+		if err := nm.deliver(legacyWebhookChannel, webhook, alert, models.TemplateStateFiring); err != nil {
+			nm.logger.Errorf("Error al enviar alerta al webhook del umbral: %v", err)
+		} else {
+			notifyChannels = append(notifyChannels, legacyWebhookChannel)
+		}
+	}
+
+	return notifyChannels
+}
+
+// legacyWebhook devuelve el cliente del webhook configurado en el umbral de la alerta, o nil
+// si el umbral ya no tiene webhook
+func (nm *NotificationManager) legacyWebhook(alert *models.Alert) (Notifier, error) {
+	if nm.templates == nil {
+		return nil, nil
+	}
+
+	data, err := nm.templates.BuildTemplateData(alert, models.TemplateStateFiring)
+	if err != nil {
+		return nil, err
+	}
+	if data.Threshold == nil || !data.Threshold.EnableWebhook || data.Threshold.WebhookURL == "" {
+		return nil, nil
+	}
+
+	return NewWebhookClient(data.Threshold.WebhookURL, "", nm.logger), nil
 }
 
 // NotifyResolvedAlert envía una notificación de alerta resuelta
 func (nm *NotificationManager) NotifyResolvedAlert(alert *models.Alert) error {
-	// Enviar solo si la alerta fue notificada previamente
+	// Enviar solo a los canales por los que se notificó la alerta
+	for _, channel := range alert.NotifyChannels {
+		nm.notifyChannel(channel, alert, models.TemplateStateResolved)
+	}
+
+	return nil
+}
+
+// NotifyAcknowledgedAlert avisa a los canales notificados de que la alerta fue reconocida
+func (nm *NotificationManager) NotifyAcknowledgedAlert(alert *models.Alert) error {
 	for _, channel := range alert.NotifyChannels {
-		switch channel {
-		case "discord":
-			if nm.discordClient != nil {
-				if err := nm.discordClient.SendResolvedAlert(alert); err != nil {
-					nm.logger.Errorf("Error al enviar resolución de alerta a Discord: %v", err)
-				}
+		nm.notifyChannel(channel, alert, models.TemplateStateAcknowledged)
+	}
+
+	return nil
+}
+
+// notifyChannel envía una notificación de seguimiento a un canal ya utilizado por la alerta
+func (nm *NotificationManager) notifyChannel(channel string, alert *models.Alert, state models.TemplateState) {
+	channelType, notifier, err := nm.notifierForChannel(channel, alert)
+	if err != nil {
+		nm.logger.Warnf("No se pudo usar el canal %s: %v", channel, err)
+		return
+	}
+	if notifier == nil {
+		return
+	}
+
+	if err := nm.deliver(channelType, notifier, alert, state); err != nil {
+		nm.logger.Errorf("Error al enviar %s de alerta al canal %s: %v", stateLabel(state), channel, err)
+	}
+}
+
+// notifierForChannel devuelve el tipo de canal y el notificador asociados a un identificador
+// de canal almacenado en la alerta. Devuelve un notificador nil si el canal ya no está configurado
+func (nm *NotificationManager) notifierForChannel(channel string, alert *models.Alert) (string, Notifier, error) {
+	switch channel {
+	case legacyWebhookChannel:
+		notifier, err := nm.legacyWebhook(alert)
+		return channel, notifier, err
+	case "discord":
+		if nm.discordClient != nil {
+			return channel, nm.discordClient, nil
+		}
+	case "telegram":
+		if nm.telegramClient != nil {
+			return channel, nm.telegramClient, nil
+		}
+	case "ntfy":
+		if nm.ntfyClient != nil {
+			return channel, nm.ntfyClient, nil
+		}
+	default:
+		if id, ok := parseContactPointChannel(channel); ok && nm.routing.provider != nil {
+			cp, notifier, err := nm.contactPointNotifier(id)
+			if err != nil {
+				return "", nil, err
 			}
-			// TODO: Otros canales
+			return string(cp.Type), notifier, nil
 		}
 	}
 
-	return nil
+	return channel, nil, nil
 }
@@ -0,0 +1,157 @@
+package notifications
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// Prioridades soportadas por ntfy (1 = mínima, 5 = urgente)
+const (
+	NtfyPriorityMin     = 1
+	NtfyPriorityLow     = 2
+	NtfyPriorityDefault = 3
+	NtfyPriorityHigh    = 4
+	NtfyPriorityUrgent  = 5
+)
+
+// NtfyClient cliente para enviar notificaciones push a un servidor ntfy
+type NtfyClient struct {
+	serverURL  string
+	topic      string
+	token      string
+	httpClient *http.Client
+	logger     logger.Logger
+}
+
+// NewNtfyClient crea un nuevo cliente de ntfy
+func NewNtfyClient(serverURL, topic, token string, log logger.Logger) *NtfyClient {
+	if serverURL == "" {
+		serverURL = "https://ntfy.sh"
+	}
+
+	return &NtfyClient{
+		serverURL:  strings.TrimRight(serverURL, "/"),
+		topic:      topic,
+		token:      token,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     log,
+	}
+}
+
+// GetNtfyPriorityForSeverity asigna una prioridad de ntfy según la severidad de la alerta
+func GetNtfyPriorityForSeverity(severity models.AlertSeverity) int {
+	switch severity {
+	case models.AlertSeverityCritical:
+		return NtfyPriorityUrgent
+	case models.AlertSeverityWarning:
+		return NtfyPriorityHigh
+	case models.AlertSeverityInfo:
+		return NtfyPriorityDefault
+	default:
+		return NtfyPriorityDefault
+	}
+}
+
+// GetNtfyTagForSeverity devuelve la etiqueta (emoji) de ntfy según la severidad
+func GetNtfyTagForSeverity(severity models.AlertSeverity) string {
+	switch severity {
+	case models.AlertSeverityCritical:
+		return "rotating_light"
+	case models.AlertSeverityWarning:
+		return "warning"
+	case models.AlertSeverityInfo:
+		return "information_source"
+	default:
+		return "bell"
+	}
+}
+
+// SendAlert envía una alerta a ntfy
+func (nc *NtfyClient) SendAlert(alert *models.Alert) error {
+	tags := []string{
+		GetNtfyTagForSeverity(alert.Severity),
+		string(alert.Severity),
+		string(alert.MetricType),
+	}
+	if alert.Server.Hostname != "" {
+		tags = append(tags, alert.Server.Hostname)
+	}
+
+	body := fmt.Sprintf("%s\nServidor: %s (%s)\nValor: %.2f (umbral %s %.2f)",
+		alert.Message, alert.Server.Hostname, alert.Server.IP,
+		alert.MetricValue, alert.Operator, alert.Threshold)
+
+	if err := nc.publish(alert.Title, body, GetNtfyPriorityForSeverity(alert.Severity), tags); err != nil {
+		nc.logger.Errorf("Error al enviar alerta a ntfy: %v", err)
+		return err
+	}
+
+	nc.logger.Infof("Alerta #%d enviada exitosamente a ntfy", alert.ID)
+	return nil
+}
+
+// SendResolvedAlert envía una notificación de alerta resuelta
+func (nc *NtfyClient) SendResolvedAlert(alert *models.Alert) error {
+	body := fmt.Sprintf("Servidor: %s", alert.Server.Hostname)
+	if alert.ResolvedAt != nil {
+		body += fmt.Sprintf("\nDuración: %s", getDurationText(alert.TriggeredAt, *alert.ResolvedAt))
+	}
+
+	return nc.publish(fmt.Sprintf("RESUELTA: %s", alert.Title), body, NtfyPriorityDefault,
+		[]string{"white_check_mark", "resolved"})
+}
+
+// SendRendered envía a ntfy un mensaje generado a partir de una plantilla
+func (nc *NtfyClient) SendRendered(alert *models.Alert, state models.TemplateState, msg *RenderedMessage) error {
+	priority := GetNtfyPriorityForSeverity(alert.Severity)
+	tags := []string{GetNtfyTagForSeverity(alert.Severity), string(alert.Severity)}
+
+	switch state {
+	case models.TemplateStateResolved:
+		priority = NtfyPriorityDefault
+		tags = []string{"white_check_mark", "resolved"}
+	case models.TemplateStateAcknowledged:
+		priority = NtfyPriorityLow
+		tags = []string{"eyes", "acknowledged"}
+	}
+
+	return nc.publish(msg.Title, msg.Body, priority, tags)
+}
+
+// publish publica un mensaje en el tópico configurado
+func (nc *NtfyClient) publish(title, body string, priority int, tags []string) error {
+	// El tópico se escapa para que caracteres como "/", "?" o "#" no cambien el destino
+	endpoint := fmt.Sprintf("%s/%s", nc.serverURL, url.PathEscape(nc.topic))
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	// ntfy acepta cabeceras codificadas según RFC 2047 para textos no ASCII
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", title))
+	req.Header.Set("Priority", fmt.Sprintf("%d", priority))
+	req.Header.Set("Tags", strings.Join(tags, ","))
+	if nc.token != "" {
+		req.Header.Set("Authorization", "Bearer "+nc.token)
+	}
+
+	resp, err := nc.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error en respuesta de ntfy. Código: %d", resp.StatusCode)
+	}
+
+	return nil
+}
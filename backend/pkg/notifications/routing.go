@@ -0,0 +1,220 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+)
+
+// contactPointChannelPrefix prefijo de los canales de notificación que apuntan a un punto de contacto
+const contactPointChannelPrefix = "contact_point:"
+
+// RouteProvider proporciona las reglas de enrutamiento y los puntos de contacto almacenados
+type RouteProvider interface {
+	// GetActiveRoutes devuelve las reglas habilitadas ordenadas por posición
+	GetActiveRoutes() ([]models.NotificationRoute, error)
+
+	// GetContactPoint obtiene un punto de contacto por ID
+	GetContactPoint(id uint) (*models.ContactPoint, error)
+
+	// GetServerGroupIDs devuelve los grupos de un servidor, incluidos sus ancestros
+	GetServerGroupIDs(serverID uint) ([]uint, error)
+
+	// GetTeamContactPointIDs devuelve los puntos de contacto habilitados de un equipo
+	GetTeamContactPointIDs(teamID uint) ([]uint, error)
+}
+
+// cachedNotifier notificador construido a partir de un punto de contacto
+type cachedNotifier struct {
+	updatedAt time.Time
+	notifier  Notifier
+}
+
+// routingState estado interno del enrutamiento del NotificationManager
+type routingState struct {
+	provider  RouteProvider
+	notifiers map[uint]cachedNotifier
+	mu        sync.Mutex
+}
+
+// ContactPointChannel devuelve el identificador de canal para un punto de contacto
+func ContactPointChannel(id uint) string {
+	return fmt.Sprintf("%s%d", contactPointChannelPrefix, id)
+}
+
+// parseContactPointChannel extrae el ID de un canal de punto de contacto
+func parseContactPointChannel(channel string) (uint, bool) {
+	if !strings.HasPrefix(channel, contactPointChannelPrefix) {
+		return 0, false
+	}
+
+	var id uint
+	if _, err := fmt.Sscanf(channel, contactPointChannelPrefix+"%d", &id); err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// SetRouteProvider configura el proveedor de reglas de enrutamiento
+func (nm *NotificationManager) SetRouteProvider(provider RouteProvider) {
+	nm.routing.provider = provider
+	nm.logger.Info("Enrutamiento de notificaciones configurado")
+}
+
+// resolveRoutes evalúa las reglas en orden y devuelve los puntos de contacto de destino.
+// useLegacy indica si deben aplicarse además los canales del umbral (ninguna regla coincidió
+// o la última regla coincidente tiene "continue"). Una regla que solo notifica al equipo
+// propietario no cuenta como coincidencia si la alerta no tiene equipo con puntos de contacto.
+func (nm *NotificationManager) resolveRoutes(alert *models.Alert) (contactPointIDs []uint, useLegacy bool) {
+	if nm.routing.provider == nil {
+		return nil, true
+	}
+
+	routes, err := nm.routing.provider.GetActiveRoutes()
+	if err != nil {
+		nm.logger.Errorf("Error al obtener reglas de enrutamiento: %v", err)
+		return nil, true
+	}
+
+	if len(routes) == 0 {
+		return nil, true
+	}
+
+	groupIDs, err := nm.routing.provider.GetServerGroupIDs(alert.ServerID)
+	if err != nil {
+		nm.logger.Warnf("Error al obtener grupos del servidor %d para enrutamiento: %v", alert.ServerID, err)
+	}
+
+	now := time.Now()
+	seen := make(map[uint]bool)
+
+	var teamContactPointIDs []uint
+	teamLoaded := false
+
+	for i := range routes {
+		route := &routes[i]
+		if !route.Matches(alert, groupIDs, now) {
+			continue
+		}
+
+		targets := route.ContactPointIDs
+		if route.NotifyOwningTeam {
+			if !teamLoaded {
+				teamContactPointIDs = nm.owningTeamContactPointIDs(alert)
+				teamLoaded = true
+			}
+			targets = append(append([]uint{}, targets...), teamContactPointIDs...)
+		}
+
+		if len(targets) == 0 {
+			continue
+		}
+
+		for _, id := range targets {
+			if !seen[id] {
+				seen[id] = true
+				contactPointIDs = append(contactPointIDs, id)
+			}
+		}
+
+		if !route.Continue {
+			return contactPointIDs, false
+		}
+	}
+
+	// Sin coincidencias, o la última coincidencia pide continuar: aplicar la ruta heredada
+	return contactPointIDs, true
+}
+
+// owningTeamContactPointIDs devuelve los puntos de contacto del equipo propietario de la alerta
+func (nm *NotificationManager) owningTeamContactPointIDs(alert *models.Alert) []uint {
+	if alert.OwnerTeamID == nil {
+		return nil
+	}
+
+	ids, err := nm.routing.provider.GetTeamContactPointIDs(*alert.OwnerTeamID)
+	if err != nil {
+		nm.logger.Warnf("Error al obtener puntos de contacto del equipo %d: %v", *alert.OwnerTeamID, err)
+		return nil
+	}
+
+	if len(ids) == 0 {
+		nm.logger.Warnf("El equipo %d propietario de la alerta no tiene puntos de contacto habilitados", *alert.OwnerTeamID)
+	}
+
+	return ids
+}
+
+// contactPointNotifier obtiene (o construye) el notificador asociado a un punto de contacto
+func (nm *NotificationManager) contactPointNotifier(id uint) (*models.ContactPoint, Notifier, error) {
+	cp, err := nm.routing.provider.GetContactPoint(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !cp.Enabled {
+		return cp, nil, fmt.Errorf("punto de contacto %s deshabilitado", cp.Name)
+	}
+
+	nm.routing.mu.Lock()
+	defer nm.routing.mu.Unlock()
+
+	if cached, ok := nm.routing.notifiers[cp.ID]; ok && cached.updatedAt.Equal(cp.UpdatedAt) {
+		return cp, cached.notifier, nil
+	}
+
+	notifier, err := nm.newNotifierForContactPoint(cp)
+	if err != nil {
+		return cp, nil, err
+	}
+
+	nm.routing.notifiers[cp.ID] = cachedNotifier{updatedAt: cp.UpdatedAt, notifier: notifier}
+	return cp, notifier, nil
+}
+
+// newNotifierForContactPoint crea el cliente correspondiente al tipo de punto de contacto
+func (nm *NotificationManager) newNotifierForContactPoint(cp *models.ContactPoint) (Notifier, error) {
+	settings := cp.Settings
+
+	switch cp.Type {
+	case models.ContactPointDiscord:
+		return NewDiscordClient(settings["webhook_url"], settings["username"], settings["avatar_url"], nm.logger), nil
+	case models.ContactPointTelegram:
+		return NewTelegramClient(settings["bot_token"], settings["chat_id"], nm.logger), nil
+	case models.ContactPointNtfy:
+		return NewNtfyClient(settings["server_url"], settings["topic"], settings["token"], nm.logger), nil
+	case models.ContactPointWebhook:
+		return NewWebhookClient(settings["url"], settings["secret"], nm.logger), nil
+	default:
+		return nil, fmt.Errorf("tipo de punto de contacto no soportado: %s", cp.Type)
+	}
+}
+
+// NotifyContactPoints envía una alerta a los puntos de contacto indicados y devuelve los canales utilizados
+func (nm *NotificationManager) NotifyContactPoints(alert *models.Alert, contactPointIDs []uint) []string {
+	var channels []string
+
+	if nm.routing.provider == nil {
+		return channels
+	}
+
+	for _, id := range contactPointIDs {
+		cp, notifier, err := nm.contactPointNotifier(id)
+		if err != nil {
+			nm.logger.Warnf("No se pudo usar el punto de contacto %d: %v", id, err)
+			continue
+		}
+
+		if err := nm.deliver(string(cp.Type), notifier, alert, models.TemplateStateFiring); err != nil {
+			nm.logger.Errorf("Error al enviar alerta al punto de contacto %s: %v", cp.Name, err)
+			continue
+		}
+
+		channels = append(channels, ContactPointChannel(cp.ID))
+	}
+
+	return channels
+}
@@ -0,0 +1,177 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// telegramAPIURL URL base de la API de bots de Telegram
+const telegramAPIURL = "https://api.telegram.org"
+
+// telegramMaxMessage longitud máxima del texto de un mensaje de Telegram
+const telegramMaxMessage = 4096
+
+// errTelegramBadRequest Telegram rechazó el mensaje (p. ej. por un Markdown mal formado)
+var errTelegramBadRequest = errors.New("Telegram rechazó el mensaje")
+
+// TelegramMessage estructura para el método sendMessage de Telegram
+type TelegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
+}
+
+// TelegramClient cliente para enviar notificaciones a través de un bot de Telegram
+type TelegramClient struct {
+	botToken   string
+	chatID     string
+	apiURL     string
+	httpClient *http.Client
+	logger     logger.Logger
+}
+
+// NewTelegramClient crea un nuevo cliente de Telegram
+func NewTelegramClient(botToken, chatID string, log logger.Logger) *TelegramClient {
+	return &TelegramClient{
+		botToken:   botToken,
+		chatID:     chatID,
+		apiURL:     telegramAPIURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     log,
+	}
+}
+
+// GetEmojiForSeverity devuelve el emoji que encabeza el mensaje según la severidad
+func GetEmojiForSeverity(severity models.AlertSeverity) string {
+	switch severity {
+	case models.AlertSeverityCritical:
+		return "🔴"
+	case models.AlertSeverityWarning:
+		return "🟠"
+	case models.AlertSeverityInfo:
+		return "🔵"
+	default:
+		return "⚪"
+	}
+}
+
+// SendAlert envía una alerta a Telegram
+func (tc *TelegramClient) SendAlert(alert *models.Alert) error {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%s *%s*\n", GetEmojiForSeverity(alert.Severity), escapeTelegramMarkdown(alert.Title)))
+	sb.WriteString(fmt.Sprintf("%s\n\n", escapeTelegramMarkdown(alert.Message)))
+	sb.WriteString(fmt.Sprintf("*Servidor:* %s (%s)\n", escapeTelegramMarkdown(alert.Server.Hostname), escapeTelegramMarkdown(alert.Server.IP)))
+	sb.WriteString(fmt.Sprintf("*Métrica:* %s\n", escapeTelegramMarkdown(string(alert.MetricType))))
+	sb.WriteString(fmt.Sprintf("*Valor:* `%.2f` (umbral `%s %.2f`)\n", alert.MetricValue, alert.Operator, alert.Threshold))
+	sb.WriteString(fmt.Sprintf("*Severidad:* %s", strings.ToUpper(string(alert.Severity))))
+
+	if err := tc.sendMessage(sb.String()); err != nil {
+		tc.logger.Errorf("Error al enviar alerta a Telegram: %v", err)
+		return err
+	}
+
+	tc.logger.Infof("Alerta #%d enviada exitosamente a Telegram", alert.ID)
+	return nil
+}
+
+// SendResolvedAlert envía una notificación de alerta resuelta
+func (tc *TelegramClient) SendResolvedAlert(alert *models.Alert) error {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("✅ *RESUELTA:* %s\n", escapeTelegramMarkdown(alert.Title)))
+	sb.WriteString(fmt.Sprintf("*Servidor:* %s\n", escapeTelegramMarkdown(alert.Server.Hostname)))
+	if alert.ResolvedAt != nil {
+		sb.WriteString(fmt.Sprintf("*Duración:* %s", getDurationText(alert.TriggeredAt, *alert.ResolvedAt)))
+	}
+
+	return tc.sendMessage(sb.String())
+}
+
+// SendRendered envía a Telegram un mensaje generado a partir de una plantilla.
+// El cuerpo se envía tal cual para que la plantilla pueda usar formato Markdown (con md para
+// escapar los datos). Si Telegram rechaza el formato, se reenvía como texto sin formato
+func (tc *TelegramClient) SendRendered(alert *models.Alert, state models.TemplateState, msg *RenderedMessage) error {
+	emoji := GetEmojiForSeverity(alert.Severity)
+	if state == models.TemplateStateResolved {
+		emoji = "✅"
+	}
+
+	text, plain := msg.Body, msg.Body
+	if msg.Title != "" {
+		text = fmt.Sprintf("%s *%s*\n%s", emoji, escapeTelegramMarkdown(msg.Title), msg.Body)
+		plain = fmt.Sprintf("%s %s\n%s", emoji, msg.Title, msg.Body)
+	}
+
+	err := tc.send(truncateText(text, telegramMaxMessage), "Markdown")
+	if errors.Is(err, errTelegramBadRequest) {
+		// Un carácter reservado en los datos de la alerta (notas, hostnames, expresiones...)
+		// invalida el Markdown: mejor sin formato que perder la notificación
+		tc.logger.Warnf("Telegram rechazó el formato del mensaje de la alerta #%d, se reenvía sin formato", alert.ID)
+		err = tc.send(truncateText(plain, telegramMaxMessage), "")
+	}
+	return err
+}
+
+// sendMessage envía un texto con formato Markdown al chat configurado
+func (tc *TelegramClient) sendMessage(text string) error {
+	return tc.send(text, "Markdown")
+}
+
+// send envía un texto al chat configurado con el modo de formato indicado (vacío = sin formato)
+func (tc *TelegramClient) send(text, parseMode string) error {
+	message := TelegramMessage{
+		ChatID:                tc.chatID,
+		Text:                  text,
+		ParseMode:             parseMode,
+		DisableWebPagePreview: true,
+	}
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", tc.apiURL, tc.botToken)
+	resp, err := tc.httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		// El error de la petición incluye la URL, que contiene el token del bot: solo se
+		// devuelve la causa
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("error al conectar con Telegram: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return fmt.Errorf("%w. Código: %d", errTelegramBadRequest, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error en respuesta de Telegram. Código: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// escapeTelegramMarkdown escapa los caracteres reservados del modo Markdown de Telegram
+func escapeTelegramMarkdown(text string) string {
+	replacer := strings.NewReplacer(
+		"_", "\\_",
+		"*", "\\*",
+		"`", "\\`",
+		"[", "\\[",
+	)
+	return replacer.Replace(text)
+}
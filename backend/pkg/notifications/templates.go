@@ -0,0 +1,339 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+)
+
+// TemplateData datos disponibles dentro de una plantilla de notificación
+type TemplateData struct {
+	State          models.TemplateState
+	Alert          *models.Alert
+	Server         *models.Server
+	Threshold      *models.AlertThreshold
+	Metric         *models.Metric   // Última métrica del servidor (puede ser nil)
+	MetricName     string           // Nombre legible de la métrica
+	ServerName     string           // Hostname o "Servidor #ID" si no se conoce
+	Duration       string           // Duración de la alerta (resuelta o en curso)
+	AcknowledgedBy string           // Usuario que reconoció la alerta
+	Incident       *models.Incident // Incidente si la notificación es el resumen de un incidente
+}
+
+// RenderedMessage título y cuerpo resultantes de aplicar una plantilla
+type RenderedMessage struct {
+	Title string
+	Body  string
+}
+
+// MessageNotifier notificadores capaces de enviar un mensaje ya renderizado con una plantilla
+type MessageNotifier interface {
+	SendRendered(alert *models.Alert, state models.TemplateState, msg *RenderedMessage) error
+}
+
+// TemplateProvider proporciona las plantillas almacenadas y los datos para renderizarlas
+type TemplateProvider interface {
+	// GetTemplate devuelve la plantilla habilitada para el canal y estado, o nil si no existe
+	GetTemplate(channel string, state models.TemplateState) (*models.NotificationTemplate, error)
+
+	// BuildTemplateData reúne servidor, umbral y última métrica de una alerta
+	BuildTemplateData(alert *models.Alert, state models.TemplateState) (*TemplateData, error)
+}
+
+// DefaultTemplates plantillas integradas para el canal "default" cuando no hay una almacenada
+var DefaultTemplates = map[models.TemplateState]RenderedMessage{
+	models.TemplateStateFiring: {
+		Title: `{{if .Incident}}{{.Alert.Title}}{{else}}Alerta: {{.MetricName}} en {{.ServerName}}{{end}}`,
+		Body: `{{if .Incident -}}
+{{.Alert.Message}}
+{{- else if and .Threshold .Threshold.Expression -}}
+Se cumple la condición {{.Threshold.Expression}}
+{{- else if and .Threshold .Threshold.IsTrend -}}
+{{.Alert.Message}}
+{{- else -}}
+La métrica {{.MetricName}} ha alcanzado un valor de {{printf "%.2f" .Alert.MetricValue}}%, superando el umbral establecido de {{printf "%.2f" .Alert.Threshold}}%
+{{- end}}`,
+	},
+	models.TemplateStateResolved: {
+		Title: `✅ RESUELTA: {{.Alert.Title}}`,
+		Body: `La alerta ha sido resuelta tras {{.Duration}}:
+{{.Alert.Message}}`,
+	},
+	models.TemplateStateAcknowledged: {
+		Title: `👀 RECONOCIDA: {{.Alert.Title}}`,
+		Body: `{{if .AcknowledgedBy}}{{.AcknowledgedBy}}{{else}}Un operador{{end}} ha reconocido la alerta.
+{{- if .Alert.Notes}}
+Notas: {{.Alert.Notes}}{{end}}`,
+	},
+}
+
+// templateFuncs funciones auxiliares disponibles en las plantillas
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"bytes": FormatBytes,
+	"percent": func(part, total int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(part) / float64(total) * 100
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"md": escapeTelegramMarkdown, // Escapa los datos interpolados en plantillas con formato Markdown de Telegram
+}
+
+// truncateText limita un texto a max caracteres, terminándolo en "…" si se recorta
+func truncateText(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// ParseTemplate compila una plantilla y devuelve un error legible si es inválida
+func ParseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("plantilla %s inválida: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// RenderTemplate compila y ejecuta una plantilla con los datos indicados
+func RenderTemplate(name, text string, data *TemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := ParseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error al renderizar plantilla %s: %v", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// RenderMessage renderiza título y cuerpo de una plantilla
+func RenderMessage(titleTemplate, bodyTemplate string, data *TemplateData) (*RenderedMessage, error) {
+	title, err := RenderTemplate("title", titleTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := RenderTemplate("body", bodyTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenderedMessage{Title: title, Body: body}, nil
+}
+
+// NewTemplateData construye los datos de plantilla derivados de una alerta
+func NewTemplateData(alert *models.Alert, state models.TemplateState) *TemplateData {
+	data := &TemplateData{
+		State:      state,
+		Alert:      alert,
+		Server:     &alert.Server,
+		MetricName: alert.MetricType.DisplayName(),
+		ServerName: alert.Server.Hostname,
+		Incident:   alert.Incident,
+	}
+
+	if data.ServerName == "" {
+		data.ServerName = fmt.Sprintf("Servidor #%d", alert.ServerID)
+	}
+
+	if alert.ResolvedAt != nil {
+		data.Duration = getDurationText(alert.TriggeredAt, *alert.ResolvedAt)
+	} else if !alert.TriggeredAt.IsZero() {
+		data.Duration = getDurationText(alert.TriggeredAt, time.Now())
+	}
+
+	return data
+}
+
+// SetTemplateProvider configura el proveedor de plantillas de notificación
+func (nm *NotificationManager) SetTemplateProvider(provider TemplateProvider) {
+	nm.templates = provider
+	nm.logger.Info("Plantillas de notificación configuradas")
+}
+
+// deliver envía una notificación a un canal aplicando la plantilla almacenada para su tipo
+// y estado. Sin plantilla se usa el formato integrado de cada notificador
+func (nm *NotificationManager) deliver(channelType string, notifier Notifier, alert *models.Alert, state models.TemplateState) error {
+	if mn, ok := notifier.(MessageNotifier); ok {
+		msg, err := nm.renderForChannel(channelType, alert, state)
+		if err != nil {
+			nm.logger.Warnf("Error al aplicar plantilla %s/%s, se usa el formato por defecto: %v", channelType, state, err)
+		} else if msg != nil {
+			return mn.SendRendered(alert, state, msg)
+		}
+	}
+
+	switch state {
+	case models.TemplateStateFiring:
+		return notifier.SendAlert(alert)
+	case models.TemplateStateResolved:
+		return notifier.SendResolvedAlert(alert)
+	}
+
+	return nil
+}
+
+// renderForChannel renderiza la plantilla del canal para el estado indicado. Devuelve nil si
+// no hay plantilla y el notificador dispone de un formato integrado para ese estado
+func (nm *NotificationManager) renderForChannel(channelType string, alert *models.Alert, state models.TemplateState) (*RenderedMessage, error) {
+	var stored *models.NotificationTemplate
+	if nm.templates != nil {
+		var err error
+		if stored, err = nm.templates.GetTemplate(channelType, state); err != nil {
+			return nil, err
+		}
+
+		// Los notificadores no tienen formato propio para el reconocimiento
+		if stored == nil && state == models.TemplateStateAcknowledged {
+			if stored, err = nm.templates.GetTemplate(models.TemplateChannelDefault, state); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var titleTemplate, bodyTemplate string
+	switch {
+	case stored != nil:
+		titleTemplate, bodyTemplate = stored.TitleTemplate, stored.BodyTemplate
+	case state == models.TemplateStateAcknowledged:
+		def := DefaultTemplates[state]
+		titleTemplate, bodyTemplate = def.Title, def.Body
+	default:
+		return nil, nil
+	}
+
+	data := NewTemplateData(alert, state)
+	if nm.templates != nil {
+		built, err := nm.templates.BuildTemplateData(alert, state)
+		if err != nil {
+			return nil, err
+		}
+		data = built
+	}
+
+	return RenderMessage(titleTemplate, bodyTemplate, data)
+}
+
+// stateLabel nombre legible del tipo de notificación para los logs
+func stateLabel(state models.TemplateState) string {
+	switch state {
+	case models.TemplateStateResolved:
+		return "resolución"
+	case models.TemplateStateAcknowledged:
+		return "reconocimiento"
+	default:
+		return "alerta"
+	}
+}
+
+// SampleTemplateData genera datos de ejemplo para previsualizar plantillas
+func SampleTemplateData(state models.TemplateState) *TemplateData {
+	now := time.Now()
+	serverID := uint(1)
+
+	server := models.Server{
+		ID:       serverID,
+		Hostname: "web-01.example.com",
+		IP:       "192.168.1.10",
+		OS:       "Ubuntu 22.04",
+		Location: "Madrid-DC1",
+		Tags:     []string{"produccion", "web"},
+	}
+
+	threshold := &models.AlertThreshold{
+		ID:              1,
+		Name:            "CPU crítico",
+		MetricType:      models.MetricTypeCPU,
+		Operator:        ">",
+		Value:           90,
+		Severity:        models.AlertSeverityCritical,
+		CooldownMinutes: 15,
+		ServerID:        &serverID,
+		Enabled:         true,
+	}
+
+	alert := &models.Alert{
+		ID:          42,
+		Title:       "Alerta: CPU en web-01.example.com",
+		Message:     "La métrica CPU ha alcanzado un valor de 97.30%, superando el umbral establecido de 90.00%",
+		MetricType:  models.MetricTypeCPU,
+		MetricValue: 97.3,
+		Threshold:   90,
+		Operator:    ">",
+		Severity:    models.AlertSeverityCritical,
+		Status:      models.AlertStatusActive,
+		ServerID:    serverID,
+		Server:      server,
+		ThresholdID: threshold.ID,
+		TriggeredAt: now.Add(-25 * time.Minute),
+	}
+
+	switch state {
+	case models.TemplateStateResolved:
+		alert.Status = models.AlertStatusResolved
+		alert.ResolvedAt = &now
+	case models.TemplateStateAcknowledged:
+		userID := uint(1)
+		alert.Status = models.AlertStatusAcknowledged
+		alert.AcknowledgedAt = &now
+		alert.AcknowledgedBy = &userID
+		alert.Notes = "Investigando el pico de CPU"
+	}
+
+	metric := &models.Metric{
+		ServerID:    serverID,
+		Timestamp:   now,
+		CPUUsage:    97.3,
+		LoadAvg1:    7.8,
+		MemoryTotal: 16 * 1024 * 1024 * 1024,
+		MemoryUsed:  11 * 1024 * 1024 * 1024,
+		MemoryFree:  5 * 1024 * 1024 * 1024,
+		DiskTotal:   500 * 1024 * 1024 * 1024,
+		DiskUsed:    320 * 1024 * 1024 * 1024,
+		DiskFree:    180 * 1024 * 1024 * 1024,
+	}
+
+	data := NewTemplateData(alert, state)
+	data.Threshold = threshold
+	data.Metric = metric
+	if state == models.TemplateStateAcknowledged {
+		data.AcknowledgedBy = "admin"
+	}
+
+	return data
+}
+
+// FormatBytes convierte bytes a una representación legible
+func FormatBytes(value int64) string {
+	const unit = 1024
+	if value < unit {
+		return fmt.Sprintf("%d B", value)
+	}
+
+	div, exp := int64(unit), 0
+	for n := value / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(value)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jminat01/dashboard-servers-go/backend/internal/models"
+	"github.com/jminat01/dashboard-servers-go/backend/pkg/logger"
+)
+
+// WebhookPayload cuerpo JSON enviado a los webhooks genéricos
+type WebhookPayload struct {
+	Status string        `json:"status"` // firing | resolved | acknowledged
+	Title  string        `json:"title,omitempty"`
+	Body   string        `json:"body,omitempty"`
+	Alert  *models.Alert `json:"alert"`
+	SentAt time.Time     `json:"sent_at"`
+}
+
+// WebhookClient cliente para enviar notificaciones a un webhook HTTP genérico
+type WebhookClient struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+	logger     logger.Logger
+}
+
+// NewWebhookClient crea un nuevo cliente de webhook. Si secret no está vacío,
+// cada petición se firma con HMAC-SHA256 en la cabecera X-Signature
+func NewWebhookClient(url, secret string, log logger.Logger) *WebhookClient {
+	return &WebhookClient{
+		url:        url,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     log,
+	}
+}
+
+// SendAlert envía una alerta al webhook
+func (wc *WebhookClient) SendAlert(alert *models.Alert) error {
+	if err := wc.post(&WebhookPayload{Status: "firing", Alert: alert, SentAt: time.Now()}); err != nil {
+		wc.logger.Errorf("Error al enviar alerta al webhook: %v", err)
+		return err
+	}
+
+	wc.logger.Infof("Alerta #%d enviada exitosamente al webhook", alert.ID)
+	return nil
+}
+
+// SendResolvedAlert envía una notificación de alerta resuelta
+func (wc *WebhookClient) SendResolvedAlert(alert *models.Alert) error {
+	return wc.post(&WebhookPayload{Status: "resolved", Alert: alert, SentAt: time.Now()})
+}
+
+// SendRendered envía al webhook un mensaje generado a partir de una plantilla
+func (wc *WebhookClient) SendRendered(alert *models.Alert, state models.TemplateState, msg *RenderedMessage) error {
+	return wc.post(&WebhookPayload{
+		Status: string(state),
+		Title:  msg.Title,
+		Body:   msg.Body,
+		Alert:  alert,
+		SentAt: time.Now(),
+	})
+}
+
+// post serializa y envía el payload
+func (wc *WebhookClient) post(payload *WebhookPayload) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, wc.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if wc.secret != "" {
+		mac := hmac.New(sha256.New, []byte(wc.secret))
+		mac.Write(jsonData)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := wc.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error en respuesta del webhook. Código: %d", resp.StatusCode)
+	}
+
+	return nil
+}
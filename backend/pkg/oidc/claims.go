@@ -0,0 +1,92 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookupClaim busca un claim por nombre. Admite rutas con puntos para claims anidados
+// (p. ej. "realm_access.roles"); un nombre que exista tal cual tiene prioridad
+func lookupClaim(claims map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := claims[name]; ok {
+		return value, true
+	}
+
+	parts := strings.Split(name, ".")
+	if len(parts) == 1 {
+		return nil, false
+	}
+
+	var current interface{} = claims
+	for _, part := range parts {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// StringClaim obtiene un claim como texto ("" si no existe o no es un valor simple)
+func StringClaim(claims map[string]interface{}, name string) string {
+	value, ok := lookupClaim(claims, name)
+	if !ok || value == nil {
+		return ""
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool, float64:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
+
+// BoolClaim obtiene un claim booleano (acepta también "true" como texto)
+func BoolClaim(claims map[string]interface{}, name string) bool {
+	value, _ := lookupClaim(claims, name)
+
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
+
+// StringsClaim obtiene un claim de lista de textos. Un texto simple se interpreta como una
+// lista de un elemento o, si contiene comas, separada por comas
+func StringsClaim(claims map[string]interface{}, name string) ([]string, bool) {
+	value, ok := lookupClaim(claims, name)
+	if !ok || value == nil {
+		return nil, false
+	}
+
+	var values []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = v
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+
+	return values, true
+}
@@ -0,0 +1,371 @@
+// Package oidc implementa el cliente de OpenID Connect para el flujo authorization code con
+// PKCE: descubrimiento del proveedor, intercambio del código y verificación del ID token
+// contra las claves publicadas en el JWKS del proveedor.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultScopes scopes solicitados si no se configuran otros
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+// Errores del cliente OIDC
+var (
+	ErrInvalidIDToken = errors.New("ID token inválido")
+	ErrNonceMismatch  = errors.New("el nonce del ID token no coincide")
+)
+
+// Tiempos del cliente
+const (
+	httpTimeout       = 10 * time.Second
+	clockSkew         = time.Minute      // Tolerancia de reloj al validar exp, iat y nbf
+	jwksRefreshMin    = 30 * time.Second // Espera mínima entre descargas del JWKS por claves desconocidas
+	maxResponseLength = 1 << 20
+)
+
+// signingMethods algoritmos de firma aceptados para el ID token (nunca "none" ni HMAC)
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Config configuración del cliente OIDC
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // Vacío para clientes públicos (solo PKCE)
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// Discovery metadatos publicados por el proveedor en /.well-known/openid-configuration
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// TokenResponse respuesta del endpoint de tokens
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// IDToken ID token verificado
+type IDToken struct {
+	Issuer  string
+	Subject string
+	Expiry  time.Time
+	Claims  map[string]interface{}
+}
+
+// Provider cliente de un proveedor OIDC. El descubrimiento se hace en el primer uso y el
+// JWKS se vuelve a descargar cuando aparece una clave desconocida (rotación de claves)
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *Discovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// NewProvider crea un cliente para el proveedor indicado
+func NewProvider(config Config) *Provider {
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = DefaultScopes
+	}
+	config.IssuerURL = strings.TrimSuffix(config.IssuerURL, "/")
+
+	return &Provider{
+		config: config,
+		client: client,
+	}
+}
+
+// Discover obtiene (y guarda) los metadatos del proveedor
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery Discovery
+	if err := p.getJSON(ctx, p.config.IssuerURL+"/.well-known/openid-configuration", "", &discovery); err != nil {
+		return nil, fmt.Errorf("error en el descubrimiento OIDC: %w", err)
+	}
+
+	// El emisor publicado debe coincidir con el configurado
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.config.IssuerURL {
+		return nil, fmt.Errorf("el emisor del descubrimiento (%s) no coincide con el configurado (%s)", discovery.Issuer, p.config.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("el descubrimiento OIDC no incluye los endpoints necesarios")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// AuthCodeURL construye la URL de autorización con state, nonce y el reto PKCE (S256)
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", PKCEChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange canjea el código de autorización por los tokens, enviando el verificador PKCE
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*TokenResponse, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		// client_secret_basic: las credenciales se codifican como formulario (RFC 6749, 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error al canjear el código: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseLength))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return nil, fmt.Errorf("el proveedor rechazó el código: %s %s", oauthErr.Error, oauthErr.Description)
+		}
+		return nil, fmt.Errorf("el proveedor respondió %d al canjear el código", resp.StatusCode)
+	}
+
+	var tokens TokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("respuesta de tokens inválida: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("la respuesta del proveedor no incluye ID token")
+	}
+
+	return &tokens, nil
+}
+
+// VerifyIDToken verifica la firma del ID token con el JWKS del proveedor, su emisor,
+// audiencia, caducidad y el nonce de la petición
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.verificationKey(ctx, discovery.JWKSURI, kid)
+		},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	// Con varias audiencias, azp debe identificar a este cliente
+	if audiences, _ := claims.GetAudience(); len(audiences) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.config.ClientID {
+			return nil, fmt.Errorf("%w: azp no coincide con el cliente", ErrInvalidIDToken)
+		}
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: falta el claim sub", ErrInvalidIDToken)
+	}
+
+	idToken := &IDToken{
+		Issuer:  discovery.Issuer,
+		Subject: subject,
+		Claims:  claims,
+	}
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		idToken.Expiry = exp.Time
+	}
+
+	return idToken, nil
+}
+
+// UserInfo obtiene los claims del endpoint userinfo (vacío si el proveedor no lo publica)
+func (p *Provider) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.UserInfoEndpoint == "" || accessToken == "" {
+		return map[string]interface{}{}, nil
+	}
+
+	claims := map[string]interface{}{}
+	if err := p.getJSON(ctx, discovery.UserInfoEndpoint, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("error al obtener userinfo: %w", err)
+	}
+
+	return claims, nil
+}
+
+// verificationKey devuelve la clave pública del JWKS para el kid indicado. Si no se conoce,
+// vuelve a descargar el JWKS (como mucho una vez cada jwksRefreshMin)
+func (p *Provider) verificationKey(ctx context.Context, jwksURI, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	if p.keys != nil && time.Since(p.keysFetchedAt) < jwksRefreshMin {
+		return nil, fmt.Errorf("clave de firma desconocida: %q", kid)
+	}
+
+	var set jsonWebKeySet
+	if err := p.getJSON(ctx, jwksURI, "", &set); err != nil {
+		return nil, fmt.Errorf("error al obtener el JWKS: %w", err)
+	}
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = time.Now()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("clave de firma desconocida: %q", kid)
+}
+
+// lookupKey busca una clave por kid; sin kid solo se acepta si el JWKS tiene una única clave
+func (p *Provider) lookupKey(kid string) interface{} {
+	if kid != "" {
+		return p.keys[kid]
+	}
+
+	if len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// getJSON hace un GET y decodifica la respuesta JSON
+func (p *Provider) getJSON(ctx context.Context, endpoint, bearer string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s respondió %d", endpoint, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseLength)).Decode(target)
+}
+
+// RandomString genera un valor aleatorio codificado en base64url (state, nonce, verificador)
+func RandomString(bytes int) (string, error) {
+	buf := make([]byte, bytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// PKCEChallenge calcula el reto S256 de un verificador PKCE
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
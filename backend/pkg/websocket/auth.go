@@ -22,8 +22,9 @@ var upgrader = websocket.Upgrader{
 
 // WSAuthMiddleware proporciona autenticación para conexiones WebSocket
 type WSAuthMiddleware struct {
-	authService interfaces.AuthServiceInterface
-	log         logger.Logger
+	authService   interfaces.AuthServiceInterface
+	accessChecker interfaces.ServerAccessInterface
+	log           logger.Logger
 }
 
 // NewWSAuthMiddleware crea un nuevo middleware de autenticación WebSocket
@@ -34,6 +35,11 @@ func NewWSAuthMiddleware(authService interfaces.AuthServiceInterface, log logger
 	}
 }
 
+// SetAccessChecker establece la comprobación de acceso del usuario al servidor solicitado
+func (m *WSAuthMiddleware) SetAccessChecker(accessChecker interfaces.ServerAccessInterface) {
+	m.accessChecker = accessChecker
+}
+
 // Authenticate verifica el token JWT en el header de la petición
 func (m *WSAuthMiddleware) Authenticate(c *gin.Context) (uint, bool) {
 	// Extraer token del header Authorization
@@ -96,6 +102,21 @@ func HandleWSConnection(c *gin.Context, hub *Hub, authMiddleware *WSAuthMiddlewa
 		return
 	}
 
+	// Verificar que el usuario puede ver el servidor según sus permisos de grupo
+	if authMiddleware.accessChecker != nil {
+		allowed, err := authMiddleware.accessChecker.CanViewServer(userID, uint(serverID))
+		if err != nil {
+			authMiddleware.log.Errorf("Error al comprobar el acceso al servidor %d: %v", serverID, err)
+			c.String(http.StatusInternalServerError, "Error interno del servidor")
+			return
+		}
+		if !allowed {
+			authMiddleware.log.Warnf("Acceso denegado: usuario %d sin permiso sobre el servidor %d", userID, serverID)
+			c.String(http.StatusForbidden, "Acceso denegado")
+			return
+		}
+	}
+
 	// Actualizar a conexión WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -54,9 +54,7 @@ const uiService = {
    */
   setupRegisterForm() {
     const registerForm = document.getElementById("registerForm");
-    const registerUsername = document.getElementById("registerUsername");
-    const registerPassword = document.getElementById("registerPassword");
-    const registerConfirm = document.getElementById("registerConfirm");
+    const registerEmail = document.getElementById("registerEmail");
     
     if (registerForm) {
       // Añadir evento de envío con tecla Enter
@@ -67,11 +65,8 @@ const uiService = {
       });
       
       // Limpiar errores al escribir
-      const inputs = [registerUsername, registerPassword, registerConfirm];
-      inputs.forEach(input => {
-        input?.addEventListener("input", () => {
-          document.getElementById("registerError").classList.add("hidden");
-        });
+      registerEmail?.addEventListener("input", () => {
+        document.getElementById("registerError").classList.add("hidden");
       });
     }
   },
@@ -193,39 +188,24 @@ const uiService = {
    * Maneja el envío del formulario de registro
    */
   async handleRegister() {
-    const username = document.getElementById("registerUsername").value.trim();
-    const password = document.getElementById("registerPassword").value;
-    const confirmPass = document.getElementById("registerConfirm").value;
+    const email = document.getElementById("registerEmail").value.trim();
 
     // Validar campos
-    if (!username || !password) {
-      this.showRegisterError("Por favor, completa todos los campos");
-      return;
-    }
-
-    if (password !== confirmPass) {
-      this.showRegisterError("Las contraseñas no coinciden");
+    if (!email) {
+      this.showRegisterError("Por favor, introduce tu email");
       return;
     }
 
     try {
-      await authService.register(username, password);
+      const data = await authService.register(email);
       
-      this.showRegisterSuccess("Usuario registrado correctamente. Puedes iniciar sesión.");
+      this.showRegisterSuccess(data.message || "Revisa tu correo para completar el registro.");
       
       // Limpiar formulario
-      document.getElementById("registerUsername").value = "";
-      document.getElementById("registerPassword").value = "";
-      document.getElementById("registerConfirm").value = "";
-
-      // Mostrar login después de 2 segundos
-      setTimeout(() => {
-        this.showLoginForm();
-        document.getElementById("registerSuccess").classList.add("hidden");
-      }, 2000);
+      document.getElementById("registerEmail").value = "";
     } catch (error) {
       this.showRegisterError(
-        error.response?.data?.message || "Error al registrar usuario"
+        error.response?.data?.error || "El registro está deshabilitado: solicita una invitación a un administrador"
       );
     }
   },